@@ -0,0 +1,81 @@
+package seriatim
+
+import (
+	"runtime/metrics"
+	"sync/atomic"
+)
+
+// allocMetrics are the runtime metrics read around the methods of
+// sequents created WithAllocSampling.
+var allocMetrics = [...]string{
+	"/gc/heap/allocs:bytes",
+	"/gc/heap/allocs:objects",
+}
+
+// WithAllocSampling estimates the memory allocated by the methods of
+// the sequent, reported by Stats, by reading the allocation counters
+// of the runtime around one in every n requests processed. The
+// counters are process wide, so allocations made by other goroutines
+// meanwhile are counted too, and small allocations are only counted
+// once the runtime refills its caches; the estimate is meant to point
+// at the sequents allocating the most rather than to be exact. A
+// lower n is more accurate but slower; n below 1 samples every
+// request.
+func WithAllocSampling(n int) Option {
+	return func(a *sequent) {
+		if n < 1 {
+			n = 1
+		}
+		a.allocEvery = uint64(n)
+	}
+}
+
+// allocSample holds the allocation counters when a sampled method
+// started.
+type allocSample struct {
+	bytes, objects uint64
+	sampled        bool
+}
+
+func readAllocs() (bytes, objects uint64, ok bool) {
+	var samples [len(allocMetrics)]metrics.Sample
+	for i, name := range allocMetrics {
+		samples[i].Name = name
+	}
+	metrics.Read(samples[:])
+	for _, sample := range samples {
+		if sample.Value.Kind() != metrics.KindUint64 {
+			return 0, 0, false
+		}
+	}
+	return samples[0].Value.Uint64(), samples[1].Value.Uint64(), true
+}
+
+// sampleAllocs starts accounting for the allocations of the method
+// about to run if it is one to be sampled. It is only called by the
+// sequent's goroutine.
+func (a *sequent) sampleAllocs() allocSample {
+	if a.allocEvery == 0 {
+		return allocSample{}
+	}
+	a.allocTick++
+	if a.allocTick%a.allocEvery != 0 {
+		return allocSample{}
+	}
+	bytes, objects, ok := readAllocs()
+	return allocSample{bytes: bytes, objects: objects, sampled: ok}
+}
+
+// recordAllocs adds the allocations made since start, scaled to the
+// requests not sampled, to the counters of Stats.
+func (a *sequent) recordAllocs(start allocSample) {
+	if !start.sampled {
+		return
+	}
+	bytes, objects, ok := readAllocs()
+	if !ok {
+		return
+	}
+	atomic.AddUint64(&a.allocBytes, (bytes-start.bytes)*a.allocEvery)
+	atomic.AddUint64(&a.allocObjects, (objects-start.objects)*a.allocEvery)
+}
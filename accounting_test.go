@@ -0,0 +1,57 @@
+package seriatim
+
+import "testing"
+
+type allocator struct {
+	kept []byte
+}
+
+func (a *allocator) Alloc(n int) {
+	// large enough for the runtime to count it right away
+	a.kept = make([]byte, n)
+}
+
+func TestAllocSampling(t *testing.T) {
+	const size = 1 << 20
+	s := NewSequent(&allocator{}, WithAllocSampling(1))
+	defer s.Terminate(nil)
+	if _, err := s.Call("Alloc", size); err != nil {
+		t.Fatal(err)
+	}
+	stats := s.Stats()
+	if stats.AllocBytes < size || stats.Allocs < 1 {
+		t.Fatalf("expected at least %d bytes in an object, got %d in %d",
+			size, stats.AllocBytes, stats.Allocs)
+	}
+	if stats.ProcessTime <= 0 {
+		t.Fatalf("expected the time the method ran, got %v",
+			stats.ProcessTime)
+	}
+
+	// only the second call is sampled and counts for both
+	s = NewSequent(&allocator{}, WithAllocSampling(2))
+	defer s.Terminate(nil)
+	if _, err := s.Call("Alloc", 0); err != nil {
+		t.Fatal(err)
+	}
+	if stats := s.Stats(); stats.AllocBytes != 0 {
+		t.Fatalf("expected the first call not to be sampled, got %d bytes",
+			stats.AllocBytes)
+	}
+	if _, err := s.Call("Alloc", size); err != nil {
+		t.Fatal(err)
+	}
+	if stats := s.Stats(); stats.AllocBytes < 2*size {
+		t.Fatalf("expected at least %d bytes, got %d", 2*size,
+			stats.AllocBytes)
+	}
+
+	s = NewSequent(&allocator{})
+	defer s.Terminate(nil)
+	if _, err := s.Call("Alloc", size); err != nil {
+		t.Fatal(err)
+	}
+	if stats := s.Stats(); stats.AllocBytes != 0 || stats.Allocs != 0 {
+		t.Fatalf("expected no accounting without sampling, got %+v", stats)
+	}
+}
@@ -0,0 +1,83 @@
+package seriatim
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBatchSize is the largest batch handed to a BatchHandler unless
+// set with WithBatchSize.
+const DefaultBatchSize = 16
+
+// BatchHandler is implemented by values that process casts more
+// cheaply together, such as ones writing to a database or emitting
+// coalesced signals. A sequent whose value implements it hands
+// HandleBatch the casts queued in its mailbox instead of calling their
+// methods, in the order they were cast and no more than the batch size
+// at a time. Calls, and casts made with CastNotify, are still
+// processed one at a time in order with the batches.
+type BatchHandler interface {
+	HandleBatch(batch []Invocation)
+}
+
+// WithBatchSize sets the largest batch handed to a BatchHandler. Sizes
+// below 1 are ignored.
+func WithBatchSize(n int) Option {
+	return func(a *sequent) {
+		if n > 0 {
+			a.batchSize = n
+		}
+	}
+}
+
+// batchable reports whether req may be processed as part of a batch.
+func (req *request) batchable() bool {
+	return req.reply == nil && req.errs == nil && req.then == nil &&
+		req.deadline.IsZero()
+}
+
+// collectBatch adds the batchable requests queued behind first to its
+// batch. A request that cannot be batched ends the batch and is
+// returned as next.
+func (a *sequent) collectBatch(first *request) (batch []*request, next *request) {
+	batch = []*request{first}
+	for len(batch) < a.batchSize {
+		select {
+		case msg, ok := <-a.queue.Dequeue():
+			if !ok {
+				return batch, nil
+			}
+			req := msg.(*request)
+			if !req.batchable() || !a.accepts(req) {
+				return batch, req
+			}
+			batch = append(batch, req)
+		default:
+			return batch, nil
+		}
+	}
+	return batch, nil
+}
+
+func (a *sequent) processBatch(h BatchHandler, batch []*request) {
+	invocations := make([]Invocation, 0, len(batch))
+	for _, req := range batch {
+		invocations = append(invocations, Invocation{
+			Method: req.name,
+			Args:   processMethodReturns(callerArgs(req)),
+		})
+	}
+	started := time.Now()
+	allocs := a.sampleAllocs()
+	// the casts of a batch have no one to report a panic to
+	a.guard("HandleBatch", func() {
+		h.HandleBatch(invocations)
+		a.publish()
+	})
+	a.recordAllocs(allocs)
+	share := time.Since(started) / time.Duration(len(batch))
+	for _, req := range batch {
+		a.record(req, started, share)
+	}
+	atomic.AddUint64(&a.processed, uint64(len(batch)))
+}
@@ -0,0 +1,75 @@
+package seriatim
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type batcher struct {
+	started chan struct{}
+	release chan struct{}
+	log     []string
+	batches []int
+}
+
+func (b *batcher) Wait() {
+	close(b.started)
+	<-b.release
+}
+
+func (b *batcher) Add(s string) {
+	b.log = append(b.log, s)
+}
+
+func (b *batcher) Log() []string {
+	return b.log
+}
+
+func (b *batcher) HandleBatch(batch []Invocation) {
+	b.batches = append(b.batches, len(batch))
+	for _, inv := range batch {
+		b.log = append(b.log, inv.Method+":"+inv.Args[0].(string))
+	}
+}
+
+func TestSequentBatch(t *testing.T) {
+	b := &batcher{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	s := NewSequent(b, WithMailboxSize(16), WithBatchSize(4))
+	defer s.Terminate(nil)
+	go s.Call("Wait")
+	<-b.started
+	for _, v := range []string{"a", "b", "c", "d", "e"} {
+		s.Cast("Add", v)
+	}
+	done := make(chan struct{})
+	go func() {
+		s.Call("Add", "call")
+		close(done)
+	}()
+	for s.Stats().QueueLen != 6 {
+		time.Sleep(time.Millisecond)
+	}
+	s.Cast("Add", "f")
+	close(b.release)
+	<-done
+
+	rets, err := s.Call("Log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"Add:a", "Add:b", "Add:c", "Add:d", "Add:e",
+		"call", "Add:f"}
+	if !reflect.DeepEqual(rets[0], want) {
+		t.Fatalf("got %v, want %v", rets[0], want)
+	}
+	if !reflect.DeepEqual(b.batches, []int{4, 1, 1}) {
+		t.Fatalf("unexpected batches %v", b.batches)
+	}
+	if s.Stats().Processed != 9 {
+		t.Fatalf("processed %d, want 9", s.Stats().Processed)
+	}
+}
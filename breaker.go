@@ -0,0 +1,163 @@
+package seriatim
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrCircuitOpen = errors.New("Circuit open")
+
+// CircuitBreaker is a Sequent failing fast with ErrCircuitOpen once the
+// sequent it wraps has failed Threshold Calls in a row, such as one
+// fronting a device that went away. A Call fails if its method returns
+// a non-nil error as its last result, it times out or the sequent has
+// stopped; invalid Calls do not count. Once ProbeInterval has passed a single
+// Call is let through, closing the circuit again if it succeeds.
+//
+// Casts are not counted but are rejected while the circuit is open.
+type CircuitBreaker struct {
+	Sequent
+	Threshold     int
+	ProbeInterval time.Duration
+	// Now returns the current time, time.Now if nil.
+	Now func() time.Time
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+	probing  bool
+}
+
+func NewCircuitBreaker(
+	s Sequent,
+	threshold int,
+	probeInterval time.Duration,
+) *CircuitBreaker {
+	return &CircuitBreaker{
+		Sequent:       s,
+		Threshold:     threshold,
+		ProbeInterval: probeInterval,
+	}
+}
+
+// Open reports whether the circuit is open.
+func (cb *CircuitBreaker) Open() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.open
+}
+
+func (cb *CircuitBreaker) Call(
+	name string,
+	args ...interface{},
+) ([]interface{}, error) {
+	return cb.CallContext(context.Background(), name, args...)
+}
+
+// CallContext counts the Calls whose ctx passes its deadline as failed,
+// like those timing out otherwise, but not those canceled.
+func (cb *CircuitBreaker) CallContext(
+	ctx context.Context,
+	name string,
+	args ...interface{},
+) ([]interface{}, error) {
+	if !cb.allow() {
+		return nil, ErrCircuitOpen
+	}
+	rets, err := cb.Sequent.CallContext(ctx, name, args...)
+	switch {
+	case err == nil:
+		cb.record(failedReturn(rets))
+	case errors.Is(err, ErrSequentStop), timedOut(err):
+		cb.record(true)
+	default:
+		// invalid call or one canceled, which says nothing about
+		// the sequent
+		cb.mu.Lock()
+		cb.probing = false
+		cb.mu.Unlock()
+	}
+	return rets, err
+}
+
+func (cb *CircuitBreaker) Cast(name string, args ...interface{}) error {
+	if cb.Open() {
+		return ErrCircuitOpen
+	}
+	return cb.Sequent.Cast(name, args...)
+}
+
+func (cb *CircuitBreaker) CastNotify(
+	name string,
+	errs chan<- error,
+	args ...interface{},
+) error {
+	if cb.Open() {
+		return ErrCircuitOpen
+	}
+	return cb.Sequent.CastNotify(name, errs, args...)
+}
+
+func (cb *CircuitBreaker) now() time.Time {
+	if cb.Now != nil {
+		return cb.Now()
+	}
+	return time.Now()
+}
+
+// allow reports whether a Call may go through, claiming the probe if
+// it is due.
+func (cb *CircuitBreaker) allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	if !cb.open {
+		return true
+	}
+	if cb.probing || cb.now().Sub(cb.openedAt) < cb.ProbeInterval {
+		return false
+	}
+	cb.probing = true
+	return true
+}
+
+func (cb *CircuitBreaker) record(failed bool) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	switch {
+	case cb.probing:
+		cb.probing = false
+		if failed {
+			cb.openedAt = cb.now()
+			return
+		}
+		cb.open = false
+		cb.failures = 0
+	case failed:
+		cb.failures++
+		if !cb.open && cb.failures >= cb.Threshold {
+			cb.open = true
+			cb.openedAt = cb.now()
+		}
+	default:
+		cb.failures = 0
+	}
+}
+
+// failedReturn reports whether the last of rets is a non-nil error.
+func failedReturn(rets []interface{}) bool {
+	if len(rets) == 0 {
+		return false
+	}
+	_, ok := rets[len(rets)-1].(error)
+	return ok
+}
+
+// timedOut reports whether err is that of a Call timing out.
+func timedOut(err error) bool {
+	return errors.Is(err, ErrMethodTimeout) ||
+		errors.Is(err, ErrCallTimeout) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,102 @@
+package seriatim
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type flaky struct {
+	fail bool
+}
+
+func (f *flaky) Poll() error {
+	if f.fail {
+		return errors.New("device unreachable")
+	}
+	return nil
+}
+
+func (f *flaky) SetFail(fail bool) {
+	f.fail = fail
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	now := time.Unix(0, 0)
+	s := NewSequent(&flaky{fail: true})
+	defer s.Terminate(nil)
+	cb := NewCircuitBreaker(s, 3, time.Second)
+	cb.Now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if cb.Open() {
+			t.Fatalf("circuit opened after %d failures", i)
+		}
+		if _, err := cb.Call("Poll"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := cb.Call("Poll"); err != ErrCircuitOpen {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if err := cb.Cast("SetFail", false); err != ErrCircuitOpen {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	// invalid calls neither count nor use up the probe
+	now = now.Add(time.Second)
+	if _, err := cb.Call("Poll", 1); err == nil {
+		t.Fatal("invalid call succeeded")
+	}
+	if rets, err := cb.Call("Poll"); err != nil || rets[0] == nil {
+		t.Fatalf("expected the failed probe, got %v %v", rets, err)
+	}
+	if _, err := cb.Call("Poll"); err != ErrCircuitOpen {
+		t.Fatal("circuit closed by a failed probe")
+	}
+
+	s.Call("SetFail", false)
+	now = now.Add(time.Second)
+	if _, err := cb.Call("Poll"); err != nil || cb.Open() {
+		t.Fatal("circuit not closed by a successful probe")
+	}
+}
+
+func TestCircuitBreakerTimeouts(t *testing.T) {
+	release := make(chan struct{})
+	s := NewSequentTable(&flaky{}, map[string]interface{}{
+		"Block": func() { <-release },
+		"Poll":  MethodSpec{Func: func() {}, Timeout: time.Millisecond},
+	})
+	defer s.Terminate(nil)
+	defer close(release)
+	cb := NewCircuitBreaker(s, 3, time.Hour)
+	if err := s.Cast("Block"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cb.Call("Poll"); err != ErrMethodTimeout {
+		t.Fatalf("expected ErrMethodTimeout, got %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	if _, err := cb.CallContext(ctx, "Poll"); err != context.DeadlineExceeded &&
+		err != ErrMethodTimeout {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	// canceled calls do not count
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	if _, err := cb.CallContext(ctx, "Poll"); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if cb.Open() {
+		t.Fatal("circuit opened by a canceled call")
+	}
+	if _, err := CallTimeout(cb, "Poll", time.Millisecond); err != ErrCallTimeout &&
+		err != ErrMethodTimeout {
+		t.Fatalf("expected a timeout, got %v", err)
+	}
+	if !cb.Open() {
+		t.Fatal("circuit not opened by calls timing out")
+	}
+}
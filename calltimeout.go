@@ -0,0 +1,102 @@
+package seriatim
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+var ErrCallTimeout = errors.New("Call timed out")
+
+// The claim of a request decides between the sequent processing it and
+// a caller giving up on it.
+const (
+	claimPending uint32 = iota
+	claimTaken
+	claimAbandoned
+)
+
+// take claims req for processing, failing if its caller gave up.
+func (req *request) take() bool {
+	return atomic.CompareAndSwapUint32(&req.claim, claimPending, claimTaken)
+}
+
+// abandon gives up on req, failing if it is already being processed.
+func (req *request) abandon() bool {
+	return atomic.CompareAndSwapUint32(&req.claim, claimPending, claimAbandoned)
+}
+
+// CallTimeout calls like Call but fails with ErrCallTimeout if s has
+// not started processing the request within d. Unlike a Call given up
+// on with CallContext, the request is then dropped from the mailbox
+// rather than processed after its caller left; one already being
+// processed is waited for, until the deadline of its MethodSpec if it
+// has one. Sequents other than those made by this package give up as
+// CallContext does.
+func CallTimeout(
+	s Sequent,
+	name string,
+	d time.Duration,
+	args ...interface{},
+) ([]interface{}, error) {
+	if seq, ok := s.(*sequent); ok {
+		return seq.callTimeout(name, d, args...)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	rets, err := s.CallContext(ctx, name, args...)
+	if err == context.DeadlineExceeded {
+		err = ErrCallTimeout
+	}
+	return rets, err
+}
+
+func (a *sequent) callTimeout(
+	name string,
+	d time.Duration,
+	args ...interface{},
+) ([]interface{}, error) {
+	replych := make(chan reply, 1)
+	req, err := a.newRequest(replych, name, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	if !a.accepting() {
+		return nil, ErrSequentStop
+	}
+
+	var expired <-chan time.Time
+	if !req.deadline.IsZero() {
+		timer := time.NewTimer(time.Until(req.deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	if err := a.enqueue(req, ctx.Done()); err != nil {
+		if ctx.Err() != nil {
+			return nil, ErrCallTimeout
+		}
+		return nil, err
+	}
+
+	select {
+	case r, ok := <-replych:
+		return answer(r, ok)
+	case <-expired:
+		// dropped by the sequent if still queued, as for CallContext
+		return nil, ErrMethodTimeout
+	case <-ctx.Done():
+		if req.abandon() {
+			return nil, ErrCallTimeout
+		}
+	}
+	select {
+	case r, ok := <-replych:
+		return answer(r, ok)
+	case <-expired:
+		return nil, ErrMethodTimeout
+	}
+}
@@ -0,0 +1,48 @@
+package seriatim
+
+import (
+	"testing"
+	"time"
+)
+
+type gated struct {
+	release chan struct{}
+	n       int
+}
+
+func (g *gated) Block() {
+	<-g.release
+}
+
+func (g *gated) Incr() int {
+	g.n++
+	return g.n
+}
+
+func TestCallTimeout(t *testing.T) {
+	g := &gated{release: make(chan struct{})}
+	s := NewSequent(g, WithMailboxSize(4))
+	defer s.Terminate(nil)
+	if err := s.Cast("Block"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := CallTimeout(s, "Incr", 10*time.Millisecond); err != ErrCallTimeout {
+		t.Fatalf("expected ErrCallTimeout, got %v", err)
+	}
+	cb := NewCircuitBreaker(s, 1, time.Minute)
+	if _, err := CallTimeout(cb, "Incr", 10*time.Millisecond); err != ErrCallTimeout {
+		t.Fatalf("expected ErrCallTimeout from a wrapper, got %v", err)
+	}
+
+	close(g.release)
+	// the first Incr was dropped, the one given up on through the
+	// wrapper is still processed
+	rets, err := CallTimeout(s, "Incr", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rets[0] != 2 {
+		t.Fatalf("expected the timed out call to be dropped, got %v", rets[0])
+	}
+}
@@ -0,0 +1,186 @@
+// Command bench measures Call and Cast throughput and latency for a
+// matrix of producer and sequent counts and prints a report that can
+// be compared between revisions.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jsouthworth/seriatim"
+)
+
+type worker struct {
+	count int
+}
+
+// Work spins for n iterations to simulate a handler doing some work.
+func (w *worker) Work(n int) int {
+	for i := 0; i < n; i++ {
+		w.count++
+	}
+	return w.count
+}
+
+func (w *worker) Sync() {}
+
+type config struct {
+	op        string
+	producers int
+	sequents  int
+	messages  int
+	work      int
+	mailbox   int
+}
+
+type result struct {
+	config
+	elapsed   time.Duration
+	latencies []time.Duration
+}
+
+func (r *result) throughput() float64 {
+	return float64(r.messages) / r.elapsed.Seconds()
+}
+
+func (r *result) percentile(p float64) time.Duration {
+	if len(r.latencies) == 0 {
+		return 0
+	}
+	i := int(float64(len(r.latencies)-1) * p)
+	return r.latencies[i]
+}
+
+func run(cfg config) *result {
+	sequents := make([]seriatim.Sequent, cfg.sequents)
+	for i := range sequents {
+		sequents[i] = seriatim.NewSequent(&worker{},
+			seriatim.WithMailboxSize(cfg.mailbox))
+	}
+	defer func() {
+		for _, s := range sequents {
+			s.Terminate(nil)
+		}
+	}()
+
+	perProducer := cfg.messages / cfg.producers
+	latencies := make([][]time.Duration, cfg.producers)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for p := 0; p < cfg.producers; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			lat := make([]time.Duration, 0, perProducer)
+			for i := 0; i < perProducer; i++ {
+				s := sequents[(p+i)%len(sequents)]
+				begin := time.Now()
+				var err error
+				if cfg.op == "call" {
+					_, err = s.Call("Work", cfg.work)
+				} else {
+					err = s.Cast("Work", cfg.work)
+				}
+				lat = append(lat, time.Since(begin))
+				if err != nil {
+					fmt.Fprintln(os.Stderr, "bench:", err)
+					return
+				}
+			}
+			latencies[p] = lat
+		}(p)
+	}
+	wg.Wait()
+	// casts are only done once they have been processed
+	for _, s := range sequents {
+		s.Call("Sync")
+	}
+
+	r := &result{
+		config:  cfg,
+		elapsed: time.Since(start),
+	}
+	r.messages = perProducer * cfg.producers
+	for _, lat := range latencies {
+		r.latencies = append(r.latencies, lat...)
+	}
+	sort.Slice(r.latencies, func(i, j int) bool {
+		return r.latencies[i] < r.latencies[j]
+	})
+	return r
+}
+
+func parseList(value string) ([]int, error) {
+	var out []int
+	for _, field := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, err
+		}
+		if n < 1 {
+			return nil, fmt.Errorf("%d must be positive", n)
+		}
+		out = append(out, n)
+	}
+	return out, nil
+}
+
+func main() {
+	messages := flag.Int("n", 100000, "messages per run")
+	work := flag.Int("work", 100, "iterations of work per message")
+	producerList := flag.String("producers", "1,4,16", "comma separated producer counts")
+	sequentList := flag.String("sequents", "1,4", "comma separated sequent counts")
+	mailbox := flag.Int("mailbox", 1, "mailbox size of each sequent")
+	ops := flag.String("ops", "call,cast", "comma separated operations to measure")
+	flag.Parse()
+
+	producers, err := parseList(*producerList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bench: -producers:", err)
+		os.Exit(2)
+	}
+	sequents, err := parseList(*sequentList)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "bench: -sequents:", err)
+		os.Exit(2)
+	}
+
+	if *mailbox < 1 {
+		fmt.Fprintln(os.Stderr, "bench: -mailbox must be positive")
+		os.Exit(2)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "op\tproducers\tsequents\tmailbox\tmsgs/s\tp50\tp90\tp99\tmax\t")
+	for _, op := range strings.Split(*ops, ",") {
+		if op != "call" && op != "cast" {
+			fmt.Fprintf(os.Stderr, "bench: unknown operation %q\n", op)
+			os.Exit(2)
+		}
+		for _, p := range producers {
+			for _, s := range sequents {
+				r := run(config{
+					op:        op,
+					producers: p,
+					sequents:  s,
+					messages:  *messages,
+					work:      *work,
+					mailbox:   *mailbox,
+				})
+				fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.0f\t%v\t%v\t%v\t%v\t\n",
+					r.op, r.producers, r.sequents, r.mailbox,
+					r.throughput(),
+					r.percentile(0.5), r.percentile(0.9),
+					r.percentile(0.99), r.percentile(1))
+			}
+		}
+	}
+	w.Flush()
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+)
+
+func writeSwitchMap(w io.Writer, names map[string]string) {
+	keys := make([]string, 0, len(names))
+	for k := range names {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Fprintf(w, "func(in string) string {\nswitch in {\n")
+	for _, k := range keys {
+		fmt.Fprintf(w, "case %q:\nreturn %q\n", k, names[k])
+	}
+	fmt.Fprintf(w, "}\nreturn in\n}")
+}
+
+func (intf *iface) proxyName() string {
+	return intf.goName + "Proxy"
+}
+
+// Client generates typed proxies for calling the interfaces on
+// remote objects. The output refers to the interfaces generated by
+// Interfaces and must be placed in the same package.
+func (gen *generator) Client() ([]byte, error) {
+	var buf bytes.Buffer
+	w := &buf
+	fmt.Fprintf(w, "// Code generated by dbusgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(w, "package %s\n\n", gen.pkg)
+	fmt.Fprintf(w, "import (\n%s\n%s\n)\n\n", godbusImport, seriatimImport)
+
+	for _, intf := range gen.ifaces {
+		proxy := intf.proxyName()
+		fmt.Fprintf(w, "// %s calls %s on a remote object.\n", proxy, intf.dbusName)
+		fmt.Fprintf(w, "type %s struct {\n*dbus.Proxy\n}\n\n", proxy)
+		fmt.Fprintf(w, "var _ %s = (*%s)(nil)\n\n", intf.goName, proxy)
+
+		fmt.Fprintf(w, "func New%s(mgr *dbus.BusManager, dest string, path godbus.ObjectPath) *%s {\n",
+			proxy, proxy)
+		fmt.Fprintf(w, "return &%s{mgr.NewProxy(dest, path, %q)}\n}\n\n",
+			proxy, intf.dbusName)
+
+		for _, m := range intf.methods {
+			fmt.Fprintf(w, "func (p *%s) %s(%s) %s {\n",
+				proxy, m.goName, m.params(), m.results(true))
+			fmt.Fprintf(w, "err = p.Call(%q", m.dbusName)
+			if len(m.in) > 0 {
+				fmt.Fprintf(w, ", %s", m.argNames())
+			}
+			fmt.Fprintf(w, ").Store(")
+			for i := range m.out {
+				if i > 0 {
+					fmt.Fprintf(w, ", ")
+				}
+				fmt.Fprintf(w, "&out%d", i)
+			}
+			fmt.Fprintf(w, ")\nreturn\n}\n\n")
+		}
+
+		for _, prop := range intf.properties {
+			if prop.readable() {
+				fmt.Fprintf(w, "func (p *%s) Get%s() (val %s, err error) {\n",
+					proxy, prop.goName, prop.typ)
+				fmt.Fprintf(w, "err = p.StoreProperty(%q, &val)\nreturn\n}\n\n",
+					prop.dbusName)
+			}
+			if prop.writable() {
+				fmt.Fprintf(w, "func (p *%s) Set%s(val %s) error {\n",
+					proxy, prop.goName, prop.typ)
+				fmt.Fprintf(w, "return p.SetProperty(%q, val)\n}\n\n", prop.dbusName)
+			}
+		}
+
+		if len(intf.signals) > 0 {
+			fmt.Fprintf(w, "// Subscribe%s delivers %s signals to obj; the value\n",
+				intf.goName, intf.dbusName)
+			fmt.Fprintf(w, "// exported on obj must implement %s.\n", intf.signalsName())
+			fmt.Fprintf(w, "func Subscribe%s(obj *dbus.Object) error {\n", intf.goName)
+			fmt.Fprintf(w, "return obj.Receives(%q, (*%s)(nil), ",
+				intf.dbusName, intf.signalsName())
+			if names := renamed(intf.signals); len(names) > 0 {
+				writeSwitchMap(w, names)
+			} else {
+				fmt.Fprintf(w, "nil")
+			}
+			fmt.Fprintf(w, ")\n}\n\n")
+		}
+	}
+	return format.Source(buf.Bytes())
+}
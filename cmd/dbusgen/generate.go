@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/godbus/dbus/introspect"
+)
+
+const (
+	godbusImport   = `godbus "github.com/godbus/dbus"`
+	seriatimImport = `"github.com/jsouthworth/seriatim/dbus"`
+)
+
+// Interfaces every exported object gets from the dbus package or
+// the bus itself; they are never generated.
+var standardInterfaces = map[string]bool{
+	"org.freedesktop.DBus.Introspectable": true,
+	"org.freedesktop.DBus.Peer":           true,
+	"org.freedesktop.DBus.Properties":     true,
+}
+
+type arg struct {
+	name string
+	typ  string
+}
+
+type member struct {
+	dbusName string
+	goName   string
+	in       []arg
+	out      []arg
+}
+
+type property struct {
+	dbusName string
+	goName   string
+	typ      string
+	access   string
+}
+
+func (prop *property) readable() bool {
+	return prop.access == "read" || prop.access == "readwrite"
+}
+
+func (prop *property) writable() bool {
+	return prop.access == "write" || prop.access == "readwrite"
+}
+
+type iface struct {
+	dbusName   string
+	goName     string
+	methods    []member
+	signals    []member
+	properties []property
+}
+
+type generator struct {
+	pkg    string
+	ifaces []*iface
+}
+
+func parseIntrospection(r io.Reader) (*introspect.Node, error) {
+	var node introspect.Node
+	if err := xml.NewDecoder(r).Decode(&node); err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
+
+func newGenerator(pkg string, node *introspect.Node) (*generator, error) {
+	gen := &generator{pkg: pkg}
+	seen := make(map[string]bool)
+	goNames := make(map[string]string)
+	var walk func(*introspect.Node) error
+	walk = func(n *introspect.Node) error {
+		for _, intro := range n.Interfaces {
+			if standardInterfaces[intro.Name] || seen[intro.Name] {
+				continue
+			}
+			seen[intro.Name] = true
+			intf, err := convertInterface(intro)
+			if err != nil {
+				return err
+			}
+			if other, ok := goNames[intf.goName]; ok {
+				return fmt.Errorf("interfaces %s and %s both map to Go name %s",
+					other, intf.dbusName, intf.goName)
+			}
+			goNames[intf.goName] = intf.dbusName
+			gen.ifaces = append(gen.ifaces, intf)
+		}
+		for i := range n.Children {
+			if err := walk(&n.Children[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(node); err != nil {
+		return nil, err
+	}
+	sort.Slice(gen.ifaces, func(i, j int) bool {
+		return gen.ifaces[i].dbusName < gen.ifaces[j].dbusName
+	})
+	return gen, nil
+}
+
+func convertInterface(intro introspect.Interface) (*iface, error) {
+	parts := strings.Split(intro.Name, ".")
+	intf := &iface{
+		dbusName: intro.Name,
+		goName:   exported(parts[len(parts)-1]),
+	}
+	for _, method := range intro.Methods {
+		m := member{dbusName: method.Name, goName: exported(method.Name)}
+		for i, a := range method.Args {
+			typ, err := goType(a.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", intro.Name, method.Name, err)
+			}
+			if a.Direction == "out" {
+				m.out = append(m.out, arg{paramName(a.Name, i), typ})
+			} else {
+				m.in = append(m.in, arg{paramName(a.Name, i), typ})
+			}
+		}
+		intf.methods = append(intf.methods, m)
+	}
+	for _, signal := range intro.Signals {
+		m := member{dbusName: signal.Name, goName: exported(signal.Name)}
+		for i, a := range signal.Args {
+			typ, err := goType(a.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %v", intro.Name, signal.Name, err)
+			}
+			m.in = append(m.in, arg{paramName(a.Name, i), typ})
+		}
+		intf.signals = append(intf.signals, m)
+	}
+	for _, prop := range intro.Properties {
+		typ, err := goType(prop.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%s.%s: %v", intro.Name, prop.Name, err)
+		}
+		intf.properties = append(intf.properties, property{
+			dbusName: prop.Name,
+			goName:   exported(prop.Name),
+			typ:      typ,
+			access:   prop.Access,
+		})
+	}
+	sort.Slice(intf.properties, func(i, j int) bool {
+		return intf.properties[i].goName < intf.properties[j].goName
+	})
+	sort.Slice(intf.methods, func(i, j int) bool {
+		return intf.methods[i].goName < intf.methods[j].goName
+	})
+	sort.Slice(intf.signals, func(i, j int) bool {
+		return intf.signals[i].goName < intf.signals[j].goName
+	})
+	return intf, nil
+}
+
+func exported(name string) string {
+	if name == "" {
+		return name
+	}
+	r := []rune(name)
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+func paramName(name string, position int) string {
+	if name == "" || !token.IsIdentifier(name) || token.IsKeyword(name) {
+		return fmt.Sprintf("arg%d", position)
+	}
+	r := []rune(name)
+	r[0] = unicode.ToLower(r[0])
+	name = string(r)
+	if token.IsKeyword(name) {
+		return fmt.Sprintf("arg%d", position)
+	}
+	return name
+}
+
+func (m *member) params() string {
+	out := make([]string, 0, len(m.in))
+	for _, a := range m.in {
+		out = append(out, a.name+" "+a.typ)
+	}
+	return strings.Join(out, ", ")
+}
+
+func (m *member) argNames() string {
+	out := make([]string, 0, len(m.in))
+	for _, a := range m.in {
+		out = append(out, a.name)
+	}
+	return strings.Join(out, ", ")
+}
+
+func (m *member) results(named bool) string {
+	out := make([]string, 0, len(m.out)+1)
+	for i, a := range m.out {
+		if named {
+			out = append(out, fmt.Sprintf("out%d %s", i, a.typ))
+		} else {
+			out = append(out, a.typ)
+		}
+	}
+	if named {
+		out = append(out, "err error")
+	} else {
+		out = append(out, "error")
+	}
+	return "(" + strings.Join(out, ", ") + ")"
+}
+
+func (intf *iface) signalsName() string {
+	return intf.goName + "Signals"
+}
+
+func renamed(members []member) map[string]string {
+	names := make(map[string]string)
+	for _, m := range members {
+		if m.goName != m.dbusName {
+			names[m.goName] = m.dbusName
+		}
+	}
+	return names
+}
+
+func needsGodbus(ifaces []*iface, signals bool) bool {
+	for _, intf := range ifaces {
+		all := [][]member{intf.methods}
+		if signals {
+			all = append(all, intf.signals)
+		}
+		for _, members := range all {
+			for _, m := range members {
+				for _, a := range append(m.in[:len(m.in):len(m.in)], m.out...) {
+					if strings.Contains(a.typ, "godbus.") {
+						return true
+					}
+				}
+			}
+		}
+	}
+	return false
+}
+
+func writeNameMap(w io.Writer, names map[string]string) {
+	keys := make([]string, 0, len(names))
+	for k := range names {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fmt.Fprintf(w, "mapNames(map[string]string{\n")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%q: %q,\n", k, names[k])
+	}
+	fmt.Fprintf(w, "})")
+}
+
+// Interfaces generates the Go interfaces for the D-Bus interfaces
+// along with the functions that register them on a dbus.Object.
+func (gen *generator) Interfaces() ([]byte, error) {
+	var buf bytes.Buffer
+	w := &buf
+	fmt.Fprintf(w, "// Code generated by dbusgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(w, "package %s\n\n", gen.pkg)
+	fmt.Fprintf(w, "import (\n")
+	if needsGodbus(gen.ifaces, true) {
+		fmt.Fprintf(w, "%s\n", godbusImport)
+	}
+	fmt.Fprintf(w, "%s\n)\n\n", seriatimImport)
+
+	mapping := false
+	for _, intf := range gen.ifaces {
+		if len(intf.properties) > 0 {
+			fmt.Fprintf(w, "// Properties of %s are not generated.\n", intf.dbusName)
+		}
+		fmt.Fprintf(w, "// %s is implemented by values exporting %s.\n",
+			intf.goName, intf.dbusName)
+		fmt.Fprintf(w, "type %s interface {\n", intf.goName)
+		for _, m := range intf.methods {
+			fmt.Fprintf(w, "%s(%s) %s\n", m.goName, m.params(), m.results(false))
+		}
+		fmt.Fprintf(w, "}\n\n")
+
+		if len(intf.signals) > 0 {
+			fmt.Fprintf(w, "// %s describes the signals of %s.\n",
+				intf.signalsName(), intf.dbusName)
+			fmt.Fprintf(w, "type %s interface {\n", intf.signalsName())
+			for _, m := range intf.signals {
+				fmt.Fprintf(w, "%s(%s)\n", m.goName, m.params())
+			}
+			fmt.Fprintf(w, "}\n\n")
+		}
+
+		fmt.Fprintf(w, "// Register%s exports %s on obj.\n", intf.goName, intf.dbusName)
+		fmt.Fprintf(w, "func Register%s(obj *dbus.Object) error {\n", intf.goName)
+		if names := renamed(intf.methods); len(names) > 0 {
+			mapping = true
+			fmt.Fprintf(w, "err := obj.ImplementsMap(%q, (*%s)(nil), ",
+				intf.dbusName, intf.goName)
+			writeNameMap(w, names)
+			fmt.Fprintf(w, ")\n")
+		} else {
+			fmt.Fprintf(w, "err := obj.Implements(%q, (*%s)(nil))\n",
+				intf.dbusName, intf.goName)
+		}
+		fmt.Fprintf(w, "if err != nil {\nreturn err\n}\n")
+		if len(intf.signals) > 0 {
+			if names := renamed(intf.signals); len(names) > 0 {
+				mapping = true
+				fmt.Fprintf(w, "return obj.EmitsMap(%q, (*%s)(nil), ",
+					intf.dbusName, intf.signalsName())
+				writeNameMap(w, names)
+				fmt.Fprintf(w, ")\n")
+			} else {
+				fmt.Fprintf(w, "return obj.Emits(%q, (*%s)(nil))\n",
+					intf.dbusName, intf.signalsName())
+			}
+		} else {
+			fmt.Fprintf(w, "return nil\n")
+		}
+		fmt.Fprintf(w, "}\n\n")
+
+		for _, m := range intf.signals {
+			fmt.Fprintf(w, "// Emit%s%s emits %s.%s from obj.\n",
+				intf.goName, m.goName, intf.dbusName, m.dbusName)
+			fmt.Fprintf(w, "func Emit%s%s(obj *dbus.Object", intf.goName, m.goName)
+			if len(m.in) > 0 {
+				fmt.Fprintf(w, ", %s", m.params())
+			}
+			fmt.Fprintf(w, ") error {\n")
+			fmt.Fprintf(w, "return obj.Emit(%q, %q", intf.dbusName, m.dbusName)
+			if len(m.in) > 0 {
+				fmt.Fprintf(w, ", %s", m.argNames())
+			}
+			fmt.Fprintf(w, ")\n}\n\n")
+		}
+	}
+
+	if mapping {
+		writeMapNames(w)
+	}
+	return format.Source(buf.Bytes())
+}
+
+func writeMapNames(w io.Writer) {
+	fmt.Fprintf(w, `func mapNames(names map[string]string) func(string) string {
+	return func(in string) string {
+		if out, ok := names[in]; ok {
+			return out
+		}
+		return in
+	}
+}
+`)
+}
+
+// Skeleton generates a type implementing every interface with
+// methods that return zero values; it is meant to be edited.
+func (gen *generator) Skeleton(typeName string) ([]byte, error) {
+	var buf bytes.Buffer
+	w := &buf
+	fmt.Fprintf(w, "package %s\n\n", gen.pkg)
+	if needsGodbus(gen.ifaces, false) {
+		fmt.Fprintf(w, "import %s\n\n", godbusImport)
+	}
+	fmt.Fprintf(w, "type %s struct{}\n\n", typeName)
+	if len(gen.ifaces) > 0 {
+		fmt.Fprintf(w, "var (\n")
+		for _, intf := range gen.ifaces {
+			fmt.Fprintf(w, "_ %s = (*%s)(nil)\n", intf.goName, typeName)
+		}
+		fmt.Fprintf(w, ")\n\n")
+	}
+
+	defined := make(map[string]string)
+	for _, intf := range gen.ifaces {
+		for _, m := range intf.methods {
+			sig := m.params() + m.results(false)
+			if other, ok := defined[m.goName]; ok {
+				if other != sig {
+					return nil, fmt.Errorf(
+						"method %s has conflicting signatures across interfaces",
+						m.goName)
+				}
+				continue
+			}
+			defined[m.goName] = sig
+			fmt.Fprintf(w, "// %s implements %s.%s.\n",
+				m.goName, intf.dbusName, m.dbusName)
+			fmt.Fprintf(w, "func (s *%s) %s(%s) %s {\nreturn\n}\n\n",
+				typeName, m.goName, m.params(), m.results(true))
+		}
+	}
+	return format.Source(buf.Bytes())
+}
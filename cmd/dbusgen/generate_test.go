@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testXML = `<node>
+  <interface name="org.freedesktop.DBus.Introspectable">
+    <method name="Introspect"><arg type="s" direction="out"/></method>
+  </interface>
+  <interface name="net.jsouthworth.Foo">
+    <method name="foo"><arg type="s" direction="out"/></method>
+    <method name="Baz">
+      <arg name="Count" type="i" direction="in"/>
+      <arg type="s" direction="out"/>
+    </method>
+    <property name="Name" type="s" access="readwrite"/>
+    <property name="count" type="u" access="read"/>
+    <signal name="Changed">
+      <arg name="path" type="o"/>
+      <arg name="props" type="a{sv}"/>
+    </signal>
+  </interface>
+  <node name="bar">
+    <interface name="net.jsouthworth.Bar">
+      <method name="Bar"><arg name="type" type="a(si)" direction="out"/></method>
+    </interface>
+  </node>
+</node>`
+
+func TestGoType(t *testing.T) {
+	tests := map[string]string{
+		"y":      "byte",
+		"ao":     "[]godbus.ObjectPath",
+		"a{sv}":  "map[string]godbus.Variant",
+		"a{sai}": "map[string][]int32",
+		"(ib)":   "[]interface{}",
+		"aa(s)":  "[][][]interface{}",
+	}
+	for sig, expected := range tests {
+		got, err := goType(sig)
+		if err != nil {
+			t.Fatal(sig, err)
+		}
+		if got != expected {
+			t.Errorf("%s: expected %s, got %s", sig, expected, got)
+		}
+	}
+	for _, sig := range []string{"", "a", "a{s", "(i", "ii", "z"} {
+		if _, err := goType(sig); err == nil {
+			t.Errorf("%q: expected error", sig)
+		}
+	}
+}
+
+func newTestGenerator(t *testing.T) *generator {
+	node, err := parseIntrospection(bytes.NewBufferString(testXML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	gen, err := newGenerator("foo", node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return gen
+}
+
+func TestGenerateInterfaces(t *testing.T) {
+	src, err := newTestGenerator(t).Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "", src, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"Baz(count int32) (string, error)",
+		"Foo() (string, error)",
+		"Changed(path godbus.ObjectPath, props map[string]godbus.Variant)",
+		`"Foo": "foo",`,
+		`return obj.Emits("net.jsouthworth.Foo", (*FooSignals)(nil))`,
+		`err := obj.Implements("net.jsouthworth.Bar", (*Bar)(nil))`,
+		"Bar() ([][]interface{}, error)",
+	} {
+		if !strings.Contains(string(src), expected) {
+			t.Errorf("missing %q in:\n%s", expected, src)
+		}
+	}
+	if strings.Contains(string(src), "Introspect") {
+		t.Errorf("standard interface generated:\n%s", src)
+	}
+}
+
+func TestGenerateSkeleton(t *testing.T) {
+	src, err := newTestGenerator(t).Skeleton("Server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "", src, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"_ Foo = (*Server)(nil)",
+		"func (s *Server) Baz(count int32) (out0 string, err error)",
+	} {
+		if !strings.Contains(string(src), expected) {
+			t.Errorf("missing %q in:\n%s", expected, src)
+		}
+	}
+}
+
+func TestGenerateNameConflict(t *testing.T) {
+	node, err := parseIntrospection(bytes.NewBufferString(`<node>
+  <interface name="a.Foo"/>
+  <interface name="b.Foo"/>
+</node>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newGenerator("foo", node); err == nil {
+		t.Fatal("expected conflicting interface names to fail")
+	}
+}
+
+func TestGenerateClient(t *testing.T) {
+	src, err := newTestGenerator(t).Client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "", src, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"var _ Foo = (*FooProxy)(nil)",
+		`return &FooProxy{mgr.NewProxy(dest, path, "net.jsouthworth.Foo")}`,
+		"func (p *FooProxy) Baz(count int32) (out0 string, err error)",
+		`err = p.Call("Baz", count).Store(&out0)`,
+		`err = p.Call("foo").Store(&out0)`,
+		"func (p *FooProxy) GetName() (val string, err error)",
+		"func (p *FooProxy) SetName(val string) error",
+		"func (p *FooProxy) GetCount() (val uint32, err error)",
+		`return obj.Receives("net.jsouthworth.Foo", (*FooSignals)(nil), nil)`,
+	} {
+		if !strings.Contains(string(src), expected) {
+			t.Errorf("missing %q in:\n%s", expected, src)
+		}
+	}
+	if strings.Contains(string(src), "SetCount") {
+		t.Errorf("read only property has a setter:\n%s", src)
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	gen := newTestGenerator(t)
+	var asserts assertions
+	asserts.Set("Server")
+	asserts.Set("Other=Bar")
+	src, err := gen.Assertions(asserts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"_ Bar = (*Server)(nil)",
+		"_ Foo = (*Server)(nil)",
+		"_ Bar = (*Other)(nil)",
+	} {
+		if !strings.Contains(string(src), expected) {
+			t.Errorf("missing %q in:\n%s", expected, src)
+		}
+	}
+	if strings.Contains(string(src), "_ Foo = (*Other)(nil)") {
+		t.Errorf("unrequested assertion in:\n%s", src)
+	}
+
+	asserts.Set("Server=Missing")
+	if _, err := gen.Assertions(asserts); err == nil {
+		t.Fatal("expected unknown interface to fail")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	gen := newTestGenerator(t)
+	want, err := gen.Interfaces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "foo_dbus.go")
+	if err := ioutil.WriteFile(path, want, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := verify(path, want); err != nil {
+		t.Fatal(err)
+	}
+
+	stale := strings.Replace(string(want),
+		"Baz(count int32) (string, error)", "Baz(count int64) (string, error)", 1)
+	stale = strings.Replace(stale, "Foo() (string, error)", "Old() error", 1)
+	if err := ioutil.WriteFile(path, []byte(stale), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err = verify(path, want)
+	if err == nil {
+		t.Fatal("expected stale file to fail verification")
+	}
+	for _, expected := range []string{
+		"Foo.Baz: signature changed",
+		"have: func(count int64) (string, error)",
+		"want: func(count int32) (string, error)",
+		"Foo.Foo: missing",
+		"Foo.Old: no longer in the XML",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("missing %q in:\n%s", expected, err)
+		}
+	}
+}
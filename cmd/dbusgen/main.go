@@ -0,0 +1,140 @@
+// Command dbusgen generates Go interfaces and seriatim/dbus
+// registration code from D-Bus introspection XML.
+//
+// Typical use is from a go:generate directive:
+//
+//	//go:generate dbusgen -o foo_dbus.go -skeleton foo.go foo.xml
+//
+// The interfaces file is regenerated on every run; the skeleton is
+// only written if it does not already exist since it is meant to be
+// filled in by hand. With -client, typed proxies for calling the
+// interfaces on remote objects are generated as well.
+//
+// To catch drift between the XML, the generated code and the values
+// exporting it, -assert Type[=Iface,...] adds compile time checks
+// that Type implements the interfaces, and -verify compares the
+// existing output files with what the XML generates instead of
+// writing them, exiting non-zero with the changed methods listed.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "dbusgen:", err)
+	os.Exit(1)
+}
+
+func main() {
+	pkg := flag.String("package", os.Getenv("GOPACKAGE"),
+		"package name of the generated code")
+	output := flag.String("o", "", "write generated interfaces to `file` (default stdout)")
+	skeleton := flag.String("skeleton", "",
+		"write a skeleton implementation to `file` unless it exists")
+	typeName := flag.String("type", "Server", "name of the skeleton type")
+	client := flag.String("client", "", "write client proxies to `file`")
+	verifyOnly := flag.Bool("verify", false,
+		"check that the output files are up to date instead of writing them")
+	var asserts assertions
+	flag.Var(&asserts, "assert",
+		"check that `Type[=Iface,...]` implements the interfaces (repeatable)")
+	flag.Parse()
+
+	if *pkg == "" {
+		*pkg = "main"
+	}
+
+	var in io.Reader = os.Stdin
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		f, err := os.Open(flag.Arg(0))
+		if err != nil {
+			fatal(err)
+		}
+		defer f.Close()
+		in = f
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	node, err := parseIntrospection(in)
+	if err != nil {
+		fatal(err)
+	}
+	gen, err := newGenerator(*pkg, node)
+	if err != nil {
+		fatal(err)
+	}
+	for _, intf := range gen.ifaces {
+		if len(intf.properties) > 0 && *client == "" {
+			fmt.Fprintf(os.Stderr, "dbusgen: ignoring properties of %s\n",
+				intf.dbusName)
+		}
+	}
+
+	write := func(path string, src []byte) {
+		if *verifyOnly {
+			if err := verify(path, src); err != nil {
+				fatal(err)
+			}
+			return
+		}
+		if err := ioutil.WriteFile(path, src, 0644); err != nil {
+			fatal(err)
+		}
+	}
+
+	src, err := gen.Interfaces()
+	if err != nil {
+		fatal(err)
+	}
+	checks, err := gen.Assertions(asserts)
+	if err != nil {
+		fatal(err)
+	}
+	if checks != nil {
+		src, err = format.Source(append(src, checks...))
+		if err != nil {
+			fatal(err)
+		}
+	}
+	switch {
+	case *output != "":
+		write(*output, src)
+	case *verifyOnly:
+		fatal(errors.New("-verify requires -o"))
+	default:
+		os.Stdout.Write(src)
+	}
+
+	if *client != "" {
+		src, err := gen.Client()
+		if err != nil {
+			fatal(err)
+		}
+		write(*client, src)
+	}
+
+	if *skeleton == "" || *verifyOnly {
+		return
+	}
+	if _, err := os.Stat(*skeleton); err == nil {
+		return
+	}
+	src, err = gen.Skeleton(*typeName)
+	if err != nil {
+		fatal(err)
+	}
+	if err := ioutil.WriteFile(*skeleton, src, 0644); err != nil {
+		fatal(err)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+var basicTypes = map[byte]string{
+	'y': "byte",
+	'b': "bool",
+	'n': "int16",
+	'q': "uint16",
+	'i': "int32",
+	'u': "uint32",
+	'x': "int64",
+	't': "uint64",
+	'd': "float64",
+	's': "string",
+	'o': "godbus.ObjectPath",
+	'g': "godbus.Signature",
+	'v': "godbus.Variant",
+	'h': "godbus.UnixFDIndex",
+}
+
+// goType converts a D-Bus type signature into the Go type godbus
+// decodes it to.
+func goType(sig string) (string, error) {
+	typ, rest, err := parseType(sig)
+	if err != nil {
+		return "", err
+	}
+	if rest != "" {
+		return "", fmt.Errorf("signature %q is not a single complete type", sig)
+	}
+	return typ, nil
+}
+
+func parseType(sig string) (string, string, error) {
+	if sig == "" {
+		return "", "", fmt.Errorf("unexpected end of signature")
+	}
+	if typ, ok := basicTypes[sig[0]]; ok {
+		return typ, sig[1:], nil
+	}
+	switch sig[0] {
+	case 'a':
+		if strings.HasPrefix(sig, "a{") {
+			key, rest, err := parseType(sig[2:])
+			if err != nil {
+				return "", "", err
+			}
+			val, rest, err := parseType(rest)
+			if err != nil {
+				return "", "", err
+			}
+			if !strings.HasPrefix(rest, "}") {
+				return "", "", fmt.Errorf("unterminated dict entry in %q", sig)
+			}
+			return "map[" + key + "]" + val, rest[1:], nil
+		}
+		elem, rest, err := parseType(sig[1:])
+		if err != nil {
+			return "", "", err
+		}
+		return "[]" + elem, rest, nil
+	case '(':
+		rest := sig[1:]
+		for !strings.HasPrefix(rest, ")") {
+			var err error
+			_, rest, err = parseType(rest)
+			if err != nil {
+				return "", "", err
+			}
+		}
+		// godbus decodes structs into a slice of their fields
+		return "[]interface{}", rest[1:], nil
+	}
+	return "", "", fmt.Errorf("unknown type code %q in signature", sig[0])
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/printer"
+	"go/token"
+	"io/ioutil"
+	"sort"
+	"strings"
+)
+
+// assertion requests compile time checks that a type implements the
+// named interfaces, or all of them when none are named.
+type assertion struct {
+	typeName   string
+	interfaces []string
+}
+
+type assertions []assertion
+
+func (a *assertions) String() string {
+	out := make([]string, 0, len(*a))
+	for _, assert := range *a {
+		if len(assert.interfaces) == 0 {
+			out = append(out, assert.typeName)
+			continue
+		}
+		out = append(out, assert.typeName+"="+strings.Join(assert.interfaces, ","))
+	}
+	return strings.Join(out, " ")
+}
+
+func (a *assertions) Set(value string) error {
+	parts := strings.SplitN(value, "=", 2)
+	if parts[0] == "" {
+		return fmt.Errorf("missing type name in %q", value)
+	}
+	assert := assertion{typeName: parts[0]}
+	if len(parts) == 2 {
+		assert.interfaces = strings.Split(parts[1], ",")
+	}
+	*a = append(*a, assert)
+	return nil
+}
+
+// Assertions generates the compile time interface checks so that
+// a value drifting from its D-Bus interfaces fails the build.
+func (gen *generator) Assertions(asserts assertions) ([]byte, error) {
+	if len(asserts) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]bool)
+	for _, intf := range gen.ifaces {
+		known[intf.goName] = true
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "\n// Values exported with these interfaces must keep implementing them.\n")
+	fmt.Fprintf(&buf, "var (\n")
+	for _, assert := range asserts {
+		names := assert.interfaces
+		if len(names) == 0 {
+			for _, intf := range gen.ifaces {
+				names = append(names, intf.goName)
+			}
+		}
+		for _, name := range names {
+			if !known[name] {
+				return nil, fmt.Errorf("unknown interface %s for %s",
+					name, assert.typeName)
+			}
+			fmt.Fprintf(&buf, "_ %s = (*%s)(nil)\n", name, assert.typeName)
+		}
+	}
+	fmt.Fprintf(&buf, ")\n")
+	return buf.Bytes(), nil
+}
+
+// signatures collects the methods of the interfaces and proxy types
+// declared in a Go file keyed by Type.Method.
+func signatures(src []byte) (map[string]string, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return nil, err
+	}
+	render := func(node ast.Node) string {
+		var buf bytes.Buffer
+		printer.Fprint(&buf, fset, node)
+		return buf.String()
+	}
+	out := make(map[string]string)
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.GenDecl:
+			for _, spec := range decl.Specs {
+				typ, ok := spec.(*ast.TypeSpec)
+				if !ok {
+					continue
+				}
+				intf, ok := typ.Type.(*ast.InterfaceType)
+				if !ok {
+					continue
+				}
+				for _, method := range intf.Methods.List {
+					for _, name := range method.Names {
+						out[typ.Name.Name+"."+name.Name] = render(method.Type)
+					}
+				}
+			}
+		case *ast.FuncDecl:
+			if decl.Recv == nil || len(decl.Recv.List) == 0 {
+				continue
+			}
+			recv := render(decl.Recv.List[0].Type)
+			recv = strings.TrimPrefix(recv, "*")
+			out[recv+"."+decl.Name.Name] = render(decl.Type)
+		}
+	}
+	return out, nil
+}
+
+// verify compares a previously generated file with what the current
+// XML generates, describing every method that changed.
+func verify(path string, want []byte) error {
+	have, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if bytes.Equal(have, want) {
+		return nil
+	}
+	haveSigs, err := signatures(have)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	wantSigs, err := signatures(want)
+	if err != nil {
+		return err
+	}
+
+	var diffs []string
+	for name, wantSig := range wantSigs {
+		haveSig, ok := haveSigs[name]
+		switch {
+		case !ok:
+			diffs = append(diffs, fmt.Sprintf("%s: missing\n\twant: %s",
+				name, wantSig))
+		case haveSig != wantSig:
+			diffs = append(diffs, fmt.Sprintf("%s: signature changed\n\thave: %s\n\twant: %s",
+				name, haveSig, wantSig))
+		}
+	}
+	for name, haveSig := range haveSigs {
+		if _, ok := wantSigs[name]; !ok {
+			diffs = append(diffs, fmt.Sprintf("%s: no longer in the XML\n\thave: %s",
+				name, haveSig))
+		}
+	}
+	sort.Strings(diffs)
+	if len(diffs) == 0 {
+		return fmt.Errorf("%s is out of date", path)
+	}
+	return fmt.Errorf("%s is out of date:\n%s", path, strings.Join(diffs, "\n"))
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"github.com/jsouthworth/seriatim/dbus"
+	"os"
+	"os/signal"
+)
+
+const (
+	dest = "com.github.jsouthworth.dbustest"
+	path = "/foo"
+)
+
+type Signal interface {
+	Sig1(string)
+	Sig2(string)
+	Sig3(string)
+}
+
+// Receives the signals.Sigs signals on the client's own object tree.
+type listener struct{}
+
+func (_ *listener) Sig1(s string) {
+	fmt.Println("Sig1:", s)
+}
+
+func (_ *listener) Sig2(s string) {
+	fmt.Println("Sig2:", s)
+}
+
+func (_ *listener) Sig3(s string) {
+	fmt.Println("Sig3:", s)
+}
+
+func handle_error(err error) {
+	if err == nil {
+		return
+	}
+	fmt.Println(err)
+}
+
+func main() {
+	client, err := dbus.NewAnonymousSessionBusManager()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	foo := client.NewProxy(dest, path, "net.jsouthworth.Foo")
+	for i := 0; i < 3; i++ {
+		var out string
+		err = foo.Call("Foo").Store(&out)
+		handle_error(err)
+		fmt.Println("Foo:", out)
+	}
+
+	var baz string
+	err = foo.Call("Baz", int32(42)).Store(&baz)
+	handle_error(err)
+	fmt.Println("Baz:", baz)
+
+	// The same method exported under a mapped name
+	var lower string
+	err = client.NewProxy(dest, path, "net.jsouthworth.foo").
+		Call("foo").Store(&lower)
+	handle_error(err)
+	fmt.Println("foo:", lower)
+
+	// Bar always fails; the error comes back as a D-Bus error
+	var bar string
+	err = client.NewProxy(dest, path, "net.jsouthworth.Bar").
+		Call("Bar").Store(&bar)
+	fmt.Println("Bar:", bar, err)
+
+	obj := client.NewObject("/listener", &listener{})
+	err = obj.Receives("signals.Sigs", (*Signal)(nil), nil)
+	handle_error(err)
+
+	fmt.Println("waiting for signals.Sigs signals, interrupt to exit")
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, os.Interrupt)
+	<-sigs
+}
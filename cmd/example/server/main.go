@@ -3,6 +3,8 @@ package main
 import (
 	"fmt"
 	"github.com/jsouthworth/seriatim/dbus"
+	"github.com/jsouthworth/seriatim/debug"
+	"github.com/jsouthworth/seriatim/wsgateway"
 	"log"
 	"net/http"
 )
@@ -10,6 +12,7 @@ import (
 import _ "net/http/pprof"
 
 func init() {
+	http.Handle("/debug/seriatim", debug.Handler())
 	go func() {
 		log.Println(http.ListenAndServe("localhost:6060", nil))
 	}()
@@ -59,9 +62,10 @@ func handle_error(err error) {
 }
 
 func main() {
-	supervisor, err := dbus.NewSessionBusManager(
+	supervisor, err := dbus.NewUnreadySessionBusManager(
 		"com.github.jsouthworth.dbustest")
 	handle_error(err)
+	http.Handle("/ws", wsgateway.New(supervisor))
 
 	obj := supervisor.NewObject("/foo", &anObject{})
 	err = obj.Implements("net.jsouthworth.Foo", (*Foo)(nil))
@@ -88,5 +92,7 @@ func main() {
 	err = obj.Implements("net.jsouthworth.Bar", (*Bar)(nil))
 	handle_error(err)
 
+	handle_error(supervisor.Ready())
+
 	select {}
 }
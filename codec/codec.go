@@ -0,0 +1,147 @@
+// Package codec encodes the argument and result lists of sequent
+// methods for the transports carrying them between processes.
+//
+// Codecs are registered by name so that a transport can tell the
+// other end which one a message was encoded with. Gob and JSON are
+// registered by this package; other encodings, such as CBOR or the
+// D-Bus wire format, are registered by the packages providing them:
+//
+//	func init() {
+//		codec.Register(cborCodec{})
+//	}
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/jsouthworth/seriatim"
+)
+
+// Codec encodes lists of values.
+type Codec interface {
+	// Name identifies the codec in the registry.
+	Name() string
+	Encode(values []interface{}) ([]byte, error)
+	// Decode decodes the values in data. When types is not nil and
+	// has a type for each value, the values are decoded into those
+	// types if the encoding allows it, otherwise into the codec's
+	// own types.
+	Decode(data []byte, types []reflect.Type) ([]interface{}, error)
+}
+
+var (
+	mu     sync.RWMutex
+	codecs = make(map[string]Codec)
+)
+
+// Register makes c available by its name. It panics if a codec with
+// the same name is already registered.
+func Register(c Codec) {
+	mu.Lock()
+	defer mu.Unlock()
+	name := c.Name()
+	if _, ok := codecs[name]; ok {
+		panic(fmt.Sprintf("codec %q registered twice", name))
+	}
+	codecs[name] = c
+}
+
+// Lookup returns the codec registered with name.
+func Lookup(name string) (Codec, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	c, ok := codecs[name]
+	return c, ok
+}
+
+// ArgTypes returns the parameter types of method as described by
+// s.Methods, or nil if s does not describe it.
+func ArgTypes(s seriatim.Sequent, method string) []reflect.Type {
+	for _, info := range s.Methods() {
+		if info.Name == method {
+			return info.Args
+		}
+	}
+	return nil
+}
+
+type gobCodec struct{}
+
+func (gobCodec) Name() string {
+	return "gob"
+}
+
+func (gobCodec) Encode(values []interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(values); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Decode ignores types as gob carries the type of every value.
+func (gobCodec) Decode(data []byte, types []reflect.Type) ([]interface{}, error) {
+	var values []interface{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&values); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+func (jsonCodec) Encode(values []interface{}) ([]byte, error) {
+	return json.Marshal(values)
+}
+
+func (jsonCodec) Decode(data []byte, types []reflect.Type) ([]interface{}, error) {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	values := make([]interface{}, len(raw))
+	for i, msg := range raw {
+		var value interface{}
+		switch {
+		case bytes.Equal(msg, []byte("null")):
+			// left nil for the sequent to reject if the
+			// parameter cannot be nil
+		case types != nil && len(types) == len(raw):
+			ptr := reflect.New(types[i])
+			if err := json.Unmarshal(msg, ptr.Interface()); err != nil {
+				return nil, fmt.Errorf("Argument %d: %w", i, err)
+			}
+			value = ptr.Elem().Interface()
+		default:
+			if err := json.Unmarshal(msg, &value); err != nil {
+				return nil, err
+			}
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+var (
+	// Gob preserves the types of values; types other than the basic
+	// ones must be registered with gob.Register on both ends.
+	Gob Codec = gobCodec{}
+	// JSON decodes values into the given types, or into the generic
+	// JSON types which a sequent converts to its parameter types
+	// where possible.
+	JSON Codec = jsonCodec{}
+)
+
+func init() {
+	Register(Gob)
+	Register(JSON)
+}
@@ -0,0 +1,81 @@
+package codec
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jsouthworth/seriatim"
+)
+
+type point struct {
+	X, Y int
+}
+
+type plotter struct {
+	points []point
+}
+
+func (p *plotter) Plot(at point, label string) int {
+	p.points = append(p.points, at)
+	return len(p.points)
+}
+
+func TestRegistry(t *testing.T) {
+	for _, c := range []Codec{Gob, JSON} {
+		if got, ok := Lookup(c.Name()); !ok || got != c {
+			t.Errorf("%s not registered", c.Name())
+		}
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("registering a name twice did not panic")
+		}
+	}()
+	Register(jsonCodec{})
+}
+
+func TestRoundTrip(t *testing.T) {
+	values := []interface{}{"a", 1, nil, true}
+	for _, c := range []Codec{Gob, JSON} {
+		data, err := c.Encode(values)
+		if err != nil {
+			t.Fatal(err)
+		}
+		types := []reflect.Type{
+			reflect.TypeOf(""), reflect.TypeOf(0),
+			reflect.TypeOf((*int)(nil)), reflect.TypeOf(false),
+		}
+		got, err := c.Decode(data, types)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, values) {
+			t.Errorf("%s decoded %#v", c.Name(), got)
+		}
+	}
+}
+
+func TestJSONArgTypes(t *testing.T) {
+	s := seriatim.NewSequent(&plotter{})
+	defer s.Terminate(nil)
+	args, err := JSON.Decode([]byte(`[{"X":1,"Y":2},"origin"]`),
+		ArgTypes(s, "Plot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Call("Plot", args...); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := JSON.Decode([]byte(`["x","origin"]`),
+		ArgTypes(s, "Plot")); err == nil {
+		t.Fatal("expected an error decoding the point")
+	}
+	// without types the generic JSON types are used
+	args, err = JSON.Decode([]byte(`[{"X":1}]`), ArgTypes(s, "Missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := args[0].(map[string]interface{}); !ok {
+		t.Fatalf("unexpected %#v", args[0])
+	}
+}
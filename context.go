@@ -0,0 +1,55 @@
+package seriatim
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// takesContext reports whether methods of type t are given a context
+// by the sequent rather than by their callers, see NewSequent.
+func takesContext(t reflect.Type) bool {
+	return t.NumIn() > 0 && t.In(0) == contextType
+}
+
+// callerArgs returns the arguments of req given by its caller.
+func callerArgs(req *request) []reflect.Value {
+	if req.method.IsValid() && takesContext(req.method.Type()) {
+		return req.args[1:]
+	}
+	return req.args
+}
+
+// withContext sets the context of args, the arguments of method, when
+// it takes one, returning the function canceling it.
+func (a *sequent) withContext(
+	method reflect.Value,
+	args []reflect.Value,
+	req *request,
+) context.CancelFunc {
+	if !takesContext(method.Type()) {
+		return func() {}
+	}
+	ctx, cancel := a.ctx, context.CancelFunc(func() {})
+	if deadline := req.contextDeadline(); !deadline.IsZero() {
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+	}
+	args[0] = reflect.ValueOf(&ctx).Elem()
+	return cancel
+}
+
+// contextDeadline returns the deadline of the context of req, zero for
+// none.
+func (req *request) contextDeadline() time.Time {
+	if req == nil {
+		return time.Time{}
+	}
+	deadline := req.deadline
+	if !req.callerDeadline.IsZero() &&
+		(deadline.IsZero() || req.callerDeadline.Before(deadline)) {
+		deadline = req.callerDeadline
+	}
+	return deadline
+}
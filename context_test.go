@@ -0,0 +1,111 @@
+package seriatim
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type poller struct {
+	started chan struct{}
+}
+
+func (p *poller) Poll(ctx context.Context, n int) error {
+	close(p.started)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (p *poller) Deadline(ctx context.Context) (time.Time, bool) {
+	return ctx.Deadline()
+}
+
+func TestContextCanceledOnTerminate(t *testing.T) {
+	p := &poller{started: make(chan struct{})}
+	s := NewSequent(p)
+	errs := make(chan error, 1)
+	if err := s.CastNotify("Poll", errs, 1); err != nil {
+		t.Fatal(err)
+	}
+	<-p.started
+	s.Terminate(nil)
+	if err := <-errs; err != context.Canceled {
+		t.Fatalf("expected the context to be canceled, got %v", err)
+	}
+	<-s.Done()
+}
+
+func TestContextDeadline(t *testing.T) {
+	s := NewSequent(&poller{})
+	defer s.Terminate(nil)
+	rets, err := s.Call("Deadline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rets[1].(bool) {
+		t.Fatalf("expected no deadline, got %v", rets[0])
+	}
+
+	deadline := time.Now().Add(time.Minute)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	rets, err = s.CallContext(ctx, "Deadline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rets[0].(time.Time).Equal(deadline) {
+		t.Fatalf("expected the deadline of the caller, got %v", rets[0])
+	}
+
+	s = NewSequentTable(&poller{}, map[string]interface{}{
+		"Deadline": MethodSpec{
+			Func:    (&poller{}).Deadline,
+			Timeout: time.Second,
+		},
+	})
+	defer s.Terminate(nil)
+	rets, err = s.CallContext(ctx, "Deadline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rets[0].(time.Time).Before(deadline) {
+		t.Fatalf("expected the deadline of the method, got %v", rets[0])
+	}
+}
+
+func TestContextArguments(t *testing.T) {
+	s := NewSequent(&poller{started: make(chan struct{})})
+	defer s.Terminate(nil)
+	var count *ArgCountError
+	if _, err := s.Call("Poll"); !errors.As(err, &count) ||
+		count.Want != 1 {
+		t.Fatalf("expected the context not to be counted, got %v", err)
+	}
+	if _, err := s.Call("Poll", context.Background(), 1); !errors.As(err, &count) {
+		t.Fatalf("expected the context not to be passed, got %v", err)
+	}
+	for _, info := range s.Methods() {
+		if info.Name == "Poll" &&
+			!reflect.DeepEqual(info.Args, []reflect.Type{reflect.TypeOf(0)}) {
+			t.Fatalf("expected the context hidden, got %v", info.Args)
+		}
+	}
+}
+
+func TestContextMiddleware(t *testing.T) {
+	passthrough := func(next Handler) Handler { return next }
+	s := NewSequent(&poller{}, WithMiddleware(passthrough))
+	defer s.Terminate(nil)
+	deadline := time.Now().Add(time.Minute)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	rets, err := s.CallContext(ctx, "Deadline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rets[0].(time.Time).Equal(deadline) {
+		t.Fatalf("expected the deadline of the caller, got %v", rets[0])
+	}
+}
@@ -0,0 +1,106 @@
+package seriatim
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// ArgRangeError is returned when a numeric argument of a method would
+// change value if converted to the type of its parameter, such as 300
+// for an int8 or -1 for a uint.
+type ArgRangeError struct {
+	Method string
+	Index  int
+	Value  interface{}
+	Want   reflect.Type
+}
+
+func (e *ArgRangeError) Error() string {
+	return fmt.Sprintf("Argument %d of method %s: %v does not fit type %s",
+		e.Index, e.Method, e.Value, e.Want)
+}
+
+// CheckArguments reports whether args can be passed to fn as a request
+// for method, as a sequent checks them when the request is made. It
+// lets code standing in for a sequent, such as a test double, reject
+// the requests the sequent would.
+func CheckArguments(method string, fn interface{}, args ...interface{}) error {
+	if spec, ok := fn.(MethodSpec); ok {
+		fn = spec.Func
+	}
+	value := reflect.ValueOf(fn)
+	if value.Kind() != reflect.Func || value.IsNil() {
+		return ErrUnknownMethod
+	}
+	_, err := processMethodArguments(method, value, args...)
+	return err
+}
+
+// convertArgument converts arg, the argument index of method, to the
+// type of its parameter. Numbers are converted between types as long
+// as they keep their value, other values as Go converts them, except
+// for integers to strings, which makes characters of them.
+func convertArgument(
+	method string,
+	index int,
+	arg reflect.Value,
+	param reflect.Type,
+) (reflect.Value, error) {
+	arg_type := arg.Type()
+	switch {
+	case arg_type.AssignableTo(param):
+		return arg, nil
+	case numeric(arg_type) && numeric(param) &&
+		arg_type.ConvertibleTo(param):
+		out := arg.Convert(param)
+		if !sameNumber(arg, out) {
+			return reflect.Value{}, &ArgRangeError{
+				Method: method,
+				Index:  index,
+				Value:  arg.Interface(),
+				Want:   param,
+			}
+		}
+		return out, nil
+	case arg_type.ConvertibleTo(param) && !shortArray(arg, param) &&
+		!(numeric(arg_type) && param.Kind() == reflect.String):
+		return arg.Convert(param), nil
+	}
+	return reflect.Value{}, &ArgTypeError{
+		Method: method,
+		Index:  index,
+		Have:   arg_type,
+		Want:   param,
+	}
+}
+
+func numeric(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Complex64, reflect.Complex128:
+		return true
+	}
+	return false
+}
+
+// sameNumber reports whether out, converted from in, has its value.
+// Floating point numbers may lose precision when narrowed but not
+// overflow.
+func sameNumber(in, out reflect.Value) bool {
+	switch {
+	case in.CanFloat() && out.CanFloat():
+		return !out.OverflowFloat(in.Float())
+	case in.CanComplex() && out.CanComplex():
+		return !out.OverflowComplex(in.Complex())
+	case in.CanInt() && in.Int() < 0 && out.CanUint(),
+		in.CanUint() && out.CanInt() && out.Int() < 0,
+		in.CanFloat() && in.Float() < 0 && out.CanUint():
+		return false
+	}
+	// a number survives the round trip if it fits
+	back := out.Convert(in.Type())
+	return back.Interface() == in.Interface()
+}
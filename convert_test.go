@@ -0,0 +1,72 @@
+package seriatim
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+type sized struct{}
+
+func (sized) Int8(n int8) int8          { return n }
+func (sized) Uint(n uint) uint          { return n }
+func (sized) Int64(n int64) int64       { return n }
+func (sized) Float32(f float32) float32 { return f }
+func (sized) Float64(f float64) float64 { return f }
+func (sized) String(s string) string    { return s }
+
+func TestConvertArguments(t *testing.T) {
+	s := NewSequent(sized{})
+	defer s.Terminate(nil)
+	for _, test := range []struct {
+		method string
+		arg    interface{}
+		want   interface{}
+	}{
+		{"Int64", 42, int64(42)},
+		{"Int8", int64(-128), int8(-128)},
+		{"Uint", int32(7), uint(7)},
+		{"Float64", 3, 3.0},
+		{"Float32", 0.1, float32(0.1)},
+		{"Int64", 2.0, int64(2)},
+	} {
+		rets, err := s.Call(test.method, test.arg)
+		if err != nil {
+			t.Fatalf("%s(%#v): %v", test.method, test.arg, err)
+		}
+		if rets[0] != test.want {
+			t.Fatalf("%s(%#v): expected %#v, got %#v", test.method,
+				test.arg, test.want, rets[0])
+		}
+	}
+
+	for _, test := range []struct {
+		method string
+		arg    interface{}
+	}{
+		{"Int8", 300},
+		{"Uint", -1},
+		{"Int64", uint64(math.MaxUint64)},
+		{"Int64", 2.5},
+		{"Int64", math.NaN()},
+		{"Float64", int64(1<<53 + 1)},
+		{"Float32", math.MaxFloat64},
+	} {
+		_, err := s.Call(test.method, test.arg)
+		var rng *ArgRangeError
+		if !errors.As(err, &rng) || rng.Method != test.method {
+			t.Fatalf("%s(%#v): expected an ArgRangeError, got %v",
+				test.method, test.arg, err)
+		}
+	}
+
+	_, err := s.Call("String", 65)
+	var typ *ArgTypeError
+	if !errors.As(err, &typ) {
+		t.Fatalf("expected integers not to become characters, got %v", err)
+	}
+	if err := CheckArguments("Int8", sized{}.Int8, 300); err == nil ||
+		err.Error() != "Argument 0 of method Int8: 300 does not fit type int8" {
+		t.Fatalf("unexpected %v", err)
+	}
+}
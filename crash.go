@@ -0,0 +1,100 @@
+package seriatim
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+)
+
+// crashArgLen is how much of each argument a CrashReport keeps.
+const crashArgLen = 128
+
+// CrashReport describes the crash of a sequent.
+type CrashReport struct {
+	Id   uintptr
+	Type string
+	// Method is the method that crashed the sequent, or the hook or
+	// HandleBatch; it is empty for crashes not caused by a method,
+	// such as ErrTerminateTimeout.
+	Method string
+	// Args summarises the arguments of Method, each formatted with %v
+	// and cut short if long, so that reports do not hold on to them.
+	Args   []string
+	Reason error
+	// Stack is the stack of the panicking goroutine, if any.
+	Stack []byte
+	Time  time.Time
+}
+
+// CrashSink is sent a report of every crash of a sequent, such as to
+// forward it to an error tracking service. Crashed is called on the
+// goroutine of the crashed sequent before its supervisor is told and
+// should not block.
+type CrashSink interface {
+	Crashed(report *CrashReport)
+}
+
+// CrashSinkFunc is a CrashSink calling itself.
+type CrashSinkFunc func(report *CrashReport)
+
+func (fn CrashSinkFunc) Crashed(report *CrashReport) {
+	fn(report)
+}
+
+type crashSinkHolder struct {
+	sink CrashSink
+}
+
+var crashSink atomic.Value
+
+// SetCrashSink sends the reports of crashes to sink rather than
+// logging them as sequent crashed events, see SetLogger. Passing nil
+// restores the default of logging them. Crashes are still kept for
+// Crashes either way.
+func SetCrashSink(sink CrashSink) {
+	crashSink.Store(crashSinkHolder{sink: sink})
+}
+
+// crashed reports the crash of the sequent in req with reason, and the
+// stack of the panic if there was one.
+func (a *sequent) crashed(req *request, reason error, stack []byte) {
+	report := &CrashReport{
+		Id:     a.Id(),
+		Type:   a.typeName(),
+		Reason: reason,
+		Stack:  stack,
+		Time:   time.Now(),
+	}
+	if req != nil {
+		report.Method = req.name
+		report.Args = summarizeArgs(req)
+	}
+	recordCrash(report)
+	holder, _ := crashSink.Load().(crashSinkHolder)
+	if holder.sink != nil {
+		holder.sink.Crashed(report)
+		return
+	}
+	a.logCrash(report)
+}
+
+func summarizeArgs(req *request) []string {
+	args := callerArgs(req)
+	if len(args) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(args))
+	for _, arg := range args {
+		s := fmt.Sprintf("%v", arg)
+		if len(s) > crashArgLen {
+			n := crashArgLen
+			for !utf8.RuneStart(s[n]) {
+				n--
+			}
+			s = s[:n] + "..."
+		}
+		out = append(out, s)
+	}
+	return out
+}
@@ -0,0 +1,75 @@
+package seriatim
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+type crasher struct{}
+
+func (c *crasher) Crash(what string, n int) {
+	panic("crashed on " + what)
+}
+
+func (c *crasher) CrashContext(ctx context.Context, what string) {
+	panic("crashed on " + what)
+}
+
+func (c *crasher) Block() {
+	select {}
+}
+
+func TestCrashSink(t *testing.T) {
+	reports := make(chan *CrashReport, 2)
+	SetCrashSink(CrashSinkFunc(func(report *CrashReport) {
+		reports <- report
+	}))
+	defer SetCrashSink(nil)
+
+	s := NewSequent(&crasher{})
+	s.Cast("Crash", strings.Repeat("x", 2*crashArgLen), 42)
+	<-s.Done()
+	report := <-reports
+	if report.Id != s.Id() || report.Type != "*seriatim.crasher" ||
+		report.Method != "Crash" || report.Time.IsZero() {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if len(report.Args) != 2 ||
+		report.Args[0] != strings.Repeat("x", crashArgLen)+"..." ||
+		report.Args[1] != "42" {
+		t.Fatalf("unexpected arguments %q", report.Args)
+	}
+	if report.Reason.Error() != "crashed on "+strings.Repeat("x", 2*crashArgLen) ||
+		!strings.Contains(string(report.Stack), "(*crasher).Crash") {
+		t.Fatalf("unexpected reason %v or stack:\n%s",
+			report.Reason, report.Stack)
+	}
+
+	s = NewSequent(&crasher{})
+	s.Cast("Block")
+	TerminateWithTimeout(s, nil, time.Millisecond)
+	report = <-reports
+	if report.Reason != ErrTerminateTimeout || report.Method != "" ||
+		report.Stack != nil {
+		t.Fatalf("unexpected report %+v", report)
+	}
+}
+
+func TestCrashReportSkipsContext(t *testing.T) {
+	reports := make(chan *CrashReport, 1)
+	SetCrashSink(CrashSinkFunc(func(report *CrashReport) {
+		reports <- report
+	}))
+	defer SetCrashSink(nil)
+
+	s := NewSequent(&crasher{})
+	s.Cast("CrashContext", "x")
+	<-s.Done()
+	report := <-reports
+	// the context is given by the sequent, not the caller
+	if len(report.Args) != 1 || report.Args[0] != "x" {
+		t.Fatalf("unexpected arguments %q", report.Args)
+	}
+}
@@ -0,0 +1,92 @@
+package dbus
+
+import (
+	"strings"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	fdtAccessDenied          = fdtDBusName + ".Error.AccessDenied"
+	fdtGetNameOwner          = fdtDBusName + ".GetNameOwner"
+	fdtGetConnectionUnixUser = fdtDBusName + ".GetConnectionUnixUser"
+)
+
+// ErrAccessDenied answers calls to an interface exported with
+// ImplementsFor from senders it does not allow.
+var ErrAccessDenied = dbus.NewError(fdtAccessDenied,
+	[]interface{}{"Sender is not allowed to call this interface"})
+
+// A Principal may call the methods of an interface exported with
+// ImplementsFor.
+type Principal interface {
+	allows(bus *BusManager, sender string) bool
+}
+
+// BusName allows the connection owning a unique or well-known name.
+type BusName string
+
+func (name BusName) allows(bus *BusManager, sender string) bool {
+	if string(name) == sender {
+		return true
+	}
+	if strings.HasPrefix(string(name), ":") || bus == nil {
+		return false
+	}
+	var owner string
+	err := bus.conn.BusObject().Call(fdtGetNameOwner, 0, string(name)).
+		Store(&owner)
+	return err == nil && owner == sender
+}
+
+// UID allows the connections of a Unix user.
+type UID uint32
+
+func (uid UID) allows(bus *BusManager, sender string) bool {
+	if bus == nil {
+		return false
+	}
+	var have uint32
+	err := bus.conn.BusObject().Call(fdtGetConnectionUnixUser, 0, sender).
+		Store(&have)
+	return err == nil && UID(have) == uid
+}
+
+// accessList restricts the senders calling the methods of an
+// interface.
+type accessList struct {
+	bus     *BusManager
+	allowed []Principal
+}
+
+// allows reports whether sender may call the methods. Calls not made
+// over the bus have no sender and are refused.
+func (a *accessList) allows(sender string) bool {
+	if a == nil {
+		return true
+	}
+	if sender == "" {
+		return false
+	}
+	for _, p := range a.allowed {
+		if p.allows(a.bus, sender) {
+			return true
+		}
+	}
+	return false
+}
+
+// ImplementsFor exports obj as the interface name like Implements, but
+// answers the calls of senders other than allowed with
+// ErrAccessDenied.
+func (o *Object) ImplementsFor(
+	name string,
+	obj interface{},
+	allowed ...Principal,
+) error {
+	access := &accessList{bus: o.bus, allowed: allowed}
+	return o.implementsTypes(name, getMethodTypes(obj),
+		func(in string) string {
+			return in
+		}, access)
+}
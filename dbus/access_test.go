@@ -0,0 +1,57 @@
+package dbus
+
+import (
+	"testing"
+
+	"github.com/godbus/dbus"
+)
+
+func TestImplementsFor(t *testing.T) {
+	methods := map[string]interface{}{
+		"CallMe": interface{}(func() string { return "hello, world" }),
+	}
+	obj := NewObjectFromTable("foo", methods, nil, nil)
+	err := obj.ImplementsFor("foo", (*testIface)(nil), BusName(":1.5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	iface, _ := obj.LookupInterface("foo")
+
+	call := func(sender string) error {
+		method, _ := iface.LookupMethod("CallMe")
+		_, err := method.(*Method).DecodeArguments(nil, sender,
+			&dbus.Message{}, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = method.Call()
+		return err
+	}
+	if err := call(":1.5"); err != nil {
+		t.Fatal(err)
+	}
+	if err := call(":1.6"); err != ErrAccessDenied {
+		t.Fatalf("expected ErrAccessDenied, got %v", err)
+	}
+	if _, err := obj.Call("foo", "CallMe"); err != ErrAccessDenied {
+		t.Fatalf("expected a call without sender to be denied, got %v", err)
+	}
+}
+
+func TestImplementsForThenEmits(t *testing.T) {
+	methods := map[string]interface{}{
+		"CallMe": interface{}(func() string { return "hello, world" }),
+	}
+	obj := NewObjectFromTable("foo", methods, nil, nil)
+	err := obj.ImplementsFor("foo", (*testIface)(nil), BusName(":1.5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := obj.Emits("foo", (*testSignals)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	// the signals do not lift the restriction on the methods
+	if _, err := obj.Call("foo", "CallMe"); err != ErrAccessDenied {
+		t.Fatalf("expected ErrAccessDenied, got %v", err)
+	}
+}
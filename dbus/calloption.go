@@ -0,0 +1,120 @@
+package dbus
+
+import (
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	fdtDisconnected = fdtDBusName + ".Error.Disconnected"
+	fdtTimeout      = fdtDBusName + ".Error.Timeout"
+
+	// flagAllowInteractiveAuthorization is the header flag letting
+	// the service prompt the user to authorize the call, which not
+	// every version of godbus names.
+	flagAllowInteractiveAuthorization dbus.Flags = 0x4
+)
+
+// ErrTimeout fails calls made through a Proxy that were not answered
+// within the time set with Timeout.
+var ErrTimeout = dbus.NewError(fdtTimeout,
+	[]interface{}{"Call was not answered in time"})
+
+type callOptions struct {
+	flags   dbus.Flags
+	timeout time.Duration
+	retries int
+}
+
+// CallOption configures the calls made through a Proxy returned by
+// With.
+type CallOption func(*callOptions)
+
+// Timeout fails calls with ErrTimeout if they are not answered within
+// d. The reply is dropped if it comes later.
+func Timeout(d time.Duration) CallOption {
+	return func(opts *callOptions) {
+		opts.timeout = d
+	}
+}
+
+// NoAutoStart keeps the bus from starting the destination of calls
+// that is not running, failing them instead.
+func NoAutoStart() CallOption {
+	return func(opts *callOptions) {
+		opts.flags |= dbus.FlagNoAutoStart
+	}
+}
+
+// AllowInteractiveAuthorization lets the destination of calls prompt
+// the user to authorize them, such as through polkit, which may take
+// long enough to need a Timeout.
+func AllowInteractiveAuthorization() CallOption {
+	return func(opts *callOptions) {
+		opts.flags |= flagAllowInteractiveAuthorization
+	}
+}
+
+// Retries retries calls up to n times while they fail with
+// org.freedesktop.DBus.Error.Disconnected, such as ones made while
+// their destination restarts.
+func Retries(n int) CallOption {
+	return func(opts *callOptions) {
+		opts.retries = n
+	}
+}
+
+// With returns a copy of p making its calls with opts on top of those
+// of p.
+func (p *Proxy) With(opts ...CallOption) *Proxy {
+	out := *p
+	for _, opt := range opts {
+		opt(&out.opts)
+	}
+	return &out
+}
+
+// call makes a call of the fully qualified method with the options of
+// p.
+func (p *Proxy) call(method string, args ...interface{}) *dbus.Call {
+	for attempt := 0; ; attempt++ {
+		call := p.callOnce(method, args...)
+		if attempt >= p.opts.retries || !disconnected(call.Err) {
+			return call
+		}
+	}
+}
+
+func (p *Proxy) callOnce(method string, args ...interface{}) *dbus.Call {
+	if p.opts.timeout <= 0 {
+		return p.object.Call(method, p.opts.flags, args...)
+	}
+	// buffered so that a late reply is dropped
+	done := make(chan *dbus.Call, 1)
+	p.object.Go(method, p.opts.flags, done, args...)
+	timer := time.NewTimer(p.opts.timeout)
+	defer timer.Stop()
+	select {
+	case call := <-done:
+		return call
+	case <-timer.C:
+		return &dbus.Call{
+			Destination: p.object.Destination(),
+			Path:        p.object.Path(),
+			Method:      method,
+			Args:        args,
+			Err:         ErrTimeout,
+		}
+	}
+}
+
+func disconnected(err error) bool {
+	switch err := err.(type) {
+	case dbus.Error:
+		return err.Name == fdtDisconnected
+	case *dbus.Error:
+		return err.Name == fdtDisconnected
+	}
+	return false
+}
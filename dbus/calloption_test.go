@@ -0,0 +1,50 @@
+package dbus
+
+import (
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+func TestProxyCallOptions(t *testing.T) {
+	obj := &fakeBusObject{body: []interface{}{"hello"}}
+	proxy := NewProxy(obj, "com.example.Foo")
+	strict := proxy.With(NoAutoStart(), AllowInteractiveAuthorization())
+	if err := strict.Call("Baz").Err; err != nil {
+		t.Fatal(err)
+	}
+	want := dbus.FlagNoAutoStart | flagAllowInteractiveAuthorization
+	if obj.flags != want {
+		t.Fatalf("expected flags %#x, got %#x", want, obj.flags)
+	}
+	if err := proxy.Call("Baz").Err; err != nil || obj.flags != 0 {
+		t.Fatalf("options leaked to the original proxy: %v, %#x", err, obj.flags)
+	}
+
+	var out string
+	if err := strict.With(Timeout(time.Second)).Call("Baz").Store(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out != "hello" || obj.flags != want {
+		t.Fatalf("unexpected answer %q with flags %#x", out, obj.flags)
+	}
+	obj.hang = true
+	if err := proxy.With(Timeout(10 * time.Millisecond)).Call("Baz").Err; err != ErrTimeout {
+		t.Fatalf("expected ErrTimeout, got %v", err)
+	}
+}
+
+func TestProxyRetries(t *testing.T) {
+	obj := &fakeBusObject{body: []interface{}{"hello"}, failures: 2}
+	proxy := NewProxy(obj, "com.example.Foo")
+	if err := proxy.With(Retries(1)).Call("Baz").Err; !disconnected(err) {
+		t.Fatalf("expected Disconnected, got %v", err)
+	}
+	if err := proxy.With(Retries(1)).Call("Baz").Err; err != nil {
+		t.Fatal(err)
+	}
+	if obj.calls != 3 {
+		t.Fatalf("expected 3 attempts, made %d", obj.calls)
+	}
+}
@@ -0,0 +1,184 @@
+package dbus
+
+import (
+	"errors"
+	"path"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+// ChunkedInterface is the D-Bus interface of the objects exported by
+// Chunks. Its Size method returns the length of their data and its
+// Read method up to count bytes of it from offset, at most
+// MaxChunkSize; its Close method removes the object.
+const ChunkedInterface = "com.github.jsouthworth.Seriatim.Chunked"
+
+const (
+	// MaxChunkSize is the most bytes Read returns at a time, well
+	// within the 64MiB D-Bus allows arrays to have.
+	MaxChunkSize = 16 << 20
+	// ChunkSize is the number of bytes ReadChunked asks for at a
+	// time.
+	ChunkSize = 1 << 20
+)
+
+// ErrShortRead fails ReadChunked when a chunked object returns less
+// data than its size.
+var ErrShortRead = errors.New("Chunked object returned less than its size")
+
+type chunkedMethods interface {
+	Size() uint64
+	Read(offset uint64, count uint32) ([]byte, error)
+	Close()
+}
+
+type chunkedData struct {
+	data  []byte
+	close func()
+}
+
+func (c *chunkedData) Size() uint64 {
+	return uint64(len(c.data))
+}
+
+func (c *chunkedData) Read(offset uint64, count uint32) ([]byte, error) {
+	if offset > uint64(len(c.data)) {
+		return nil, invalidArgs("Offset %d is past the end of %d bytes",
+			offset, len(c.data))
+	}
+	if count > MaxChunkSize {
+		count = MaxChunkSize
+	}
+	end := offset + uint64(count)
+	if end > uint64(len(c.data)) {
+		end = uint64(len(c.data))
+	}
+	return c.data[offset:end], nil
+}
+
+func (c *chunkedData) Close() {
+	// the object cannot be removed while it processes this call
+	go c.close()
+}
+
+// Chunks exports data too large to be sent in a single message, whose
+// size D-Bus limits, as chunked objects read a chunk at a time by
+// ReadChunked. Reply lets a method fall back to one only when the data
+// does not fit in its reply:
+//
+//	func (s *service) Dump() ([]byte, dbus.ObjectPath, error) {
+//		return s.chunks.Reply(s.dump())
+//	}
+type Chunks struct {
+	parent *Object
+	path   dbus.ObjectPath
+	idle   time.Duration
+	next   uint64
+}
+
+// NewChunks returns Chunks exporting its chunked objects under path.
+// They are removed when closed by their reader or once they have not
+// been read from for idle, if it is not zero.
+func (o *Object) NewChunks(path dbus.ObjectPath, idle time.Duration) *Chunks {
+	return &Chunks{parent: o, path: path, idle: idle}
+}
+
+// Serve exports data as a chunked object and returns its path. data
+// must not be modified afterwards.
+func (chunks *Chunks) Serve(data []byte) (dbus.ObjectPath, error) {
+	n := atomic.AddUint64(&chunks.next, 1)
+	objPath := dbus.ObjectPath(
+		path.Join(string(chunks.path), strconv.FormatUint(n, 10)))
+	state := &chunkedData{data: data}
+	var obj *Object
+	if chunks.idle > 0 {
+		obj = chunks.parent.NewExpiringObject(objPath, state,
+			Expiry{Idle: chunks.idle})
+	} else {
+		obj = chunks.parent.NewObject(objPath, state)
+	}
+	state.close = func() {
+		// unless it expired meanwhile
+		if current, ok := obj.parent.LookupObject(obj.name); ok &&
+			current == obj {
+			obj.removeFrom(chunks.parent, objPath)
+		}
+	}
+	if err := obj.Implements(ChunkedInterface, (*chunkedMethods)(nil)); err != nil {
+		chunks.parent.DeleteObject(objPath)
+		return "", err
+	}
+	return obj.Path(), nil
+}
+
+// Reply returns data as the reply of a method when it is shorter than
+// MaxChunkSize, and otherwise the path of a chunked object serving it
+// in its stead. The root path stands for no object; ReadReply reads
+// either back.
+func (chunks *Chunks) Reply(data []byte) ([]byte, dbus.ObjectPath, error) {
+	if len(data) < MaxChunkSize {
+		return data, "/", nil
+	}
+	path, err := chunks.Serve(data)
+	return []byte{}, path, err
+}
+
+// ReadChunked reads all the data of the chunked object at path owned
+// by dest, making its calls with opts, and closes it.
+func (mgr *BusManager) ReadChunked(
+	dest string,
+	path dbus.ObjectPath,
+	opts ...CallOption,
+) ([]byte, error) {
+	p := mgr.NewProxy(dest, path, ChunkedInterface).With(opts...)
+	defer p.Call("Close")
+	var size uint64
+	if err := p.Call("Size").Store(&size); err != nil {
+		return nil, err
+	}
+	return readChunks(size, func(offset uint64, count uint32) ([]byte, error) {
+		var chunk []byte
+		err := p.Call("Read", offset, count).Store(&chunk)
+		return chunk, err
+	})
+}
+
+// ReadReply returns the data of a reply made with Chunks.Reply, reading
+// it from the chunked object at path owned by dest unless it came
+// inline.
+func (mgr *BusManager) ReadReply(
+	dest string,
+	data []byte,
+	path dbus.ObjectPath,
+	opts ...CallOption,
+) ([]byte, error) {
+	if path == "/" {
+		return data, nil
+	}
+	return mgr.ReadChunked(dest, path, opts...)
+}
+
+func readChunks(
+	size uint64,
+	read func(offset uint64, count uint32) ([]byte, error),
+) ([]byte, error) {
+	out := make([]byte, 0, size)
+	for uint64(len(out)) < size {
+		count := size - uint64(len(out))
+		if count > ChunkSize {
+			count = ChunkSize
+		}
+		chunk, err := read(uint64(len(out)), uint32(count))
+		if err != nil {
+			return nil, err
+		}
+		if len(chunk) == 0 {
+			return nil, ErrShortRead
+		}
+		out = append(out, chunk...)
+	}
+	return out, nil
+}
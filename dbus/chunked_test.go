@@ -0,0 +1,100 @@
+package dbus
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestChunks(t *testing.T) {
+	mgr := newPipeBusManager(t)
+	defer mgr.conn.Close()
+	chunks := mgr.NewChunks("/transfers", 0)
+	data := make([]byte, 2*ChunkSize+5)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	path, err := chunks.Serve(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/transfers/1" {
+		t.Fatalf("unexpected path %s", path)
+	}
+	found, ok := mgr.LookupObject(path)
+	if !ok {
+		t.Fatal("chunked object not exported")
+	}
+	obj := found.(*Object)
+	iface, _ := obj.LookupInterface(ChunkedInterface)
+	if args := iface.(*Interface).methods["Close"].introspection.Args; len(args) != 0 {
+		t.Fatalf("unexpected introspection of Close %+v", args)
+	}
+
+	rets, err := deliverCall(obj, ChunkedInterface, "Size")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reads := 0
+	out, err := readChunks(rets[0].(uint64),
+		func(offset uint64, count uint32) ([]byte, error) {
+			reads++
+			rets, err := deliverCall(obj, ChunkedInterface, "Read", offset, count)
+			if err != nil {
+				return nil, err
+			}
+			return rets[0].([]byte), nil
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, data) || reads != 3 {
+		t.Fatalf("read %d bytes in %d chunks", len(out), reads)
+	}
+	if _, err := deliverCall(obj, ChunkedInterface, "Read",
+		uint64(len(data)+1), uint32(1)); err == nil {
+		t.Fatal("expected reading past the end to fail")
+	}
+
+	if _, err := deliverCall(obj, ChunkedInterface, "Close"); err != nil {
+		t.Fatal(err)
+	}
+	waitRemoved(t, mgr.Object, "transfers", "1")
+}
+
+func TestChunksReply(t *testing.T) {
+	mgr := newPipeBusManager(t)
+	defer mgr.conn.Close()
+	chunks := mgr.NewChunks("/transfers", 0)
+	data, path, err := chunks.Reply([]byte("small"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/" || string(data) != "small" {
+		t.Fatalf("expected the data inline, got %q and %s", data, path)
+	}
+	out, err := mgr.ReadReply("", data, path)
+	if err != nil || string(out) != "small" {
+		t.Fatalf("expected the inline data, got %q: %v", out, err)
+	}
+
+	data, path, err = chunks.Reply(make([]byte, MaxChunkSize))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/transfers/1" || len(data) != 0 {
+		t.Fatalf("expected a chunked object, got %d bytes and %s",
+			len(data), path)
+	}
+}
+
+func TestReadChunksShort(t *testing.T) {
+	_, err := readChunks(10, func(offset uint64, count uint32) ([]byte, error) {
+		if offset != 0 {
+			return nil, nil
+		}
+		return make([]byte, 4), nil
+	})
+	if err != ErrShortRead {
+		t.Fatalf("expected ErrShortRead, got %v", err)
+	}
+}
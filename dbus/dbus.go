@@ -1,16 +1,20 @@
 package dbus
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
 	"github.com/godbus/dbus"
 	"github.com/godbus/dbus/introspect"
 	"github.com/jsouthworth/seriatim"
+	"github.com/jsouthworth/seriatim/events"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 )
 
 const (
@@ -21,8 +25,9 @@ const (
 )
 
 var (
-	errtype    = reflect.TypeOf((*error)(nil)).Elem()
-	sendertype = reflect.TypeOf((*dbus.Sender)(nil)).Elem()
+	errtype     = reflect.TypeOf((*error)(nil)).Elem()
+	sendertype  = reflect.TypeOf((*dbus.Sender)(nil)).Elem()
+	contexttype = reflect.TypeOf((*context.Context)(nil)).Elem()
 )
 
 type multiWriterValue struct {
@@ -39,8 +44,50 @@ func (value *multiWriterValue) Update(fn func(*atomic.Value)) {
 // Acts as a root to the object tree
 type BusManager struct {
 	*Object
-	conn  *dbus.Conn
-	state seriatim.Sequent
+	// SignalPolicy decides what happens to the signals received for
+	// an object registered with Receives, after it is set, while its
+	// buffer is full; see the events package. It defaults to
+	// events.Block, bounded by the BlockTimeout of Events.
+	SignalPolicy events.Policy
+	// IntrospectHeader, if set, replaces the XML declaration and
+	// doctype put before the introspection data of the objects, and
+	// OmitIntrospectHeader leaves them out.
+	IntrospectHeader     string
+	OmitIntrospectHeader bool
+	// IntrospectRootPath names the root node of the introspection data
+	// with the object's path rather than leaving it unnamed, which
+	// busctl expects.
+	IntrospectRootPath bool
+	// ExportDebug exports DebugInterface on every object.
+	ExportDebug bool
+	// FloodRate, if positive, is the number of messages per second
+	// above which a sender floods the bus manager, counting the
+	// method calls to its objects and the signals it receives.
+	// OnFlood is then called with the sender and its rate, at most
+	// once a second, on the goroutine reading the connection; it must
+	// not block. Rates are tracked regardless, see SenderStats.
+	FloodRate float64
+	OnFlood   func(sender string, rate float64)
+	// OnSendError, if set, is told about the method replies and
+	// signals that could not be sent, such as when the connection is
+	// lost. Replies are then marshalled twice to find those that
+	// cannot be.
+	OnSendError func(*SendError)
+	// Metrics, if set, is told about every method call from the bus,
+	// such as a dbusprom.MethodCollector.
+	Metrics CallMetrics
+
+	conn       *dbus.Conn
+	name       string
+	sessions   seriatim.Sequent
+	state      seriatim.Sequent
+	dispatcher seriatim.Sequent
+	events     *events.Bus
+	observers  observerSet
+	received   observerSet
+	rates      senderRates
+	// closed is set once the connection is closed, see Terminate.
+	closed uint32
 }
 
 type mgrState struct {
@@ -51,12 +98,24 @@ func mkSignalKey(iface, member string) string {
 	return iface + "." + member
 }
 
+// matchRule returns the rule matching the signals named member of
+// iface, any of them for empty names.
+func matchRule(iface, member string) string {
+	rule := "type='signal'"
+	if iface != "" {
+		rule += ",interface='" + iface + "'"
+	}
+	if member != "" {
+		rule += ",member='" + member + "'"
+	}
+	return rule
+}
+
 func (s *mgrState) AddMatchSignal(conn *dbus.Conn, iface, member string) {
 	// Only register for signal if not already registered
 	key := mkSignalKey(iface, member)
 	if s.sigref[key] == 0 {
-		conn.BusObject().Call(fdtAddMatch, 0,
-			"type='signal',interface='"+iface+"',member='"+member+"'")
+		conn.BusObject().Call(fdtAddMatch, 0, matchRule(iface, member))
 	}
 	s.sigref[key]++
 }
@@ -69,8 +128,7 @@ func (s *mgrState) RemoveMatchSignal(conn *dbus.Conn, iface, member string) {
 	}
 	s.sigref[key]--
 	if s.sigref[key] == 0 {
-		conn.BusObject().Call(fdtRemoveMatch, 0,
-			"type='signal',interface='"+iface+"',member='"+member+"'")
+		conn.BusObject().Call(fdtRemoveMatch, 0, matchRule(iface, member))
 	}
 }
 
@@ -81,8 +139,10 @@ func NewAnonymousBusManager(
 	handler := &BusManager{
 		Object: NewObject("", nil, nil, nil),
 		state:  seriatim.NewSupervisedSequent(state, nil),
+		events: events.NewBus(),
 	}
 	handler.bus = handler
+	handler.dispatcher = newSignalDispatcher(handler)
 	conn, err := busfn(handler, handler)
 	if err != nil {
 		return nil, err
@@ -106,12 +166,12 @@ func NewBusManager(
 	name string,
 ) (*BusManager, error) {
 
-	handler, err := NewAnonymousBusManager(busfn)
+	handler, err := NewUnreadyBusManager(busfn, name)
 	if err != nil {
 		return nil, err
 	}
 
-	err = handler.RequestName(name)
+	err = handler.Ready()
 	if err != nil {
 		handler.conn.Close()
 		return nil, err
@@ -120,10 +180,30 @@ func NewBusManager(
 	return handler, nil
 }
 
+// NewUnreadyBusManager connects to the bus like NewBusManager but only
+// requests name when Ready is called. Clients started when the name
+// appears would otherwise find objects missing that are exported after
+// the manager is made.
+func NewUnreadyBusManager(
+	busfn func(dbus.Handler, dbus.SignalHandler) (*dbus.Conn, error),
+	name string,
+) (*BusManager, error) {
+	handler, err := NewAnonymousBusManager(busfn)
+	if err != nil {
+		return nil, err
+	}
+	handler.name = name
+	return handler, nil
+}
+
 func NewSessionBusManager(name string) (*BusManager, error) {
 	return NewBusManager(dbus.SessionBusPrivateHandler, name)
 }
 
+func NewUnreadySessionBusManager(name string) (*BusManager, error) {
+	return NewUnreadyBusManager(dbus.SessionBusPrivateHandler, name)
+}
+
 func NewAnonymousSessionBusManager() (*BusManager, error) {
 	return NewAnonymousBusManager(dbus.SessionBusPrivateHandler)
 }
@@ -132,10 +212,23 @@ func NewSystemBusManager(name string) (*BusManager, error) {
 	return NewBusManager(dbus.SystemBusPrivateHandler, name)
 }
 
+func NewUnreadySystemBusManager(name string) (*BusManager, error) {
+	return NewUnreadyBusManager(dbus.SystemBusPrivateHandler, name)
+}
+
 func NewAnonymousSystemBusManager() (*BusManager, error) {
 	return NewAnonymousBusManager(dbus.SystemBusPrivateHandler)
 }
 
+// Ready requests the name given to NewUnreadyBusManager, once the
+// objects to be found under it are exported.
+func (mgr *BusManager) Ready() error {
+	if mgr.name == "" {
+		return errors.New("Bus manager has no name to request")
+	}
+	return mgr.RequestName(mgr.name)
+}
+
 func (mgr *BusManager) RequestName(name string) error {
 	_, err := mgr.conn.RequestName(name, 0)
 	if err != nil {
@@ -169,11 +262,39 @@ func (mgr *BusManager) Call(
 	return object.(*Object).Call(ifaceName, method, args...)
 }
 
+// Events returns the bus on which the signals received from D-Bus are
+// published, on topics named interface.member. Objects registered with
+// Receives are subscribed to it.
+func (mgr *BusManager) Events() *events.Bus {
+	return mgr.events
+}
+
+// DeliverSignal queues a signal read from the connection for the
+// dispatcher, so that slow listeners hold up the dispatcher rather than
+// the connection reader until signalQueueSize signals are pending.
 func (mgr *BusManager) DeliverSignal(iface, member string, signal *dbus.Signal) {
-	objects := mgr.objects.Load().(map[string]*Object)
-	for _, obj := range objects {
-		obj.DeliverSignal(iface, member, signal)
-	}
+	mgr.countMessage(signal.Sender)
+	mgr.dispatcher.Cast("Dispatch", iface, member, signal)
+}
+
+// signalQueueSize is the number of received signals the dispatcher
+// holds before DeliverSignal blocks.
+const signalQueueSize = 256
+
+// signalDispatcher hands the received signals to the observers and
+// subscribers of a bus in the order they were read.
+type signalDispatcher struct {
+	mgr *BusManager
+}
+
+func newSignalDispatcher(mgr *BusManager) seriatim.Sequent {
+	return seriatim.NewSequent(&signalDispatcher{mgr: mgr},
+		seriatim.WithMailboxSize(signalQueueSize))
+}
+
+func (d *signalDispatcher) Dispatch(iface, member string, signal *dbus.Signal) {
+	d.mgr.notifyReceived(iface, member, signal)
+	d.mgr.events.Publish(mkSignalKey(iface, member), signal.Body...)
 }
 
 type Method struct {
@@ -183,6 +304,55 @@ type Method struct {
 	sender        string
 	message       *dbus.Message
 	value         reflect.Value
+	plan          *decodePlan
+	access        *accessList
+	bus           *BusManager
+	// overloads are the other methods exported under the same name,
+	// told apart by their input signature.
+	overloads []*Method
+}
+
+// decodePlan is worked out once per method so that decoding the
+// arguments of a call only allocates the values it returns.
+type decodePlan struct {
+	// types are those of the arguments passed to the sequent, which
+	// gives a leading context.Context itself.
+	types []reflect.Type
+	// injected marks the arguments set to the sender of the call or
+	// its Session rather than decoded from the body.
+	injected []bool
+	decoded  int
+	scratch  sync.Pool
+}
+
+func newDecodePlan(method reflect.Type) *decodePlan {
+	skip := 0
+	if method.NumIn() > 0 && method.In(0) == contexttype {
+		skip = 1
+	}
+	plan := &decodePlan{
+		types:    make([]reflect.Type, method.NumIn()-skip),
+		injected: make([]bool, method.NumIn()-skip),
+	}
+	for i := range plan.types {
+		plan.types[i] = method.In(i + skip)
+		plan.injected[i] = plan.types[i] == sendertype ||
+			plan.types[i] == sessiontype
+		if !plan.injected[i] {
+			plan.decoded++
+		}
+	}
+	plan.scratch.New = func() interface{} {
+		return make([]interface{}, 0, plan.decoded)
+	}
+	return plan
+}
+
+func (method *Method) decodePlan() *decodePlan {
+	if method.plan == nil {
+		method.plan = newDecodePlan(method.value.Type())
+	}
+	return method.plan
 }
 
 func (method *Method) DecodeArguments(
@@ -191,57 +361,118 @@ func (method *Method) DecodeArguments(
 	msg *dbus.Message,
 	args []interface{},
 ) ([]interface{}, error) {
+	plan := method.decodePlan()
 	body := msg.Body
-	pointers := make([]interface{}, method.NumArguments())
-	decode := make([]interface{}, 0, len(body))
 
 	method.sender = sender
 	method.message = msg
-
-	for i := 0; i < method.NumArguments(); i++ {
-		tp := reflect.TypeOf(method.ArgumentValue(i))
-		val := reflect.New(tp)
-		pointers[i] = val.Interface()
-		if tp == sendertype {
-			val.Elem().SetString(sender)
-		} else {
-			decode = append(decode, pointers[i])
-		}
+	if method.bus != nil {
+		method.bus.countMessage(sender)
 	}
 
-	if len(decode) != len(body) {
-		return nil, dbus.ErrMsgInvalidArg
+	if plan.decoded != len(body) {
+		return nil, invalidArgs("Method %s takes %d arguments, have %d",
+			method.introspection.Name, plan.decoded, len(body))
 	}
 
-	if err := dbus.Store(body, decode...); err != nil {
-		return nil, dbus.ErrMsgInvalidArg
+	values := make([]reflect.Value, len(plan.types))
+	decode := plan.scratch.Get().([]interface{})
+	for i, tp := range plan.types {
+		values[i] = reflect.New(tp)
+		switch {
+		case tp == sendertype:
+			values[i].Elem().SetString(sender)
+		case tp == sessiontype:
+			values[i].Elem().Set(reflect.ValueOf(method.session(sender)))
+		default:
+			decode = append(decode, values[i].Interface())
+		}
+	}
+	var err error
+	for i, arg := range body {
+		if err = storeArgument(arg, decode[i]); err != nil {
+			err = invalidArgs("Argument %d of method %s: %v",
+				i, method.introspection.Name, err)
+			break
+		}
 	}
-	// Deref the pointers created by reflect.New above
-	for i, ptr := range pointers {
-		pointers[i] = reflect.ValueOf(ptr).Elem().Interface()
+	for i := range decode {
+		decode[i] = nil
 	}
-	return pointers, nil
+	plan.scratch.Put(decode[:0])
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]interface{}, len(values))
+	for i, val := range values {
+		out[i] = val.Elem().Interface()
+	}
+	return out, nil
+}
+
+const fdtInvalidArgs = fdtDBusName + ".Error.InvalidArgs"
+
+// invalidArgs returns the InvalidArgs error answering a call whose
+// arguments cannot be decoded.
+func invalidArgs(format string, args ...interface{}) *dbus.Error {
+	return dbus.NewError(fdtInvalidArgs,
+		[]interface{}{fmt.Sprintf(format, args...)})
 }
 
+// storeArgument decodes arg into the value ptr points to. Values
+// godbus cannot store, however they came to be in a message, are
+// reported rather than left to panic.
+func storeArgument(arg, ptr interface{}) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("%v", rec)
+		}
+	}()
+	return dbus.Store([]interface{}{arg}, ptr)
+}
+
+// Call calls the method on its object's sequent. Results of type
+// func() T are deferred replies: the method returns them instead of a
+// T that is costly to build, such as a large array made from a
+// snapshot of its state, and they are called here, on the caller's
+// goroutine, so that building the reply does not hold up the other
+// calls to the object. The reply is then marshalled by the connection.
 func (method *Method) Call(args ...interface{}) ([]interface{}, error) {
+	started := time.Now()
+	ret, err := method.call(args...)
+	method.observe(time.Since(started), err)
+	method.checkReply(ret, err)
+	return ret, err
+}
+
+func (method *Method) call(args ...interface{}) ([]interface{}, error) {
+	if !method.access.allows(method.sender) {
+		method.logCall(ErrAccessDenied)
+		return nil, ErrAccessDenied
+	}
 	method_type := method.value.Type()
 	ret, err := method.sequent.Call(method.name, args...)
 	if err != nil {
+		method.logCall(err)
 		return nil, err
 	}
 	last := method_type.NumOut() - 1
-	if method_type.Out(last).Implements(errtype) {
+	if last >= 0 && method_type.Out(last).Implements(errtype) {
 		// Last parameter is of type error
 		if ret[last] != nil {
+			method.logCall(ret[last].(error))
 			return ret[:last], ret[last].(error)
 		}
-		return ret[:last], nil
+		method.logCall(nil)
+		return expandReplies(ret[:last]), nil
 	}
-	return ret, nil
+	method.logCall(nil)
+	return expandReplies(ret), nil
 }
 
 func (method *Method) NumArguments() int {
-	return method.value.Type().NumIn()
+	return len(method.decodePlan().types)
 }
 
 func (method *Method) NumReturns() int {
@@ -249,16 +480,55 @@ func (method *Method) NumReturns() int {
 }
 
 func (method *Method) ArgumentValue(position int) interface{} {
-	return reflect.Zero(method.value.Type().In(position)).Interface()
+	return reflect.Zero(method.decodePlan().types[position]).Interface()
 }
 
 func (method *Method) ReturnValue(position int) interface{} {
-	return reflect.Zero(method.value.Type().Out(position)).Interface()
+	return reflect.Zero(replyType(method.value.Type().Out(position))).Interface()
+}
+
+// replyType returns the type sent for a result of type t, which is T
+// for a deferred reply of type func() T.
+func replyType(t reflect.Type) reflect.Type {
+	if isDeferred(t) {
+		return t.Out(0)
+	}
+	return t
+}
+
+func isDeferred(t reflect.Type) bool {
+	return t.Kind() == reflect.Func && t.NumIn() == 0 && t.NumOut() == 1
+}
+
+// expandReplies replaces the deferred replies in rets by their value.
+func expandReplies(rets []interface{}) []interface{} {
+	for i, ret := range rets {
+		v := reflect.ValueOf(ret)
+		if !v.IsValid() || !isDeferred(v.Type()) {
+			continue
+		}
+		if v.IsNil() {
+			rets[i] = reflect.Zero(v.Type().Out(0)).Interface()
+			continue
+		}
+		rets[i] = v.Call(nil)[0].Interface()
+	}
+	return rets
 }
 
 type Signal struct {
-	name    string
-	sequent seriatim.Sequent
+	name          string
+	sequent       seriatim.Sequent
+	introspection introspect.Signal
+	subscription  *events.Subscription
+}
+
+func (signal *Signal) signature() string {
+	var sig string
+	for _, arg := range signal.introspection.Args {
+		sig += arg.Type
+	}
+	return sig
 }
 
 func (signal *Signal) Deliver(args ...interface{}) error {
@@ -272,6 +542,8 @@ type Interface struct {
 	object  *Object
 	methods map[string]*Method
 	signals map[string]*Signal
+	// access, if set, restricts who may call the methods.
+	access *accessList
 }
 
 func (intf *Interface) LookupMethod(name string) (dbus.Method, bool) {
@@ -279,28 +551,57 @@ func (intf *Interface) LookupMethod(name string) (dbus.Method, bool) {
 	if !ok {
 		return nil, false
 	}
-	// Methods have two mutable fields that are caller specific
-	// Make a new method with the immutable fields from the stored
-	// method.
-	new_method := &Method{
+	intf.object.touch()
+	if len(method.overloads) > 0 {
+		return &overloadedMethod{
+			candidates: append([]*Method{method}, method.overloads...),
+			access:     intf.access,
+		}, true
+	}
+	return method.instance(intf.access), true
+}
+
+// instance returns the method to handle one call. Methods have two
+// mutable fields that are caller specific, so a new method is made
+// with the immutable fields from the stored method.
+func (method *Method) instance(access *accessList) *Method {
+	return &Method{
 		introspection: method.introspection,
 		value:         method.value,
 		sequent:       method.sequent,
 		name:          method.name,
+		plan:          method.plan,
+		access:        access,
+		bus:           method.bus,
 	}
-	return new_method, ok
 }
 
 type Object struct {
+	// lastCall is the time in Unix nanoseconds the object's methods
+	// were last looked up, first for 64-bit alignment on 32-bit
+	// platforms.
+	lastCall    int64
 	name        string
 	methodTable map[string]interface{}
 	sequent     seriatim.Sequent
 	interfaces  multiWriterValue
 	listeners   multiWriterValue
 	emitterm    multiWriterValue
-	objects     multiWriterValue
-	bus         *BusManager
-	parent      *Object
+	// objects holds the children in an *objectMap. It is shared with
+	// the objects replacing this one so that children added
+	// concurrently with a replacement are kept.
+	objects    *multiWriterValue
+	bus        *BusManager
+	parent     *Object
+	terminated int32
+	// placeholder objects stand in for the elements of paths that
+	// have no object of their own.
+	placeholder bool
+	// factory, if set, makes the value of a replacement for the
+	// object when its sequent terminates on its own.
+	factory func() interface{}
+	// forward, if set, receives the calls made to the object.
+	forward *forwarder
 }
 
 func NewObject(
@@ -330,55 +631,32 @@ func NewObjectFromTable(
 	bus *BusManager,
 ) *Object {
 	table = filterTable(table)
+	var opts []seriatim.Option
+	if bus != nil {
+		opts = append(opts, seriatim.WithMetrics(sequentMetrics{bus}))
+	}
 	obj := &Object{
 		name:   name,
 		bus:    bus,
 		parent: parent,
 		sequent: seriatim.NewSupervisedSequentTable(struct{}{},
-			table, parent),
+			table, parent, opts...),
 		methodTable: table,
 	}
 	obj.interfaces.Store(make(map[string]*Interface))
 	obj.listeners.Store(make(map[string]*Interface))
-	obj.objects.Store(make(map[string]*Object))
+	obj.objects = &multiWriterValue{}
+	obj.objects.Store(emptyObjectMap)
 	obj.emitterm.Store(make([]chan<- struct{}, 0))
 	obj.addInterface(fdtIntrospectable, newIntrospection(obj))
 	return obj
 }
 
-func (o *Object) implements(iface map[string]reflect.Type) bool {
-	if len(iface) > len(o.methodTable) {
-		return false
-	}
-	for method_name, iface_method_type := range iface {
-		if _, ok := o.methodTable[method_name]; !ok {
-			return false
-		}
-		method_type := reflect.TypeOf(o.methodTable[method_name])
-		if iface_method_type.NumIn() != method_type.NumIn() {
-			return false
-		}
-		if iface_method_type.NumOut() != method_type.NumOut() {
-			return false
-		}
-		for j := 0; j < iface_method_type.NumIn(); j++ {
-			if iface_method_type.In(j) != method_type.In(j) {
-				return false
-			}
-		}
-		for j := 0; j < iface_method_type.NumOut(); j++ {
-			if iface_method_type.Out(j) != method_type.Out(j) {
-				return false
-			}
-		}
-	}
-	return true
-}
-
 func (o *Object) removeListeners() {
 	o.listeners.Update(func(value *atomic.Value) {
 		for dbusIfaceName, intf := range value.Load().(map[string]*Interface) {
-			for sigName, _ := range intf.signals {
+			for sigName, signal := range intf.signals {
+				signal.subscription.Cancel()
 				o.bus.state.Call("RemoveMatchSignal", o.bus.conn,
 					dbusIfaceName, sigName)
 			}
@@ -389,21 +667,28 @@ func (o *Object) removeListeners() {
 
 func (o *Object) SequentTerminated(reason error, id uintptr) {
 	o.objects.Update(func(value *atomic.Value) {
-		objects := make(map[string]*Object)
-		for name, obj := range value.Load().(map[string]*Object) {
-			if obj.hasActions() && obj.sequent.Id() == id {
+		objects := value.Load().(*objectMap)
+		objects.Range(func(name string, obj *Object) bool {
+			if !obj.hasActions() || obj.sequent.Id() != id {
+				return true
+			}
+			logObjectTerminated(obj, reason)
+			switch {
+			case obj.factory != nil &&
+				atomic.LoadInt32(&obj.terminated) == 0:
+				objects = objects.Set(name, obj.restart())
+			case obj.hasChildren():
 				obj.removeListeners()
 				// if there are children replace with placeholder
-				if obj.hasChildren() {
-					object := NewObject(name, nil, o, o.bus)
-					object.objects = obj.objects
-					obj = object
-				} else {
-					continue
-				}
+				object := newPlaceholder(name, o)
+				object.objects = obj.objects
+				objects = objects.Set(name, object)
+			default:
+				obj.removeListeners()
+				objects = objects.Delete(name)
 			}
-			objects[name] = obj
-		}
+			return true
+		})
 		value.Store(objects)
 	})
 	if !o.hasActions() && o.parent != nil {
@@ -411,8 +696,20 @@ func (o *Object) SequentTerminated(reason error, id uintptr) {
 	}
 }
 
-func (o *Object) getObjects() map[string]*Object {
-	return o.objects.Load().(map[string]*Object)
+// Path returns the object path of o relative to the root of its tree.
+func (o *Object) Path() dbus.ObjectPath {
+	if o.parent == nil {
+		return "/"
+	}
+	parent := o.parent.Path()
+	if parent == "/" {
+		return dbus.ObjectPath("/" + o.name)
+	}
+	return parent + dbus.ObjectPath("/"+o.name)
+}
+
+func (o *Object) getObjects() *objectMap {
+	return o.objects.Load().(*objectMap)
 }
 
 func (o *Object) getInterfaces() map[string]*Interface {
@@ -434,8 +731,7 @@ func (o *Object) newObject(path []string, table map[string]interface{}) *Object
 		obj, ok := o.LookupObject(name)
 		if !ok {
 			//placeholder object for introspection
-			obj = NewObject(name, nil, o, o.bus)
-			o.addObject(name, obj)
+			obj = o.addPlaceholder(name)
 		}
 		return obj.newObject(path[1:], table)
 	}
@@ -466,36 +762,131 @@ func (o *Object) NewObjectFromTable(
 	return o.newObject(ps, table)
 }
 
+// NewObjectFactory adds an object at path whose value is made by
+// factory. When the object's sequent terminates on its own, such as by
+// crashing, the object is replaced by one with a fresh value from
+// factory, exporting the same interfaces and receiving the same
+// signals; LookupObject returns the replacement. Objects terminated by
+// DeleteObject are not replaced.
+func (o *Object) NewObjectFactory(
+	path dbus.ObjectPath,
+	factory func() interface{},
+) *Object {
+	obj := o.NewObject(path, factory())
+	obj.factory = factory
+	return obj
+}
+
+// restart returns the replacement for o made from a fresh value of
+// its factory.
+func (o *Object) restart() *Object {
+	obj := NewObjectFromTable(o.name,
+		seriatim.GetMethods(o.factory()), o.parent, o.bus)
+	obj.factory = o.factory
+	obj.objects = o.objects
+	for name, intf := range o.getInterfaces() {
+		if name == fdtIntrospectable {
+			continue
+		}
+		obj.addInterface(name, intf.rebind(obj))
+	}
+	for name, intf := range o.getListeners() {
+		obj.addListener(name, intf.rebindListener(obj, name))
+	}
+	return obj
+}
+
+// rebind returns a copy of intf whose methods are processed by obj.
+// The signals it emits are kept as they are.
+func (intf *Interface) rebind(obj *Object) *Interface {
+	out := &Interface{
+		object:  obj,
+		methods: make(map[string]*Method, len(intf.methods)),
+		signals: intf.signals,
+		access:  intf.access,
+	}
+	for mapped_name, method := range intf.methods {
+		rebound := method.rebind(obj)
+		for _, overload := range method.overloads {
+			rebound.overloads = append(rebound.overloads,
+				overload.rebind(obj))
+		}
+		out.methods[mapped_name] = rebound
+	}
+	return out
+}
+
+func (method *Method) rebind(obj *Object) *Method {
+	return &Method{
+		name:          method.name,
+		sequent:       obj.sequent,
+		value:         reflect.ValueOf(obj.methodTable[method.name]),
+		introspection: method.introspection,
+		plan:          method.plan,
+		bus:           obj.bus,
+	}
+}
+
+// rebindListener returns a copy of the listener intf for the D-Bus
+// interface dbusIfaceName delivering its signals to obj. The match
+// rules added for intf are kept.
+func (intf *Interface) rebindListener(
+	obj *Object,
+	dbusIfaceName string,
+) *Interface {
+	out := &Interface{
+		object:  obj,
+		signals: make(map[string]*Signal, len(intf.signals)),
+	}
+	for mapped_name, signal := range intf.signals {
+		signal.subscription.Cancel()
+		rebound := &Signal{
+			name:          signal.name,
+			sequent:       obj.sequent,
+			introspection: signal.introspection,
+		}
+		rebound.subscription = obj.subscribeSignal(dbusIfaceName,
+			mapped_name, signal.name)
+		out.signals[mapped_name] = rebound
+	}
+	return out
+}
+
+func newPlaceholder(name string, parent *Object) *Object {
+	obj := NewObject(name, nil, parent, parent.bus)
+	obj.placeholder = true
+	return obj
+}
+
 func (o *Object) hasActions() bool {
 	return o.sequent != nil
 }
 
 func (o *Object) hasChildren() bool {
-	return len(o.getObjects()) > 0
+	return o.getObjects().Len() > 0
 }
 
 func (o *Object) terminate() {
-	if o.hasActions() {
+	// Objects stay in the tree until their parent hears that they
+	// terminated; deleting them again must not terminate them twice.
+	if o.hasActions() && atomic.CompareAndSwapInt32(&o.terminated, 0, 1) {
 		o.sequent.Terminate(nil)
 	}
 }
 
 func (o *Object) rmChildObject(name string) {
 	o.objects.Update(func(value *atomic.Value) {
-		objects := make(map[string]*Object)
-		for child, obj := range o.getObjects() {
-			objects[child] = obj
-		}
-		if obj, ok := objects[name]; ok {
+		objects := value.Load().(*objectMap)
+		if obj, ok := objects.Get(name); ok {
 			obj.terminate()
 			if !obj.hasActions() {
 				// if there are children replace with placeholder
 				if obj.hasChildren() {
-					object := NewObject(name, nil, o, o.bus)
+					object := newPlaceholder(name, o)
 					object.objects = obj.objects
-					obj = object
+					objects = objects.Set(name, object)
 				} else {
-					delete(objects, name)
+					objects = objects.Delete(name)
 				}
 			}
 		}
@@ -506,6 +897,22 @@ func (o *Object) rmChildObject(name string) {
 	}
 }
 
+// rmPlaceholder removes obj if it is a placeholder without children
+// and nothing replaced it since it was looked up.
+func (o *Object) rmPlaceholder(obj *Object) {
+	if !obj.placeholder {
+		return
+	}
+	o.objects.Update(func(value *atomic.Value) {
+		current, _ := value.Load().(*objectMap).Get(obj.name)
+		if current != obj || obj.hasChildren() {
+			return
+		}
+		// SequentTerminated removes it from the tree.
+		obj.terminate()
+	})
+}
+
 func (o *Object) delObject(path []string) {
 	name := path[0]
 	switch len(path) {
@@ -516,9 +923,7 @@ func (o *Object) delObject(path []string) {
 	default:
 		if child, ok := o.LookupObject(name); ok {
 			child.delObject(path[1:])
-			if len(child.getObjects()) == 0 {
-				o.rmChildObject(child.name)
-			}
+			o.rmPlaceholder(child)
 		}
 	}
 }
@@ -548,11 +953,16 @@ func (o *Object) lookupObjectPath(path []string) (*Object, bool) {
 }
 
 func (o *Object) LookupObject(name string) (*Object, bool) {
-	obj, ok := o.getObjects()[name]
-	return obj, ok
+	return o.getObjects().Get(name)
 }
 
 func (o *Object) LookupInterface(name string) (dbus.Interface, bool) {
+	if o.forward != nil {
+		return o.forward.lookupInterface(name), true
+	}
+	if name == DebugInterface && o.debugExported() {
+		return newDebugInterface(o), true
+	}
 	iface, ok := o.getInterfaces()[name]
 	return iface, ok
 }
@@ -568,30 +978,61 @@ func (o *Object) addInterface(name string, iface *Interface) {
 	})
 }
 
+func (o *Object) updateInterface(
+	name string,
+	fn func(*Interface) *Interface,
+) {
+	o.interfaces.Update(func(value *atomic.Value) {
+		interfaces := make(map[string]*Interface)
+		for name, intf := range value.Load().(map[string]*Interface) {
+			interfaces[name] = intf
+		}
+		interfaces[name] = fn(interfaces[name])
+		value.Store(interfaces)
+	})
+}
+
 func (o *Object) addListener(name string, iface *Interface) {
 	o.listeners.Update(func(value *atomic.Value) {
 		listeners := make(map[string]*Interface)
 		for name, intf := range value.Load().(map[string]*Interface) {
 			listeners[name] = intf
 		}
+		if old, ok := listeners[name]; ok {
+			for _, signal := range old.signals {
+				signal.subscription.Cancel()
+			}
+		}
 		listeners[name] = iface
 		value.Store(listeners)
 	})
 }
 
-func (o *Object) addObject(name string, object *Object) {
+// addPlaceholder adds a placeholder for name unless an object was
+// added there since it was looked up, and returns the object at name.
+func (o *Object) addPlaceholder(name string) *Object {
+	var out *Object
 	o.objects.Update(func(value *atomic.Value) {
-		objects := make(map[string]*Object)
-		for name, obj := range value.Load().(map[string]*Object) {
-			objects[name] = obj
+		objects := value.Load().(*objectMap)
+		if obj, ok := objects.Get(name); ok {
+			out = obj
+			return
 		}
-		if obj, ok := objects[name]; ok {
+		out = newPlaceholder(name, o)
+		value.Store(objects.Set(name, out))
+	})
+	return out
+}
+
+func (o *Object) addObject(name string, object *Object) {
+	o.objects.Update(func(value *atomic.Value) {
+		objects := value.Load().(*objectMap)
+		if obj, ok := objects.Get(name); ok {
 			//there may be child objects of the object that is being
 			//replaced; keep them
 			object.objects = obj.objects
 		}
-		objects[name] = object
-		value.Store(objects)
+		value.Store(objects.Set(name, object))
 	})
 }
 
@@ -602,22 +1043,27 @@ func (o *Object) getMethods(
 	methods := make(map[string]*Method)
 	for method_name, method_type := range iface {
 		mapped_name := mapfn(method_name)
+		in := getIntrospectionArguments(method_type.NumIn, method_type.In, "in")
+		out := getIntrospectionArguments(method_type.NumOut, method_type.Out, "out")
 		method := &Method{
 			sequent: o.sequent,
 			name:    method_name,
 			value:   reflect.ValueOf(o.methodTable[method_name]),
+			plan:    newDecodePlan(reflect.TypeOf(o.methodTable[method_name])),
+			bus:     o.bus,
 			introspection: introspect.Method{
-				Name: mapped_name,
-				Args: make([]introspect.Arg, 0,
-					method_type.NumIn()+method_type.NumOut()-1),
+				Name:        mapped_name,
+				Args:        make([]introspect.Arg, 0, len(in)+len(out)),
 				Annotations: make([]introspect.Annotation, 0),
 			},
 		}
-		method.introspection.Args = append(method.introspection.Args,
-			getIntrospectionArguments(method_type.NumIn, method_type.In, "in")...)
-		method.introspection.Args = append(method.introspection.Args,
-			getIntrospectionArguments(method_type.NumOut, method_type.Out, "out")...)
+		method.introspection.Args = append(method.introspection.Args, in...)
+		method.introspection.Args = append(method.introspection.Args, out...)
 
+		if first, ok := methods[mapped_name]; ok {
+			first.overloads = append(first.overloads, method)
+			continue
+		}
 		methods[mapped_name] = method
 	}
 	return methods
@@ -640,6 +1086,8 @@ func (o *Object) getSignals(
 			name:    signal_name,
 			sequent: o.sequent,
 		}
+		signal.subscription = o.subscribeSignal(dbusIfaceName,
+			mapped_name, signal_name)
 		signals[mapped_name] = signal
 		o.bus.state.Call("AddMatchSignal", o.bus.conn, dbusIfaceName, mapped_name)
 	}
@@ -658,7 +1106,7 @@ func (o *Object) ImplementsMap(
 	obj interface{},
 	mapfn func(string) string,
 ) error {
-	return o.implementsTypes(name, getMethodTypes(obj), mapfn)
+	return o.implementsTypes(name, getMethodTypes(obj), mapfn, nil)
 }
 
 func (o *Object) ImplementsTable(
@@ -676,23 +1124,116 @@ func (o *Object) ImplementsTableMap(
 	table map[string]interface{},
 	mapfn func(string) string,
 ) error {
-	return o.implementsTypes(name, methodTableToTypes(table), mapfn)
+	return o.implementsTypes(name, methodTableToTypes(table), mapfn, nil)
 
 }
 func (o *Object) implementsTypes(
 	name string,
 	types map[string]reflect.Type,
 	mapfn func(string) string,
+	access *accessList,
 ) error {
-	if !o.implements(types) {
-		return fmt.Errorf("Object does not implement interface")
+	if err := o.implements(name, types); err != nil {
+		return err
 	}
-	intf := &Interface{
-		methods: o.getMethods(types, mapfn),
-		object:  o,
+	methods := o.getMethods(types, mapfn)
+	o.updateInterface(name, func(old *Interface) *Interface {
+		intf := &Interface{
+			methods: methods,
+			object:  o,
+			access:  access,
+		}
+		if old != nil {
+			// keep any signals registered with Emits
+			intf.signals = old.signals
+		}
+		return intf
+	})
+	return nil
+}
+
+func (o *Object) Emits(name string, iface_ptr interface{}) error {
+	return o.EmitsMap(name, iface_ptr,
+		func(in string) string {
+			return in
+		})
+}
+
+// Call for each D-Bus interface this object emits signals on. The
+// methods of the interface describe the signals and their arguments.
+func (o *Object) EmitsMap(
+	name string,
+	iface_ptr interface{},
+	mapfn func(string) string,
+) error {
+	iface, is_iface := resolveType(iface_ptr)
+	if !is_iface {
+		return errors.New("must be pointer to interface")
+	}
+	signals := make(map[string]*Signal)
+	for i := 0; i < iface.NumMethod(); i++ {
+		method := iface.Method(i)
+		if method.PkgPath != "" {
+			continue // skip private methods
+		}
+		if method.Type.NumOut() != 0 {
+			return fmt.Errorf("Signal %s must not have return values",
+				method.Name)
+		}
+		mapped_name := mapfn(method.Name)
+		signals[mapped_name] = &Signal{
+			name: method.Name,
+			introspection: introspect.Signal{
+				Name: mapped_name,
+				Args: getIntrospectionArguments(
+					method.Type.NumIn, method.Type.In, ""),
+			},
+		}
 	}
+	o.updateInterface(name, func(old *Interface) *Interface {
+		intf := &Interface{
+			signals: signals,
+			object:  o,
+		}
+		if old != nil {
+			intf.methods = old.methods
+			intf.access = old.access
+		}
+		return intf
+	})
+	return nil
+}
 
-	o.addInterface(name, intf)
+// Emit sends a signal registered with Emits from this object's path.
+func (o *Object) Emit(name, member string, args ...interface{}) error {
+	iface, ok := o.getInterfaces()[name]
+	if !ok {
+		return fmt.Errorf("Object does not emit %s", name)
+	}
+	signal, ok := iface.signals[member]
+	if !ok {
+		return fmt.Errorf("Object does not emit %s.%s", name, member)
+	}
+	expected := signal.signature()
+	if got := dbus.SignatureOf(args...).String(); got != expected {
+		return fmt.Errorf("Signal %s.%s expects signature %q, have %q",
+			name, member, expected, got)
+	}
+	if o.bus == nil || o.bus.conn == nil {
+		return errors.New("Object is not attached to a bus")
+	}
+	path := o.Path()
+	err := o.bus.conn.Emit(path, name+"."+member, args...)
+	if err != nil {
+		o.bus.emitFailed(path, name, member, err)
+		return err
+	}
+	o.bus.notifyEmitted(&EmittedSignal{
+		Path:      path,
+		Interface: name,
+		Member:    member,
+		Body:      args,
+	})
 	return nil
 }
 
@@ -724,9 +1265,13 @@ func (o *Object) Receives(
 		return errors.New("must be pointer to interface")
 	}
 
-	if !o.implements(getMethodTypes(iface)) {
-		return errors.New(
-			fmt.Sprintf("Object does not implement %s", iface))
+	if err := o.implements(iface.String(), getMethodTypes(iface_ptr)); err != nil {
+		return err
+	}
+	if mapfn == nil {
+		mapfn = func(in string) string {
+			return in
+		}
 	}
 
 	intf := &Interface{
@@ -738,9 +1283,16 @@ func (o *Object) Receives(
 }
 
 // Deliver the signal to this object's listeners and all child objects
+// directly, rather than through the bus manager's events.
 func (o *Object) DeliverSignal(iface, member string, signal *dbus.Signal) {
 	listeners := o.getListeners()
 	for sigiface, intf := range listeners {
+		if sigiface == anyInterface {
+			for _, s := range intf.signals {
+				s.sequent.Cast(s.name, iface, member, signal.Body)
+			}
+			continue
+		}
 		if iface != sigiface {
 			continue
 		}
@@ -752,10 +1304,10 @@ func (o *Object) DeliverSignal(iface, member string, signal *dbus.Signal) {
 		}
 	}
 
-	objects := o.getObjects()
-	for _, obj := range objects {
+	o.getObjects().Range(func(name string, obj *Object) bool {
 		obj.DeliverSignal(iface, member, signal)
-	}
+		return true
+	})
 }
 
 func (o *Object) Call(
@@ -778,11 +1330,15 @@ func (o *Object) Call(
 func (o *Object) Introspect() *introspect.Node {
 	getChildren := func() []introspect.Node {
 		children := o.getObjects()
-		out := make([]introspect.Node, 0, len(children))
-		for _, child := range children {
+		out := make([]introspect.Node, 0, children.Len())
+		children.Range(func(name string, child *Object) bool {
 			intro := child.Introspect()
 			out = append(out, *intro)
-		}
+			return true
+		})
+		sort.Slice(out, func(i, j int) bool {
+			return out[i].Name < out[j].Name
+		})
 		return out
 	}
 	getMethods := func(iface *Interface) []introspect.Method {
@@ -790,18 +1346,29 @@ func (o *Object) Introspect() *introspect.Node {
 		out := make([]introspect.Method, 0, len(methods))
 		for _, method := range methods {
 			out = append(out, method.introspection)
+			for _, overload := range method.overloads {
+				out = append(out, overload.introspection)
+			}
+		}
+		sort.Slice(out, func(i, j int) bool {
+			if out[i].Name != out[j].Name {
+				return out[i].Name < out[j].Name
+			}
+			return inputSignature(out[i]) < inputSignature(out[j])
+		})
+		return out
+	}
+	getSignals := func(iface *Interface) []introspect.Signal {
+		signals := iface.signals
+		out := make([]introspect.Signal, 0, len(signals))
+		for _, signal := range signals {
+			out = append(out, signal.introspection)
 		}
+		sort.Slice(out, func(i, j int) bool {
+			return out[i].Name < out[j].Name
+		})
 		return out
 	}
-	// TODO: When we support emitting signals, we will need this
-	// getSignals := func(iface *Interface) []introspect.Signal {
-	// 	signals := iface.signals
-	// 	out := make([]introspect.Signal, 0, len(signals))
-	// 	for _, signal := range signals {
-	// 		out = append(out, signal.introspection)
-	// 	}
-	// 	return out
-	// }
 	getInterfaces := func() []introspect.Interface {
 		if o.sequent == nil {
 			return nil
@@ -812,10 +1379,16 @@ func (o *Object) Introspect() *introspect.Node {
 			intro := introspect.Interface{
 				Name:    name,
 				Methods: getMethods(iface),
-				// Signals: getSignals(iface),
+				Signals: getSignals(iface),
 			}
 			out = append(out, intro)
 		}
+		if o.debugExported() {
+			out = append(out, debugIntrospection)
+		}
+		sort.Slice(out, func(i, j int) bool {
+			return out[i].Name < out[j].Name
+		})
 		return out
 	}
 	node := &introspect.Node{
@@ -834,10 +1407,24 @@ func (intro intro_fn) Call(
 ) ([]interface{}, error) {
 	return []interface{}{intro()}, nil
 }
+func (intro intro_fn) CallContext(
+	ctx context.Context,
+	name string,
+	args ...interface{},
+) ([]interface{}, error) {
+	return intro.Call(name, args...)
+}
 func (intro intro_fn) Cast(name string, args ...interface{}) error {
 	go intro.Call(name, args)
 	return nil
 }
+func (intro intro_fn) CastNotify(
+	name string,
+	errs chan<- error,
+	args ...interface{},
+) error {
+	return intro.Cast(name, args...)
+}
 func (intro intro_fn) Running() bool {
 	return true
 }
@@ -845,12 +1432,48 @@ func (intro intro_fn) Id() uintptr {
 	return reflect.ValueOf(intro).Pointer()
 }
 
+func (intro intro_fn) State() seriatim.State {
+	return seriatim.StateRunning
+}
+
+// StateChanges never changes as introspection always runs.
+func (intro intro_fn) StateChanges() <-chan seriatim.State {
+	ch := make(chan seriatim.State, 1)
+	ch <- seriatim.StateRunning
+	return ch
+}
+
+// Done returns nil as introspection never terminates.
+func (intro intro_fn) Done() <-chan struct{} {
+	return nil
+}
+func (intro intro_fn) Err() error {
+	return nil
+}
 func (intro intro_fn) Terminate(err error) {
 }
+func (intro intro_fn) Replace(val interface{}) error {
+	return seriatim.ErrCannotReplace
+}
+
+func (intro intro_fn) Methods() []seriatim.MethodInfo {
+	return []seriatim.MethodInfo{{
+		Name:    "Introspect",
+		Returns: []reflect.Type{reflect.TypeOf("")},
+	}}
+}
+
+func (intro intro_fn) Stats() seriatim.Stats {
+	return seriatim.Stats{
+		Id:      intro.Id(),
+		Type:    fmt.Sprintf("%T", intro),
+		Running: true,
+	}
+}
 
 func newIntrospection(o *Object) *Interface {
 	intro := func() string {
-		out, _ := introspectNode(o.Introspect())
+		out, _ := o.introspectXML()
 		return out
 	}
 
@@ -861,6 +1484,7 @@ func newIntrospection(o *Object) *Interface {
 				name:    "Introspect",
 				sequent: intro_fn(intro),
 				value:   reflect.ValueOf(intro),
+				plan:    newDecodePlan(reflect.TypeOf(intro)),
 				introspection: introspect.Method{
 					Name: "Introspect",
 					Args: []introspect.Arg{
@@ -872,16 +1496,30 @@ func newIntrospection(o *Object) *Interface {
 	}
 }
 
-func introspectNode(n *introspect.Node) (string, error) {
+// introspectXML returns the introspection data of o as sent to the
+// bus.
+func (o *Object) introspectXML() (string, error) {
+	n := o.Introspect()
 	n.Name = "" // Make it work with busctl.
 	//Busctl doesn't treat the optional
 	//name attribute of the root node correctly.
+	header := strings.TrimSpace(introspect.IntrospectDeclarationString)
+	if mgr := o.bus; mgr != nil {
+		if mgr.IntrospectHeader != "" {
+			header = mgr.IntrospectHeader
+		}
+		if mgr.OmitIntrospectHeader {
+			header = ""
+		}
+		if mgr.IntrospectRootPath {
+			n.Name = string(o.Path())
+		}
+	}
 	b, err := xml.Marshal(n)
 	if err != nil {
 		return "", err
 	}
-	declaration := strings.TrimSpace(introspect.IntrospectDeclarationString)
-	return declaration + string(b), nil
+	return header + string(b), nil
 }
 
 func getIntrospectionArguments(
@@ -897,7 +1535,11 @@ func getIntrospectionArguments(
 				continue
 			}
 		}
-		if typ == "in" && arg == sendertype {
+		if typ == "out" {
+			arg = replyType(arg)
+		}
+		if typ == "in" && (arg == sendertype || arg == sessiontype ||
+			j == 0 && arg == contexttype) {
 			// Hide argument from introspection
 			continue
 		}
@@ -2,10 +2,14 @@ package dbus
 
 import (
 	"bytes"
+	"context"
 	"encoding/xml"
+	"github.com/godbus/dbus"
 	"github.com/godbus/dbus/introspect"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 type testIface interface {
@@ -136,6 +140,35 @@ func TestTableObjectImplementsTooManyOutputs(t *testing.T) {
 	}
 }
 
+func TestTableObjectImplementsReport(t *testing.T) {
+	methods := map[string]interface{}{
+		"CallMe": interface{}(func() string { return "hello, world" }),
+	}
+	obj := NewObjectFromTable("foo", methods, nil, nil)
+
+	err := obj.Implements("com.example.Foo", (*testTooManyMethods)(nil))
+	report, ok := err.(*ImplementsError)
+	if !ok {
+		t.Fatalf("expected an ImplementsError, got %v", err)
+	}
+	if report.Interface != "com.example.Foo" ||
+		!reflect.DeepEqual(report.Missing, []string{"CallMe2"}) ||
+		len(report.Mismatched) != 0 {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	err = obj.Implements("com.example.Foo", (*testMismatchedTypes)(nil))
+	report, ok = err.(*ImplementsError)
+	if !ok || len(report.Mismatched) != 1 {
+		t.Fatalf("unexpected error %v", err)
+	}
+	const expected = "Object does not implement com.example.Foo\n" +
+		"\tmethod CallMe is func() string, want func() bool"
+	if err.Error() != expected {
+		t.Fatalf("expected %q, got %q", expected, err.Error())
+	}
+}
+
 func TestTableObjectImplementsMoreThanOneFunction(t *testing.T) {
 	methods := map[string]interface{}{
 		"CallMe":  interface{}(func() string { return "hello, world" }),
@@ -162,7 +195,7 @@ func decodeIntrospection(intro string) *introspect.Node {
 
 func TestTableObjectIntrospection(t *testing.T) {
 	const introExpected = `<!DOCTYPE node PUBLIC "-//freedesktop//DTD D-BUS Object Introspection 1.0//EN"
-	"http://www.freedesktop.org/standards/dbus/1.0/introspect.dtd"><node><interface name="org.freedesktop.DBus.Introspectable"><method name="Introspect"><arg name="out" type="s" direction="out"></arg></method></interface><interface name="foo"><method name="CallMe"><arg type="s" direction="out"></arg></method></interface></node>`
+	"http://www.freedesktop.org/standards/dbus/1.0/introspect.dtd"><node><interface name="foo"><method name="CallMe"><arg type="s" direction="out"></arg></method></interface><interface name="org.freedesktop.DBus.Introspectable"><method name="Introspect"><arg name="out" type="s" direction="out"></arg></method></interface></node>`
 	methods := map[string]interface{}{
 		"CallMe": interface{}(func() string { return "hello, world" }),
 	}
@@ -195,6 +228,113 @@ func TestTableObjectIntrospection(t *testing.T) {
 	}
 }
 
+type testDeferred interface {
+	List() func() []string
+}
+
+func TestTableObjectDeferredReply(t *testing.T) {
+	items := []string{"a", "b"}
+	methods := map[string]interface{}{
+		"List": interface{}(func() func() []string {
+			snapshot := items
+			return func() []string {
+				return append([]string(nil), snapshot...)
+			}
+		}),
+	}
+	obj := NewObjectFromTable("foo", methods, nil, nil)
+	err := obj.Implements("foo", (*testDeferred)(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	iface, _ := obj.LookupInterface("foo")
+	method, exists := iface.LookupMethod("List")
+	if !exists {
+		t.Fatal("export failed")
+	}
+	if _, ok := method.ReturnValue(0).([]string); !ok {
+		t.Fatalf("unexpected return value %T", method.ReturnValue(0))
+	}
+	if args := iface.(*Interface).methods["List"].introspection.Args; len(args) != 1 ||
+		args[0].Type != "as" {
+		t.Fatalf("unexpected introspection %+v", args)
+	}
+
+	outs, err := method.Call()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(outs, []interface{}{[]string{"a", "b"}}) {
+		t.Fatalf("unexpected reply %v", outs)
+	}
+}
+
+type testNoArgs interface {
+	Ping()
+}
+
+func TestTableObjectNoArguments(t *testing.T) {
+	pinged := make(chan struct{}, 1)
+	methods := map[string]interface{}{
+		"Ping": interface{}(func() { pinged <- struct{}{} }),
+	}
+	obj := NewObjectFromTable("foo", methods, nil, nil)
+	err := obj.Implements("foo", (*testNoArgs)(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	iface, _ := obj.LookupInterface("foo")
+	method, exists := iface.LookupMethod("Ping")
+	if !exists {
+		t.Fatal("export failed")
+	}
+	if args := iface.(*Interface).methods["Ping"].introspection.Args; len(args) != 0 {
+		t.Fatalf("unexpected introspection %+v", args)
+	}
+	outs, err := method.Call()
+	if err != nil || len(outs) != 0 {
+		t.Fatalf("unexpected reply %v, %v", outs, err)
+	}
+	<-pinged
+}
+
+func TestIntrospectionOrder(t *testing.T) {
+	methods := map[string]interface{}{
+		"B": func() string { return "" },
+		"A": func(int32) string { return "" },
+		"C": func(string, bool) int32 { return 0 },
+	}
+	root := NewObject("", nil, nil, nil)
+	for _, path := range []string{"/z", "/a", "/m/b", "/m/a"} {
+		obj := root.NewObjectFromTable(dbus.ObjectPath(path), methods)
+		for _, name := range []string{"com.example.B", "com.example.A"} {
+			if err := obj.ImplementsTable(name, methods); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	first, err := root.introspectXML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		intro, _ := root.introspectXML()
+		if intro != first {
+			t.Fatalf("introspection changed between calls:\n%s\n%s", first, intro)
+		}
+	}
+	node := root.Introspect()
+	if node.Children[0].Name != "a" || node.Children[1].Name != "m" ||
+		node.Children[1].Children[0].Name != "a" {
+		t.Fatalf("children not sorted: %+v", node.Children)
+	}
+	iface := node.Children[0].Interfaces[0]
+	if iface.Name != "com.example.A" || iface.Methods[0].Name != "A" ||
+		iface.Methods[2].Name != "C" {
+		t.Fatalf("interface not sorted: %+v", iface)
+	}
+}
+
 func TestTableObjectBogusMethod(t *testing.T) {
 	methods := map[string]interface{}{
 		"CallMe": "foobar",
@@ -237,3 +377,281 @@ func TestImplementsTableWithTable(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+type testSignals interface {
+	Changed(string, int32)
+}
+
+func TestTableObjectEmits(t *testing.T) {
+	obj := NewObjectFromTable("foo", nil, nil, nil)
+	if obj == nil {
+		t.Fatal("unexpected nil")
+	}
+	err := obj.Emits("foo", (*testSignals)(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = obj.Emits("foo", &struct{}{})
+	if err == nil {
+		t.Fatal("Emits should require a pointer to an interface")
+	}
+
+	node := obj.Introspect()
+	var found bool
+	for _, iface := range node.Interfaces {
+		if iface.Name != "foo" {
+			continue
+		}
+		if len(iface.Signals) != 1 || iface.Signals[0].Name != "Changed" {
+			t.Fatalf("unexpected signals %v", iface.Signals)
+		}
+		found = true
+	}
+	if !found {
+		t.Fatal("signal interface missing from introspection")
+	}
+
+	if err := obj.Emit("foo", "Changed", "x"); err == nil {
+		t.Fatal("Emit should reject a mismatched signature")
+	}
+	if err := obj.Emit("foo", "Missing"); err == nil {
+		t.Fatal("Emit should reject unknown signals")
+	}
+	if err := obj.Emit("foo", "Changed", "x", int32(1)); err == nil {
+		t.Fatal("Emit should fail without a bus")
+	}
+}
+
+func TestImplementsKeepsSignals(t *testing.T) {
+	methods := map[string]interface{}{
+		"CallMe": interface{}(func() string { return "hello, world" }),
+	}
+	obj := NewObjectFromTable("foo", methods, nil, nil)
+	if err := obj.Emits("foo", (*testSignals)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := obj.Implements("foo", (*testIface)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	intf := obj.getInterfaces()["foo"]
+	if len(intf.methods) != 1 || len(intf.signals) != 1 {
+		t.Fatal("Implements replaced the registered signals")
+	}
+}
+
+type crashesTwice struct {
+	calls int
+}
+
+func (c *crashesTwice) CallMe() string {
+	c.calls++
+	if c.calls > 1 {
+		panic("called twice")
+	}
+	return "hello, world"
+}
+
+func TestObjectFactoryRestart(t *testing.T) {
+	parent := NewObjectFromTable("", nil, nil, nil)
+	obj := parent.NewObjectFactory("/child", func() interface{} {
+		return &crashesTwice{}
+	})
+	if err := obj.Implements("foo", (*testIface)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := obj.Call("foo", "CallMe"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := obj.Call("foo", "CallMe"); err == nil {
+		t.Fatal("expected the crash")
+	}
+
+	var replacement *Object
+	deadline := time.Now().Add(time.Second)
+	for {
+		replacement, _ = parent.LookupObject("child")
+		if replacement != obj {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("object not replaced")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	outs, err := replacement.Call("foo", "CallMe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outs[0].(string) != "hello, world" {
+		t.Fatal("replacement does not have a fresh value")
+	}
+}
+
+func TestDecodeArgumentsSender(t *testing.T) {
+	fn := func(sender dbus.Sender, n int32, s string) {}
+	method := &Method{
+		name:  "Foo",
+		value: reflect.ValueOf(fn),
+		plan:  newDecodePlan(reflect.TypeOf(fn)),
+	}
+	msg := &dbus.Message{Body: []interface{}{int32(3), "x"}}
+	for i := 0; i < 2; i++ {
+		args, err := method.DecodeArguments(nil, ":1.7", msg, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := []interface{}{dbus.Sender(":1.7"), int32(3), "x"}
+		if !reflect.DeepEqual(args, want) {
+			t.Fatalf("expected %v, got %v", want, args)
+		}
+	}
+	msg.Body = msg.Body[:1]
+	_, err := method.DecodeArguments(nil, ":1.7", msg, nil)
+	if derr, ok := err.(*dbus.Error); !ok || derr.Name != fdtInvalidArgs {
+		t.Fatalf("expected InvalidArgs, got %v", err)
+	}
+}
+
+func TestDecodeArgumentsContext(t *testing.T) {
+	fn := func(ctx context.Context, sender dbus.Sender, n int32) {}
+	method := &Method{
+		name:  "Foo",
+		value: reflect.ValueOf(fn),
+		plan:  newDecodePlan(reflect.TypeOf(fn)),
+	}
+	msg := &dbus.Message{Body: []interface{}{int32(3)}}
+	args, err := method.DecodeArguments(nil, ":1.7", msg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{dbus.Sender(":1.7"), int32(3)}
+	if !reflect.DeepEqual(args, want) {
+		t.Fatalf("expected the context left to the sequent, got %v", args)
+	}
+	if n := method.NumArguments(); n != 2 {
+		t.Fatalf("expected 2 arguments, got %d", n)
+	}
+	in := getIntrospectionArguments(reflect.TypeOf(fn).NumIn,
+		reflect.TypeOf(fn).In, "in")
+	if len(in) != 1 || in[0].Type != "i" {
+		t.Fatalf("expected only n introspected, got %v", in)
+	}
+}
+
+type hostile struct {
+	calls int
+}
+
+func (h *hostile) Set(n int32, name string) {
+	h.calls++
+}
+
+func (h *hostile) Count() int {
+	return h.calls
+}
+
+type hostileIface interface {
+	Set(int32, string)
+	Count() int
+}
+
+// deliverCall handles a method call the way the connection does.
+func deliverCall(
+	obj *Object,
+	ifaceName, name string,
+	body ...interface{},
+) ([]interface{}, error) {
+	iface, ok := obj.LookupInterface(ifaceName)
+	if !ok {
+		return nil, dbus.ErrMsgUnknownInterface
+	}
+	method, ok := iface.LookupMethod(name)
+	if !ok {
+		return nil, dbus.ErrMsgUnknownMethod
+	}
+	msg := &dbus.Message{Type: dbus.TypeMethodCall, Body: body}
+	args, err := method.(dbus.ArgumentDecoder).DecodeArguments(nil, ":1.9", msg, body)
+	if err != nil {
+		return nil, err
+	}
+	return method.Call(args...)
+}
+
+func TestMalformedCalls(t *testing.T) {
+	obj := NewObject("", &hostile{}, nil, nil)
+	if err := obj.Implements("com.example.Hostile", (*hostileIface)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		body    []interface{}
+		message string
+	}{
+		{[]interface{}{int32(1)},
+			"Method Set takes 2 arguments, have 1"},
+		{[]interface{}{int32(1), "a", "b"},
+			"Method Set takes 2 arguments, have 3"},
+		{[]interface{}{int32(1), []string{"a"}},
+			"Argument 1 of method Set: "},
+		{[]interface{}{dbus.MakeVariant(true), "a"},
+			"Argument 0 of method Set: "},
+		{[]interface{}{[]interface{}{int32(1)}, "a"},
+			"Argument 0 of method Set: "},
+	}
+	for _, test := range tests {
+		_, err := deliverCall(obj, "com.example.Hostile", "Set", test.body...)
+		derr, ok := err.(*dbus.Error)
+		if !ok || derr.Name != fdtInvalidArgs || len(derr.Body) != 1 {
+			t.Fatalf("%v: expected InvalidArgs, got %v", test.body, err)
+		}
+		if msg, _ := derr.Body[0].(string); !strings.HasPrefix(msg, test.message) {
+			t.Fatalf("%v: expected %q, got %q", test.body, test.message, msg)
+		}
+	}
+
+	if _, err := deliverCall(obj, "com.example.Hostile", "Set", int32(1), "a"); err != nil {
+		t.Fatal(err)
+	}
+	outs, err := deliverCall(obj, "com.example.Hostile", "Count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outs[0] != 1 {
+		t.Fatalf("expected only the valid call to be made, got %v", outs[0])
+	}
+}
+
+func TestReadyWithoutName(t *testing.T) {
+	mgr := newPipeBusManager(t)
+	defer mgr.conn.Close()
+	if err := mgr.Ready(); err == nil {
+		t.Fatal("expected an anonymous manager not to be made ready")
+	}
+}
+
+func TestIntrospectHeader(t *testing.T) {
+	mgr := newPipeBusManager(t)
+	defer mgr.conn.Close()
+	obj := mgr.NewObject("/foo/bar", &job{})
+	declaration := strings.TrimSpace(introspect.IntrospectDeclarationString)
+
+	tests := []struct {
+		configure func()
+		prefix    string
+	}{
+		{func() {}, declaration + "<node>"},
+		{func() { mgr.IntrospectHeader = "<!-- header -->" }, "<!-- header --><node>"},
+		{func() { mgr.OmitIntrospectHeader = true }, "<node>"},
+		{func() { mgr.IntrospectRootPath = true }, `<node name="/foo/bar">`},
+	}
+	for _, test := range tests {
+		test.configure()
+		out, err := obj.introspectXML()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(out, test.prefix) {
+			t.Fatalf("expected introspection starting with %q, got %q",
+				test.prefix, out)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+// Package dbusprom exports the calls made to the objects of a
+// dbus.BusManager to Prometheus.
+package dbusprom
+
+import (
+	"time"
+
+	godbus "github.com/godbus/dbus"
+	"github.com/jsouthworth/seriatim/dbus"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// MethodCollector is a prometheus.Collector of the calls made to the
+// objects of a BusManager, when set as its Metrics:
+//
+//	seriatim_dbus_calls_total{path,interface,member}
+//	seriatim_dbus_call_errors_total{path,interface,member,error}
+//	seriatim_dbus_call_duration_seconds{path,interface,member}
+//	seriatim_dbus_queue_duration_seconds{member}
+//
+// error is the D-Bus name of the error a call failed with. The time
+// calls waited in the mailboxes of the objects comes from the
+// seriatim.Metrics of their sequents, which know the member but not
+// the object. Objects made per request, such as those of Jobs, each
+// have a path of their own; set Path to map them to fewer labels.
+//
+//	collector := dbusprom.NewMethodCollector()
+//	prometheus.MustRegister(collector)
+//	mgr.Metrics = collector
+type MethodCollector struct {
+	// Path returns the label of the calls to the object at path. It
+	// defaults to the path itself.
+	Path func(godbus.ObjectPath) string
+
+	calls    *prometheus.CounterVec
+	errors   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	queued   *prometheus.HistogramVec
+}
+
+func NewMethodCollector() *MethodCollector {
+	labels := []string{"path", "interface", "member"}
+	return &MethodCollector{
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "seriatim",
+			Subsystem: "dbus",
+			Name:      "calls_total",
+			Help:      "Method calls received from the bus.",
+		}, labels),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "seriatim",
+			Subsystem: "dbus",
+			Name:      "call_errors_total",
+			Help:      "Method calls answered with an error, by error name.",
+		}, append(labels, "error")),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "seriatim",
+			Subsystem: "dbus",
+			Name:      "call_duration_seconds",
+			Help:      "Time taken to answer method calls, including queueing.",
+			Buckets:   prometheus.DefBuckets,
+		}, labels),
+		queued: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "seriatim",
+			Subsystem: "dbus",
+			Name:      "queue_duration_seconds",
+			Help:      "Time method calls waited in the mailboxes of objects.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"member"}),
+	}
+}
+
+func (c *MethodCollector) Describe(ch chan<- *prometheus.Desc) {
+	c.calls.Describe(ch)
+	c.errors.Describe(ch)
+	c.duration.Describe(ch)
+	c.queued.Describe(ch)
+}
+
+func (c *MethodCollector) Collect(ch chan<- prometheus.Metric) {
+	c.calls.Collect(ch)
+	c.errors.Collect(ch)
+	c.duration.Collect(ch)
+	c.queued.Collect(ch)
+}
+
+// Called implements dbus.CallMetrics.
+func (c *MethodCollector) Called(
+	path godbus.ObjectPath,
+	iface, member string,
+	took time.Duration,
+	err error,
+) {
+	label := string(path)
+	if c.Path != nil {
+		label = c.Path(path)
+	}
+	c.calls.WithLabelValues(label, iface, member).Inc()
+	c.duration.WithLabelValues(label, iface, member).Observe(took.Seconds())
+	if err != nil {
+		c.errors.WithLabelValues(label, iface, member,
+			dbus.ErrorName(err)).Inc()
+	}
+}
+
+// Processed implements seriatim.Metrics. The requests the package
+// makes of the sequents itself have no method name and are left out.
+func (c *MethodCollector) Processed(
+	id uintptr,
+	method string,
+	waited, took time.Duration,
+) {
+	if method == "" {
+		return
+	}
+	c.queued.WithLabelValues(method).Observe(waited.Seconds())
+}
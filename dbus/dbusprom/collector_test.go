@@ -0,0 +1,44 @@
+package dbusprom
+
+import (
+	"testing"
+	"time"
+
+	godbus "github.com/godbus/dbus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var errNope = godbus.NewError("com.example.Error.Nope", nil)
+
+func TestMethodCollector(t *testing.T) {
+	collector := NewMethodCollector()
+	collector.Path = func(godbus.ObjectPath) string { return "/job" }
+	for i := 0; i < 4; i++ {
+		var err error
+		if i%2 == 1 {
+			err = errNope
+		}
+		collector.Called("/job/1", "com.example.Job", "Try",
+			time.Millisecond, err)
+	}
+	collector.Processed(1, "Try", time.Millisecond, time.Millisecond)
+	// the requests of the package itself are not counted
+	collector.Processed(1, "", time.Millisecond, time.Millisecond)
+
+	calls := testutil.ToFloat64(collector.calls.WithLabelValues(
+		"/job", "com.example.Job", "Try"))
+	if calls != 4 {
+		t.Fatalf("expected 4 calls, got %v", calls)
+	}
+	failed := testutil.ToFloat64(collector.errors.WithLabelValues(
+		"/job", "com.example.Job", "Try", "com.example.Error.Nope"))
+	if failed != 2 {
+		t.Fatalf("expected 2 errors, got %v", failed)
+	}
+	if n := testutil.CollectAndCount(collector.duration); n != 1 {
+		t.Fatalf("expected a duration histogram, got %d", n)
+	}
+	if n := testutil.CollectAndCount(collector.queued); n != 1 {
+		t.Fatalf("expected a queue histogram, got %d", n)
+	}
+}
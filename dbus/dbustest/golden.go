@@ -0,0 +1,163 @@
+// Package dbustest provides helpers for testing D-Bus objects built
+// with the seriatim dbus package.
+package dbustest
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus/introspect"
+)
+
+// Update makes Golden rewrite golden files instead of comparing
+// against them:
+//
+//	go test -dbustest.update
+var Update = flag.Bool("dbustest.update", false,
+	"rewrite golden introspection files")
+
+// Introspector is implemented by *dbus.Object.
+type Introspector interface {
+	Introspect() *introspect.Node
+}
+
+// Canonical renders node as indented XML with its interfaces, members,
+// annotations and children sorted by name, so the same API always
+// renders the same way. Arguments keep their order as it is part of
+// the signature. The name of node itself is left out so an object can
+// be compared wherever it is exported.
+func Canonical(node *introspect.Node) ([]byte, error) {
+	n := sortNode(*node)
+	n.Name = ""
+	b, err := xml.MarshalIndent(&n, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	buf.WriteString(strings.TrimSpace(introspect.IntrospectDeclarationString))
+	buf.WriteByte('\n')
+	buf.Write(b)
+	buf.WriteByte('\n')
+	return buf.Bytes(), nil
+}
+
+func sortNode(node introspect.Node) introspect.Node {
+	ifaces := make([]introspect.Interface, len(node.Interfaces))
+	for i, iface := range node.Interfaces {
+		ifaces[i] = sortInterface(iface)
+	}
+	sort.Slice(ifaces, func(i, j int) bool { return ifaces[i].Name < ifaces[j].Name })
+	children := make([]introspect.Node, len(node.Children))
+	for i, child := range node.Children {
+		children[i] = sortNode(child)
+	}
+	sort.Slice(children, func(i, j int) bool { return children[i].Name < children[j].Name })
+	node.Interfaces = ifaces
+	node.Children = children
+	return node
+}
+
+func sortInterface(iface introspect.Interface) introspect.Interface {
+	methods := make([]introspect.Method, len(iface.Methods))
+	for i, method := range iface.Methods {
+		method.Annotations = sortAnnotations(method.Annotations)
+		methods[i] = method
+	}
+	sort.Slice(methods, func(i, j int) bool { return methods[i].Name < methods[j].Name })
+	signals := make([]introspect.Signal, len(iface.Signals))
+	for i, signal := range iface.Signals {
+		signal.Annotations = sortAnnotations(signal.Annotations)
+		signals[i] = signal
+	}
+	sort.Slice(signals, func(i, j int) bool { return signals[i].Name < signals[j].Name })
+	props := make([]introspect.Property, len(iface.Properties))
+	for i, prop := range iface.Properties {
+		prop.Annotations = sortAnnotations(prop.Annotations)
+		props[i] = prop
+	}
+	sort.Slice(props, func(i, j int) bool { return props[i].Name < props[j].Name })
+	iface.Methods = methods
+	iface.Signals = signals
+	iface.Properties = props
+	iface.Annotations = sortAnnotations(iface.Annotations)
+	return iface
+}
+
+func sortAnnotations(annotations []introspect.Annotation) []introspect.Annotation {
+	out := append([]introspect.Annotation(nil), annotations...)
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Golden fails t if the canonical introspection of obj differs from
+// the golden file at path, or writes the file when Update is set.
+func Golden(t testing.TB, obj Introspector, path string) {
+	t.Helper()
+	got, err := Canonical(obj.Introspect())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *Update {
+		if err := ioutil.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with -dbustest.update to create it)", err)
+	}
+	if diff := diffLines(string(want), string(got)); diff != "" {
+		t.Errorf("introspection differs from %s (-want +got):\n%s", path, diff)
+	}
+}
+
+// diffLines returns a line diff of want and got, or "" if they are
+// equal.
+func diffLines(want, got string) string {
+	if want == got {
+		return ""
+	}
+	a := strings.Split(strings.TrimSuffix(want, "\n"), "\n")
+	b := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+	// lcs[i][j] is the length of the longest common subsequence of
+	// a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var buf bytes.Buffer
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&buf, "  %s\n", a[i])
+			i++
+			j++
+		case j < len(b) && (i == len(a) || lcs[i][j+1] > lcs[i+1][j]):
+			fmt.Fprintf(&buf, "+ %s\n", b[j])
+			j++
+		default:
+			fmt.Fprintf(&buf, "- %s\n", a[i])
+			i++
+		}
+	}
+	return buf.String()
+}
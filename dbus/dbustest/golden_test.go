@@ -0,0 +1,75 @@
+package dbustest
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/jsouthworth/seriatim/dbus"
+)
+
+type thermostat struct{}
+
+func (thermostat) SetTarget(target int32) error { return nil }
+func (thermostat) Target() int32                { return 0 }
+func (thermostat) Reset() error                 { return nil }
+func (thermostat) Rename(name string) string    { return name }
+
+type thermostatIface interface {
+	SetTarget(int32) error
+	Target() int32
+	Reset() error
+}
+
+type namedIface interface {
+	Rename(string) string
+}
+
+func newThermostat(t *testing.T) *dbus.Object {
+	obj := dbus.NewObject("", thermostat{}, nil, nil)
+	if err := obj.Implements("com.example.Thermostat",
+		(*thermostatIface)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := obj.Implements("com.example.Named", (*namedIface)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	return obj
+}
+
+func TestGolden(t *testing.T) {
+	obj := newThermostat(t)
+	// Map ordering differs between runs of Introspect.
+	for i := 0; i < 10; i++ {
+		Golden(t, obj, filepath.Join("testdata", "thermostat.xml"))
+	}
+}
+
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (tb *recordingTB) Helper() {}
+
+func (tb *recordingTB) Errorf(format string, args ...interface{}) {
+	tb.errors = append(tb.errors, format)
+}
+
+func TestGoldenMismatch(t *testing.T) {
+	obj := newThermostat(t)
+	tb := &recordingTB{TB: t}
+	Golden(tb, obj, filepath.Join("testdata", "stale.xml"))
+	if len(tb.errors) != 1 {
+		t.Fatalf("expected a mismatch, got %v", tb.errors)
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	if diff := diffLines("a\nb\n", "a\nb\n"); diff != "" {
+		t.Fatalf("unexpected diff %q", diff)
+	}
+	diff := diffLines("a\nb\nc\n", "a\nx\nc\n")
+	if diff != "  a\n- b\n+ x\n  c\n" {
+		t.Fatalf("unexpected diff %q", diff)
+	}
+}
@@ -0,0 +1,305 @@
+package dbustest
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	godbus "github.com/godbus/dbus"
+	"github.com/jsouthworth/seriatim"
+	"github.com/jsouthworth/seriatim/dbus"
+)
+
+// StressInterface is the D-Bus interface implemented by the objects
+// Stress creates.
+const StressInterface = "org.seriatim.Stress"
+
+// StressConfig controls a Stress run. Zero fields take the defaults
+// noted.
+type StressConfig struct {
+	// Prefix is the path below which objects are created, "/stress"
+	// by default. Every other path is a child of the one before it
+	// so objects are also replaced and deleted while they have
+	// children.
+	Prefix string
+	// Paths is the number of object paths, 8 by default.
+	Paths int
+	// Mutators create, replace and delete objects; each owns a
+	// share of the paths. 4 by default.
+	Mutators int
+	// Callers call methods on random paths, 4 by default.
+	Callers int
+	// Signalers deliver signals through the tree, 2 by default.
+	Signalers int
+	// Iterations is the number of operations made by each
+	// goroutine, 200 by default.
+	Iterations int
+	// Settle bounds the wait for asynchronous terminations once
+	// the operations are done, 5 seconds by default.
+	Settle time.Duration
+	Seed   int64
+}
+
+// StressReport counts the operations made by a Stress run.
+type StressReport struct {
+	Creates, Replaces, Deletes uint64
+	// Calls that returned a result and calls that failed because
+	// the object was being replaced or deleted.
+	Calls, FailedCalls uint64
+	Signals            uint64
+}
+
+type stressValue struct {
+	generation int32
+}
+
+func (v *stressValue) Ping(n int32) (int32, int32) {
+	return n, v.generation
+}
+
+func (v *stressValue) Changed(what string) {}
+
+type stressIface interface {
+	Ping(int32) (int32, int32)
+}
+
+// Stress concurrently creates, replaces and deletes objects below root
+// while methods are called on them and signals are delivered through
+// the tree. It returns an error for the first panic, call returning a
+// wrong result or unexpected error, or object left without its
+// interface once the operations are done. Objects are not registered
+// to receive signals, which needs a connection to a bus, so signal
+// delivery exercises the traversal of the changing tree.
+//
+// Stress leaves the paths it used deleted.
+func Stress(root *dbus.Object, cfg StressConfig) (*StressReport, error) {
+	if cfg.Prefix == "" {
+		cfg.Prefix = "/stress"
+	}
+	if cfg.Paths == 0 {
+		cfg.Paths = 8
+	}
+	if cfg.Mutators == 0 {
+		cfg.Mutators = 4
+	}
+	if cfg.Callers == 0 {
+		cfg.Callers = 4
+	}
+	if cfg.Signalers == 0 {
+		cfg.Signalers = 2
+	}
+	if cfg.Iterations == 0 {
+		cfg.Iterations = 200
+	}
+	if cfg.Settle == 0 {
+		cfg.Settle = 5 * time.Second
+	}
+	s := &stress{
+		root:   root,
+		cfg:    cfg,
+		paths:  make([]string, cfg.Paths),
+		live:   make([]bool, cfg.Paths),
+		report: &StressReport{},
+	}
+	for i := range s.paths {
+		if i%2 == 1 {
+			s.paths[i] = s.paths[i-1] + "/child"
+		} else {
+			s.paths[i] = fmt.Sprintf("%s/n%d", cfg.Prefix, i)
+		}
+	}
+
+	var wg sync.WaitGroup
+	start := func(seed int64, fn func(*rand.Rand)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() {
+				if r := recover(); r != nil {
+					s.fail(fmt.Errorf("panic: %v", r))
+				}
+			}()
+			fn(rand.New(rand.NewSource(seed)))
+		}()
+	}
+	seed := cfg.Seed
+	for i := 0; i < cfg.Mutators; i++ {
+		worker := i
+		start(seed, func(r *rand.Rand) { s.mutate(r, worker) })
+		seed++
+	}
+	for i := 0; i < cfg.Callers; i++ {
+		start(seed, s.call)
+		seed++
+	}
+	for i := 0; i < cfg.Signalers; i++ {
+		start(seed, s.signal)
+		seed++
+	}
+	wg.Wait()
+	if err := s.failure(); err != nil {
+		return s.report, err
+	}
+	if err := s.settle(); err != nil {
+		return s.report, err
+	}
+	for i := len(s.paths) - 1; i >= 0; i-- {
+		root.DeleteObject(godbus.ObjectPath(s.paths[i]))
+	}
+	return s.report, nil
+}
+
+type stress struct {
+	root   *dbus.Object
+	cfg    StressConfig
+	paths  []string
+	live   []bool
+	report *StressReport
+
+	mu  sync.Mutex
+	err error
+}
+
+func (s *stress) fail(err error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = err
+	}
+	s.mu.Unlock()
+}
+
+func (s *stress) failure() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// lookup walks the tree from the root to path.
+func (s *stress) lookup(path string) (*dbus.Object, bool) {
+	obj := s.root
+	for _, name := range strings.Split(strings.Trim(path, "/"), "/") {
+		var ok bool
+		obj, ok = obj.LookupObject(name)
+		if !ok {
+			return nil, false
+		}
+	}
+	return obj, true
+}
+
+// mutate changes the paths owned by worker, recording which of them
+// should exist afterwards.
+func (s *stress) mutate(r *rand.Rand, worker int) {
+	var owned []int
+	for i := worker; i < len(s.paths); i += s.cfg.Mutators {
+		owned = append(owned, i)
+	}
+	if len(owned) == 0 {
+		return
+	}
+	for n := 0; n < s.cfg.Iterations && s.failure() == nil; n++ {
+		i := owned[r.Intn(len(owned))]
+		path := godbus.ObjectPath(s.paths[i])
+		if s.live[i] && r.Intn(3) == 0 {
+			s.root.DeleteObject(path)
+			s.live[i] = false
+			atomic.AddUint64(&s.report.Deletes, 1)
+			continue
+		}
+		obj := s.root.NewObject(path, &stressValue{generation: int32(n)})
+		if err := obj.Implements(StressInterface, (*stressIface)(nil)); err != nil {
+			s.fail(fmt.Errorf("%s: %v", path, err))
+			return
+		}
+		if s.live[i] {
+			atomic.AddUint64(&s.report.Replaces, 1)
+		} else {
+			atomic.AddUint64(&s.report.Creates, 1)
+		}
+		s.live[i] = true
+	}
+}
+
+func (s *stress) call(r *rand.Rand) {
+	for n := 0; n < s.cfg.Iterations && s.failure() == nil; n++ {
+		path := s.paths[r.Intn(len(s.paths))]
+		obj, ok := s.lookup(path)
+		if !ok {
+			continue
+		}
+		ret, err := obj.Call(StressInterface, "Ping", int32(n))
+		switch {
+		case err == nil:
+			if len(ret) != 2 || ret[0] != int32(n) {
+				s.fail(fmt.Errorf("%s: Ping(%d) returned %v", path, n, ret))
+				return
+			}
+			atomic.AddUint64(&s.report.Calls, 1)
+		case errors.Is(err, seriatim.ErrSequentStop) || isUnknownInterface(err):
+			// placeholders and objects going away
+			atomic.AddUint64(&s.report.FailedCalls, 1)
+		default:
+			s.fail(fmt.Errorf("%s: Ping: %v", path, err))
+			return
+		}
+	}
+}
+
+func isUnknownInterface(err error) bool {
+	derr, ok := err.(godbus.Error)
+	return ok && derr.Name == godbus.ErrMsgUnknownInterface.Name
+}
+
+func (s *stress) signal(r *rand.Rand) {
+	for n := 0; n < s.cfg.Iterations && s.failure() == nil; n++ {
+		s.root.DeliverSignal(StressInterface, "Changed", &godbus.Signal{
+			Path: godbus.ObjectPath(s.paths[r.Intn(len(s.paths))]),
+			Name: StressInterface + ".Changed",
+			Body: []interface{}{"changed"},
+		})
+		atomic.AddUint64(&s.report.Signals, 1)
+	}
+}
+
+// settle waits for the tree to reflect the last operation on every
+// path: live paths serve the interface and deleted ones do not.
+// Deletions terminate objects asynchronously so the tree may lag.
+func (s *stress) settle() error {
+	deadline := time.Now().Add(s.cfg.Settle)
+	for {
+		err := s.check()
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (s *stress) check() error {
+	for i, path := range s.paths {
+		obj, ok := s.lookup(path)
+		if !s.live[i] {
+			if ok {
+				if _, ok := obj.LookupInterface(StressInterface); ok {
+					return fmt.Errorf("%s: deleted object still serves %s",
+						path, StressInterface)
+				}
+			}
+			continue
+		}
+		if !ok {
+			return fmt.Errorf("%s: object lost", path)
+		}
+		if _, ok := obj.LookupInterface(StressInterface); !ok {
+			return fmt.Errorf("%s: interface %s lost", path, StressInterface)
+		}
+		if _, err := obj.Call(StressInterface, "Ping", int32(0)); err != nil {
+			return fmt.Errorf("%s: Ping: %v", path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package dbustest
+
+import (
+	"testing"
+
+	"github.com/jsouthworth/seriatim/dbus"
+)
+
+func TestStress(t *testing.T) {
+	root := dbus.NewObject("", nil, nil, nil)
+	for seed := int64(0); seed < 4; seed++ {
+		report, err := Stress(root, StressConfig{Seed: seed})
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+		if report.Calls == 0 || report.Deletes == 0 || report.Replaces == 0 {
+			t.Fatalf("seed %d: operations missing from %+v", seed, report)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+package dbus
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+const (
+	fdtObjectManager  = "org.freedesktop.DBus.ObjectManager"
+	interfacesRemoved = "InterfacesRemoved"
+)
+
+// Expiry says when an object made by NewExpiringObject is removed.
+// Either or both may be set; the object goes at whichever comes first.
+type Expiry struct {
+	// At is the time the object is removed, zero for never.
+	At time.Time
+	// Idle is how long the object may go without being called
+	// before it is removed, zero for ever.
+	Idle time.Duration
+}
+
+// NewExpiringObject adds an object at path that is removed from the
+// tree, terminating its sequent, once expiry says so, such as one
+// exposing an operation started by a request that its caller may
+// never come back for. Lookups of the object's methods count as calls
+// for Idle. When the object is attached to a bus its removal is
+// announced by an InterfacesRemoved signal of the
+// org.freedesktop.DBus.ObjectManager interface from the root of the
+// tree.
+func (o *Object) NewExpiringObject(
+	path dbus.ObjectPath,
+	val interface{},
+	expiry Expiry,
+) *Object {
+	obj := o.NewObject(path, val)
+	if obj == o {
+		return obj
+	}
+	obj.touch()
+	e := &expiring{parent: o, path: path, obj: obj, expiry: expiry}
+	e.timer = time.AfterFunc(e.next(time.Now()), e.check)
+	return obj
+}
+
+type expiring struct {
+	parent *Object
+	path   dbus.ObjectPath
+	obj    *Object
+	expiry Expiry
+	timer  *time.Timer
+}
+
+// next returns how long to wait from now before the object may have
+// expired.
+func (e *expiring) next(now time.Time) time.Duration {
+	var wait time.Duration = -1
+	if !e.expiry.At.IsZero() {
+		wait = e.expiry.At.Sub(now)
+	}
+	if e.expiry.Idle > 0 {
+		idle := e.obj.lastCalled().Add(e.expiry.Idle).Sub(now)
+		if wait < 0 || idle < wait {
+			wait = idle
+		}
+	}
+	if wait < 0 {
+		return 0
+	}
+	return wait
+}
+
+func (e *expiring) check() {
+	if atomic.LoadInt32(&e.obj.terminated) != 0 || !e.current() {
+		// deleted or replaced in the meantime
+		return
+	}
+	if wait := e.next(time.Now()); wait > 0 {
+		e.timer.Reset(wait)
+		return
+	}
+	e.obj.removeFrom(e.parent, e.path)
+}
+
+// current reports whether the expiring object is still the one at
+// its path.
+func (e *expiring) current() bool {
+	if e.obj.parent == nil {
+		return false
+	}
+	obj, ok := e.obj.parent.LookupObject(e.obj.name)
+	return ok && obj == e.obj
+}
+
+// removeFrom deletes o, found at path under parent, announcing its
+// removal.
+func (o *Object) removeFrom(parent *Object, path dbus.ObjectPath) {
+	names := make([]string, 0, len(o.getInterfaces()))
+	for name := range o.getInterfaces() {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	full := o.Path()
+	parent.DeleteObject(path)
+	o.emitRemoved(full, names)
+}
+
+// emitRemoved announces that the object at path with the interfaces
+// names went away.
+func (o *Object) emitRemoved(path dbus.ObjectPath, names []string) {
+	if o.bus == nil || o.bus.conn == nil {
+		return
+	}
+	root := o
+	for root.parent != nil {
+		root = root.parent
+	}
+	from := root.Path()
+	err := o.bus.conn.Emit(from, fdtObjectManager+"."+interfacesRemoved,
+		path, names)
+	if err != nil {
+		o.bus.emitFailed(from, fdtObjectManager, interfacesRemoved, err)
+		return
+	}
+	o.bus.notifyEmitted(&EmittedSignal{
+		Path:      from,
+		Interface: fdtObjectManager,
+		Member:    interfacesRemoved,
+		Body:      []interface{}{path, names},
+	})
+}
+
+func (o *Object) touch() {
+	atomic.StoreInt64(&o.lastCall, time.Now().UnixNano())
+}
+
+func (o *Object) lastCalled() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&o.lastCall))
+}
@@ -0,0 +1,97 @@
+package dbus
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+type job struct {
+	n int
+}
+
+func (j *job) Progress() int {
+	j.n++
+	return j.n
+}
+
+type jobIface interface {
+	Progress() int
+}
+
+// waitRemoved waits for the object at path to leave the tree.
+func waitRemoved(t *testing.T, root *Object, path ...string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, ok := root.lookupObjectPath(path); !ok {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("object %v was not removed", path)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestExpiringObjectIdle(t *testing.T) {
+	mgr := newPipeBusManager(t)
+	defer mgr.conn.Close()
+	removed := make(chan *EmittedSignal, 1)
+	defer mgr.Observe(func(signal *EmittedSignal) {
+		removed <- signal
+	})()
+
+	const idle = 50 * time.Millisecond
+	obj := mgr.NewExpiringObject("/jobs/1", &job{}, Expiry{Idle: idle})
+	if err := obj.Implements("com.example.Job", (*jobIface)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		time.Sleep(idle / 2)
+		if _, err := deliverCall(obj, "com.example.Job", "Progress"); err != nil {
+			t.Fatalf("call %d failed after %v: %v", i, idle/2, err)
+		}
+	}
+	waitRemoved(t, mgr.Object, "jobs", "1")
+	if obj.sequent.Running() {
+		t.Fatal("expired object still running")
+	}
+
+	signal := <-removed
+	want := &EmittedSignal{
+		Path:      "/",
+		Interface: "org.freedesktop.DBus.ObjectManager",
+		Member:    "InterfacesRemoved",
+		Body: []interface{}{
+			dbus.ObjectPath("/jobs/1"),
+			[]string{"com.example.Job", fdtIntrospectable},
+		},
+	}
+	if !reflect.DeepEqual(signal, want) {
+		t.Fatalf("expected %+v, got %+v", want, signal)
+	}
+}
+
+func TestExpiringObjectAt(t *testing.T) {
+	root := NewObject("", nil, nil, nil)
+	obj := root.NewExpiringObject("/jobs/1", &job{},
+		Expiry{At: time.Now().Add(10 * time.Millisecond)})
+	waitRemoved(t, root, "jobs", "1")
+	if obj.sequent.Running() {
+		t.Fatal("expired object still running")
+	}
+}
+
+func TestExpiringObjectReplaced(t *testing.T) {
+	root := NewObject("", nil, nil, nil)
+	root.NewExpiringObject("/jobs/1", &job{},
+		Expiry{At: time.Now().Add(10 * time.Millisecond)})
+	replacement := root.NewObject("/jobs/1", &job{})
+	time.Sleep(50 * time.Millisecond)
+	obj, ok := root.lookupObjectPath([]string{"jobs", "1"})
+	if !ok || obj != replacement {
+		t.Fatal("expiry removed the object replacing the expiring one")
+	}
+}
@@ -0,0 +1,149 @@
+package dbus
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// rateWindow is the period over which the rate of messages of a
+	// sender is measured.
+	rateWindow = time.Second
+	// senderIdle is how long a sender goes without a message before
+	// it is forgotten.
+	senderIdle = time.Minute
+)
+
+// SenderStats describes the messages received from a connection: the
+// method calls made to the objects of a BusManager and the signals it
+// received.
+type SenderStats struct {
+	Sender   string
+	Messages uint64
+	// Rate is the number of messages per second received during the
+	// last complete second.
+	Rate float64
+	// Floods is the number of seconds during which more than the
+	// FloodRate of the BusManager were received.
+	Floods uint64
+	Last   time.Time
+}
+
+type senderRate struct {
+	messages uint64
+	floods   uint64
+	last     time.Time
+	// window started at start and counts n messages; rate is that of
+	// the window before it.
+	start   time.Time
+	n       uint64
+	rate    float64
+	flooded bool
+}
+
+// senderRates tracks the rate of messages of every sender seen within
+// senderIdle.
+type senderRates struct {
+	mu      sync.Mutex
+	senders map[string]*senderRate
+	pruned  time.Time
+}
+
+// count records a message from sender at now, returning the rate it
+// reached if that exceeds limit for the first time in the current
+// window.
+func (rates *senderRates) count(
+	sender string,
+	now time.Time,
+	limit float64,
+) (rate float64, flooded bool) {
+	rates.mu.Lock()
+	defer rates.mu.Unlock()
+	if rates.senders == nil {
+		rates.senders = make(map[string]*senderRate)
+	}
+	if now.Sub(rates.pruned) >= senderIdle {
+		rates.prune(now)
+	}
+	s, ok := rates.senders[sender]
+	if !ok {
+		s = &senderRate{start: now}
+		rates.senders[sender] = s
+	}
+	s.roll(now)
+	s.messages++
+	s.n++
+	s.last = now
+	if limit <= 0 || s.flooded || float64(s.n) <= limit*rateWindow.Seconds() {
+		return 0, false
+	}
+	s.flooded = true
+	s.floods++
+	return float64(s.n) / rateWindow.Seconds(), true
+}
+
+func (rates *senderRates) prune(now time.Time) {
+	for sender, s := range rates.senders {
+		if now.Sub(s.last) >= senderIdle {
+			delete(rates.senders, sender)
+		}
+	}
+	rates.pruned = now
+}
+
+// roll starts a new window if the current one is over.
+func (s *senderRate) roll(now time.Time) {
+	elapsed := now.Sub(s.start)
+	switch {
+	case elapsed < rateWindow:
+		return
+	case elapsed < 2*rateWindow:
+		s.rate = float64(s.n) / rateWindow.Seconds()
+		s.start = s.start.Add(rateWindow)
+	default:
+		// nothing was received during the last window
+		s.rate = 0
+		s.start = now
+	}
+	s.n = 0
+	s.flooded = false
+}
+
+func (rates *senderRates) stats(now time.Time) []SenderStats {
+	rates.mu.Lock()
+	defer rates.mu.Unlock()
+	out := make([]SenderStats, 0, len(rates.senders))
+	for sender, s := range rates.senders {
+		s.roll(now)
+		out = append(out, SenderStats{
+			Sender:   sender,
+			Messages: s.messages,
+			Rate:     s.rate,
+			Floods:   s.floods,
+			Last:     s.last,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Sender < out[j].Sender
+	})
+	return out
+}
+
+// SenderStats returns the statistics of the connections that sent
+// messages to mgr within the last minute, ordered by name.
+func (mgr *BusManager) SenderStats() []SenderStats {
+	return mgr.rates.stats(time.Now())
+}
+
+// countMessage accounts for a message from sender, calling OnFlood if
+// it floods mgr.
+func (mgr *BusManager) countMessage(sender string) {
+	if sender == "" {
+		return
+	}
+	rate, flooded := mgr.rates.count(sender, time.Now(), mgr.FloodRate)
+	if flooded && mgr.OnFlood != nil {
+		mgr.OnFlood(sender, rate)
+	}
+}
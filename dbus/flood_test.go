@@ -0,0 +1,77 @@
+package dbus
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSenderRates(t *testing.T) {
+	var rates senderRates
+	start := time.Now()
+	at := func(d time.Duration) time.Time { return start.Add(d) }
+
+	for i := 0; i < 3; i++ {
+		if _, flooded := rates.count(":1.1", at(0), 3); flooded {
+			t.Fatalf("message %d flooded below the limit", i)
+		}
+	}
+	rate, flooded := rates.count(":1.1", at(time.Millisecond), 3)
+	if !flooded || rate != 4 {
+		t.Fatalf("expected a flood at rate 4, got %v at %v", flooded, rate)
+	}
+	if _, flooded := rates.count(":1.1", at(2*time.Millisecond), 3); flooded {
+		t.Fatal("flood reported twice in a window")
+	}
+	rates.count(":1.2", at(0), 3)
+
+	stats := rates.stats(at(rateWindow))
+	expected := []SenderStats{
+		{Sender: ":1.1", Messages: 5, Rate: 5, Floods: 1,
+			Last: at(2 * time.Millisecond)},
+		{Sender: ":1.2", Messages: 1, Rate: 1, Last: at(0)},
+	}
+	if len(stats) != len(expected) {
+		t.Fatalf("expected %v, got %v", expected, stats)
+	}
+	for i := range stats {
+		if stats[i] != expected[i] {
+			t.Fatalf("expected %v, got %v", expected[i], stats[i])
+		}
+	}
+
+	if stats := rates.stats(at(3 * rateWindow)); stats[0].Rate != 0 {
+		t.Fatalf("expected an idle sender to have no rate, got %v",
+			stats[0].Rate)
+	}
+	rates.count(":1.3", at(senderIdle+rateWindow), 3)
+	if stats := rates.stats(at(senderIdle + rateWindow)); len(stats) != 1 ||
+		stats[0].Sender != ":1.3" {
+		t.Fatalf("expected idle senders to be forgotten, got %v", stats)
+	}
+}
+
+func TestOnFlood(t *testing.T) {
+	mgr := newPipeBusManager(t)
+	defer mgr.conn.Close()
+	obj := mgr.NewObject("/foo", &job{})
+	if err := obj.Implements("com.example.Job", (*interface{ Progress() int })(nil)); err != nil {
+		t.Fatal(err)
+	}
+	var floods []string
+	mgr.FloodRate = 2
+	mgr.OnFlood = func(sender string, rate float64) {
+		floods = append(floods, sender)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := deliverCall(obj, "com.example.Job", "Progress"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(floods) != 1 || floods[0] != ":1.9" {
+		t.Fatalf("expected :1.9 to flood, got %v", floods)
+	}
+	stats := mgr.SenderStats()
+	if len(stats) != 1 || stats[0].Messages != 3 || stats[0].Floods != 1 {
+		t.Fatalf("unexpected sender stats %+v", stats)
+	}
+}
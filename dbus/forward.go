@@ -0,0 +1,94 @@
+package dbus
+
+import (
+	"github.com/godbus/dbus"
+)
+
+// forwarder sends the calls made to an object to a remote one.
+type forwarder struct {
+	target dbus.BusObject
+	rename func(string) string
+}
+
+func (f *forwarder) lookupInterface(name string) dbus.Interface {
+	if f.rename != nil {
+		name = f.rename(name)
+	}
+	return &forwardInterface{proxy: NewProxy(f.target, name)}
+}
+
+// NewForwarder adds an object at path whose calls are forwarded to
+// target, such as to keep serving an interface at a path it moved
+// from or to front a service from another bus name. rename maps the
+// interfaces called to those of target, nil keeps them. The replies
+// and errors of target, including its introspection data, are passed
+// back as they are.
+func (o *Object) NewForwarder(
+	path dbus.ObjectPath,
+	target dbus.BusObject,
+	rename func(string) string,
+) *Object {
+	obj := o.NewObjectFromTable(path, map[string]interface{}{})
+	obj.forward = &forwarder{target: target, rename: rename}
+	return obj
+}
+
+// Forward adds an object at path forwarding its calls to the object
+// at remote owned by dest; see NewForwarder.
+func (mgr *BusManager) Forward(
+	path dbus.ObjectPath,
+	dest string,
+	remote dbus.ObjectPath,
+	rename func(string) string,
+) *Object {
+	return mgr.NewForwarder(path, mgr.conn.Object(dest, remote), rename)
+}
+
+type forwardInterface struct {
+	proxy *Proxy
+}
+
+func (intf *forwardInterface) LookupMethod(name string) (dbus.Method, bool) {
+	return &forwardMethod{proxy: intf.proxy, name: name}, true
+}
+
+// forwardMethod passes the body of a call through undecoded.
+type forwardMethod struct {
+	proxy *Proxy
+	name  string
+}
+
+func (method *forwardMethod) DecodeArguments(
+	conn *dbus.Conn,
+	sender string,
+	msg *dbus.Message,
+	args []interface{},
+) ([]interface{}, error) {
+	return msg.Body, nil
+}
+
+func (method *forwardMethod) Call(args ...interface{}) ([]interface{}, error) {
+	call := method.proxy.Call(method.name, args...)
+	if call.Err != nil {
+		return nil, call.Err
+	}
+	return call.Body, nil
+}
+
+// The signature of forwarded methods is only known to the target.
+
+func (method *forwardMethod) NumArguments() int {
+	return 0
+}
+
+func (method *forwardMethod) NumReturns() int {
+	return 0
+}
+
+func (method *forwardMethod) ArgumentValue(position int) interface{} {
+	return nil
+}
+
+func (method *forwardMethod) ReturnValue(position int) interface{} {
+	return nil
+}
@@ -0,0 +1,54 @@
+package dbus
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus"
+)
+
+func TestForwarder(t *testing.T) {
+	root := NewObject("", nil, nil, nil)
+	target := &fakeBusObject{body: []interface{}{"hello"}}
+	root.NewForwarder("/compat/foo", target, func(in string) string {
+		if in == "com.example.Old" {
+			return "com.example.Foo"
+		}
+		return in
+	})
+	obj, ok := root.lookupObjectPath([]string{"compat", "foo"})
+	if !ok {
+		t.Fatal("forwarder not exported")
+	}
+
+	iface, ok := obj.LookupInterface("com.example.Old")
+	if !ok {
+		t.Fatal("expected every interface to be found")
+	}
+	method, _ := iface.LookupMethod("Baz")
+	msg := &dbus.Message{Body: []interface{}{int32(1), "x"}}
+	args, err := method.(*forwardMethod).DecodeArguments(nil, ":1.3", msg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	outs, err := method.Call(args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target.method != "com.example.Foo.Baz" ||
+		!reflect.DeepEqual(target.args, msg.Body) {
+		t.Fatalf("forwarded %s%v", target.method, target.args)
+	}
+	if !reflect.DeepEqual(outs, []interface{}{"hello"}) {
+		t.Fatalf("unexpected reply %v", outs)
+	}
+
+	target.err = errors.New("remote failed")
+	if _, err := obj.Call("com.example.Bar", "Baz"); err != target.err {
+		t.Fatalf("expected the remote error, got %v", err)
+	}
+	if target.method != "com.example.Bar.Baz" {
+		t.Fatalf("forwarded %s", target.method)
+	}
+}
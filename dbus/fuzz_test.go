@@ -0,0 +1,77 @@
+package dbus
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus"
+)
+
+type fuzzPoint struct {
+	X, Y int32
+}
+
+var fuzzMethods = []interface{}{
+	func(a int32, s string) {},
+	func(sender dbus.Sender, path dbus.ObjectPath, b []byte) {},
+	func(v dbus.Variant, m map[string]dbus.Variant) {},
+	func(p fuzzPoint, ps []fuzzPoint, strs []string) {},
+	func(b bool, d float64, u uint64, sig dbus.Signature) {},
+	func(nested [][]map[int32]string) {},
+	func() {},
+}
+
+// fuzzBodies are the values a fuzz input builds message bodies from,
+// in the forms godbus decodes them to.
+var fuzzBodies = []interface{}{
+	int32(1),
+	uint64(7),
+	"a",
+	true,
+	1.5,
+	dbus.ObjectPath("/a"),
+	dbus.Signature{},
+	[]byte("b"),
+	[]string{"c"},
+	dbus.MakeVariant(int32(1)),
+	map[string]dbus.Variant{"a": dbus.MakeVariant("b")},
+	[]interface{}{int32(1), int32(2)},
+	[]interface{}{int32(1)},
+	[][]interface{}{{int32(3), int32(4)}},
+	[][]map[int32]string{{{1: "a"}}},
+	map[int32]string{},
+}
+
+// Hostile message bodies must be rejected with an error, and those
+// accepted must decode to the types of the method's parameters.
+func FuzzDecodeArguments(f *testing.F) {
+	f.Add(uint8(0), []byte{0, 2})
+	f.Add(uint8(1), []byte{5, 7})
+	f.Add(uint8(2), []byte{9, 11})
+	f.Add(uint8(3), []byte{12, 14, 8})
+	f.Add(uint8(4), []byte{3, 4, 1, 6})
+	f.Add(uint8(5), []byte{15})
+	f.Add(uint8(6), []byte{})
+	f.Fuzz(func(t *testing.T, which uint8, data []byte) {
+		body := make([]interface{}, 0, len(data))
+		for _, b := range data {
+			body = append(body, fuzzBodies[int(b)%len(fuzzBodies)])
+		}
+		msg := &dbus.Message{Type: dbus.TypeMethodCall, Body: body}
+		fn := reflect.ValueOf(fuzzMethods[int(which)%len(fuzzMethods)])
+		method := &Method{name: "Bar", value: fn}
+		args, err := method.DecodeArguments(nil, ":1.1", msg, nil)
+		if err != nil {
+			return
+		}
+		if len(args) != fn.Type().NumIn() {
+			t.Fatalf("decoded %d arguments for %d parameters",
+				len(args), fn.Type().NumIn())
+		}
+		for i, arg := range args {
+			if reflect.TypeOf(arg) != fn.Type().In(i) {
+				t.Fatalf("argument %d is a %T, not %s", i, arg, fn.Type().In(i))
+			}
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package dbus
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ImplementsError reports why an object does not implement an
+// interface: the methods it lacks and those whose signature differs.
+type ImplementsError struct {
+	Interface  string
+	Missing    []string
+	Mismatched []MethodMismatch
+}
+
+// MethodMismatch is a method of an object whose type is not the one
+// the interface asks for.
+type MethodMismatch struct {
+	Name string
+	Want reflect.Type
+	Have reflect.Type
+}
+
+func (e *ImplementsError) Error() string {
+	var b strings.Builder
+	b.WriteString("Object does not implement ")
+	b.WriteString(e.Interface)
+	for _, name := range e.Missing {
+		b.WriteString("\n\tmissing method ")
+		b.WriteString(name)
+	}
+	for _, m := range e.Mismatched {
+		b.WriteString("\n\tmethod ")
+		b.WriteString(m.Name)
+		b.WriteString(" is ")
+		b.WriteString(m.Have.String())
+		b.WriteString(", want ")
+		b.WriteString(m.Want.String())
+	}
+	return b.String()
+}
+
+// implements returns an ImplementsError naming iface if the methods of
+// o do not match the types in methods, nil otherwise.
+func (o *Object) implements(
+	iface string,
+	methods map[string]reflect.Type,
+) error {
+	report := &ImplementsError{Interface: iface}
+	for name, want := range methods {
+		method, ok := o.methodTable[name]
+		if !ok {
+			report.Missing = append(report.Missing, name)
+			continue
+		}
+		if have := reflect.TypeOf(method); !sameSignature(want, have) {
+			report.Mismatched = append(report.Mismatched,
+				MethodMismatch{Name: name, Want: want, Have: have})
+		}
+	}
+	if len(report.Missing) == 0 && len(report.Mismatched) == 0 {
+		return nil
+	}
+	sort.Strings(report.Missing)
+	sort.Slice(report.Mismatched, func(i, j int) bool {
+		return report.Mismatched[i].Name < report.Mismatched[j].Name
+	})
+	return report
+}
+
+func sameSignature(want, have reflect.Type) bool {
+	if want.NumIn() != have.NumIn() || want.NumOut() != have.NumOut() {
+		return false
+	}
+	for j := 0; j < want.NumIn(); j++ {
+		if want.In(j) != have.In(j) {
+			return false
+		}
+	}
+	for j := 0; j < want.NumOut(); j++ {
+		if want.Out(j) != have.Out(j) {
+			return false
+		}
+	}
+	return true
+}
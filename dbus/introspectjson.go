@@ -0,0 +1,158 @@
+package dbus
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/godbus/dbus/introspect"
+)
+
+// DebugInterface is exported on every object of a BusManager with
+// ExportDebug set. Its IntrospectJSON method returns what
+// Object.IntrospectJSON does.
+const DebugInterface = "com.github.jsouthworth.Seriatim.Debug"
+
+type jsonArg struct {
+	Name      string `json:"name,omitempty"`
+	Type      string `json:"type"`
+	Direction string `json:"direction,omitempty"`
+}
+
+type jsonMember struct {
+	Name        string            `json:"name"`
+	Args        []jsonArg         `json:"args"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type jsonProperty struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Access string `json:"access"`
+}
+
+type jsonInterface struct {
+	Name       string         `json:"name"`
+	Methods    []jsonMember   `json:"methods"`
+	Signals    []jsonMember   `json:"signals"`
+	Properties []jsonProperty `json:"properties"`
+}
+
+type jsonNode struct {
+	Name       string          `json:"name"`
+	Interfaces []jsonInterface `json:"interfaces"`
+	Children   []jsonNode      `json:"children"`
+}
+
+// IntrospectJSON returns the introspection data of o and its children
+// as JSON, for tooling that would rather not parse the XML. The root
+// node is named with the path of o and its children with their last
+// path element:
+//
+//	{"name": "/foo", "interfaces": [{"name": "com.example.Foo",
+//	  "methods": [{"name": "Bar", "args": [{"type": "s",
+//	  "direction": "in"}]}], "signals": [], "properties": []}],
+//	  "children": [{"name": "bar", ...}]}
+func (o *Object) IntrospectJSON() ([]byte, error) {
+	node := newJSONNode(o.Introspect())
+	node.Name = string(o.Path())
+	return json.Marshal(node)
+}
+
+func newJSONNode(n *introspect.Node) jsonNode {
+	out := jsonNode{
+		Name:       n.Name,
+		Interfaces: make([]jsonInterface, 0, len(n.Interfaces)),
+		Children:   make([]jsonNode, 0, len(n.Children)),
+	}
+	for _, iface := range n.Interfaces {
+		desc := jsonInterface{
+			Name:       iface.Name,
+			Methods:    make([]jsonMember, 0, len(iface.Methods)),
+			Signals:    make([]jsonMember, 0, len(iface.Signals)),
+			Properties: make([]jsonProperty, 0, len(iface.Properties)),
+		}
+		for _, method := range iface.Methods {
+			desc.Methods = append(desc.Methods,
+				newJSONMember(method.Name, method.Args, method.Annotations))
+		}
+		for _, signal := range iface.Signals {
+			desc.Signals = append(desc.Signals,
+				newJSONMember(signal.Name, signal.Args, signal.Annotations))
+		}
+		for _, prop := range iface.Properties {
+			desc.Properties = append(desc.Properties, jsonProperty{
+				Name:   prop.Name,
+				Type:   prop.Type,
+				Access: prop.Access,
+			})
+		}
+		out.Interfaces = append(out.Interfaces, desc)
+	}
+	for i := range n.Children {
+		out.Children = append(out.Children, newJSONNode(&n.Children[i]))
+	}
+	return out
+}
+
+func newJSONMember(
+	name string,
+	args []introspect.Arg,
+	annotations []introspect.Annotation,
+) jsonMember {
+	out := jsonMember{
+		Name: name,
+		Args: make([]jsonArg, 0, len(args)),
+	}
+	for _, arg := range args {
+		out.Args = append(out.Args, jsonArg{
+			Name:      arg.Name,
+			Type:      arg.Type,
+			Direction: arg.Direction,
+		})
+	}
+	if len(annotations) != 0 {
+		out.Annotations = make(map[string]string, len(annotations))
+		for _, annotation := range annotations {
+			out.Annotations[annotation.Name] = annotation.Value
+		}
+	}
+	return out
+}
+
+// debugExported reports whether o is part of a BusManager exporting
+// DebugInterface.
+func (o *Object) debugExported() bool {
+	return o.bus != nil && o.bus.ExportDebug
+}
+
+var debugIntrospection = introspect.Interface{
+	Name: DebugInterface,
+	Methods: []introspect.Method{{
+		Name: "IntrospectJSON",
+		Args: []introspect.Arg{
+			{"out", "s", "out"},
+		},
+	}},
+}
+
+// newDebugInterface returns DebugInterface of o. It is made when
+// looked up rather than added to o so that ExportDebug can be set at
+// any time.
+func newDebugInterface(o *Object) *Interface {
+	intro := func() string {
+		out, _ := o.IntrospectJSON()
+		return string(out)
+	}
+	return &Interface{
+		object: o,
+		methods: map[string]*Method{
+			"IntrospectJSON": &Method{
+				name:          "IntrospectJSON",
+				sequent:       intro_fn(intro),
+				value:         reflect.ValueOf(intro),
+				plan:          newDecodePlan(reflect.TypeOf(intro)),
+				introspection: debugIntrospection.Methods[0],
+			},
+		},
+	}
+}
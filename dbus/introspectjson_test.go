@@ -0,0 +1,91 @@
+package dbus
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestIntrospectJSON(t *testing.T) {
+	mgr := newPipeBusManager(t)
+	defer mgr.conn.Close()
+	obj := mgr.NewObject("/foo", &job{})
+	if err := obj.Implements("com.example.Job", (*interface{ Progress() int })(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := obj.Emits("com.example.Job", (*testSignals)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	mgr.NewObject("/foo/bar", &job{})
+
+	out, err := obj.IntrospectJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var node jsonNode
+	if err := json.Unmarshal(out, &node); err != nil {
+		t.Fatal(err)
+	}
+	if node.Name != "/foo" {
+		t.Fatalf("expected the root named /foo, got %q", node.Name)
+	}
+	if len(node.Children) != 1 || node.Children[0].Name != "bar" {
+		t.Fatalf("expected the child bar, got %+v", node.Children)
+	}
+	var found *jsonInterface
+	for i := range node.Interfaces {
+		if node.Interfaces[i].Name == "com.example.Job" {
+			found = &node.Interfaces[i]
+		}
+	}
+	if found == nil {
+		t.Fatalf("com.example.Job missing from %+v", node.Interfaces)
+	}
+	expected := jsonInterface{
+		Name: "com.example.Job",
+		Methods: []jsonMember{{
+			Name: "Progress",
+			Args: []jsonArg{{Type: "i", Direction: "out"}},
+		}},
+		Signals: []jsonMember{{
+			Name: "Changed",
+			Args: []jsonArg{
+				{Type: "s"},
+				{Type: "i"},
+			},
+		}},
+		Properties: []jsonProperty{},
+	}
+	if !reflect.DeepEqual(*found, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, *found)
+	}
+}
+
+func TestDebugInterface(t *testing.T) {
+	mgr := newPipeBusManager(t)
+	defer mgr.conn.Close()
+	obj := mgr.NewObject("/foo", &job{})
+	if _, err := deliverCall(obj, DebugInterface, "IntrospectJSON"); err == nil {
+		t.Fatal("debug interface exported without ExportDebug")
+	}
+
+	mgr.ExportDebug = true
+	rets, err := deliverCall(obj, DebugInterface, "IntrospectJSON")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, err := obj.IntrospectJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rets[0] != string(expected) {
+		t.Fatalf("expected %s, got %v", expected, rets[0])
+	}
+	found := false
+	for _, iface := range obj.Introspect().Interfaces {
+		found = found || iface.Name == DebugInterface
+	}
+	if !found {
+		t.Fatal("debug interface missing from the introspection data")
+	}
+}
@@ -0,0 +1,137 @@
+package dbus
+
+import (
+	"context"
+	"path"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/godbus/dbus"
+)
+
+// JobInterface is the D-Bus interface of the job objects started by
+// Jobs. Its Progress method returns the progress of the job, from 0
+// to 1, and its status; the Changed signal carries them whenever they
+// change and the Completed signal whether the job succeeded and the
+// error message if it did not, before the job object is removed. Its
+// Cancel method cancels the context of the job.
+const JobInterface = "com.github.jsouthworth.Seriatim.Job"
+
+type jobMethods interface {
+	Progress() (float64, string)
+	Cancel()
+}
+
+type jobSignals interface {
+	Changed(progress float64, status string)
+	Completed(ok bool, message string)
+}
+
+// jobState is the value of a job object. Report is called by the job
+// and not exported on the bus.
+type jobState struct {
+	progress float64
+	status   string
+	cancel   context.CancelFunc
+}
+
+func (s *jobState) Progress() (float64, string) {
+	return s.progress, s.status
+}
+
+func (s *jobState) Cancel() {
+	s.cancel()
+}
+
+func (s *jobState) Report(progress float64, status string) {
+	s.progress = progress
+	s.status = status
+}
+
+// Jobs exports long-running operations as job objects: a method
+// starting one returns right away with the path of a fresh object
+// under the path given to NewJobs, which reports the progress of the
+// operation and is removed once it completes.
+//
+//	func (s *service) Backup(dest string) (dbus.ObjectPath, error) {
+//		return s.jobs.Start(func(job *Job) error {
+//			return s.backup(job.Context(), dest, job.Report)
+//		})
+//	}
+type Jobs struct {
+	parent *Object
+	path   dbus.ObjectPath
+	next   uint64
+}
+
+// NewJobs returns Jobs exporting its job objects under path.
+func (o *Object) NewJobs(path dbus.ObjectPath) *Jobs {
+	return &Jobs{parent: o, path: path}
+}
+
+// Start exports a job object, runs run on a goroutine of its own and
+// returns the path of the object.
+func (jobs *Jobs) Start(run func(job *Job) error) (dbus.ObjectPath, error) {
+	n := atomic.AddUint64(&jobs.next, 1)
+	jobPath := dbus.ObjectPath(
+		path.Join(string(jobs.path), strconv.FormatUint(n, 10)))
+	ctx, cancel := context.WithCancel(context.Background())
+	obj := jobs.parent.NewObject(jobPath, &jobState{cancel: cancel})
+	if err := obj.Implements(JobInterface, (*jobMethods)(nil)); err != nil {
+		cancel()
+		jobs.parent.DeleteObject(jobPath)
+		return "", err
+	}
+	if err := obj.Emits(JobInterface, (*jobSignals)(nil)); err != nil {
+		cancel()
+		jobs.parent.DeleteObject(jobPath)
+		return "", err
+	}
+	job := &Job{obj: obj, ctx: ctx}
+	go func() {
+		err := run(job)
+		cancel()
+		message := ""
+		if err != nil {
+			message = err.Error()
+		}
+		job.emit("Completed", err == nil, message)
+		obj.removeFrom(jobs.parent, jobPath)
+	}()
+	return obj.Path(), nil
+}
+
+// Job is a running job, handed to the function started by Jobs.
+type Job struct {
+	obj *Object
+	ctx context.Context
+}
+
+// Context returns the context of the job, canceled when a client calls
+// the Cancel method of the job object. Jobs should give up once it is
+// done, returning an error such as its Err.
+func (job *Job) Context() context.Context {
+	return job.ctx
+}
+
+// Path returns the path of the job object.
+func (job *Job) Path() dbus.ObjectPath {
+	return job.obj.Path()
+}
+
+// Report sets the progress of the job, from 0 to 1, and its status,
+// announcing them with the Changed signal.
+func (job *Job) Report(progress float64, status string) error {
+	if _, err := job.obj.sequent.Call("Report", progress, status); err != nil {
+		return err
+	}
+	return job.emit("Changed", progress, status)
+}
+
+// emit sends a signal of the job object if it is attached to a bus.
+func (job *Job) emit(member string, args ...interface{}) error {
+	if job.obj.bus == nil || job.obj.bus.conn == nil {
+		return nil
+	}
+	return job.obj.Emit(JobInterface, member, args...)
+}
@@ -0,0 +1,112 @@
+package dbus
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus"
+)
+
+func TestJobs(t *testing.T) {
+	mgr := newPipeBusManager(t)
+	defer mgr.conn.Close()
+	signals := make(chan *EmittedSignal, 5)
+	defer mgr.Observe(func(signal *EmittedSignal) {
+		signals <- signal
+	})()
+
+	jobs := mgr.NewJobs("/jobs")
+	reported := make(chan struct{})
+	finish := make(chan struct{})
+	path, err := jobs.Start(func(job *Job) error {
+		if err := job.Report(0.5, "copying"); err != nil {
+			return err
+		}
+		close(reported)
+		<-finish
+		return errors.New("disk full")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "/jobs/1" {
+		t.Fatalf("unexpected job path %q", path)
+	}
+
+	<-reported
+	obj, ok := mgr.lookupObjectPath([]string{"jobs", "1"})
+	if !ok {
+		t.Fatal("job object not exported")
+	}
+	outs, err := deliverCall(obj, JobInterface, "Progress")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(outs, []interface{}{0.5, "copying"}) {
+		t.Fatalf("unexpected progress %v", outs)
+	}
+
+	close(finish)
+	waitRemoved(t, mgr.Object, "jobs", "1")
+	want := []*EmittedSignal{
+		{
+			Path:      path,
+			Interface: JobInterface,
+			Member:    "Changed",
+			Body:      []interface{}{0.5, "copying"},
+		},
+		{
+			Path:      path,
+			Interface: JobInterface,
+			Member:    "Completed",
+			Body:      []interface{}{false, "disk full"},
+		},
+		{
+			Path:      "/",
+			Interface: fdtObjectManager,
+			Member:    interfacesRemoved,
+			Body: []interface{}{
+				dbus.ObjectPath("/jobs/1"),
+				[]string{JobInterface, fdtIntrospectable},
+			},
+		},
+	}
+	for _, w := range want {
+		if got := <-signals; !reflect.DeepEqual(got, w) {
+			t.Fatalf("expected %+v, got %+v", w, got)
+		}
+	}
+
+	if path, err = jobs.Start(func(*Job) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if path != "/jobs/2" {
+		t.Fatalf("unexpected job path %q", path)
+	}
+}
+
+func TestJobCancel(t *testing.T) {
+	root := NewObject("", nil, nil, nil)
+	jobs := root.NewJobs("/jobs")
+	done := make(chan error, 1)
+	if _, err := jobs.Start(func(job *Job) error {
+		<-job.Context().Done()
+		done <- job.Context().Err()
+		return job.Context().Err()
+	}); err != nil {
+		t.Fatal(err)
+	}
+	obj, ok := root.lookupObjectPath([]string{"jobs", "1"})
+	if !ok {
+		t.Fatal("job object not exported")
+	}
+	if _, err := deliverCall(obj, JobInterface, "Cancel"); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected the job to be canceled, got %v", err)
+	}
+	waitRemoved(t, root, "jobs", "1")
+}
@@ -0,0 +1,52 @@
+package dbus
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/jsouthworth/seriatim"
+)
+
+// Attribute keys of the events logged to seriatim.Logger.
+const (
+	ObjectPathKey = "object_path"
+	SenderKey     = "dbus_sender"
+)
+
+// logCall records a method call received from the bus at debug level
+// so that who called what can be audited.
+func (method *Method) logCall(err error) {
+	l := seriatim.Logger()
+	if l == nil || method.message == nil {
+		return
+	}
+	path, iface := method.target()
+	args := []interface{}{
+		seriatim.SequentIdKey, method.sequent.Id(),
+		ObjectPathKey, string(path),
+		SenderKey, method.sender,
+		"interface", iface,
+		"member", method.introspection.Name,
+	}
+	if err != nil {
+		args = append(args, "error", err)
+	}
+	l.Debug("dbus method call", args...)
+}
+
+func logObjectTerminated(obj *Object, reason error) {
+	l := seriatim.Logger()
+	if l == nil || reason == nil {
+		return
+	}
+	l.Warn("dbus object terminated",
+		seriatim.SequentIdKey, obj.sequent.Id(),
+		ObjectPathKey, string(obj.Path()),
+		"reason", reason)
+}
+
+// target returns the path and interface the call being processed by
+// method was made to.
+func (method *Method) target() (dbus.ObjectPath, string) {
+	path, _ := method.message.Headers[dbus.FieldPath].Value().(dbus.ObjectPath)
+	iface, _ := method.message.Headers[dbus.FieldInterface].Value().(string)
+	return path, iface
+}
@@ -0,0 +1,92 @@
+package dbus
+
+import (
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/jsouthworth/seriatim"
+)
+
+const fdtFailed = fdtDBusName + ".Error.Failed"
+
+// CallMetrics is told about every call from the bus to the objects of
+// a BusManager with Metrics set, like seriatim.Metrics is about the
+// requests processed by a sequent. took includes the time the call
+// waited in the mailbox of the object and err is the error it was
+// answered with, if any. Called is invoked on the goroutine handling
+// the call and must not block.
+//
+// A CallMetrics that is also a seriatim.Metrics is set as the Metrics
+// of the sequents of the objects, so it is told too how long each
+// request waited in their mailboxes and how long its method ran.
+// Package dbusprom has one exported to Prometheus.
+type CallMetrics interface {
+	Called(
+		path dbus.ObjectPath,
+		iface, member string,
+		took time.Duration,
+		err error,
+	)
+}
+
+// CallMetricsFunc is a CallMetrics calling itself.
+type CallMetricsFunc func(
+	path dbus.ObjectPath,
+	iface, member string,
+	took time.Duration,
+	err error,
+)
+
+func (fn CallMetricsFunc) Called(
+	path dbus.ObjectPath,
+	iface, member string,
+	took time.Duration,
+	err error,
+) {
+	fn(path, iface, member, took, err)
+}
+
+// ErrorName returns the name of the D-Bus error err is sent as.
+func ErrorName(err error) string {
+	switch err := err.(type) {
+	case dbus.Error:
+		return err.Name
+	case *dbus.Error:
+		return err.Name
+	case interface {
+		DBusError() (string, []interface{})
+	}:
+		name, _ := err.DBusError()
+		return name
+	}
+	return fdtFailed
+}
+
+// observe reports a call from the bus that took took to the Metrics of
+// its bus manager.
+func (method *Method) observe(took time.Duration, err error) {
+	if method.bus == nil || method.bus.Metrics == nil ||
+		method.message == nil {
+		return
+	}
+	path, iface := method.target()
+	method.bus.Metrics.Called(path, iface, method.introspection.Name,
+		took, err)
+}
+
+// sequentMetrics is the seriatim.Metrics of the sequents of the
+// objects of mgr, passing the requests they process on to its Metrics
+// if it takes them.
+type sequentMetrics struct {
+	mgr *BusManager
+}
+
+func (m sequentMetrics) Processed(
+	id uintptr,
+	method string,
+	waited, took time.Duration,
+) {
+	if metrics, ok := m.mgr.Metrics.(seriatim.Metrics); ok {
+		metrics.Processed(id, method, waited, took)
+	}
+}
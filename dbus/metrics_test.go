@@ -0,0 +1,97 @@
+package dbus
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+)
+
+var errNope = dbus.NewError("com.example.Error.Nope", nil)
+
+type flaky struct {
+	n int
+}
+
+func (f *flaky) Try() error {
+	f.n++
+	if f.n%2 == 0 {
+		return errNope
+	}
+	return nil
+}
+
+type recordedMetrics struct {
+	mu        sync.Mutex
+	called    []string
+	processed []string
+}
+
+func (m *recordedMetrics) Called(
+	path dbus.ObjectPath,
+	iface, member string,
+	took time.Duration,
+	err error,
+) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name := ""
+	if err != nil {
+		name = ErrorName(err)
+	}
+	m.called = append(m.called, member+" "+name)
+}
+
+func (m *recordedMetrics) Processed(
+	id uintptr,
+	method string,
+	waited, took time.Duration,
+) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.processed = append(m.processed, method)
+}
+
+func TestCallMetrics(t *testing.T) {
+	mgr := newPipeBusManager(t)
+	defer mgr.conn.Close()
+	obj := mgr.NewObject("/foo", &flaky{})
+	if err := obj.Implements("com.example.Flaky", (*interface{ Try() error })(nil)); err != nil {
+		t.Fatal(err)
+	}
+	metrics := &recordedMetrics{}
+	mgr.Metrics = metrics
+	for i := 0; i < 2; i++ {
+		deliverCall(obj, "com.example.Flaky", "Try")
+	}
+
+	metrics.mu.Lock()
+	defer metrics.mu.Unlock()
+	if len(metrics.called) != 2 ||
+		metrics.called[0] != "Try " ||
+		metrics.called[1] != "Try com.example.Error.Nope" {
+		t.Fatalf("got calls %q", metrics.called)
+	}
+	if len(metrics.processed) != 2 || metrics.processed[0] != "Try" {
+		t.Fatalf("got processed requests %q", metrics.processed)
+	}
+}
+
+func TestErrorName(t *testing.T) {
+	tests := []struct {
+		err  error
+		name string
+	}{
+		{errNope, "com.example.Error.Nope"},
+		{*errNope, "com.example.Error.Nope"},
+		{ErrAccessDenied, fdtAccessDenied},
+		{errors.New("plain"), fdtFailed},
+	}
+	for _, test := range tests {
+		if name := ErrorName(test.err); name != test.name {
+			t.Fatalf("expected %s for %v, got %s", test.name, test.err, name)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package dbus
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/jsouthworth/seriatim"
+	"github.com/jsouthworth/seriatim/events"
+)
+
+// anyInterface is the interface name the listener registered with
+// ReceivesAll is kept under, as is its signal.
+const anyInterface = ""
+
+var monitorType = reflect.TypeOf(func(string, string, []interface{}) {})
+
+// ReceivesAll has method called with the interface, member and body of
+// every signal received by the bus, whatever its interface, such as
+// for objects logging or recording the traffic:
+//
+//	func (r *recorder) Signal(iface, member string, body []interface{})
+//
+// The signals are delivered with the SignalPolicy of the bus like
+// those of Receives.
+func (o *Object) ReceivesAll(method string) error {
+	err := o.implements("signal monitor",
+		map[string]reflect.Type{method: monitorType})
+	if err != nil {
+		return err
+	}
+	signal := &Signal{
+		name:    method,
+		sequent: o.sequent,
+	}
+	signal.subscription = o.subscribeSignal(anyInterface, anyInterface, method)
+	o.bus.state.Call("AddMatchSignal", o.bus.conn, anyInterface, anyInterface)
+	o.addListener(anyInterface, &Interface{
+		signals: map[string]*Signal{anyInterface: signal},
+		object:  o,
+	})
+	return nil
+}
+
+// subscribeSignal subscribes method of o to the signals received for
+// member of dbusIfaceName, or to every signal for anyInterface.
+func (o *Object) subscribeSignal(
+	dbusIfaceName, member, method string,
+) *events.Subscription {
+	if dbusIfaceName == anyInterface {
+		sub, _ := o.bus.events.SubscribeTopic("*",
+			topicSplitter{o.sequent}, method, o.bus.SignalPolicy)
+		return sub
+	}
+	// D-Bus names have no characters special in patterns
+	sub, _ := o.bus.events.Subscribe(mkSignalKey(dbusIfaceName, member),
+		o.sequent, method, o.bus.SignalPolicy)
+	return sub
+}
+
+// topicSplitter casts the events of a topic subscription, given as
+// the topic and the slice of its arguments, to a method taking the
+// interface, member and body of the signal.
+type topicSplitter struct {
+	seriatim.Sequent
+}
+
+func (t topicSplitter) Cast(name string, args ...interface{}) error {
+	topic, _ := args[0].(string)
+	body, _ := args[1].([]interface{})
+	iface, member := "", topic
+	if dot := strings.LastIndex(topic, "."); dot >= 0 {
+		iface, member = topic[:dot], topic[dot+1:]
+	}
+	return t.Sequent.Cast(name, iface, member, body)
+}
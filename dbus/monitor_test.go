@@ -0,0 +1,45 @@
+package dbus
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/jsouthworth/seriatim"
+	"github.com/jsouthworth/seriatim/events"
+)
+
+type recordedSignal struct {
+	iface, member string
+	body          []interface{}
+}
+
+type recorder struct {
+	signals chan recordedSignal
+}
+
+func (r *recorder) Signal(iface, member string, body []interface{}) {
+	r.signals <- recordedSignal{iface, member, body}
+}
+
+func TestTopicSplitter(t *testing.T) {
+	r := &recorder{signals: make(chan recordedSignal, 2)}
+	s := seriatim.NewSequent(r)
+	defer s.Terminate(nil)
+	bus := events.NewBus()
+	_, err := bus.SubscribeTopic("*", topicSplitter{s}, "Signal", events.Block)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bus.Publish("com.example.Foo.Changed", "x", int32(1))
+	bus.Publish("org.example.Bar.Gone")
+	expected := []recordedSignal{
+		{"com.example.Foo", "Changed", []interface{}{"x", int32(1)}},
+		{"org.example.Bar", "Gone", nil},
+	}
+	for _, want := range expected {
+		if got := <-r.signals; !reflect.DeepEqual(got, want) {
+			t.Fatalf("expected %+v, got %+v", want, got)
+		}
+	}
+}
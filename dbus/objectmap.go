@@ -0,0 +1,281 @@
+package dbus
+
+import (
+	"math/bits"
+)
+
+// objectMap is an immutable map from names to objects, a hash array
+// mapped trie. Set and Delete return a new map sharing all but the
+// path to the changed entry with the old one, so the children of an
+// object can be replaced under a lock in time logarithmic in their
+// number while readers keep using the map they loaded.
+type objectMap struct {
+	root *hamtNode
+	size int
+}
+
+const (
+	hamtBits = 5
+	hamtMask = 1<<hamtBits - 1
+)
+
+type hamtNode struct {
+	bitmap uint32
+	// children holds a *hamtNode or *hamtLeaf for every bit set in
+	// bitmap, in order.
+	children []interface{}
+}
+
+// hamtLeaf holds the entries whose names hash to the same value.
+type hamtLeaf struct {
+	hash    uint32
+	entries []objectEntry
+}
+
+type objectEntry struct {
+	name string
+	obj  *Object
+}
+
+var emptyObjectMap = &objectMap{root: &hamtNode{}}
+
+func hashName(name string) uint32 {
+	// FNV-1a
+	h := uint32(2166136261)
+	for i := 0; i < len(name); i++ {
+		h ^= uint32(name[i])
+		h *= 16777619
+	}
+	return h
+}
+
+func (n *hamtNode) index(hash uint32, shift uint) (uint32, int) {
+	bit := uint32(1) << ((hash >> shift) & hamtMask)
+	return bit, bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+// Len returns the number of objects in m.
+func (m *objectMap) Len() int {
+	return m.size
+}
+
+func (m *objectMap) Get(name string) (*Object, bool) {
+	hash := hashName(name)
+	node := m.root
+	for shift := uint(0); ; shift += hamtBits {
+		bit, i := node.index(hash, shift)
+		if node.bitmap&bit == 0 {
+			return nil, false
+		}
+		switch child := node.children[i].(type) {
+		case *hamtNode:
+			node = child
+		case *hamtLeaf:
+			if child.hash != hash {
+				return nil, false
+			}
+			for _, entry := range child.entries {
+				if entry.name == name {
+					return entry.obj, true
+				}
+			}
+			return nil, false
+		}
+	}
+}
+
+// Set returns a map with name bound to obj.
+func (m *objectMap) Set(name string, obj *Object) *objectMap {
+	root, added := m.root.set(hashName(name), 0, name, obj)
+	out := &objectMap{root: root, size: m.size}
+	if added {
+		out.size++
+	}
+	return out
+}
+
+// Delete returns a map without name.
+func (m *objectMap) Delete(name string) *objectMap {
+	root, removed := m.root.delete(hashName(name), 0, name)
+	if !removed {
+		return m
+	}
+	return &objectMap{root: root, size: m.size - 1}
+}
+
+// Range calls fn for every object in m until it returns false.
+func (m *objectMap) Range(fn func(name string, obj *Object) bool) {
+	m.root.rangeEntries(fn)
+}
+
+func (n *hamtNode) rangeEntries(fn func(string, *Object) bool) bool {
+	for _, child := range n.children {
+		switch child := child.(type) {
+		case *hamtNode:
+			if !child.rangeEntries(fn) {
+				return false
+			}
+		case *hamtLeaf:
+			for _, entry := range child.entries {
+				if !fn(entry.name, entry.obj) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func (n *hamtNode) with(i int, child interface{}) *hamtNode {
+	out := &hamtNode{
+		bitmap:   n.bitmap,
+		children: make([]interface{}, len(n.children)),
+	}
+	copy(out.children, n.children)
+	out.children[i] = child
+	return out
+}
+
+func (n *hamtNode) set(
+	hash uint32,
+	shift uint,
+	name string,
+	obj *Object,
+) (*hamtNode, bool) {
+	bit, i := n.index(hash, shift)
+	if n.bitmap&bit == 0 {
+		out := &hamtNode{
+			bitmap:   n.bitmap | bit,
+			children: make([]interface{}, len(n.children)+1),
+		}
+		copy(out.children, n.children[:i])
+		out.children[i] = &hamtLeaf{
+			hash:    hash,
+			entries: []objectEntry{{name: name, obj: obj}},
+		}
+		copy(out.children[i+1:], n.children[i:])
+		return out, true
+	}
+	switch child := n.children[i].(type) {
+	case *hamtNode:
+		sub, added := child.set(hash, shift+hamtBits, name, obj)
+		return n.with(i, sub), added
+	case *hamtLeaf:
+		if child.hash == hash {
+			leaf, added := child.set(name, obj)
+			return n.with(i, leaf), added
+		}
+		leaf := &hamtLeaf{
+			hash:    hash,
+			entries: []objectEntry{{name: name, obj: obj}},
+		}
+		return n.with(i, mergeLeaves(child, leaf, shift+hamtBits)), true
+	}
+	panic("unreachable")
+}
+
+func (n *hamtNode) delete(
+	hash uint32,
+	shift uint,
+	name string,
+) (*hamtNode, bool) {
+	bit, i := n.index(hash, shift)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+	var replacement interface{}
+	switch child := n.children[i].(type) {
+	case *hamtNode:
+		sub, removed := child.delete(hash, shift+hamtBits, name)
+		if !removed {
+			return n, false
+		}
+		replacement = sub
+		switch len(sub.children) {
+		case 0:
+			replacement = nil
+		case 1:
+			// Pull a lone leaf up so that lookups stay short.
+			if leaf, ok := sub.children[0].(*hamtLeaf); ok {
+				replacement = leaf
+			}
+		}
+	case *hamtLeaf:
+		if child.hash != hash {
+			return n, false
+		}
+		leaf, removed := child.delete(name)
+		if !removed {
+			return n, false
+		}
+		if leaf != nil {
+			replacement = leaf
+		}
+	}
+	if replacement != nil {
+		return n.with(i, replacement), true
+	}
+	out := &hamtNode{
+		bitmap:   n.bitmap &^ bit,
+		children: make([]interface{}, 0, len(n.children)-1),
+	}
+	out.children = append(out.children, n.children[:i]...)
+	out.children = append(out.children, n.children[i+1:]...)
+	return out, true
+}
+
+// mergeLeaves returns a node holding two leaves with different hashes,
+// which differ in some chunk at shift or below.
+func mergeLeaves(a, b *hamtLeaf, shift uint) *hamtNode {
+	ia := (a.hash >> shift) & hamtMask
+	ib := (b.hash >> shift) & hamtMask
+	if ia == ib {
+		return &hamtNode{
+			bitmap:   1 << ia,
+			children: []interface{}{mergeLeaves(a, b, shift+hamtBits)},
+		}
+	}
+	n := &hamtNode{bitmap: 1<<ia | 1<<ib}
+	if ia < ib {
+		n.children = []interface{}{a, b}
+	} else {
+		n.children = []interface{}{b, a}
+	}
+	return n
+}
+
+func (l *hamtLeaf) set(name string, obj *Object) (*hamtLeaf, bool) {
+	out := &hamtLeaf{
+		hash:    l.hash,
+		entries: make([]objectEntry, len(l.entries), len(l.entries)+1),
+	}
+	copy(out.entries, l.entries)
+	for i := range out.entries {
+		if out.entries[i].name == name {
+			out.entries[i].obj = obj
+			return out, false
+		}
+	}
+	out.entries = append(out.entries, objectEntry{name: name, obj: obj})
+	return out, true
+}
+
+// delete returns the leaf without name, nil if it is left empty.
+func (l *hamtLeaf) delete(name string) (*hamtLeaf, bool) {
+	for i, entry := range l.entries {
+		if entry.name != name {
+			continue
+		}
+		if len(l.entries) == 1 {
+			return nil, true
+		}
+		out := &hamtLeaf{
+			hash:    l.hash,
+			entries: make([]objectEntry, 0, len(l.entries)-1),
+		}
+		out.entries = append(out.entries, l.entries[:i]...)
+		out.entries = append(out.entries, l.entries[i+1:]...)
+		return out, true
+	}
+	return l, false
+}
@@ -0,0 +1,75 @@
+package dbus
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestObjectMap(t *testing.T) {
+	const n = 2000
+	objs := make([]*Object, n)
+	m := emptyObjectMap
+	for i := range objs {
+		objs[i] = &Object{name: strconv.Itoa(i)}
+		m = m.Set(objs[i].name, objs[i])
+	}
+	if m.Len() != n {
+		t.Fatalf("expected %d objects, got %d", n, m.Len())
+	}
+	for _, obj := range objs {
+		if got, ok := m.Get(obj.name); !ok || got != obj {
+			t.Fatalf("lookup of %q failed", obj.name)
+		}
+	}
+
+	before := m
+	for i := 0; i < n; i += 2 {
+		m = m.Delete(objs[i].name)
+	}
+	m = m.Delete("missing")
+	if m.Len() != n/2 || before.Len() != n {
+		t.Fatalf("unexpected lengths %d and %d", m.Len(), before.Len())
+	}
+	for i, obj := range objs {
+		if _, ok := m.Get(obj.name); ok != (i%2 == 1) {
+			t.Fatalf("unexpected presence of %q", obj.name)
+		}
+		if _, ok := before.Get(obj.name); !ok {
+			t.Fatalf("deleting %q changed the old map", obj.name)
+		}
+	}
+
+	seen := make(map[string]bool)
+	m.Range(func(name string, obj *Object) bool {
+		if obj.name != name || seen[name] {
+			t.Fatalf("unexpected entry %q", name)
+		}
+		seen[name] = true
+		return true
+	})
+	if len(seen) != n/2 {
+		t.Fatalf("expected %d entries, ranged over %d", n/2, len(seen))
+	}
+
+	replaced := &Object{name: objs[1].name}
+	if m.Set(replaced.name, replaced).Len() != n/2 {
+		t.Fatal("replacing an object changed the length")
+	}
+}
+
+func TestObjectMapCollisions(t *testing.T) {
+	a := &Object{name: "a"}
+	b := &Object{name: "b"}
+	leaf := &hamtLeaf{hash: 1, entries: []objectEntry{{name: "a", obj: a}}}
+	leaf, added := leaf.set("b", b)
+	if !added || len(leaf.entries) != 2 {
+		t.Fatalf("unexpected leaf %+v", leaf)
+	}
+	leaf, removed := leaf.delete("a")
+	if !removed || len(leaf.entries) != 1 || leaf.entries[0].obj != b {
+		t.Fatalf("unexpected leaf %+v", leaf)
+	}
+	if leaf, _ = leaf.delete("b"); leaf != nil {
+		t.Fatalf("expected an empty leaf, got %+v", leaf)
+	}
+}
@@ -0,0 +1,352 @@
+package dbus
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/godbus/dbus"
+	"github.com/jsouthworth/seriatim"
+)
+
+const (
+	interfacesAdded   = "InterfacesAdded"
+	getManagedObjects = "GetManagedObjects"
+	propertiesChanged = "PropertiesChanged"
+)
+
+// ManagedObjects holds the properties of the interfaces of objects, by
+// path and interface, as returned by GetManagedObjects.
+type ManagedObjects map[dbus.ObjectPath]map[string]map[string]dbus.Variant
+
+// ObjectEventKind says what changed in an ObjectEvent.
+type ObjectEventKind int
+
+const (
+	// InterfacesAdded events carry the properties of the interfaces
+	// added to an object in Interfaces.
+	InterfacesAdded ObjectEventKind = iota
+	// InterfacesRemoved events name the interfaces removed from an
+	// object in Names; an object without interfaces is gone.
+	InterfacesRemoved
+	// PropertiesChanged events carry the new values of the properties
+	// of one interface in Interfaces and name the properties that
+	// changed without their value being sent in Names.
+	PropertiesChanged
+)
+
+// ObjectEvent describes a change to the objects mirrored by an
+// ObjectMirror.
+type ObjectEvent struct {
+	Kind       ObjectEventKind
+	Path       dbus.ObjectPath
+	Interfaces map[string]map[string]dbus.Variant
+	Names      []string
+}
+
+// ObjectMirror keeps a copy of the objects managed by a remote
+// org.freedesktop.DBus.ObjectManager, such as the devices of BlueZ, in
+// sync with its InterfacesAdded, InterfacesRemoved and
+// PropertiesChanged signals.
+type ObjectMirror struct {
+	mgr     *BusManager
+	tree    seriatim.Sequent
+	cancel  func()
+	matches [][2]string
+}
+
+// MirrorObjects mirrors the objects managed by the object at path of
+// dest. Every change is cast to method of consumer, if it is not nil,
+// as an *ObjectEvent, starting with an InterfacesAdded event for each
+// object already there. When dest goes away its objects are removed
+// and when it comes back they are fetched again.
+func (mgr *BusManager) MirrorObjects(
+	dest string,
+	path dbus.ObjectPath,
+	consumer seriatim.Sequent,
+	method string,
+) (*ObjectMirror, error) {
+	fetch := func() (string, ManagedObjects, error) {
+		var owner string
+		err := mgr.conn.BusObject().Call(fdtGetNameOwner, 0, dest).
+			Store(&owner)
+		if err != nil {
+			return "", nil, err
+		}
+		objects := make(ManagedObjects)
+		err = mgr.conn.Object(dest, path).
+			Call(fdtObjectManager+"."+getManagedObjects, 0).
+			Store(&objects)
+		return owner, objects, err
+	}
+	m := &ObjectMirror{
+		mgr:  mgr,
+		tree: newObjectTree(dest, path, fetch, consumer, method),
+		matches: [][2]string{
+			{fdtObjectManager, interfacesAdded},
+			{fdtObjectManager, interfacesRemoved},
+			{fdtProperties, propertiesChanged},
+			{fdtDBusName, fdtNameOwnerChanged},
+		},
+	}
+	m.cancel = mgr.Received().Observe(func(signal *EmittedSignal) {
+		m.tree.Cast("Signal", signal)
+	})
+	for _, match := range m.matches {
+		mgr.state.Call("AddMatchSignal", mgr.conn, match[0], match[1])
+	}
+	if _, err := m.tree.Call("Sync"); err != nil {
+		m.Close()
+		return nil, err
+	}
+	return m, nil
+}
+
+// Objects returns the objects as they are now. The maps must not be
+// modified.
+func (m *ObjectMirror) Objects() ManagedObjects {
+	rets, err := m.tree.Call("Objects")
+	if err != nil {
+		return nil
+	}
+	return rets[0].(ManagedObjects)
+}
+
+// Close stops mirroring the objects.
+func (m *ObjectMirror) Close() {
+	m.cancel()
+	for _, match := range m.matches {
+		m.mgr.state.Call("RemoveMatchSignal", m.mgr.conn, match[0], match[1])
+	}
+	m.tree.Terminate(nil)
+}
+
+// objectTree holds the mirrored objects. Its maps are replaced rather
+// than modified so that those returned by Objects can be read
+// concurrently.
+type objectTree struct {
+	dest     string
+	root     dbus.ObjectPath
+	fetch    func() (owner string, objects ManagedObjects, err error)
+	consumer seriatim.Sequent
+	method   string
+	owner    string
+	objects  ManagedObjects
+	// pending holds the signals received before the first Sync.
+	pending []*EmittedSignal
+	synced  bool
+}
+
+func newObjectTree(
+	dest string,
+	root dbus.ObjectPath,
+	fetch func() (string, ManagedObjects, error),
+	consumer seriatim.Sequent,
+	method string,
+) seriatim.Sequent {
+	// Signals are cast by the bus's dispatcher, which must not be
+	// held up by a full mailbox.
+	return seriatim.NewSequent(&objectTree{
+		dest:     dest,
+		root:     root,
+		fetch:    fetch,
+		consumer: consumer,
+		method:   method,
+		objects:  make(ManagedObjects),
+	}, seriatim.WithUnboundedMailbox())
+}
+
+func (t *objectTree) Objects() ManagedObjects {
+	out := make(ManagedObjects, len(t.objects))
+	for path, ifaces := range t.objects {
+		out[path] = ifaces
+	}
+	return out
+}
+
+// Sync replaces the objects with those fetched from their owner,
+// applying the signals received meanwhile again.
+func (t *objectTree) Sync() error {
+	owner, objects, err := t.fetch()
+	if err != nil {
+		return err
+	}
+	t.removeAll()
+	t.owner = owner
+	for _, path := range sortedPaths(objects) {
+		t.added(path, objects[path])
+	}
+	pending := t.pending
+	t.pending, t.synced = nil, true
+	for _, signal := range pending {
+		t.Signal(signal)
+	}
+	return nil
+}
+
+func (t *objectTree) Signal(signal *EmittedSignal) {
+	if !t.synced {
+		t.pending = append(t.pending, signal)
+		return
+	}
+	if signal.Interface == fdtDBusName &&
+		signal.Member == fdtNameOwnerChanged {
+		t.nameOwnerChanged(signal.Body)
+		return
+	}
+	if t.owner == "" || signal.Sender != t.owner {
+		return
+	}
+	switch {
+	case signal.Interface == fdtObjectManager &&
+		signal.Member == interfacesAdded:
+		var path dbus.ObjectPath
+		var ifaces map[string]map[string]dbus.Variant
+		if dbus.Store(signal.Body, &path, &ifaces) == nil && t.under(path) {
+			t.added(path, ifaces)
+		}
+	case signal.Interface == fdtObjectManager &&
+		signal.Member == interfacesRemoved:
+		var path dbus.ObjectPath
+		var names []string
+		if dbus.Store(signal.Body, &path, &names) == nil && t.under(path) {
+			t.removed(path, names)
+		}
+	case signal.Interface == fdtProperties &&
+		signal.Member == propertiesChanged:
+		var iface string
+		var changed map[string]dbus.Variant
+		var invalidated []string
+		err := dbus.Store(signal.Body, &iface, &changed, &invalidated)
+		if err == nil {
+			t.changed(signal.Path, iface, changed, invalidated)
+		}
+	}
+}
+
+func (t *objectTree) nameOwnerChanged(body []interface{}) {
+	var name, oldOwner, newOwner string
+	if dbus.Store(body, &name, &oldOwner, &newOwner) != nil || name != t.dest {
+		return
+	}
+	if newOwner == "" {
+		t.removeAll()
+		t.owner = ""
+		return
+	}
+	t.Sync()
+}
+
+// under reports whether path is managed by the object at the root.
+func (t *objectTree) under(path dbus.ObjectPath) bool {
+	if t.root == "/" {
+		return true
+	}
+	return strings.HasPrefix(string(path), string(t.root)+"/")
+}
+
+func (t *objectTree) added(
+	path dbus.ObjectPath,
+	added map[string]map[string]dbus.Variant,
+) {
+	ifaces := make(map[string]map[string]dbus.Variant,
+		len(t.objects[path])+len(added))
+	for name, props := range t.objects[path] {
+		ifaces[name] = props
+	}
+	for name, props := range added {
+		ifaces[name] = props
+	}
+	t.objects[path] = ifaces
+	t.notify(&ObjectEvent{
+		Kind:       InterfacesAdded,
+		Path:       path,
+		Interfaces: added,
+	})
+}
+
+func (t *objectTree) removed(path dbus.ObjectPath, names []string) {
+	old, ok := t.objects[path]
+	if !ok {
+		return
+	}
+	ifaces := make(map[string]map[string]dbus.Variant, len(old))
+	for name, props := range old {
+		ifaces[name] = props
+	}
+	for _, name := range names {
+		delete(ifaces, name)
+	}
+	if len(ifaces) == 0 {
+		delete(t.objects, path)
+	} else {
+		t.objects[path] = ifaces
+	}
+	t.notify(&ObjectEvent{
+		Kind:  InterfacesRemoved,
+		Path:  path,
+		Names: names,
+	})
+}
+
+func (t *objectTree) changed(
+	path dbus.ObjectPath,
+	iface string,
+	changed map[string]dbus.Variant,
+	invalidated []string,
+) {
+	old, ok := t.objects[path][iface]
+	if !ok {
+		return
+	}
+	props := make(map[string]dbus.Variant, len(old)+len(changed))
+	for name, value := range old {
+		props[name] = value
+	}
+	for name, value := range changed {
+		props[name] = value
+	}
+	for _, name := range invalidated {
+		delete(props, name)
+	}
+	ifaces := make(map[string]map[string]dbus.Variant, len(t.objects[path]))
+	for name, p := range t.objects[path] {
+		ifaces[name] = p
+	}
+	ifaces[iface] = props
+	t.objects[path] = ifaces
+	t.notify(&ObjectEvent{
+		Kind:       PropertiesChanged,
+		Path:       path,
+		Interfaces: map[string]map[string]dbus.Variant{iface: changed},
+		Names:      invalidated,
+	})
+}
+
+// removeAll removes every object, as when their owner goes away.
+func (t *objectTree) removeAll() {
+	for _, path := range sortedPaths(t.objects) {
+		names := make([]string, 0, len(t.objects[path]))
+		for name := range t.objects[path] {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		t.removed(path, names)
+	}
+}
+
+func (t *objectTree) notify(event *ObjectEvent) {
+	if t.consumer != nil {
+		t.consumer.Cast(t.method, event)
+	}
+}
+
+func sortedPaths(objects ManagedObjects) []dbus.ObjectPath {
+	paths := make([]dbus.ObjectPath, 0, len(objects))
+	for path := range objects {
+		paths = append(paths, path)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return paths[i] < paths[j]
+	})
+	return paths
+}
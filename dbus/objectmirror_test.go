@@ -0,0 +1,126 @@
+package dbus
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/godbus/dbus"
+	"github.com/jsouthworth/seriatim"
+)
+
+type objectEvents struct {
+	events []*ObjectEvent
+}
+
+func (e *objectEvents) Changed(event *ObjectEvent) {
+	e.events = append(e.events, event)
+}
+
+func (e *objectEvents) Events() []*ObjectEvent {
+	return e.events
+}
+
+func TestObjectTree(t *testing.T) {
+	props := func(kv ...interface{}) map[string]dbus.Variant {
+		out := make(map[string]dbus.Variant)
+		for i := 0; i < len(kv); i += 2 {
+			out[kv[i].(string)] = dbus.MakeVariant(kv[i+1])
+		}
+		return out
+	}
+	owner := ":1.7"
+	fetch := func() (string, ManagedObjects, error) {
+		return owner, ManagedObjects{
+			"/org/bluez/hci0": {
+				"org.bluez.Adapter1": props("Powered", false),
+			},
+		}, nil
+	}
+	consumer := seriatim.NewSequent(&objectEvents{})
+	defer consumer.Terminate(nil)
+	tree := newObjectTree("org.bluez", "/", fetch, consumer, "Changed")
+	defer tree.Terminate(nil)
+
+	signals := []*EmittedSignal{
+		{
+			Sender:    ":1.7",
+			Path:      "/org/bluez/hci0",
+			Interface: fdtProperties,
+			Member:    propertiesChanged,
+			Body: []interface{}{"org.bluez.Adapter1",
+				props("Powered", true), []string{}},
+		},
+		{
+			// not from the owner of org.bluez
+			Sender:    ":1.9",
+			Path:      "/",
+			Interface: fdtObjectManager,
+			Member:    interfacesAdded,
+			Body: []interface{}{dbus.ObjectPath("/evil"),
+				map[string]map[string]dbus.Variant{"x.Y": props()}},
+		},
+		{
+			Sender:    ":1.7",
+			Path:      "/",
+			Interface: fdtObjectManager,
+			Member:    interfacesAdded,
+			Body: []interface{}{dbus.ObjectPath("/org/bluez/hci0/dev_1"),
+				map[string]map[string]dbus.Variant{
+					"org.bluez.Device1": props("Name", "kbd"),
+				}},
+		},
+	}
+	// received before the tree was synced
+	tree.Cast("Signal", signals[0])
+	if _, err := tree.Call("Sync"); err != nil {
+		t.Fatal(err)
+	}
+	for _, signal := range signals[1:] {
+		tree.Cast("Signal", signal)
+	}
+	rets, err := tree.Call("Objects")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ManagedObjects{
+		"/org/bluez/hci0": {
+			"org.bluez.Adapter1": props("Powered", true),
+		},
+		"/org/bluez/hci0/dev_1": {
+			"org.bluez.Device1": props("Name", "kbd"),
+		},
+	}
+	if !reflect.DeepEqual(rets[0], want) {
+		t.Fatalf("expected %v, got %v", want, rets[0])
+	}
+
+	// org.bluez goes away
+	tree.Cast("Signal", &EmittedSignal{
+		Sender:    fdtDBusName,
+		Path:      "/org/freedesktop/DBus",
+		Interface: fdtDBusName,
+		Member:    fdtNameOwnerChanged,
+		Body:      []interface{}{"org.bluez", ":1.7", ""},
+	})
+	if rets, _ = tree.Call("Objects"); len(rets[0].(ManagedObjects)) != 0 {
+		t.Fatalf("expected the objects to go with their owner, have %v", rets[0])
+	}
+
+	rets, err = consumer.Call("Events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kinds []ObjectEventKind
+	var paths []dbus.ObjectPath
+	for _, event := range rets[0].([]*ObjectEvent) {
+		kinds = append(kinds, event.Kind)
+		paths = append(paths, event.Path)
+	}
+	wantKinds := []ObjectEventKind{InterfacesAdded, PropertiesChanged,
+		InterfacesAdded, InterfacesRemoved, InterfacesRemoved}
+	wantPaths := []dbus.ObjectPath{"/org/bluez/hci0", "/org/bluez/hci0",
+		"/org/bluez/hci0/dev_1", "/org/bluez/hci0", "/org/bluez/hci0/dev_1"}
+	if !reflect.DeepEqual(kinds, wantKinds) || !reflect.DeepEqual(paths, wantPaths) {
+		t.Fatalf("unexpected events %v at %v", kinds, paths)
+	}
+}
@@ -0,0 +1,113 @@
+package dbus
+
+import (
+	"sync/atomic"
+
+	"github.com/godbus/dbus"
+)
+
+// EmittedSignal describes a signal sent with Object.Emit, or one
+// received from the bus when observed through Received, in which case
+// Sender is the unique name of the connection that sent it.
+type EmittedSignal struct {
+	Path      dbus.ObjectPath
+	Interface string
+	Member    string
+	Body      []interface{}
+	Sender    string
+}
+
+// SignalSource is implemented by BusManager for emitted signals and by
+// the value of Received for signals received from the bus.
+type SignalSource interface {
+	Observe(fn func(*EmittedSignal)) (cancel func())
+}
+
+type observeFunc func(fn func(*EmittedSignal)) func()
+
+func (observe observeFunc) Observe(fn func(*EmittedSignal)) func() {
+	return observe(fn)
+}
+
+type observerSet struct {
+	multiWriterValue
+	next uint64
+}
+
+func (set *observerSet) get() map[uint64]func(*EmittedSignal) {
+	observers, _ := set.Load().(map[uint64]func(*EmittedSignal))
+	return observers
+}
+
+func (set *observerSet) add(fn func(*EmittedSignal)) uint64 {
+	id := atomic.AddUint64(&set.next, 1)
+	set.Update(func(value *atomic.Value) {
+		old := set.get()
+		observers := make(map[uint64]func(*EmittedSignal), len(old)+1)
+		for k, v := range old {
+			observers[k] = v
+		}
+		observers[id] = fn
+		value.Store(observers)
+	})
+	return id
+}
+
+func (set *observerSet) remove(id uint64) {
+	set.Update(func(value *atomic.Value) {
+		old := set.get()
+		observers := make(map[uint64]func(*EmittedSignal), len(old))
+		for k, v := range old {
+			if k != id {
+				observers[k] = v
+			}
+		}
+		value.Store(observers)
+	})
+}
+
+// Observe registers fn to be called with every signal emitted by the
+// objects of this bus after it has been sent. fn is called on the
+// emitting goroutine and must not block. The returned function removes
+// the observer.
+func (mgr *BusManager) Observe(fn func(*EmittedSignal)) (cancel func()) {
+	id := mgr.observers.add(fn)
+	return func() {
+		mgr.observers.remove(id)
+	}
+}
+
+func (mgr *BusManager) notifyEmitted(signal *EmittedSignal) {
+	for _, fn := range mgr.observers.get() {
+		fn(signal)
+	}
+}
+
+// Received returns the source of the signals this bus delivers to its
+// objects. Only signals matched for a Receives registration reach the
+// connection.
+func (mgr *BusManager) Received() SignalSource {
+	return observeFunc(func(fn func(*EmittedSignal)) func() {
+		id := mgr.received.add(fn)
+		return func() {
+			mgr.received.remove(id)
+		}
+	})
+}
+
+func (mgr *BusManager) notifyReceived(iface, member string, signal *dbus.Signal) {
+	observers := mgr.received.get()
+	if len(observers) == 0 {
+		return
+	}
+	received := &EmittedSignal{
+		Path:      signal.Path,
+		Interface: iface,
+		Member:    member,
+		Body:      signal.Body,
+		Sender:    signal.Sender,
+	}
+	for _, fn := range observers {
+		fn(received)
+	}
+}
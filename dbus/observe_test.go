@@ -0,0 +1,162 @@
+package dbus
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/godbus/dbus"
+	"github.com/jsouthworth/seriatim"
+	"github.com/jsouthworth/seriatim/events"
+)
+
+// newPipeBusManager returns a manager whose connection discards
+// everything sent on it.
+func newPipeBusManager(t *testing.T) *BusManager {
+	client, server := net.Pipe()
+	go io.Copy(ioutil.Discard, server)
+	conn, err := dbus.NewConn(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr := &BusManager{
+		Object: NewObject("", nil, nil, nil),
+		conn:   conn,
+		events: events.NewBus(),
+	}
+	mgr.bus = mgr
+	mgr.dispatcher = newSignalDispatcher(mgr)
+	return mgr
+}
+
+func TestObserveEmitted(t *testing.T) {
+	mgr := newPipeBusManager(t)
+	defer mgr.conn.Close()
+	obj := mgr.NewObject("/foo/bar", nil)
+	if err := obj.Emits("com.example.Foo", (*testSignals)(nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	var seen []*EmittedSignal
+	cancel := mgr.Observe(func(signal *EmittedSignal) {
+		seen = append(seen, signal)
+	})
+	if err := obj.Emit("com.example.Foo", "Changed", "x", int32(1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := obj.Emit("com.example.Foo", "Changed", "x"); err == nil {
+		t.Fatal("Emit should reject a mismatched signature")
+	}
+	cancel()
+	if err := obj.Emit("com.example.Foo", "Changed", "y", int32(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(seen) != 1 {
+		t.Fatalf("expected 1 observed signal, got %d", len(seen))
+	}
+	signal := seen[0]
+	if signal.Path != "/foo/bar" || signal.Interface != "com.example.Foo" ||
+		signal.Member != "Changed" || len(signal.Body) != 2 {
+		t.Fatalf("unexpected signal %+v", signal)
+	}
+}
+
+func TestObserveReceived(t *testing.T) {
+	mgr := newPipeBusManager(t)
+	defer mgr.conn.Close()
+
+	seen := make(chan *EmittedSignal, 2)
+	cancel := mgr.Received().Observe(func(signal *EmittedSignal) {
+		seen <- signal
+	})
+	all := make(chan *EmittedSignal, 2)
+	defer mgr.Received().Observe(func(signal *EmittedSignal) {
+		all <- signal
+	})()
+	mgr.DeliverSignal("com.example.Foo", "Changed", &dbus.Signal{
+		Sender: ":1.42",
+		Path:   "/remote",
+		Name:   "com.example.Foo.Changed",
+		Body:   []interface{}{"x"},
+	})
+	signal := <-seen
+	cancel()
+	mgr.DeliverSignal("com.example.Foo", "Changed", &dbus.Signal{Path: "/remote"})
+	<-all
+	<-all
+
+	if len(seen) != 0 {
+		t.Fatalf("expected 1 observed signal, got %d", len(seen)+1)
+	}
+	if signal.Sender != ":1.42" || signal.Path != "/remote" ||
+		signal.Interface != "com.example.Foo" || signal.Member != "Changed" {
+		t.Fatalf("unexpected signal %+v", signal)
+	}
+}
+
+type signalLog struct {
+	topics chan string
+}
+
+func (l *signalLog) Signal(topic string, args ...interface{}) {
+	l.topics <- topic
+}
+
+func TestDeliverSignalPublishes(t *testing.T) {
+	mgr := newPipeBusManager(t)
+	defer mgr.conn.Close()
+
+	log := &signalLog{topics: make(chan string, 2)}
+	s := seriatim.NewSequent(log)
+	defer s.Terminate(nil)
+	_, err := mgr.Events().SubscribeTopic("com.example.Foo.*", s,
+		"Signal", events.Block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mgr.DeliverSignal("com.example.Bar", "Changed", &dbus.Signal{})
+	mgr.DeliverSignal("com.example.Foo", "Changed", &dbus.Signal{
+		Body: []interface{}{"x"},
+	})
+	if topic := <-log.topics; topic != "com.example.Foo.Changed" {
+		t.Fatalf("unexpected topic %q", topic)
+	}
+}
+
+type stalled struct {
+	release chan struct{}
+}
+
+func (s *stalled) Signal(topic string, args ...interface{}) {
+	<-s.release
+}
+
+func TestDeliverSignalSlowListener(t *testing.T) {
+	mgr := newPipeBusManager(t)
+	defer mgr.conn.Close()
+
+	listener := &stalled{release: make(chan struct{})}
+	s := seriatim.NewSequent(listener, seriatim.WithMailboxSize(0))
+	defer s.Terminate(nil)
+	_, err := mgr.Events().SubscribeTopic("com.example.Foo.*", s,
+		"Signal", events.Block)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivered := make(chan struct{})
+	go func() {
+		for i := 0; i < 10; i++ {
+			mgr.DeliverSignal("com.example.Foo", "Changed", &dbus.Signal{})
+		}
+		close(delivered)
+	}()
+	select {
+	case <-delivered:
+	case <-time.After(time.Second):
+		t.Fatal("DeliverSignal blocked on a slow listener")
+	}
+	close(listener.release)
+}
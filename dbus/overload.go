@@ -0,0 +1,85 @@
+package dbus
+
+import (
+	"github.com/godbus/dbus"
+	"github.com/godbus/dbus/introspect"
+)
+
+// overloadedMethod handles a call to a name exported by several
+// methods, such as ones ImplementsMap maps to the same name, with the
+// method whose input signature is that of the message.
+type overloadedMethod struct {
+	candidates []*Method
+	access     *accessList
+	chosen     *Method
+}
+
+func (method *overloadedMethod) DecodeArguments(
+	conn *dbus.Conn,
+	sender string,
+	msg *dbus.Message,
+	args []interface{},
+) ([]interface{}, error) {
+	signature := messageSignature(msg)
+	for _, candidate := range method.candidates {
+		if inputSignature(candidate.introspection) == signature {
+			method.chosen = candidate.instance(method.access)
+			return method.chosen.DecodeArguments(conn, sender, msg, args)
+		}
+	}
+	return nil, invalidArgs("No method %s takes arguments of signature %q",
+		method.candidates[0].introspection.Name, signature)
+}
+
+func (method *overloadedMethod) Call(args ...interface{}) ([]interface{}, error) {
+	if method.chosen == nil {
+		return nil, dbus.ErrMsgInvalidArg
+	}
+	return method.chosen.Call(args...)
+}
+
+// target returns the chosen method, or the first candidate until one
+// is chosen.
+func (method *overloadedMethod) target() *Method {
+	if method.chosen != nil {
+		return method.chosen
+	}
+	return method.candidates[0]
+}
+
+func (method *overloadedMethod) NumArguments() int {
+	return method.target().NumArguments()
+}
+
+func (method *overloadedMethod) NumReturns() int {
+	return method.target().NumReturns()
+}
+
+func (method *overloadedMethod) ArgumentValue(position int) interface{} {
+	return method.target().ArgumentValue(position)
+}
+
+func (method *overloadedMethod) ReturnValue(position int) interface{} {
+	return method.target().ReturnValue(position)
+}
+
+// inputSignature returns the signature of the arguments of a call to
+// method.
+func inputSignature(method introspect.Method) string {
+	var sig string
+	for _, arg := range method.Args {
+		if arg.Direction == "in" {
+			sig += arg.Type
+		}
+	}
+	return sig
+}
+
+func messageSignature(msg *dbus.Message) string {
+	if v, ok := msg.Headers[dbus.FieldSignature]; ok {
+		if sig, ok := v.Value().(dbus.Signature); ok {
+			return sig.String()
+		}
+	}
+	return dbus.SignatureOf(msg.Body...).String()
+}
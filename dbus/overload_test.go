@@ -0,0 +1,56 @@
+package dbus
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus"
+)
+
+func TestOverloadedMethod(t *testing.T) {
+	methods := map[string]interface{}{
+		"SetInt":    interface{}(func(n int32) string { return "int" }),
+		"SetString": interface{}(func(s string) string { return "string" }),
+	}
+	obj := NewObjectFromTable("foo", methods, nil, nil)
+	err := obj.ImplementsTableMap("com.example.Legacy", methods,
+		func(in string) string {
+			return "Set"
+		})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		arg  interface{}
+		want string
+	}{
+		{int32(1), "int"},
+		{"a", "string"},
+	} {
+		outs, err := deliverCall(obj, "com.example.Legacy", "Set", test.arg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if outs[0] != test.want {
+			t.Fatalf("expected the %s overload, got %v", test.want, outs[0])
+		}
+	}
+	_, err = deliverCall(obj, "com.example.Legacy", "Set", true)
+	if derr, ok := err.(*dbus.Error); !ok || derr.Name != fdtInvalidArgs {
+		t.Fatalf("expected InvalidArgs, got %v", err)
+	}
+
+	var sigs []string
+	for _, iface := range obj.Introspect().Interfaces {
+		if iface.Name != "com.example.Legacy" {
+			continue
+		}
+		for _, method := range iface.Methods {
+			sigs = append(sigs, method.Name+"("+inputSignature(method)+")")
+		}
+	}
+	if got := strings.Join(sigs, " "); got != "Set(i) Set(s)" {
+		t.Fatalf("unexpected introspection %q", got)
+	}
+}
@@ -0,0 +1,62 @@
+package dbus
+
+import (
+	"github.com/godbus/dbus"
+)
+
+const fdtProperties = fdtDBusName + ".Properties"
+
+// Proxy is a client side handle on one interface of a remote object.
+type Proxy struct {
+	object dbus.BusObject
+	iface  string
+	opts   callOptions
+}
+
+func NewProxy(object dbus.BusObject, iface string) *Proxy {
+	return &Proxy{
+		object: object,
+		iface:  iface,
+	}
+}
+
+// NewProxy returns a proxy for iface on the object at path owned by
+// dest using the manager's connection.
+func (mgr *BusManager) NewProxy(
+	dest string,
+	path dbus.ObjectPath,
+	iface string,
+) *Proxy {
+	return NewProxy(mgr.conn.Object(dest, path), iface)
+}
+
+func (p *Proxy) Interface() string {
+	return p.iface
+}
+
+func (p *Proxy) Object() dbus.BusObject {
+	return p.object
+}
+
+// Call invokes method on the proxied interface and waits for the reply.
+func (p *Proxy) Call(method string, args ...interface{}) *dbus.Call {
+	return p.call(p.iface+"."+method, args...)
+}
+
+func (p *Proxy) GetProperty(name string) (dbus.Variant, error) {
+	return p.object.GetProperty(p.iface + "." + name)
+}
+
+func (p *Proxy) SetProperty(name string, value interface{}) error {
+	return p.call(fdtProperties+".Set",
+		p.iface, name, dbus.MakeVariant(value)).Err
+}
+
+// StoreProperty reads a property into the value pointed to by ptr.
+func (p *Proxy) StoreProperty(name string, ptr interface{}) error {
+	variant, err := p.GetProperty(name)
+	if err != nil {
+		return err
+	}
+	return dbus.Store([]interface{}{variant.Value()}, ptr)
+}
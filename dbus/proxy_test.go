@@ -0,0 +1,109 @@
+package dbus
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/godbus/dbus"
+)
+
+type fakeBusObject struct {
+	method string
+	args   []interface{}
+	flags  dbus.Flags
+	body   []interface{}
+	err    error
+	// failures is the number of calls to fail with Disconnected
+	// before succeeding.
+	failures int
+	calls    int
+	// hang keeps Go from answering.
+	hang bool
+}
+
+func (o *fakeBusObject) Call(
+	method string,
+	flags dbus.Flags,
+	args ...interface{},
+) *dbus.Call {
+	o.method = method
+	o.args = args
+	o.flags = flags
+	o.calls++
+	if o.calls <= o.failures {
+		return &dbus.Call{Method: method, Args: args,
+			Err: dbus.NewError(fdtDisconnected, nil)}
+	}
+	return &dbus.Call{Method: method, Args: args, Body: o.body, Err: o.err}
+}
+
+func (o *fakeBusObject) Go(
+	method string,
+	flags dbus.Flags,
+	ch chan *dbus.Call,
+	args ...interface{},
+) *dbus.Call {
+	call := o.Call(method, flags, args...)
+	if !o.hang {
+		ch <- call
+	}
+	return call
+}
+
+func (o *fakeBusObject) GetProperty(p string) (dbus.Variant, error) {
+	o.method = p
+	if o.err != nil {
+		return dbus.Variant{}, o.err
+	}
+	return dbus.MakeVariant(o.body[0]), nil
+}
+
+func (o *fakeBusObject) Destination() string {
+	return "com.example"
+}
+
+func (o *fakeBusObject) Path() dbus.ObjectPath {
+	return "/foo"
+}
+
+func TestProxyCall(t *testing.T) {
+	obj := &fakeBusObject{body: []interface{}{"hello"}}
+	proxy := NewProxy(obj, "com.example.Foo")
+	var out string
+	if err := proxy.Call("Baz", int32(1)).Store(&out); err != nil {
+		t.Fatal(err)
+	}
+	if obj.method != "com.example.Foo.Baz" {
+		t.Fatalf("called %s", obj.method)
+	}
+	if out != "hello" {
+		t.Fatalf("unexpected output %q", out)
+	}
+}
+
+func TestProxyProperties(t *testing.T) {
+	obj := &fakeBusObject{body: []interface{}{uint32(7)}}
+	proxy := NewProxy(obj, "com.example.Foo")
+	var out uint32
+	if err := proxy.StoreProperty("Count", &out); err != nil {
+		t.Fatal(err)
+	}
+	if obj.method != "com.example.Foo.Count" || out != 7 {
+		t.Fatalf("read %s = %d", obj.method, out)
+	}
+
+	if err := proxy.SetProperty("Count", uint32(8)); err != nil {
+		t.Fatal(err)
+	}
+	if obj.method != fdtProperties+".Set" {
+		t.Fatalf("called %s", obj.method)
+	}
+	if obj.args[0] != "com.example.Foo" || obj.args[1] != "Count" {
+		t.Fatalf("unexpected arguments %v", obj.args)
+	}
+
+	obj.err = errors.New("failed")
+	if err := proxy.StoreProperty("Count", &out); err != obj.err {
+		t.Fatalf("expected error, got %v", err)
+	}
+}
@@ -0,0 +1,315 @@
+package dbus
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/godbus/dbus"
+)
+
+var varianttype = reflect.TypeOf(dbus.Variant{})
+
+// RESTGateway serves the object tree of a bus over HTTP. Object paths
+// map to URL paths:
+//
+//	GET  /foo/bar                          describe the object
+//	GET  /foo/bar?watch[&interface=i&member=m]
+//	                                       stream the signals it emits as
+//	                                       Server-Sent Events
+//	POST /foo/bar/com.example.Foo.Method   call Method with the JSON
+//	                                       array in the body
+//
+// Path elements of D-Bus objects cannot contain dots, so the last
+// element names a member whenever it does. Arguments are converted
+// from JSON to the types of the method's parameters, which determine
+// their D-Bus types.
+type RESTGateway struct {
+	mgr *BusManager
+	// Backlog is the number of signals buffered for each event
+	// stream; signals arriving while it is full are dropped.
+	Backlog int
+}
+
+func NewRESTGateway(mgr *BusManager) *RESTGateway {
+	return &RESTGateway{mgr: mgr, Backlog: 64}
+}
+
+type restMember struct {
+	In  []string `json:"in,omitempty"`
+	Out []string `json:"out,omitempty"`
+}
+
+type restInterface struct {
+	Methods map[string]restMember `json:"methods,omitempty"`
+	Signals map[string]restMember `json:"signals,omitempty"`
+}
+
+type restObject struct {
+	Path       string                   `json:"path"`
+	Interfaces map[string]restInterface `json:"interfaces"`
+	Children   []string                 `json:"children"`
+}
+
+type restEvent struct {
+	Path      string        `json:"path"`
+	Interface string        `json:"interface"`
+	Member    string        `json:"member"`
+	Body      []interface{} `json:"body"`
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	out := map[string]string{"error": err.Error()}
+	if derr, ok := err.(dbus.Error); ok {
+		out["name"] = derr.Name
+	}
+	writeJSON(w, status, out)
+}
+
+func (gw *RESTGateway) lookup(path string) (*Object, bool) {
+	if path == "" || path == "/" {
+		return gw.mgr.Object, true
+	}
+	obj, ok := gw.mgr.LookupObject(dbus.ObjectPath(path))
+	if !ok {
+		return nil, false
+	}
+	o, ok := obj.(*Object)
+	return o, ok
+}
+
+func (gw *RESTGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := "/" + strings.Trim(r.URL.Path, "/")
+	var member string
+	if i := strings.LastIndex(path, "/"); strings.Contains(path[i+1:], ".") {
+		path, member = path[:i], path[i+1:]
+	}
+	obj, ok := gw.lookup(path)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("no object at %s", path))
+		return
+	}
+
+	switch {
+	case member != "" && r.Method == "POST":
+		gw.call(w, r, obj, member)
+	case member == "" && r.Method == "GET":
+		if _, ok := r.URL.Query()["watch"]; ok {
+			gw.watch(w, r, path)
+			return
+		}
+		writeJSON(w, http.StatusOK, describe(path, obj))
+	default:
+		allow := "GET"
+		if member != "" {
+			allow = "POST"
+		}
+		w.Header().Set("Allow", allow)
+		writeError(w, http.StatusMethodNotAllowed,
+			fmt.Errorf("%s not allowed on %s", r.Method, r.URL.Path))
+	}
+}
+
+func describe(path string, obj *Object) *restObject {
+	node := obj.Introspect()
+	out := &restObject{
+		Path:       path,
+		Interfaces: make(map[string]restInterface, len(node.Interfaces)),
+		Children:   make([]string, 0, len(node.Children)),
+	}
+	for _, iface := range node.Interfaces {
+		desc := restInterface{
+			Methods: make(map[string]restMember, len(iface.Methods)),
+			Signals: make(map[string]restMember, len(iface.Signals)),
+		}
+		for _, method := range iface.Methods {
+			var m restMember
+			for _, arg := range method.Args {
+				if arg.Direction == "out" {
+					m.Out = append(m.Out, arg.Type)
+				} else {
+					m.In = append(m.In, arg.Type)
+				}
+			}
+			desc.Methods[method.Name] = m
+		}
+		for _, signal := range iface.Signals {
+			var m restMember
+			for _, arg := range signal.Args {
+				m.Out = append(m.Out, arg.Type)
+			}
+			desc.Signals[signal.Name] = m
+		}
+		out.Interfaces[iface.Name] = desc
+	}
+	for _, child := range node.Children {
+		out.Children = append(out.Children, child.Name)
+	}
+	return out
+}
+
+// decodeJSONArgument converts a JSON value to typ. Variants hold
+// whatever the JSON decodes to.
+func decodeJSONArgument(raw json.RawMessage, typ reflect.Type) (interface{}, error) {
+	if typ == varianttype {
+		var value interface{}
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return nil, err
+		}
+		return dbus.MakeVariant(value), nil
+	}
+	ptr := reflect.New(typ)
+	if err := json.Unmarshal(raw, ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+// restArguments converts the JSON arguments of a call to the parameter
+// types of method.
+func restArguments(method *Method, raw []json.RawMessage) ([]interface{}, error) {
+	typ := method.value.Type()
+	args := make([]interface{}, 0, typ.NumIn())
+	for i := 0; i < typ.NumIn(); i++ {
+		if i == 0 && typ.In(i) == contexttype {
+			// given by the sequent
+			continue
+		}
+		if typ.In(i) == sendertype {
+			args = append(args, dbus.Sender(""))
+			continue
+		}
+		if typ.In(i) == sessiontype {
+			args = append(args, &Session{})
+			continue
+		}
+		if len(raw) == 0 {
+			return nil, dbus.ErrMsgInvalidArg
+		}
+		arg, err := decodeJSONArgument(raw[0], typ.In(i))
+		if err != nil {
+			return nil, fmt.Errorf("argument %d: %v", len(args), err)
+		}
+		raw = raw[1:]
+		args = append(args, arg)
+	}
+	if len(raw) != 0 {
+		return nil, dbus.ErrMsgInvalidArg
+	}
+	return args, nil
+}
+
+func (gw *RESTGateway) call(w http.ResponseWriter, r *http.Request, obj *Object, member string) {
+	dot := strings.LastIndex(member, ".")
+	ifaceName, methodName := member[:dot], member[dot+1:]
+	iface, ok := obj.getInterfaces()[ifaceName]
+	if !ok {
+		writeError(w, http.StatusNotFound, dbus.ErrMsgUnknownInterface)
+		return
+	}
+	method, ok := iface.methods[methodName]
+	if !ok {
+		writeError(w, http.StatusNotFound, dbus.ErrMsgUnknownMethod)
+		return
+	}
+
+	var raw []json.RawMessage
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&raw); err != nil {
+			writeError(w, http.StatusBadRequest,
+				fmt.Errorf("body must be a JSON array of arguments: %v", err))
+			return
+		}
+	}
+	// of the methods exported under the name, call the one the
+	// arguments suit
+	var chosen *Method
+	var args []interface{}
+	var err error
+	for _, candidate := range append([]*Method{method}, method.overloads...) {
+		candidateArgs, candidateErr := restArguments(candidate, raw)
+		if candidateErr != nil {
+			if err == nil {
+				err = candidateErr
+			}
+			continue
+		}
+		if chosen != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf(
+				"arguments suit several methods named %s", methodName))
+			return
+		}
+		chosen, args = candidate, candidateArgs
+	}
+	if chosen == nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	iface.object.touch()
+	ret, err := chosen.instance(iface.access).Call(args...)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if ret == nil {
+		ret = []interface{}{}
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"result": ret})
+}
+
+func (gw *RESTGateway) watch(w http.ResponseWriter, r *http.Request, path string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError,
+			fmt.Errorf("streaming is not supported"))
+		return
+	}
+	query := r.URL.Query()
+	iface, member := query.Get("interface"), query.Get("member")
+	events := make(chan *EmittedSignal, gw.Backlog)
+	cancel := gw.mgr.Observe(func(signal *EmittedSignal) {
+		if string(signal.Path) != path ||
+			(iface != "" && iface != signal.Interface) ||
+			(member != "" && member != signal.Member) {
+			return
+		}
+		select {
+		case events <- signal:
+		default:
+		}
+	})
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case signal := <-events:
+			data, err := json.Marshal(&restEvent{
+				Path:      string(signal.Path),
+				Interface: signal.Interface,
+				Member:    signal.Member,
+				Body:      signal.Body,
+			})
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s.%s\ndata: %s\n\n",
+				signal.Interface, signal.Member, data)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,228 @@
+package dbus
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/godbus/dbus"
+)
+
+type restThermostat struct {
+	target int32
+}
+
+func (t *restThermostat) SetTarget(target int32, reason string) (int32, error) {
+	if target > 30 {
+		return t.target, errors.New("too hot")
+	}
+	t.target = target
+	return t.target, nil
+}
+
+func (t *restThermostat) Whoami(sender dbus.Sender) string {
+	return string(sender)
+}
+
+type restThermostatIface interface {
+	SetTarget(int32, string) (int32, error)
+	Whoami(dbus.Sender) string
+}
+
+func newRESTServer(t *testing.T) (*BusManager, *Object, *httptest.Server) {
+	mgr := newPipeBusManager(t)
+	obj := mgr.NewObject("/house/thermostat", &restThermostat{})
+	if err := obj.Implements("com.example.Thermostat",
+		(*restThermostatIface)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	if err := obj.Emits("com.example.Thermostat", (*testSignals)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	return mgr, obj, httptest.NewServer(NewRESTGateway(mgr))
+}
+
+func post(t *testing.T, url, body string) (int, map[string]interface{}) {
+	res, err := http.Post(url, "application/json", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var out map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	return res.StatusCode, out
+}
+
+func TestRESTDescribe(t *testing.T) {
+	mgr, _, srv := newRESTServer(t)
+	defer mgr.conn.Close()
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/house/thermostat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var desc restObject
+	if err := json.NewDecoder(res.Body).Decode(&desc); err != nil {
+		t.Fatal(err)
+	}
+	iface := desc.Interfaces["com.example.Thermostat"]
+	setTarget := iface.Methods["SetTarget"]
+	if strings.Join(setTarget.In, "") != "is" || strings.Join(setTarget.Out, "") != "i" {
+		t.Fatalf("unexpected SetTarget description %+v", setTarget)
+	}
+	if _, ok := iface.Signals["Changed"]; !ok {
+		t.Fatalf("signals missing from %+v", iface)
+	}
+
+	res, err = http.Get(srv.URL + "/house")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("placeholder object not served: %s", res.Status)
+	}
+	res, err = http.Get(srv.URL + "/nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected not found, got %s", res.Status)
+	}
+}
+
+func TestRESTCall(t *testing.T) {
+	mgr, _, srv := newRESTServer(t)
+	defer mgr.conn.Close()
+	defer srv.Close()
+	url := srv.URL + "/house/thermostat/com.example.Thermostat."
+
+	status, out := post(t, url+"SetTarget", `[21, "cold"]`)
+	if status != http.StatusOK || out["result"].([]interface{})[0] != 21.0 {
+		t.Fatalf("unexpected response %d %v", status, out)
+	}
+	status, out = post(t, url+"SetTarget", `[40, "warm"]`)
+	if status != http.StatusInternalServerError || out["error"] != "too hot" {
+		t.Fatalf("unexpected response %d %v", status, out)
+	}
+	status, out = post(t, url+"Whoami", ``)
+	if status != http.StatusOK {
+		t.Fatalf("unexpected response %d %v", status, out)
+	}
+
+	tests := map[string]int{
+		`["21", "cold"]`: http.StatusBadRequest,
+		`[21]`:           http.StatusBadRequest,
+		`[21, "a", "b"]`: http.StatusBadRequest,
+		`{}`:             http.StatusBadRequest,
+	}
+	for body, expected := range tests {
+		if status, out := post(t, url+"SetTarget", body); status != expected {
+			t.Errorf("%s: expected %d, got %d %v", body, expected, status, out)
+		}
+	}
+	status, out = post(t, url+"Missing", `[]`)
+	if status != http.StatusNotFound ||
+		out["name"] != "org.freedesktop.DBus.Error.UnknownMethod" {
+		t.Fatalf("unexpected response %d %v", status, out)
+	}
+}
+
+func TestRESTWatch(t *testing.T) {
+	mgr, obj, srv := newRESTServer(t)
+	defer mgr.conn.Close()
+	defer srv.Close()
+
+	res, err := http.Get(srv.URL + "/house/thermostat?watch&member=Changed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+
+	// The observer is registered before the headers are sent.
+	err = obj.Emit("com.example.Thermostat", "Changed", "target", int32(21))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(res.Body)
+	event, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := r.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event != "event: com.example.Thermostat.Changed\n" {
+		t.Fatalf("unexpected event line %q", event)
+	}
+	var payload restEvent
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Path != "/house/thermostat" || len(payload.Body) != 2 {
+		t.Fatalf("unexpected payload %+v", payload)
+	}
+}
+
+type restLegacy struct{}
+
+func (restLegacy) SetInt(n int32) string     { return "int" }
+func (restLegacy) SetString(s string) string { return "string" }
+func (restLegacy) SetLong(n int64) string    { return "long" }
+
+func TestRESTCallOverloaded(t *testing.T) {
+	mgr, _, srv := newRESTServer(t)
+	defer mgr.conn.Close()
+	defer srv.Close()
+	val := restLegacy{}
+	obj := mgr.NewObject("/legacy", val)
+	err := obj.ImplementsTableMap("com.example.Legacy",
+		map[string]interface{}{
+			"SetInt":    val.SetInt,
+			"SetString": val.SetString,
+		},
+		func(string) string { return "Set" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = obj.ImplementsTableMap("com.example.Numbers",
+		map[string]interface{}{
+			"SetInt":  val.SetInt,
+			"SetLong": val.SetLong,
+		},
+		func(string) string { return "Set" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	url := srv.URL + "/legacy/com.example."
+
+	for body, want := range map[string]string{`[1]`: "int", `["a"]`: "string"} {
+		status, out := post(t, url+"Legacy.Set", body)
+		if status != http.StatusOK || out["result"].([]interface{})[0] != want {
+			t.Fatalf("%s: unexpected response %d %v", body, status, out)
+		}
+	}
+	status, out := post(t, url+"Legacy.Set", `[true]`)
+	if status != http.StatusBadRequest {
+		t.Fatalf("unexpected response %d %v", status, out)
+	}
+	// both int32 and int64 take a number
+	status, out = post(t, url+"Numbers.Set", `[1]`)
+	if status != http.StatusBadRequest {
+		t.Fatalf("expected an ambiguous call to be rejected, got %d %v",
+			status, out)
+	}
+}
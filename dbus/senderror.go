@@ -0,0 +1,128 @@
+package dbus
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sync/atomic"
+
+	"github.com/godbus/dbus"
+)
+
+// ErrConnectionClosed is the reason of the SendErrors of the replies
+// and signals sent once the connection of a BusManager has closed.
+var ErrConnectionClosed = errors.New("Connection to the bus is closed")
+
+// SendError describes a method reply or signal a BusManager failed to
+// send, such as because the connection to the bus was lost or the
+// message exceeds the size the bus accepts.
+type SendError struct {
+	Path      dbus.ObjectPath
+	Interface string
+	Member    string
+	// Destination is the caller a reply was for; it is empty for
+	// signals.
+	Destination string
+	Err         error
+}
+
+func (e *SendError) Error() string {
+	what := "signal"
+	if e.Destination != "" {
+		what = "reply to " + e.Destination + " for"
+	}
+	return fmt.Sprintf("Sending %s %s.%s of %s: %s",
+		what, e.Interface, e.Member, e.Path, e.Err)
+}
+
+func (e *SendError) Unwrap() error {
+	return e.Err
+}
+
+// Terminate is called by the connection of mgr when it is closed, after
+// which the replies to the calls still being processed are reported to
+// OnSendError as they cannot be sent.
+func (mgr *BusManager) Terminate() {
+	atomic.StoreUint32(&mgr.closed, 1)
+}
+
+func (mgr *BusManager) connClosed() bool {
+	return atomic.LoadUint32(&mgr.closed) != 0
+}
+
+func (mgr *BusManager) sendFailed(err *SendError) {
+	if mgr.OnSendError != nil {
+		mgr.OnSendError(err)
+	}
+}
+
+// checkReply reports the reply of a call from the bus to OnSendError
+// if the connection is closed or it cannot be marshalled, which godbus
+// would silently drop.
+func (method *Method) checkReply(ret []interface{}, err error) {
+	mgr := method.bus
+	msg := method.message
+	if mgr == nil || mgr.OnSendError == nil || msg == nil ||
+		msg.Flags&dbus.FlagNoReplyExpected != 0 {
+		return
+	}
+	path, iface := method.target()
+	failed := &SendError{
+		Path:        path,
+		Interface:   iface,
+		Member:      method.introspection.Name,
+		Destination: method.sender,
+	}
+	if mgr.connClosed() {
+		failed.Err = ErrConnectionClosed
+		mgr.sendFailed(failed)
+		return
+	}
+	if err != nil {
+		return
+	}
+	reply := &dbus.Message{
+		Type: dbus.TypeMethodReply,
+		Headers: map[dbus.HeaderField]dbus.Variant{
+			dbus.FieldReplySerial: dbus.MakeVariant(msg.Serial()),
+		},
+		Body: ret,
+	}
+	if len(ret) != 0 {
+		reply.Headers[dbus.FieldSignature] =
+			dbus.MakeVariant(dbus.SignatureOf(ret...))
+	}
+	if failed.Err = encodeReply(reply); failed.Err != nil {
+		mgr.sendFailed(failed)
+	}
+}
+
+// encodeReply marshals reply as the connection would, reporting values
+// that cannot be rather than panicking.
+func encodeReply(reply *dbus.Message) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("%v", rec)
+		}
+	}()
+	return reply.EncodeTo(ioutil.Discard, binary.LittleEndian)
+}
+
+// emitFailed reports the signal member of iface from path that could
+// not be emitted.
+func (mgr *BusManager) emitFailed(
+	path dbus.ObjectPath,
+	iface, member string,
+	err error,
+) {
+	if mgr.connClosed() {
+		err = ErrConnectionClosed
+	}
+	mgr.sendFailed(&SendError{
+		Path:      path,
+		Interface: iface,
+		Member:    member,
+		Err:       err,
+	})
+}
@@ -0,0 +1,60 @@
+package dbus
+
+import (
+	"testing"
+)
+
+func TestSendErrorReply(t *testing.T) {
+	mgr := newPipeBusManager(t)
+	defer mgr.conn.Close()
+	obj := mgr.NewObject("/foo", &job{})
+	if err := obj.Implements("com.example.Job", (*interface{ Progress() int })(nil)); err != nil {
+		t.Fatal(err)
+	}
+	var failed []*SendError
+	mgr.OnSendError = func(err *SendError) {
+		failed = append(failed, err)
+	}
+	if _, err := deliverCall(obj, "com.example.Job", "Progress"); err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 0 {
+		t.Fatalf("unexpected send errors %v", failed)
+	}
+
+	mgr.Terminate()
+	if _, err := deliverCall(obj, "com.example.Job", "Progress"); err != nil {
+		t.Fatal(err)
+	}
+	if len(failed) != 1 {
+		t.Fatalf("expected a send error, got %v", failed)
+	}
+	if failed[0].Err != ErrConnectionClosed ||
+		failed[0].Destination != ":1.9" ||
+		failed[0].Member != "Progress" {
+		t.Fatalf("unexpected send error %+v", failed[0])
+	}
+}
+
+func TestSendErrorSignal(t *testing.T) {
+	mgr := newPipeBusManager(t)
+	obj := mgr.NewObject("/foo", nil)
+	if err := obj.Emits("com.example.Foo", (*testSignals)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	var failed []*SendError
+	mgr.OnSendError = func(err *SendError) {
+		failed = append(failed, err)
+	}
+	mgr.conn.Close()
+	if err := obj.Emit("com.example.Foo", "Changed", "x", int32(1)); err == nil {
+		t.Fatal("expected emitting on a closed connection to fail")
+	}
+	if len(failed) != 1 {
+		t.Fatalf("expected a send error, got %v", failed)
+	}
+	if failed[0].Path != "/foo" || failed[0].Interface != "com.example.Foo" ||
+		failed[0].Member != "Changed" || failed[0].Destination != "" {
+		t.Fatalf("unexpected send error %+v", failed[0])
+	}
+}
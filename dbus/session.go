@@ -0,0 +1,95 @@
+package dbus
+
+import (
+	"reflect"
+
+	"github.com/jsouthworth/seriatim"
+	"github.com/jsouthworth/seriatim/events"
+)
+
+const fdtNameOwnerChanged = "NameOwnerChanged"
+
+// Session is the state kept for a client of the bus across its calls,
+// such as the agents registered by a caller. Methods taking a *Session
+// parameter, which is hidden from the bus, are given the session of
+// their caller.
+type Session struct {
+	Sender string
+	// Value is made by the open function given to TrackSessions.
+	// Methods of different objects may be called with the same
+	// session at the same time.
+	Value interface{}
+}
+
+var sessiontype = reflect.TypeOf((*Session)(nil))
+
+// TrackSessions keeps a Session for every sender calling a method that
+// takes one, with the Value made by open on the sender's first such
+// call, until the sender disconnects from the bus; close, which may be
+// nil, is then called with it. It is meant to be called before the
+// objects are exported. Without it, methods are given a new session
+// without a Value on every call.
+func (mgr *BusManager) TrackSessions(
+	open func(sender string) interface{},
+	close func(*Session),
+) {
+	mgr.sessions = newSessionTable(open, close)
+	mgr.events.Subscribe(mkSignalKey(fdtDBusName, fdtNameOwnerChanged),
+		mgr.sessions, fdtNameOwnerChanged, events.Block)
+	mgr.state.Call("AddMatchSignal", mgr.conn, fdtDBusName,
+		fdtNameOwnerChanged)
+}
+
+// session returns the session of sender.
+func (method *Method) session(sender string) *Session {
+	if sender == "" || method.bus == nil || method.bus.sessions == nil {
+		return &Session{Sender: sender}
+	}
+	rets, err := method.bus.sessions.Call("Get", sender)
+	if err != nil {
+		return &Session{Sender: sender}
+	}
+	return rets[0].(*Session)
+}
+
+type sessionTable struct {
+	open     func(sender string) interface{}
+	close    func(*Session)
+	sessions map[string]*Session
+}
+
+func newSessionTable(
+	open func(sender string) interface{},
+	close func(*Session),
+) seriatim.Sequent {
+	return seriatim.NewSequent(&sessionTable{
+		open:     open,
+		close:    close,
+		sessions: make(map[string]*Session),
+	})
+}
+
+func (t *sessionTable) Get(sender string) *Session {
+	s, ok := t.sessions[sender]
+	if !ok {
+		s = &Session{Sender: sender, Value: t.open(sender)}
+		t.sessions[sender] = s
+	}
+	return s
+}
+
+// NameOwnerChanged ends the session of a unique name whose connection
+// went away.
+func (t *sessionTable) NameOwnerChanged(name, oldOwner, newOwner string) {
+	if newOwner != "" {
+		return
+	}
+	s, ok := t.sessions[name]
+	if !ok {
+		return
+	}
+	delete(t.sessions, name)
+	if t.close != nil {
+		t.close(s)
+	}
+}
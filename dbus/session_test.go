@@ -0,0 +1,65 @@
+package dbus
+
+import (
+	"testing"
+)
+
+type agents struct {
+	n int
+}
+
+func (a *agents) Register(s *Session, name string) int {
+	registered := s.Value.(*[]string)
+	*registered = append(*registered, name)
+	return len(*registered)
+}
+
+type agentsIface interface {
+	Register(*Session, string) int
+}
+
+func TestSessions(t *testing.T) {
+	mgr := newPipeBusManager(t)
+	defer mgr.conn.Close()
+	opened := 0
+	closed := make(chan *Session, 1)
+	mgr.sessions = newSessionTable(func(sender string) interface{} {
+		opened++
+		return new([]string)
+	}, func(s *Session) {
+		closed <- s
+	})
+	defer mgr.sessions.Terminate(nil)
+
+	obj := mgr.NewObject("/agents", &agents{})
+	if err := obj.Implements("com.example.Agents", (*agentsIface)(nil)); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"a", "b"} {
+		outs, err := deliverCall(obj, "com.example.Agents", "Register", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if outs[0] != i+1 {
+			t.Fatalf("expected %d registrations, got %v", i+1, outs[0])
+		}
+	}
+	if opened != 1 {
+		t.Fatalf("expected one session, opened %d", opened)
+	}
+
+	_, err := mgr.sessions.Call(fdtNameOwnerChanged, ":1.9", ":1.9", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := <-closed; s.Sender != ":1.9" || len(*s.Value.(*[]string)) != 2 {
+		t.Fatalf("unexpected closed session %+v", s)
+	}
+	outs, err := deliverCall(obj, "com.example.Agents", "Register", "c")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outs[0] != 1 || opened != 2 {
+		t.Fatalf("expected a new session, got %v registrations", outs[0])
+	}
+}
@@ -0,0 +1,289 @@
+package debug
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"github.com/jsouthworth/seriatim"
+)
+
+const consoleHelp = `commands:
+  list [tag]                    running sequents, those tagged tag if given
+  top [n]                       the n sequents whose methods ran the longest
+  stats <id>                    statistics for a sequent
+  methods <id>                  methods that can be called
+  state <id>                    the value of a sequent between its requests
+  crashes                       recent crashes
+  call <id> <method> [args...]  call a method and print its results
+  cast <id> <method> [args...]  cast a method
+  help                          this text
+  quit                          close the console
+arguments are Go style literals: "strings", 42, 1.5, true; bare words are strings
+`
+
+var errQuit = errors.New("quit")
+
+// Console is a line oriented REPL for poking at the registered
+// sequents of a live process during development.
+type Console struct {
+	in     *bufio.Scanner
+	out    io.Writer
+	prompt string
+}
+
+func NewConsole(r io.Reader, w io.Writer) *Console {
+	return &Console{
+		in:     bufio.NewScanner(r),
+		out:    w,
+		prompt: "seriatim> ",
+	}
+}
+
+// Run reads and executes commands until the input is exhausted or
+// the quit command is given.
+func (c *Console) Run() error {
+	for {
+		fmt.Fprint(c.out, c.prompt)
+		if !c.in.Scan() {
+			return c.in.Err()
+		}
+		err := c.Exec(c.in.Text())
+		if err == errQuit {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintln(c.out, "error:", err)
+		}
+	}
+}
+
+// Exec runs a single command line.
+func (c *Console) Exec(line string) error {
+	words, err := splitLine(line)
+	if err != nil {
+		return err
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	switch words[0] {
+	case "list":
+		if len(words) > 2 {
+			return errors.New("usage: list [tag]")
+		}
+		sequents := seriatim.Sequents()
+		if len(words) == 2 {
+			sequents = seriatim.Tagged(words[1])
+		}
+		c.list(sequents)
+	case "top":
+		n := 10
+		if len(words) > 2 {
+			return errors.New("usage: top [n]")
+		}
+		if len(words) == 2 {
+			var err error
+			if n, err = strconv.Atoi(words[1]); err != nil || n < 1 {
+				return fmt.Errorf("invalid count %q", words[1])
+			}
+		}
+		c.top(n)
+	case "stats":
+		if len(words) != 2 {
+			return errors.New("usage: stats <id>")
+		}
+		s, err := lookup(words[1])
+		if err != nil {
+			return err
+		}
+		stats := s.Stats()
+		fmt.Fprintf(c.out, "id: %#x\ntype: %s\ntags: %s\nrunning: %v\nqueue: %d/%d\nprocessed: %d\nprocess time: %v\nallocated: %d bytes in %d objects\n",
+			stats.Id, stats.Type, strings.Join(stats.Tags, ", "),
+			stats.Running, stats.QueueLen, stats.QueueCap,
+			stats.Processed, stats.ProcessTime,
+			stats.AllocBytes, stats.Allocs)
+	case "methods":
+		if len(words) != 2 {
+			return errors.New("usage: methods <id>")
+		}
+		s, err := lookup(words[1])
+		if err != nil {
+			return err
+		}
+		for _, method := range s.Methods() {
+			fmt.Fprintln(c.out, method)
+		}
+	case "state":
+		if len(words) != 2 {
+			return errors.New("usage: state <id>")
+		}
+		s, err := lookup(words[1])
+		if err != nil {
+			return err
+		}
+		state, err := seriatim.GetState(s, func(val interface{}) interface{} {
+			return fmt.Sprintf("%+v", val)
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(c.out, state)
+	case "crashes":
+		for _, crash := range seriatim.Crashes() {
+			fmt.Fprintf(c.out, "%s %#x %s.%s: %v\n",
+				crash.Time.Format("15:04:05.000"), crash.Id,
+				crash.Type, crash.Method, crash.Reason)
+		}
+	case "call", "cast":
+		if len(words) < 3 {
+			return fmt.Errorf("usage: %s <id> <method> [args...]", words[0])
+		}
+		s, err := lookup(words[1])
+		if err != nil {
+			return err
+		}
+		inv := seriatim.Invocation{
+			Method: words[2],
+			Args:   make([]interface{}, 0, len(words)-3),
+		}
+		for _, word := range words[3:] {
+			inv.Args = append(inv.Args, parseLiteral(word))
+		}
+		if words[0] == "cast" {
+			return inv.Cast(s)
+		}
+		rets, err := inv.Call(s)
+		if err != nil {
+			return err
+		}
+		for _, ret := range rets {
+			fmt.Fprintf(c.out, "%#v\n", ret)
+		}
+	case "help":
+		fmt.Fprint(c.out, consoleHelp)
+	case "quit", "exit":
+		return errQuit
+	default:
+		return fmt.Errorf("unknown command %q, try help", words[0])
+	}
+	return nil
+}
+
+func (c *Console) list(sequents []seriatim.Sequent) {
+	w := tabwriter.NewWriter(c.out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTYPE\tRUNNING\tQUEUE\tPROCESSED")
+	for _, s := range sequents {
+		stats := s.Stats()
+		fmt.Fprintf(w, "%#x\t%s\t%v\t%d/%d\t%d\n",
+			stats.Id, stats.Type, stats.Running,
+			stats.QueueLen, stats.QueueCap, stats.Processed)
+	}
+	w.Flush()
+}
+
+// top lists the n sequents that spent the most time in their
+// methods, busiest first.
+func (c *Console) top(n int) {
+	sequents := seriatim.Sequents()
+	stats := make([]seriatim.Stats, 0, len(sequents))
+	for _, s := range sequents {
+		stats = append(stats, s.Stats())
+	}
+	sort.SliceStable(stats, func(i, j int) bool {
+		return stats[i].ProcessTime > stats[j].ProcessTime
+	})
+	if len(stats) > n {
+		stats = stats[:n]
+	}
+	w := tabwriter.NewWriter(c.out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tTYPE\tPROCESSED\tTIME\tALLOCATED")
+	for _, s := range stats {
+		fmt.Fprintf(w, "%#x\t%s\t%d\t%v\t%d\n",
+			s.Id, s.Type, s.Processed, s.ProcessTime, s.AllocBytes)
+	}
+	w.Flush()
+}
+
+func lookup(id string) (seriatim.Sequent, error) {
+	n, err := strconv.ParseUint(id, 0, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id %q", id)
+	}
+	s, ok := seriatim.Lookup(uintptr(n))
+	if !ok {
+		return nil, fmt.Errorf("no sequent with id %s", id)
+	}
+	return s, nil
+}
+
+// splitLine splits on white space keeping quoted strings, quotes
+// included, as single words.
+func splitLine(line string) ([]string, error) {
+	var words []string
+	var word strings.Builder
+	quoted := false
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted && r == '\\':
+			escaped = true
+		case r == '"':
+			quoted = !quoted
+		case !quoted && unicode.IsSpace(r):
+			if word.Len() > 0 {
+				words = append(words, word.String())
+				word.Reset()
+			}
+			continue
+		}
+		word.WriteRune(r)
+	}
+	if quoted {
+		return nil, errors.New("unterminated string")
+	}
+	if word.Len() > 0 {
+		words = append(words, word.String())
+	}
+	return words, nil
+}
+
+func parseLiteral(word string) interface{} {
+	if s, err := strconv.Unquote(word); err == nil {
+		return s
+	}
+	if word == "true" || word == "false" {
+		return word == "true"
+	}
+	if i, err := strconv.ParseInt(word, 0, 64); err == nil {
+		return int(i)
+	}
+	if f, err := strconv.ParseFloat(word, 64); err == nil {
+		return f
+	}
+	return word
+}
+
+// ServeConsole runs a Console for every connection accepted on l,
+// typically a unix socket, until l is closed.
+func ServeConsole(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			NewConsole(conn, conn).Run()
+		}()
+	}
+}
@@ -0,0 +1,115 @@
+package debug
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jsouthworth/seriatim"
+)
+
+type greeter struct {
+	greeted []string
+}
+
+func (g *greeter) Greet(name string, times int32) string {
+	g.greeted = append(g.greeted, name)
+	return strings.Repeat("hello "+name+" ", int(times))
+}
+
+func runConsole(t *testing.T, lines ...string) string {
+	var out bytes.Buffer
+	in := strings.NewReader(strings.Join(lines, "\n"))
+	if err := NewConsole(in, &out).Run(); err != nil {
+		t.Fatal(err)
+	}
+	return out.String()
+}
+
+func TestConsoleCall(t *testing.T) {
+	g := &greeter{}
+	s := seriatim.NewSequent(g)
+	defer s.Terminate(nil)
+	id := fmt.Sprintf("%#x", s.Id())
+
+	out := runConsole(t,
+		"list",
+		`call `+id+` Greet "big world" 2`,
+		"cast "+id+" Greet bob 1",
+		// calls are processed after the cast so stats see both
+		"call "+id+" Greet bob 0",
+		"stats "+id,
+		"top 1000",
+		"methods "+id,
+		"state "+id,
+		"call "+id+" Missing",
+		"quit",
+		"list",
+	)
+	for _, expected := range []string{
+		"*debug.greeter",
+		`"hello big world hello big world "`,
+		"processed: 3",
+		"process time: ",
+		"ALLOCATED",
+		"Greet(string, int32) string",
+		"&{greeted:[big world bob bob]}",
+		"error: Unknown method",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("missing %q in:\n%s", expected, out)
+		}
+	}
+	if strings.Count(out, "*debug.greeter") != 3 {
+		t.Errorf("commands ran after quit:\n%s", out)
+	}
+}
+
+func TestConsoleErrors(t *testing.T) {
+	out := runConsole(t, "bogus", "stats nope", "stats 0x1", `call 1 "open`,
+		"top 0")
+	for _, expected := range []string{
+		`unknown command "bogus"`,
+		`invalid count "0"`,
+		`invalid id "nope"`,
+		"no sequent with id 0x1",
+		"unterminated string",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("missing %q in:\n%s", expected, out)
+		}
+	}
+}
+
+func TestParseLiteral(t *testing.T) {
+	tests := map[string]interface{}{
+		`"a b"`: "a b",
+		"word":  "word",
+		"true":  true,
+		"1":     1,
+		"0x10":  16,
+		"1.5":   1.5,
+	}
+	for word, expected := range tests {
+		if got := parseLiteral(word); !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %#v, got %#v", word, expected, got)
+		}
+	}
+}
+
+func TestConsoleTags(t *testing.T) {
+	tagged := seriatim.NewSequent(&greeter{}, seriatim.WithTags("greeter"))
+	other := seriatim.NewSequent(&struct{ greeter }{})
+	defer tagged.Terminate(nil)
+	defer other.Terminate(nil)
+	out := runConsole(t, "list greeter", "stats "+fmt.Sprintf("%#x", tagged.Id()))
+	if strings.Count(out, "*debug.greeter") != 2 ||
+		strings.Contains(out, "struct") {
+		t.Errorf("untagged sequent listed:\n%s", out)
+	}
+	if !strings.Contains(out, "tags: greeter") {
+		t.Errorf("missing tags in:\n%s", out)
+	}
+}
@@ -0,0 +1,120 @@
+// Package debug exposes the sequents registered with seriatim over
+// HTTP, alongside net/http/pprof and friends.
+//
+//	http.Handle("/debug/seriatim", debug.Handler())
+//
+// The page is HTML by default; JSON is served when the request has
+// format=json in its query or accepts application/json.
+package debug
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/jsouthworth/seriatim"
+)
+
+type sequentInfo struct {
+	Id        uintptr `json:"id"`
+	Type      string  `json:"type"`
+	Running   bool    `json:"running"`
+	QueueLen  int     `json:"queue_len"`
+	QueueCap  int     `json:"queue_cap"`
+	Processed uint64  `json:"processed"`
+	// ProcessTime is in nanoseconds.
+	ProcessTime time.Duration `json:"process_time"`
+	AllocBytes  uint64        `json:"alloc_bytes"`
+}
+
+type crashInfo struct {
+	Id     uintptr   `json:"id"`
+	Type   string    `json:"type"`
+	Method string    `json:"method"`
+	Reason string    `json:"reason"`
+	Time   time.Time `json:"time"`
+}
+
+type report struct {
+	Sequents []sequentInfo `json:"sequents"`
+	Crashes  []crashInfo   `json:"crashes"`
+}
+
+func newReport() *report {
+	sequents := seriatim.Sequents()
+	crashes := seriatim.Crashes()
+	r := &report{
+		Sequents: make([]sequentInfo, 0, len(sequents)),
+		Crashes:  make([]crashInfo, 0, len(crashes)),
+	}
+	for _, s := range sequents {
+		stats := s.Stats()
+		r.Sequents = append(r.Sequents, sequentInfo{
+			Id:          stats.Id,
+			Type:        stats.Type,
+			Running:     stats.Running,
+			QueueLen:    stats.QueueLen,
+			QueueCap:    stats.QueueCap,
+			Processed:   stats.Processed,
+			ProcessTime: stats.ProcessTime,
+			AllocBytes:  stats.AllocBytes,
+		})
+	}
+	// most recent first
+	for i := len(crashes) - 1; i >= 0; i-- {
+		c := crashes[i]
+		var reason string
+		if c.Reason != nil {
+			reason = c.Reason.Error()
+		}
+		r.Crashes = append(r.Crashes, crashInfo{
+			Id:     c.Id,
+			Type:   c.Type,
+			Method: c.Method,
+			Reason: reason,
+			Time:   c.Time,
+		})
+	}
+	return r
+}
+
+var page = template.Must(template.New("seriatim").Parse(`<!DOCTYPE html>
+<html>
+<head><title>seriatim</title></head>
+<body>
+<h1>Sequents ({{len .Sequents}})</h1>
+<table border="1">
+<tr><th>Id</th><th>Type</th><th>Running</th><th>Queue</th><th>Processed</th><th>Busy</th><th>Allocated</th></tr>
+{{range .Sequents}}<tr><td>{{printf "%#x" .Id}}</td><td>{{.Type}}</td><td>{{.Running}}</td><td>{{.QueueLen}}/{{.QueueCap}}</td><td>{{.Processed}}</td><td>{{.ProcessTime}}</td><td>{{.AllocBytes}}</td></tr>
+{{end}}</table>
+<h1>Recent crashes</h1>
+<table border="1">
+<tr><th>Time</th><th>Id</th><th>Type</th><th>Method</th><th>Reason</th></tr>
+{{range .Crashes}}<tr><td>{{.Time.Format "2006-01-02T15:04:05.000Z07:00"}}</td><td>{{printf "%#x" .Id}}</td><td>{{.Type}}</td><td>{{.Method}}</td><td>{{.Reason}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+func wantsJSON(r *http.Request) bool {
+	if r.URL.Query().Get("format") == "json" {
+		return true
+	}
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// Handler lists the running sequents and the most recent crashes.
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := newReport()
+		if wantsJSON(r) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(report)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		page.Execute(w, report)
+	})
+}
@@ -0,0 +1,64 @@
+package debug
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jsouthworth/seriatim"
+)
+
+type value struct {
+	pings int
+}
+
+func (v *value) Ping() {
+	v.pings++
+}
+
+func (v *value) Crash() {
+	panic("crashed")
+}
+
+func TestHandlerJSON(t *testing.T) {
+	s := seriatim.NewSequent(&value{})
+	defer s.Terminate(nil)
+	if _, err := s.Call("Ping"); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/?format=json", nil))
+	var r report
+	if err := json.NewDecoder(rec.Body).Decode(&r); err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, info := range r.Sequents {
+		if info.Id != s.Id() {
+			continue
+		}
+		found = true
+		if info.Type != "*debug.value" || info.Processed != 1 || !info.Running {
+			t.Fatalf("unexpected info %+v", info)
+		}
+	}
+	if !found {
+		t.Fatal("sequent missing from report")
+	}
+}
+
+func TestHandlerHTMLCrash(t *testing.T) {
+	s := seriatim.NewSequent(&value{})
+	s.Call("Crash")
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("unexpected content type %s", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "crashed") {
+		t.Fatalf("crash missing from page:\n%s", rec.Body.String())
+	}
+}
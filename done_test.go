@@ -0,0 +1,42 @@
+package seriatim
+
+import (
+	"errors"
+	"testing"
+)
+
+var errExploded = errors.New("exploded")
+
+type exploding struct {
+	n int
+}
+
+func (e *exploding) Explode() {
+	panic(errExploded)
+}
+
+func TestDoneAndErr(t *testing.T) {
+	g := &gated{release: make(chan struct{})}
+	s := NewSequent(g)
+	select {
+	case <-s.Done():
+		t.Fatal("Done closed while running")
+	default:
+	}
+	if s.Err() != nil {
+		t.Fatalf("unexpected reason %v while running", s.Err())
+	}
+	reason := errors.New("finished")
+	s.Terminate(reason)
+	<-s.Done()
+	if s.Err() != reason {
+		t.Fatalf("expected %v, got %v", reason, s.Err())
+	}
+
+	crashed := NewSequent(&exploding{})
+	crashed.Call("Explode")
+	<-crashed.Done()
+	if crashed.Err() != errExploded {
+		t.Fatalf("expected the crash reason, got %v", crashed.Err())
+	}
+}
@@ -0,0 +1,339 @@
+// Package events routes events published on named topics to the
+// sequents subscribed to them.
+//
+// Topic names are dot separated, like D-Bus interface and member
+// names. Subscriptions name the topics they receive with a pattern in
+// the syntax of path.Match, so "com.example.Device.*" receives every
+// topic under com.example.Device and "*" receives them all.
+//
+// Each subscription buffers the events published to it and casts them
+// to its sequent in the order they were published, so a busy
+// subscriber does not hold up the publisher or the other subscribers.
+// What happens once the buffer is full is decided by the
+// subscription's Policy.
+//
+// Topics carrying a single value of a known type are declared with
+// NewTopic:
+//
+//	temperature := events.NewTopic[float64](bus, "sensor.Temperature")
+//	temperature.Subscribe(display, "ShowTemperature", events.DropOldest)
+//	temperature.Publish(21.5)
+package events
+
+import (
+	"errors"
+	"path"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jsouthworth/seriatim"
+)
+
+// DefaultBuffer is the number of events buffered for a subscription
+// unless the bus sets another.
+const DefaultBuffer = 64
+
+// Policy decides what happens to events published to a subscription
+// whose buffer is full.
+type Policy int
+
+const (
+	// Block makes the publisher wait until there is room, or until
+	// the BlockTimeout of the bus has passed and the event is
+	// discarded.
+	Block Policy = iota
+	// DropNewest discards the event being published.
+	DropNewest
+	// DropOldest discards the oldest buffered event to make room.
+	DropOldest
+	// Coalesce replaces the buffered event of the same topic with
+	// the one being published, keeping its place, or discards the
+	// oldest buffered event if there is none. Subscribers only see
+	// the latest of the events of a topic published while they were
+	// busy.
+	Coalesce
+)
+
+type Bus struct {
+	// Buffer is the size of the buffer of subscriptions made after
+	// it is set.
+	Buffer int
+	// BlockTimeout bounds how long publishing waits for room in the
+	// buffer of the Block subscriptions made after it is set. Zero
+	// waits for as long as it takes.
+	BlockTimeout time.Duration
+
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+func NewBus() *Bus {
+	return &Bus{
+		Buffer: DefaultBuffer,
+		subs:   make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscribe casts method to s with the arguments of every event
+// published on a topic matching pattern.
+func (b *Bus) Subscribe(
+	pattern string,
+	s seriatim.Sequent,
+	method string,
+	policy Policy,
+) (*Subscription, error) {
+	return b.subscribe(pattern, s, method, policy, false)
+}
+
+// SubscribeTopic is like Subscribe but casts the name of the topic
+// before the arguments of the event, for wildcard subscribers that
+// need to tell topics apart. method can take the arguments as a
+// variadic parameter:
+//
+//	func (l *logger) Event(topic string, args ...interface{})
+func (b *Bus) SubscribeTopic(
+	pattern string,
+	s seriatim.Sequent,
+	method string,
+	policy Policy,
+) (*Subscription, error) {
+	return b.subscribe(pattern, s, method, policy, true)
+}
+
+func (b *Bus) subscribe(
+	pattern string,
+	s seriatim.Sequent,
+	method string,
+	policy Policy,
+	withTopic bool,
+) (*Subscription, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	buffer := b.Buffer
+	if buffer < 1 {
+		buffer = DefaultBuffer
+	}
+	sub := &Subscription{
+		bus:       b,
+		pattern:   pattern,
+		sequent:   s,
+		method:    method,
+		policy:    policy,
+		withTopic: withTopic,
+		buffer:    buffer,
+		timeout:   b.BlockTimeout,
+		wake:      make(chan struct{}, 1),
+		space:     make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	go sub.forward()
+	return sub, nil
+}
+
+// Publish delivers an event with args to the subscriptions matching
+// topic.
+func (b *Bus) Publish(topic string, args ...interface{}) {
+	b.mu.RLock()
+	matched := make([]*Subscription, 0, len(b.subs))
+	for sub := range b.subs {
+		if ok, _ := path.Match(sub.pattern, topic); ok {
+			matched = append(matched, sub)
+		}
+	}
+	b.mu.RUnlock()
+	for _, sub := range matched {
+		ev := event{topic: topic, args: args}
+		if sub.withTopic {
+			ev.args = []interface{}{topic, args}
+		}
+		sub.deliver(ev)
+	}
+}
+
+type event struct {
+	topic string
+	args  []interface{}
+}
+
+type Subscription struct {
+	dropped   uint64
+	bus       *Bus
+	pattern   string
+	sequent   seriatim.Sequent
+	method    string
+	policy    Policy
+	withTopic bool
+	buffer    int
+	timeout   time.Duration
+
+	mu    sync.Mutex
+	queue []event
+	// wake tells forward that events were queued, space tells the
+	// blocked publishers that one was taken.
+	wake  chan struct{}
+	space chan struct{}
+	done  chan struct{}
+	once  sync.Once
+}
+
+// Dropped reports the number of events discarded by the policy.
+func (sub *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&sub.dropped)
+}
+
+// Cancel stops the delivery of events, including the buffered ones.
+// Subscriptions are canceled when their sequent stops.
+func (sub *Subscription) Cancel() {
+	sub.once.Do(func() {
+		sub.bus.mu.Lock()
+		delete(sub.bus.subs, sub)
+		sub.bus.mu.Unlock()
+		close(sub.done)
+	})
+}
+
+func (sub *Subscription) deliver(ev event) {
+	var expired <-chan time.Time
+	sub.mu.Lock()
+	for len(sub.queue) >= sub.buffer {
+		switch sub.policy {
+		case DropNewest:
+			sub.mu.Unlock()
+			atomic.AddUint64(&sub.dropped, 1)
+			return
+		case Coalesce:
+			for i := range sub.queue {
+				if sub.queue[i].topic == ev.topic {
+					sub.queue[i] = ev
+					sub.mu.Unlock()
+					atomic.AddUint64(&sub.dropped, 1)
+					return
+				}
+			}
+			fallthrough
+		case DropOldest:
+			sub.queue = sub.queue[1:]
+			atomic.AddUint64(&sub.dropped, 1)
+		default:
+			sub.mu.Unlock()
+			if expired == nil && sub.timeout > 0 {
+				timer := time.NewTimer(sub.timeout)
+				defer timer.Stop()
+				expired = timer.C
+			}
+			select {
+			case <-sub.space:
+			case <-sub.done:
+				return
+			case <-expired:
+				atomic.AddUint64(&sub.dropped, 1)
+				return
+			}
+			sub.mu.Lock()
+		}
+	}
+	sub.queue = append(sub.queue, ev)
+	room := len(sub.queue) < sub.buffer
+	sub.mu.Unlock()
+	signal(sub.wake)
+	if room {
+		// pass the room on to the next blocked publisher
+		signal(sub.space)
+	}
+}
+
+// signal wakes the one waiting on ch, if it is not already woken.
+func signal(ch chan struct{}) {
+	select {
+	case ch <- struct{}{}:
+	default:
+	}
+}
+
+// next takes the oldest buffered event.
+func (sub *Subscription) next() (event, bool) {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if len(sub.queue) == 0 {
+		return event{}, false
+	}
+	ev := sub.queue[0]
+	sub.queue[0] = event{}
+	sub.queue = sub.queue[1:]
+	return ev, true
+}
+
+func (sub *Subscription) forward() {
+	for {
+		select {
+		case <-sub.wake:
+		case <-sub.done:
+			return
+		}
+		for {
+			ev, ok := sub.next()
+			if !ok {
+				break
+			}
+			signal(sub.space)
+			select {
+			case <-sub.done:
+				return
+			default:
+			}
+			err := sub.sequent.Cast(sub.method, ev.args...)
+			if errors.Is(err, seriatim.ErrSequentStop) {
+				sub.Cancel()
+				return
+			}
+		}
+	}
+}
+
+// Topic is a topic whose events are a single value of type T.
+type Topic[T any] struct {
+	bus  *Bus
+	name string
+}
+
+func NewTopic[T any](bus *Bus, name string) Topic[T] {
+	return Topic[T]{bus: bus, name: name}
+}
+
+func (t Topic[T]) Name() string {
+	return t.name
+}
+
+func (t Topic[T]) Publish(event T) {
+	t.bus.Publish(t.name, event)
+}
+
+// Subscribe casts method, which takes a T, to s with every event
+// published on the topic.
+func (t Topic[T]) Subscribe(
+	s seriatim.Sequent,
+	method string,
+	policy Policy,
+) *Subscription {
+	// escaped names are always valid patterns
+	sub, _ := t.bus.Subscribe(escape(t.name), s, method, policy)
+	return sub
+}
+
+// escape quotes the characters of name that are special in patterns.
+func escape(name string) string {
+	out := make([]byte, 0, len(name))
+	for i := 0; i < len(name); i++ {
+		switch name[i] {
+		case '*', '?', '[', '\\':
+			out = append(out, '\\')
+		}
+		out = append(out, name[i])
+	}
+	return string(out)
+}
@@ -0,0 +1,188 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jsouthworth/seriatim"
+)
+
+type recorder struct {
+	started chan struct{}
+	release chan struct{}
+	events  []interface{}
+}
+
+func (r *recorder) Temperature(t float64) {
+	r.events = append(r.events, t)
+}
+
+func (r *recorder) Event(topic string, args ...interface{}) {
+	r.events = append(r.events, topic)
+}
+
+func (r *recorder) Value(n int) {
+	r.events = append(r.events, n)
+}
+
+func (r *recorder) Block() {
+	close(r.started)
+	<-r.release
+}
+
+func (r *recorder) Events() []interface{} {
+	return r.events
+}
+
+func waitEvents(t *testing.T, s seriatim.Sequent, n int) []interface{} {
+	deadline := time.Now().Add(time.Second)
+	for {
+		rets, err := s.Call("Events")
+		if err != nil {
+			t.Fatal(err)
+		}
+		events := rets[0].([]interface{})
+		if len(events) >= n {
+			return events
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d events, want %d", len(events), n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestTopic(t *testing.T) {
+	bus := NewBus()
+	s := seriatim.NewSequent(&recorder{})
+	defer s.Terminate(nil)
+	temperature := NewTopic[float64](bus, "sensor.Temperature")
+	temperature.Subscribe(s, "Temperature", Block)
+	NewTopic[float64](bus, "sensor.Humidity").Publish(50)
+	temperature.Publish(21.5)
+	temperature.Publish(22)
+	events := waitEvents(t, s, 2)
+	if !reflect.DeepEqual(events, []interface{}{21.5, 22.0}) {
+		t.Fatalf("unexpected events %v", events)
+	}
+}
+
+func TestWildcard(t *testing.T) {
+	bus := NewBus()
+	s := seriatim.NewSequent(&recorder{})
+	defer s.Terminate(nil)
+	if _, err := bus.SubscribeTopic("sensor.*", s, "Event", Block); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bus.Subscribe("[", s, "Event", Block); err == nil {
+		t.Fatal("invalid pattern accepted")
+	}
+	bus.Publish("sensor.Temperature", 21.5)
+	bus.Publish("motor.Speed", 10)
+	bus.Publish("sensor.Humidity", 50, "%")
+	events := waitEvents(t, s, 2)
+	want := []interface{}{"sensor.Temperature", "sensor.Humidity"}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("unexpected events %v", events)
+	}
+}
+
+func testDrop(t *testing.T, policy Policy) []interface{} {
+	bus := NewBus()
+	bus.Buffer = 2
+	r := &recorder{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	s := seriatim.NewSequent(r)
+	defer s.Terminate(nil)
+	sub, _ := bus.Subscribe("values", s, "Value", policy)
+	go s.Call("Block")
+	<-r.started
+	for i := 0; i < 10; i++ {
+		bus.Publish("values", i)
+	}
+	close(r.release)
+	// at most one event in the mailbox, one being cast and the
+	// buffered ones get through
+	if sub.Dropped() < 6 {
+		t.Fatalf("dropped %d of 10 events", sub.Dropped())
+	}
+	return waitEvents(t, s, 10-int(sub.Dropped()))
+}
+
+func TestDropNewest(t *testing.T) {
+	events := testDrop(t, DropNewest)
+	if events[0] != 0 {
+		t.Fatalf("first event dropped %v", events)
+	}
+}
+
+func TestDropOldest(t *testing.T) {
+	events := testDrop(t, DropOldest)
+	if events[len(events)-1] != 9 {
+		t.Fatalf("last event dropped %v", events)
+	}
+}
+
+func TestSequentStopCancels(t *testing.T) {
+	bus := NewBus()
+	s := seriatim.NewSequent(&recorder{})
+	sub, _ := bus.Subscribe("values", s, "Value", Block)
+	s.Terminate(nil)
+	bus.Publish("values", 1)
+	select {
+	case <-sub.done:
+	case <-time.After(time.Second):
+		t.Fatal("subscription not canceled")
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	bus := NewBus()
+	bus.Buffer = 2
+	r := &recorder{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	s := seriatim.NewSequent(r)
+	defer s.Terminate(nil)
+	sub, _ := bus.Subscribe("sensor.*", s, "Value", Coalesce)
+	go s.Call("Block")
+	<-r.started
+	for i := 0; i < 10; i++ {
+		bus.Publish("sensor.A", i)
+		bus.Publish("sensor.B", 100+i)
+	}
+	close(r.release)
+	events := waitEvents(t, s, 20-int(sub.Dropped()))
+	last := events[len(events)-2:]
+	if !reflect.DeepEqual(last, []interface{}{9, 109}) &&
+		!reflect.DeepEqual(last, []interface{}{109, 9}) {
+		t.Fatalf("latest events not delivered %v", events)
+	}
+}
+
+func TestBlockTimeout(t *testing.T) {
+	bus := NewBus()
+	bus.Buffer = 1
+	bus.BlockTimeout = 10 * time.Millisecond
+	r := &recorder{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	s := seriatim.NewSequent(r)
+	defer s.Terminate(nil)
+	sub, _ := bus.Subscribe("values", s, "Value", Block)
+	go s.Call("Block")
+	<-r.started
+	for i := 0; i < 5; i++ {
+		bus.Publish("values", i)
+	}
+	if sub.Dropped() == 0 {
+		t.Fatal("blocked publishing did not time out")
+	}
+	close(r.release)
+	waitEvents(t, s, 5-int(sub.Dropped()))
+}
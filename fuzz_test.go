@@ -0,0 +1,121 @@
+package seriatim
+
+import (
+	"reflect"
+	"testing"
+)
+
+type fuzzTarget struct{}
+
+func (fuzzTarget) Int(a int, b int64) int              { return a + int(b) }
+func (fuzzTarget) String(s string, b []byte) string    { return s + string(b) }
+func (fuzzTarget) Ptr(p *int, m map[string]int) bool   { return p == nil && m == nil }
+func (fuzzTarget) Iface(v interface{}, err error) bool { return v == nil }
+func (fuzzTarget) Array(a [4]int, p *[2]int) int {
+	if p == nil {
+		return a[3]
+	}
+	return a[3] + p[1]
+}
+func (fuzzTarget) Variadic(prefix string, n ...int) int { return len(n) }
+func (fuzzTarget) None()                                {}
+
+// fuzzValues are the arguments a fuzz input picks from.
+var fuzzValues = []interface{}{
+	nil,
+	0,
+	int8(-1),
+	uint64(1 << 63),
+	3.5,
+	"",
+	"string",
+	true,
+	[]byte("bytes"),
+	[]int{},
+	[]int{1, 2, 3, 4, 5},
+	[4]int{},
+	(*int)(nil),
+	new(int),
+	map[string]int{},
+	struct{}{},
+	fuzzTarget{},
+	ErrSequentStop,
+	[]interface{}{1},
+}
+
+func fuzzArguments(data []byte) []interface{} {
+	args := make([]interface{}, 0, len(data))
+	for _, b := range data {
+		args = append(args, fuzzValues[int(b)%len(fuzzValues)])
+	}
+	return args
+}
+
+// Arguments that are accepted must be callable and those that are not
+// must be rejected with an error rather than a panic.
+func FuzzProcessMethodArguments(f *testing.F) {
+	f.Add(uint8(0), []byte{1, 2})
+	f.Add(uint8(1), []byte{6, 8})
+	f.Add(uint8(2), []byte{0, 0})
+	f.Add(uint8(4), []byte{10, 10})
+	f.Add(uint8(5), []byte{6, 10})
+	methods := GetMethods(fuzzTarget{})
+	names := make([]string, 0, len(methods))
+	for name := range methods {
+		names = append(names, name)
+	}
+	converted := convertMethods(methods)
+	f.Fuzz(func(t *testing.T, which uint8, data []byte) {
+		name := names[int(which)%len(names)]
+		args, err := processMethodArguments(name, converted[name], fuzzArguments(data)...)
+		if err != nil {
+			return
+		}
+		callMethod(converted[name], args)
+	})
+}
+
+func FuzzConvertMethods(f *testing.F) {
+	candidates := []interface{}{
+		nil,
+		"not a function",
+		42,
+		(func())(nil),
+		func() {},
+		func(int) int { return 0 },
+		fuzzTarget{}.Variadic,
+	}
+	f.Add([]byte{0, 1, 2, 3, 4})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		table := make(map[string]interface{})
+		for i, b := range data {
+			table[string(rune('a'+i%26))] = candidates[int(b)%len(candidates)]
+		}
+		for name, method := range convertMethods(table) {
+			if _, ok := table[name]; !ok {
+				t.Fatalf("%s is not in the table", name)
+			}
+			if method.Kind() != reflect.Func || method.IsNil() {
+				t.Fatalf("%s is not callable", name)
+			}
+		}
+	})
+}
+
+func TestProcessMethodArgumentsNil(t *testing.T) {
+	methods := convertMethods(GetMethods(fuzzTarget{}))
+	args, err := processMethodArguments("Ptr", methods["Ptr"], nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret := callMethod(methods["Ptr"], args); !ret[0].Bool() {
+		t.Fatal("nil arguments should be passed as zero values")
+	}
+	if _, err := processMethodArguments("Int", methods["Int"], nil, 1); err == nil {
+		t.Fatal("nil is not an int")
+	}
+	if _, err := processMethodArguments("Array", methods["Array"],
+		[]int{1, 2, 3}, new([2]int)); err == nil {
+		t.Fatal("short slices cannot be converted to arrays")
+	}
+}
@@ -0,0 +1,89 @@
+package seriatim
+
+import (
+	"context"
+	"reflect"
+	"time"
+)
+
+// Go runs fn on a goroutine of its own under supervision, such as a
+// poller or a listener, and returns the sequent standing for it, which
+// has no methods. The sequent crashes, reporting the crash and telling
+// supervisor as a crashing sequent does, when fn returns an error or
+// panics, and stops with a nil reason when fn returns nil. Terminating
+// the sequent cancels the context given to fn and waits for fn to
+// return. This lets the goroutines of a daemon be children of a
+// supervisor.Supervisor alongside its sequents:
+//
+//	supervisor.ChildSpec{
+//		Name: "poller",
+//		Start: func(sup seriatim.Supervisor) seriatim.Sequent {
+//			return seriatim.Go(sup, poll)
+//		},
+//	}
+func Go(
+	supervisor Supervisor,
+	fn func(ctx context.Context) error,
+	opts ...Option,
+) Sequent {
+	ctx, cancel := context.WithCancel(context.Background())
+	return NewSupervisedSequentTable(&routine{
+		fn:     fn,
+		ctx:    ctx,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}, nil, supervisor, opts...)
+}
+
+// routine is the value of a sequent made by Go.
+type routine struct {
+	fn     func(ctx context.Context) error
+	ctx    context.Context
+	cancel context.CancelFunc
+	// done is closed once fn has returned.
+	done chan struct{}
+}
+
+func (r *routine) OnStart(s Sequent) {
+	a := s.(*sequent)
+	go func() {
+		defer close(r.done)
+		r.exited(a, r.run())
+	}()
+}
+
+// OnTerminate stops fn, keeping the sequent from stopping before fn
+// has.
+func (r *routine) OnTerminate(reason error) {
+	r.cancel()
+	<-r.done
+}
+
+func (r *routine) run() (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = panicReason(rec)
+		}
+	}()
+	return r.fn(r.ctx)
+}
+
+// exited ends the sequent a after fn returned err on its own.
+func (r *routine) exited(a *sequent, err error) {
+	if r.ctx.Err() != nil {
+		// stopped by OnTerminate
+		return
+	}
+	if err == nil {
+		a.Terminate(nil)
+		return
+	}
+	// crashes the sequent on its goroutine, as a method would
+	a.enqueue(&request{
+		method: reflect.ValueOf(func() {
+			panic(err)
+		}),
+		made:  time.Now(),
+		owner: a,
+	}, nil)
+}
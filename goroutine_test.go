@@ -0,0 +1,69 @@
+package seriatim
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestGo(t *testing.T) {
+	failed := errors.New("Poll failed")
+	terminated := make(reasons, 1)
+	s := Go(terminated, func(ctx context.Context) error {
+		return failed
+	})
+	if reason := <-terminated; reason != failed {
+		t.Fatalf("expected the error of fn, got %v", reason)
+	}
+	<-s.Done()
+	if s.State() != StateCrashed || s.Err() != failed {
+		t.Fatalf("expected a crash with the error of fn, got %v, %v",
+			s.State(), s.Err())
+	}
+
+	s = Go(terminated, func(ctx context.Context) error {
+		panic("poller panicked")
+	})
+	if reason := <-terminated; reason == nil ||
+		reason.Error() != "poller panicked" {
+		t.Fatalf("expected the panic of fn, got %v", reason)
+	}
+
+	s = Go(terminated, func(ctx context.Context) error {
+		return nil
+	})
+	if reason := <-terminated; reason != nil {
+		t.Fatalf("expected no reason, got %v", reason)
+	}
+	<-s.Done()
+	if s.State() != StateStopped {
+		t.Fatalf("expected a stopped sequent, got %v", s.State())
+	}
+}
+
+func TestGoTerminate(t *testing.T) {
+	terminated := make(reasons, 1)
+	started := make(chan struct{})
+	returned := make(chan struct{})
+	s := Go(terminated, func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		close(returned)
+		return ctx.Err()
+	})
+	<-started
+	finished := errors.New("finished")
+	s.Terminate(finished)
+	<-s.Done()
+	select {
+	case <-returned:
+	default:
+		t.Fatal("stopped before fn returned")
+	}
+	if reason := <-terminated; reason != finished {
+		t.Fatalf("expected the reason given to Terminate, got %v", reason)
+	}
+	if s.State() != StateStopped || s.Err() != finished {
+		t.Fatalf("unexpected end %v, %v", s.State(), s.Err())
+	}
+}
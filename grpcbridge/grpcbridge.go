@@ -0,0 +1,271 @@
+// Package grpcbridge serves sequents over gRPC.
+//
+// Sequents can be reached through a generic service, seriatim.Sequents,
+// whose Call and Cast methods carry a sequent name, a method name and
+// JSON packed arguments, or through the service descriptors generated
+// from a protobuf definition by registering a sequent whose methods
+// have the same signatures as the generated server interface.
+package grpcbridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/jsouthworth/seriatim"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/status"
+)
+
+const serviceName = "seriatim.Sequents"
+
+// Codec encodes the messages of the generic service. Clients select
+// it with grpc.CallContentSubtype(Codec.Name()).
+var Codec encoding.Codec = jsonCodec{}
+
+func init() {
+	encoding.RegisterCodec(Codec)
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (jsonCodec) Name() string {
+	return "json"
+}
+
+type Request struct {
+	Sequent string            `json:"sequent"`
+	Method  string            `json:"method"`
+	Args    []json.RawMessage `json:"args,omitempty"`
+}
+
+type Response struct {
+	Results []json.RawMessage `json:"results,omitempty"`
+}
+
+// Server implements the generic service for a set of named sequents.
+type Server struct {
+	mu       sync.RWMutex
+	sequents map[string]seriatim.Sequent
+}
+
+func NewServer() *Server {
+	return &Server{
+		sequents: make(map[string]seriatim.Sequent),
+	}
+}
+
+func (srv *Server) Register(name string, s seriatim.Sequent) {
+	srv.mu.Lock()
+	srv.sequents[name] = s
+	srv.mu.Unlock()
+}
+
+func (srv *Server) Unregister(name string) {
+	srv.mu.Lock()
+	delete(srv.sequents, name)
+	srv.mu.Unlock()
+}
+
+// Attach registers the generic service with a gRPC server.
+func (srv *Server) Attach(s grpc.ServiceRegistrar) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+func (srv *Server) lookup(req *Request) (seriatim.Sequent, []interface{}, error) {
+	srv.mu.RLock()
+	s, ok := srv.sequents[req.Sequent]
+	srv.mu.RUnlock()
+	if !ok {
+		return nil, nil, status.Errorf(codes.NotFound,
+			"no sequent named %q", req.Sequent)
+	}
+	args := make([]interface{}, 0, len(req.Args))
+	for i, raw := range req.Args {
+		var arg interface{}
+		if err := json.Unmarshal(raw, &arg); err != nil {
+			return nil, nil, status.Errorf(codes.InvalidArgument,
+				"argument %d: %v", i, err)
+		}
+		args = append(args, arg)
+	}
+	return s, args, nil
+}
+
+func (srv *Server) call(ctx context.Context, req *Request) (*Response, error) {
+	s, args, err := srv.lookup(req)
+	if err != nil {
+		return nil, err
+	}
+	values, err := s.Call(req.Method, args...)
+	if err != nil {
+		return nil, statusFor(err)
+	}
+	if err := lastError(values); err != nil {
+		return nil, statusFor(err)
+	}
+	resp := &Response{Results: make([]json.RawMessage, 0, len(values))}
+	for _, value := range values {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		resp.Results = append(resp.Results, raw)
+	}
+	return resp, nil
+}
+
+func (srv *Server) cast(ctx context.Context, req *Request) (*Response, error) {
+	s, args, err := srv.lookup(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Cast(req.Method, args...); err != nil {
+		return nil, statusFor(err)
+	}
+	return &Response{}, nil
+}
+
+func lastError(values []interface{}) error {
+	if n := len(values); n > 0 {
+		if err, ok := values[n-1].(error); ok {
+			return err
+		}
+	}
+	return nil
+}
+
+func statusFor(err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	switch {
+	case err == seriatim.ErrUnknownMethod:
+		return status.Error(codes.Unimplemented, err.Error())
+	case errors.Is(err, seriatim.ErrSequentStop):
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+// methodHandler is the type of grpc.MethodDesc.Handler.
+type methodHandler = func(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error)
+
+func genericHandler(
+	fn func(*Server, context.Context, *Request) (*Response, error),
+	method string,
+) methodHandler {
+	return func(
+		srv interface{},
+		ctx context.Context,
+		dec func(interface{}) error,
+		interceptor grpc.UnaryServerInterceptor,
+	) (interface{}, error) {
+		in := new(Request)
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+		if interceptor == nil {
+			return fn(srv.(*Server), ctx, in)
+		}
+		info := &grpc.UnaryServerInfo{
+			Server:     srv,
+			FullMethod: "/" + serviceName + "/" + method,
+		}
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			return fn(srv.(*Server), ctx, req.(*Request))
+		}
+		return interceptor(ctx, in, info, handler)
+	}
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*interface{})(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Call",
+			Handler:    genericHandler((*Server).call, "Call"),
+		},
+		{
+			MethodName: "Cast",
+			Handler:    genericHandler((*Server).cast, "Cast"),
+		},
+	},
+	Metadata: "seriatim",
+}
+
+// Client invokes the generic service.
+type Client struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewClient(cc grpc.ClientConnInterface) *Client {
+	return &Client{cc: cc}
+}
+
+func (c *Client) invoke(
+	ctx context.Context,
+	method, sequent, name string,
+	args []interface{},
+) (*Response, error) {
+	req := &Request{
+		Sequent: sequent,
+		Method:  name,
+		Args:    make([]json.RawMessage, 0, len(args)),
+	}
+	for _, arg := range args {
+		raw, err := json.Marshal(arg)
+		if err != nil {
+			return nil, err
+		}
+		req.Args = append(req.Args, raw)
+	}
+	resp := new(Response)
+	err := c.cc.Invoke(ctx, "/"+serviceName+"/"+method, req, resp,
+		grpc.CallContentSubtype(Codec.Name()))
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Call calls method on the named sequent and returns its JSON encoded
+// results.
+func (c *Client) Call(
+	ctx context.Context,
+	sequent, method string,
+	args ...interface{},
+) ([]json.RawMessage, error) {
+	resp, err := c.invoke(ctx, "Call", sequent, method, args)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Results, nil
+}
+
+// Cast returns once method has been queued on the named sequent.
+func (c *Client) Cast(
+	ctx context.Context,
+	sequent, method string,
+	args ...interface{},
+) error {
+	_, err := c.invoke(ctx, "Cast", sequent, method, args)
+	return err
+}
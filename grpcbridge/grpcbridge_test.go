@@ -0,0 +1,185 @@
+package grpcbridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/jsouthworth/seriatim"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type counter struct {
+	n int
+}
+
+func (c *counter) Add(n int) int {
+	c.n += n
+	return c.n
+}
+
+func (c *counter) Fail() (int, error) {
+	return 0, errors.New("failed")
+}
+
+type helloRequest struct {
+	Name string
+}
+
+type helloReply struct {
+	Message string
+}
+
+type greeterServer interface {
+	SayHello(context.Context, *helloRequest) (*helloReply, error)
+}
+
+type greeter struct {
+	greeted int
+}
+
+func (g *greeter) SayHello(ctx context.Context, in *helloRequest) (*helloReply, error) {
+	if in.Name == "" {
+		return nil, status.Error(codes.InvalidArgument, "no name")
+	}
+	g.greeted++
+	return &helloReply{Message: "hello " + in.Name}, nil
+}
+
+// greeterHandler is written the way protoc-gen-go-grpc writes handlers.
+func greeterHandler(
+	srv interface{},
+	ctx context.Context,
+	dec func(interface{}) error,
+	interceptor grpc.UnaryServerInterceptor,
+) (interface{}, error) {
+	in := new(helloRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(greeterServer).SayHello(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/test.Greeter/SayHello",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(greeterServer).SayHello(ctx, req.(*helloRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var greeterDesc = grpc.ServiceDesc{
+	ServiceName: "test.Greeter",
+	HandlerType: (*greeterServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "SayHello", Handler: greeterHandler},
+	},
+	Metadata: "test.proto",
+}
+
+func dial(t *testing.T, s *grpc.Server) *grpc.ClientConn {
+	l := bufconn.Listen(1 << 16)
+	go s.Serve(l)
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return l.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn
+}
+
+func TestGenericService(t *testing.T) {
+	s := seriatim.NewSequent(&counter{})
+	defer s.Terminate(nil)
+	srv := NewServer()
+	srv.Register("counter", s)
+	gs := grpc.NewServer()
+	defer gs.Stop()
+	srv.Attach(gs)
+	conn := dial(t, gs)
+	defer conn.Close()
+	client := NewClient(conn)
+	ctx := context.Background()
+
+	if err := client.Cast(ctx, "counter", "Add", 2); err != nil {
+		t.Fatal(err)
+	}
+	results, err := client.Call(ctx, "counter", "Add", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	if len(results) != 1 || json.Unmarshal(results[0], &n) != nil || n != 5 {
+		t.Fatalf("unexpected results %s", results)
+	}
+
+	tests := []struct {
+		sequent, method string
+		code            codes.Code
+	}{
+		{"nope", "Add", codes.NotFound},
+		{"counter", "Missing", codes.Unimplemented},
+		{"counter", "Fail", codes.Unknown},
+	}
+	for _, test := range tests {
+		_, err := client.Call(ctx, test.sequent, test.method)
+		if status.Code(err) != test.code {
+			t.Errorf("%s.%s: expected %v, got %v",
+				test.sequent, test.method, test.code, err)
+		}
+	}
+}
+
+func TestRegisterService(t *testing.T) {
+	g := &greeter{}
+	s := seriatim.NewSequent(g)
+	defer s.Terminate(nil)
+	intercepted := 0
+	gs := grpc.NewServer(grpc.UnaryInterceptor(func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		intercepted++
+		return handler(ctx, req)
+	}))
+	defer gs.Stop()
+	RegisterService(gs, &greeterDesc, s)
+	conn := dial(t, gs)
+	defer conn.Close()
+	ctx := context.Background()
+
+	out := new(helloReply)
+	err := conn.Invoke(ctx, "/test.Greeter/SayHello", &helloRequest{Name: "bob"},
+		out, grpc.CallContentSubtype(Codec.Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Message != "hello bob" {
+		t.Fatalf("unexpected reply %q", out.Message)
+	}
+
+	err = conn.Invoke(ctx, "/test.Greeter/SayHello", &helloRequest{},
+		out, grpc.CallContentSubtype(Codec.Name()))
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected the sequent's status, got %v", err)
+	}
+	if intercepted != 2 {
+		t.Fatalf("interceptor ran %d times, expected 2", intercepted)
+	}
+	if stats := s.Stats(); stats.Processed != 2 {
+		t.Fatalf("sequent processed %d requests, expected 2", stats.Processed)
+	}
+}
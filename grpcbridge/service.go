@@ -0,0 +1,80 @@
+package grpcbridge
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jsouthworth/seriatim"
+	"google.golang.org/grpc"
+)
+
+// RegisterService registers a service described by desc, usually the
+// Foo_ServiceDesc generated by protoc-gen-go-grpc, so that its unary
+// methods are served by s. The sequent's methods have the signatures
+// of the generated FooServer interface,
+//
+//	func (t *T) SayHello(ctx context.Context, in *HelloRequest) (*HelloReply, error)
+//
+// and are called with the request already decoded by the generated
+// handler. The context is given by the sequent, as for any method
+// taking one, carrying the deadline of the RPC but not its values such
+// as metadata; the call gives up once the RPC is canceled. Streaming
+// methods are not supported and are left out.
+func RegisterService(r grpc.ServiceRegistrar, desc *grpc.ServiceDesc, s seriatim.Sequent) {
+	adapted := &grpc.ServiceDesc{
+		ServiceName: desc.ServiceName,
+		HandlerType: (*interface{})(nil),
+		Methods:     make([]grpc.MethodDesc, 0, len(desc.Methods)),
+		Metadata:    desc.Metadata,
+	}
+	for _, method := range desc.Methods {
+		adapted.Methods = append(adapted.Methods, grpc.MethodDesc{
+			MethodName: method.MethodName,
+			Handler:    sequentHandler(s, method.MethodName, method.Handler),
+		})
+	}
+	r.RegisterService(adapted, s)
+}
+
+// sequentHandler runs the generated handler with an interceptor of its
+// own. Generated handlers decode the request and hand it to the
+// interceptor before touching their server value, which lets the
+// request be forwarded to the sequent instead.
+func sequentHandler(
+	s seriatim.Sequent,
+	name string,
+	generated methodHandler,
+) methodHandler {
+	call := func(ctx context.Context, req interface{}) (interface{}, error) {
+		values, err := s.CallContext(ctx, name, req)
+		if err != nil {
+			return nil, statusFor(err)
+		}
+		if len(values) != 2 {
+			return nil, fmt.Errorf("%s returned %d values, expected 2",
+				name, len(values))
+		}
+		if err := lastError(values); err != nil {
+			return nil, statusFor(err)
+		}
+		return values[0], nil
+	}
+	return func(
+		srv interface{},
+		ctx context.Context,
+		dec func(interface{}) error,
+		interceptor grpc.UnaryServerInterceptor,
+	) (interface{}, error) {
+		return generated(srv, ctx, dec, func(
+			ctx context.Context,
+			req interface{},
+			info *grpc.UnaryServerInfo,
+			_ grpc.UnaryHandler,
+		) (interface{}, error) {
+			if interceptor == nil {
+				return call(ctx, req)
+			}
+			return interceptor(ctx, req, info, call)
+		})
+	}
+}
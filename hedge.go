@@ -0,0 +1,54 @@
+package seriatim
+
+import "time"
+
+// Hedge calls name on primary and, if it has not answered within
+// delay, on secondary too, returning the first successful answer. This
+// bounds the latency of sequents fronting redundant backends at the
+// cost of some duplicate work, so the method should be safe to run on
+// both. If the primary fails before delay the secondary is called
+// right away; the error is returned only if both fail.
+func Hedge(
+	primary, secondary Sequent,
+	delay time.Duration,
+	name string,
+	args ...interface{},
+) ([]interface{}, error) {
+	type answer struct {
+		rets []interface{}
+		err  error
+	}
+	// buffered so that the call not waited for does not leak
+	answers := make(chan answer, 2)
+	call := func(s Sequent) {
+		go func() {
+			rets, err := s.Call(name, args...)
+			answers <- answer{rets, err}
+		}()
+	}
+
+	call(primary)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	pending, hedged := 1, false
+	for {
+		select {
+		case a := <-answers:
+			pending--
+			if a.err == nil || (hedged && pending == 0) {
+				return a.rets, a.err
+			}
+			if !hedged {
+				hedged = true
+				pending++
+				call(secondary)
+			}
+		case <-timer.C:
+			if !hedged {
+				hedged = true
+				pending++
+				call(secondary)
+			}
+		}
+	}
+}
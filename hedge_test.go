@@ -0,0 +1,65 @@
+package seriatim
+
+import (
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type backend struct {
+	name  string
+	delay time.Duration
+	err   error
+	calls int32
+}
+
+func (b *backend) Get() (string, error) {
+	atomic.AddInt32(&b.calls, 1)
+	time.Sleep(b.delay)
+	return b.name, b.err
+}
+
+func hedge(t *testing.T, primary, secondary *backend) (string, error) {
+	p, s := NewSequent(primary), NewSequent(secondary)
+	defer p.Terminate(nil)
+	defer s.Terminate(nil)
+	rets, err := Hedge(p, s, 10*time.Millisecond, "Get")
+	if err != nil {
+		return "", err
+	}
+	if rets[1] != nil {
+		return "", rets[1].(error)
+	}
+	return rets[0].(string), nil
+}
+
+func TestHedge(t *testing.T) {
+	fast := &backend{name: "fast"}
+	backup := &backend{name: "backup"}
+	if got, _ := hedge(t, fast, backup); got != "fast" {
+		t.Fatalf("got answer from %s", got)
+	}
+	if atomic.LoadInt32(&backup.calls) != 0 {
+		t.Fatal("secondary called although the primary answered in time")
+	}
+
+	slow := &backend{name: "slow", delay: 100 * time.Millisecond}
+	if got, _ := hedge(t, slow, backup); got != "backup" {
+		t.Fatalf("got answer from %s", got)
+	}
+}
+
+func TestHedgeFailure(t *testing.T) {
+	p := NewSequent(&backend{})
+	p.Terminate(nil)
+	s := NewSequent(&backend{name: "backup"})
+	rets, err := Hedge(p, s, time.Hour, "Get")
+	if err != nil || rets[0] != "backup" {
+		t.Fatalf("secondary not called when the primary failed: %v", err)
+	}
+	s.Terminate(nil)
+	if _, err := Hedge(p, s, time.Hour, "Get"); !errors.Is(err, ErrSequentStop) {
+		t.Fatalf("expected ErrSequentStop, got %v", err)
+	}
+}
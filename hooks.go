@@ -0,0 +1,43 @@
+package seriatim
+
+import "reflect"
+
+// Starter is implemented by values that acquire resources once their
+// sequent runs, such as opening a connection. OnStart is called on the
+// sequent's goroutine with the sequent before any request is
+// processed; requests made meanwhile wait in the mailbox. A panic in
+// OnStart crashes the sequent.
+type Starter interface {
+	OnStart(Sequent)
+}
+
+// Terminator is implemented by values that release resources when
+// their sequent is terminated. OnTerminate is called on the sequent's
+// goroutine with the reason given to Terminate once the request being
+// processed completes, before the queued ones are purged. It is not
+// called when the sequent crashes, as the value may be inconsistent.
+type Terminator interface {
+	OnTerminate(reason error)
+}
+
+// hookNames are the methods of the lifecycle hooks, which are not
+// methods of the sequent.
+var hookNames = map[string]func(interface{}) bool{
+	"OnStart": func(val interface{}) bool {
+		_, ok := val.(Starter)
+		return ok
+	},
+	"OnTerminate": func(val interface{}) bool {
+		_, ok := val.(Terminator)
+		return ok
+	},
+}
+
+// removeHooks keeps the lifecycle hooks of val from being requested.
+func removeHooks(val interface{}, methods map[string]reflect.Value) {
+	for name, implemented := range hookNames {
+		if implemented(val) {
+			delete(methods, name)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package seriatim
+
+import (
+	"errors"
+	"testing"
+)
+
+type hooked struct {
+	started    Sequent
+	terminated chan error
+	n          int
+}
+
+func (h *hooked) OnStart(s Sequent) {
+	h.started = s
+}
+
+func (h *hooked) OnTerminate(reason error) {
+	h.terminated <- reason
+}
+
+func (h *hooked) Started() Sequent {
+	return h.started
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	h := &hooked{terminated: make(chan error, 1)}
+	s := NewSequent(h)
+	rets, err := s.Call("Started")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rets[0] != s {
+		t.Fatalf("OnStart was given %v, want %v", rets[0], s)
+	}
+	for _, hook := range []string{"OnStart", "OnTerminate"} {
+		if _, err := s.Call(hook); err != ErrUnknownMethod {
+			t.Fatalf("expected %s not to be callable, got %v", hook, err)
+		}
+	}
+	if len(s.Methods()) != 1 {
+		t.Fatalf("expected only Started, got %v", s.Methods())
+	}
+
+	reason := errors.New("done")
+	s.Terminate(reason)
+	if got := <-h.terminated; got != reason {
+		t.Fatalf("OnTerminate was given %v, want %v", got, reason)
+	}
+}
+
+type failingStart struct {
+	n int
+}
+
+func (f *failingStart) OnStart(Sequent) {
+	panic("no connection")
+}
+
+func (f *failingStart) Get() int {
+	return f.n
+}
+
+func TestLifecycleHookPanic(t *testing.T) {
+	s := NewSequent(&failingStart{}, WithMailboxSize(1))
+	for state := range s.StateChanges() {
+		if state.Final() && state != StateCrashed {
+			t.Fatalf("expected the sequent to crash, ended %v", state)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package seriatim
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+)
+
+// ErrNotInspectable is returned by GetState for sequents not made by
+// this package, whose value may not even be in this process.
+var ErrNotInspectable = errors.New("Sequent state cannot be inspected")
+
+// GetState calls fn with the value of s on the goroutine of s, once the
+// requests queued before have been processed, and returns what fn
+// returns. fn has the value to itself as a method would, so it can
+// safely copy out whatever a debugging console is to show:
+//
+//	state, err := seriatim.GetState(s, func(val interface{}) interface{} {
+//		c := val.(*counter)
+//		return c.n
+//	})
+//
+// What fn returns must not share anything the value goes on to
+// change. A panic in fn is returned as a *PanicError without crashing
+// s. GetState blocks like a Call; use GetStateContext to give up.
+func GetState(
+	s Sequent,
+	fn func(val interface{}) interface{},
+) (interface{}, error) {
+	return GetStateContext(context.Background(), s, fn)
+}
+
+// GetStateContext is GetState giving up with the error of ctx once it
+// is done, such as when s is stuck in a method. fn is still called
+// once s gets to it.
+func GetStateContext(
+	ctx context.Context,
+	s Sequent,
+	fn func(val interface{}) interface{},
+) (interface{}, error) {
+	a, ok := s.(*sequent)
+	if !ok {
+		return nil, ErrNotInspectable
+	}
+	if !a.accepting() {
+		return nil, ErrSequentStop
+	}
+	replych := make(chan reply, 1)
+	req := &request{
+		method: reflect.ValueOf(func() (state interface{}, err error) {
+			defer func() {
+				if rec := recover(); rec != nil {
+					err = &PanicError{
+						Method: "GetState",
+						Reason: panicReason(rec),
+					}
+				}
+			}()
+			return fn(a.val), nil
+		}),
+		reply: replych,
+		made:  time.Now(),
+		owner: a,
+	}
+	if err := a.enqueue(req, ctx.Done()); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	select {
+	case reply, ok := <-replych:
+		rets, err := answer(reply, ok)
+		if err != nil {
+			return nil, err
+		}
+		if err, _ := rets[1].(error); err != nil {
+			return nil, err
+		}
+		return rets[0], nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
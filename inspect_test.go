@@ -0,0 +1,65 @@
+package seriatim
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestGetState(t *testing.T) {
+	g := &gated{release: make(chan struct{})}
+	s := NewSequent(g, WithMailboxSize(4))
+	defer s.Terminate(nil)
+	if err := s.Cast("Block"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Cast("Incr"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := GetStateContext(ctx, s, func(val interface{}) interface{} {
+		return val.(*gated).n
+	})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected to give up on a blocked sequent, got %v", err)
+	}
+
+	close(g.release)
+	state, err := GetState(s, func(val interface{}) interface{} {
+		return val.(*gated).n
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.(int) != 1 {
+		t.Fatalf("expected the state after the queued requests, got %v",
+			state)
+	}
+}
+
+func TestGetStatePanic(t *testing.T) {
+	s := NewSequent(&gated{})
+	defer s.Terminate(nil)
+	_, err := GetState(s, func(val interface{}) interface{} {
+		panic("bad inspector")
+	})
+	if !errors.Is(err, ErrMethodPanicked) {
+		t.Fatalf("expected ErrMethodPanicked, got %v", err)
+	}
+	if !s.Running() {
+		t.Fatal("a panicking inspector crashed the sequent")
+	}
+	s.Terminate(nil)
+	<-s.Done()
+	if _, err := GetState(s, nil); err != ErrSequentStop {
+		t.Fatalf("expected ErrSequentStop, got %v", err)
+	}
+	r := NewReplica(NewSequent(&gated{}), NewSequent(&gated{}))
+	defer r.Terminate(nil)
+	if _, err := GetState(r, nil); err != ErrNotInspectable {
+		t.Fatalf("expected ErrNotInspectable, got %v", err)
+	}
+}
@@ -0,0 +1,47 @@
+package seriatim
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Invocation is a method invocation, as queued for a sequent or carried
+// by the code that routes, records or replays requests.
+type Invocation struct {
+	Method string
+	Args   []interface{}
+	// Metadata holds what the code handling the invocation on its
+	// way needs to know about it, such as where it came from. The
+	// sequent does not look at it.
+	Metadata map[string]interface{}
+}
+
+// Call calls the method on s.
+func (inv Invocation) Call(s Sequent) ([]interface{}, error) {
+	return s.Call(inv.Method, inv.Args...)
+}
+
+// Cast casts the method to s.
+func (inv Invocation) Cast(s Sequent) error {
+	return s.Cast(inv.Method, inv.Args...)
+}
+
+// String formats the invocation as a Go call.
+func (inv Invocation) String() string {
+	args := make([]string, len(inv.Args))
+	for i, arg := range inv.Args {
+		args[i] = fmt.Sprintf("%#v", arg)
+	}
+	return inv.Method + "(" + strings.Join(args, ", ") + ")"
+}
+
+// CastBatch casts invs to s in order, stopping at the first one that
+// fails. It returns the number of invocations cast.
+func CastBatch(s Sequent, invs []Invocation) (int, error) {
+	for i, inv := range invs {
+		if err := inv.Cast(s); err != nil {
+			return i, err
+		}
+	}
+	return len(invs), nil
+}
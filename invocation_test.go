@@ -0,0 +1,28 @@
+package seriatim
+
+import "testing"
+
+func TestCastBatch(t *testing.T) {
+	s := NewSequent(&tally{}, WithMailboxSize(4))
+	defer s.Terminate(nil)
+	n, err := CastBatch(s, []Invocation{
+		{Method: "Add", Args: []interface{}{1}},
+		{Method: "Add", Args: []interface{}{2}},
+		{Method: "Missing"},
+		{Method: "Add", Args: []interface{}{4}},
+	})
+	if n != 2 || err != ErrUnknownMethod {
+		t.Fatalf("unexpected %d %v", n, err)
+	}
+	rets, err := Invocation{Method: "Total"}.Call(s)
+	if err != nil || rets[0] != 3 {
+		t.Fatalf("unexpected %v %v", rets, err)
+	}
+}
+
+func TestInvocationString(t *testing.T) {
+	inv := Invocation{Method: "Greet", Args: []interface{}{"bob", 2}}
+	if got := inv.String(); got != `Greet("bob", 2)` {
+		t.Fatalf("unexpected %q", got)
+	}
+}
@@ -0,0 +1,64 @@
+package seriatim
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrMethodPanicked = errors.New("Method panicked")
+
+// PanicError is returned by a Call whose method panicked in a sequent
+// isolating panics. It matches ErrMethodPanicked and the reason of the
+// panic. Unlike a CrashError the sequent is still running.
+type PanicError struct {
+	Method string
+	Reason error
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("Method %s panicked: %s", e.Method, e.Reason)
+}
+
+func (e *PanicError) Unwrap() []error {
+	return []error{ErrMethodPanicked, e.Reason}
+}
+
+// WithPanicIsolation keeps the sequent running when a method panics.
+// The panic fails the request that caused it with a *PanicError
+// instead of terminating the sequent, which suits stateless values or
+// values whose methods leave them consistent when they panic. A panic
+// in HandleBatch drops the rest of the batch.
+func WithPanicIsolation() Option {
+	return func(a *sequent) {
+		a.isolatePanics = true
+	}
+}
+
+// panicReason returns the value a panic was called with as an error.
+func panicReason(rec interface{}) error {
+	if err, ok := rec.(error); ok {
+		return err
+	}
+	return fmt.Errorf("%s", rec)
+}
+
+// guard calls fn, returning the panic of method as a *PanicError if
+// the sequent isolates panics. Otherwise the panic terminates the
+// sequent.
+func (a *sequent) guard(method string, fn func()) (err error) {
+	if !a.isolatePanics {
+		fn()
+		return nil
+	}
+	defer func() {
+		rec := recover()
+		if rec == nil {
+			return
+		}
+		reason := panicReason(rec)
+		a.logPanic("method panicked", method, reason)
+		err = &PanicError{Method: method, Reason: reason}
+	}()
+	fn()
+	return nil
+}
@@ -0,0 +1,39 @@
+package seriatim
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPanicIsolation(t *testing.T) {
+	s := NewSequent(validator{}, WithPanicIsolation())
+	defer s.Terminate(nil)
+
+	_, err := s.Call("Crash")
+	var perr *PanicError
+	if !errors.As(err, &perr) || perr.Method != "Crash" ||
+		!errors.Is(err, ErrMethodPanicked) {
+		t.Fatalf("expected a PanicError, got %v", err)
+	}
+	if errors.Is(err, ErrSequentStop) {
+		t.Fatal("isolated panic reported as stopping the sequent")
+	}
+	if !s.Running() {
+		t.Fatal("sequent terminated by an isolated panic")
+	}
+
+	errs := make(chan error, 1)
+	if err := s.CastNotify("Crash", errs); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; !errors.Is(err, ErrMethodPanicked) {
+		t.Fatalf("expected a PanicError, got %v", err)
+	}
+	rets, err := s.Call("Check", true)
+	if err != nil || rets[0] != 1 {
+		t.Fatalf("unexpected %v %v", rets, err)
+	}
+	if n := s.Stats().Processed; n != 3 {
+		t.Fatalf("expected 3 processed, got %d", n)
+	}
+}
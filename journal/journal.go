@@ -0,0 +1,203 @@
+// Package journal is a log/slog handler writing to the systemd journal
+// over its native protocol, for use with seriatim.SetLogger.
+//
+// Attributes become journal fields named by upper casing their keys, so
+// the sequent_id, object_path and dbus_sender attributes logged by
+// seriatim and its dbus package can be queried as
+//
+//	journalctl SEQUENT_ID=42
+//	journalctl OBJECT_PATH=/com/example/Foo DBUS_SENDER=:1.7
+//
+// Grouped attributes are prefixed with their group names joined by
+// underscores.
+package journal
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// SocketPath is where journald listens for native protocol messages.
+const SocketPath = "/run/systemd/journal/socket"
+
+// Priorities from syslog(3) used for the PRIORITY field.
+const (
+	priErr     = 3
+	priWarning = 4
+	priInfo    = 6
+	priDebug   = 7
+)
+
+type Handler struct {
+	conn       *net.UnixConn
+	opts       slog.HandlerOptions
+	identifier string
+	// fields holds the encoded attributes added with WithAttrs.
+	fields []byte
+	prefix string
+	mu     *sync.Mutex
+}
+
+// NewHandler connects to the journal. It fails when journald is not
+// running, so callers can fall back to another handler. opts may be
+// nil.
+func NewHandler(opts *slog.HandlerOptions) (*Handler, error) {
+	return newHandler(SocketPath, opts)
+}
+
+func newHandler(path string, opts *slog.HandlerOptions) (*Handler, error) {
+	conn, err := net.DialUnix("unixgram", nil,
+		&net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	h := &Handler{
+		conn:       conn,
+		identifier: filepath.Base(os.Args[0]),
+		mu:         &sync.Mutex{},
+	}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h, nil
+}
+
+// Close closes the connection to the journal shared by h and the
+// handlers derived from it.
+func (h *Handler) Close() error {
+	return h.conn.Close()
+}
+
+func (h *Handler) Enabled(_ context.Context, level slog.Level) bool {
+	min := slog.LevelInfo
+	if h.opts.Level != nil {
+		min = h.opts.Level.Level()
+	}
+	return level >= min
+}
+
+func (h *Handler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	appendField(&buf, "MESSAGE", r.Message)
+	appendField(&buf, "PRIORITY", strconv.Itoa(priority(r.Level)))
+	appendField(&buf, "SYSLOG_IDENTIFIER", h.identifier)
+	if h.opts.AddSource && r.PC != 0 {
+		frames := runtime.CallersFrames([]uintptr{r.PC})
+		frame, _ := frames.Next()
+		appendField(&buf, "CODE_FILE", frame.File)
+		appendField(&buf, "CODE_LINE", strconv.Itoa(frame.Line))
+		appendField(&buf, "CODE_FUNC", frame.Function)
+	}
+	buf.Write(h.fields)
+	r.Attrs(func(attr slog.Attr) bool {
+		h.appendAttr(&buf, h.prefix, attr)
+		return true
+	})
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.conn.Write(buf.Bytes())
+	return err
+}
+
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var buf bytes.Buffer
+	buf.Write(h.fields)
+	for _, attr := range attrs {
+		h.appendAttr(&buf, h.prefix, attr)
+	}
+	h2 := *h
+	h2.fields = buf.Bytes()
+	return &h2
+}
+
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	h2 := *h
+	h2.prefix = h.prefix + name + "_"
+	return &h2
+}
+
+func (h *Handler) appendAttr(buf *bytes.Buffer, prefix string, attr slog.Attr) {
+	if h.opts.ReplaceAttr != nil && attr.Value.Kind() != slog.KindGroup {
+		attr = h.opts.ReplaceAttr(nil, attr)
+	}
+	attr.Value = attr.Value.Resolve()
+	if attr.Equal(slog.Attr{}) {
+		return
+	}
+	if attr.Value.Kind() == slog.KindGroup {
+		if attr.Key != "" {
+			prefix += attr.Key + "_"
+		}
+		for _, a := range attr.Value.Group() {
+			h.appendAttr(buf, prefix, a)
+		}
+		return
+	}
+	name := fieldName(prefix + attr.Key)
+	if name == "" {
+		return
+	}
+	appendField(buf, name, attr.Value.String())
+}
+
+func priority(level slog.Level) int {
+	switch {
+	case level >= slog.LevelError:
+		return priErr
+	case level >= slog.LevelWarn:
+		return priWarning
+	case level >= slog.LevelInfo:
+		return priInfo
+	}
+	return priDebug
+}
+
+// fieldName maps key to a valid journal field name: upper case letters,
+// digits and underscores, not starting with an underscore, which is
+// reserved for fields set by journald, or a digit.
+func fieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+	name = strings.TrimLeft(name, "_")
+	if name != "" && name[0] >= '0' && name[0] <= '9' {
+		name = "F_" + name
+	}
+	return name
+}
+
+// appendField encodes a field of the native protocol. Values containing
+// newlines, such as stack traces, are sent as the name, a newline and
+// the length of the value as a little endian uint64 followed by the
+// value itself.
+func appendField(buf *bytes.Buffer, name, value string) {
+	if !strings.Contains(value, "\n") {
+		fmt.Fprintf(buf, "%s=%s\n", name, value)
+		return
+	}
+	buf.WriteString(name)
+	buf.WriteByte('\n')
+	binary.Write(buf, binary.LittleEndian, uint64(len(value)))
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
@@ -0,0 +1,122 @@
+package journal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"github.com/jsouthworth/seriatim"
+)
+
+func listen(t *testing.T) (*net.UnixConn, string) {
+	path := filepath.Join(t.TempDir(), "socket")
+	conn, err := net.ListenUnixgram("unixgram",
+		&net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, path
+}
+
+// parse decodes a native protocol message.
+func parse(t *testing.T, msg []byte) map[string]string {
+	fields := make(map[string]string)
+	for len(msg) > 0 {
+		nl := bytes.IndexByte(msg, '\n')
+		if nl < 0 {
+			t.Fatalf("unterminated field %q", msg)
+		}
+		line := msg[:nl]
+		msg = msg[nl+1:]
+		if eq := bytes.IndexByte(line, '='); eq >= 0 {
+			fields[string(line[:eq])] = string(line[eq+1:])
+			continue
+		}
+		size := binary.LittleEndian.Uint64(msg)
+		fields[string(line)] = string(msg[8 : 8+size])
+		msg = msg[8+size+1:]
+	}
+	return fields
+}
+
+func receive(t *testing.T, conn *net.UnixConn) map[string]string {
+	buf := make([]byte, 65536)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return parse(t, buf[:n])
+}
+
+func TestHandler(t *testing.T) {
+	conn, path := listen(t)
+	defer conn.Close()
+	h, err := newHandler(path, &slog.HandlerOptions{Level: slog.LevelDebug})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	l := slog.New(h).With("object_path", "/com/example/Foo")
+	l.WithGroup("req").Warn("dbus object terminated",
+		seriatim.SequentIdKey, 42,
+		"dbus_sender", ":1.7",
+		"reason", errors.New("first line\nsecond line"))
+	fields := receive(t, conn)
+	expected := map[string]string{
+		"MESSAGE":         "dbus object terminated",
+		"PRIORITY":        "4",
+		"OBJECT_PATH":     "/com/example/Foo",
+		"REQ_SEQUENT_ID":  "42",
+		"REQ_DBUS_SENDER": ":1.7",
+		"REQ_REASON":      "first line\nsecond line",
+	}
+	for name, value := range expected {
+		if fields[name] != value {
+			t.Errorf("%s: expected %q, got %q", name, value, fields[name])
+		}
+	}
+}
+
+func TestHandlerLevel(t *testing.T) {
+	conn, path := listen(t)
+	defer conn.Close()
+	h, err := newHandler(path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer h.Close()
+
+	l := slog.New(h)
+	l.Debug("dropped")
+	l.Error("kept")
+	if fields := receive(t, conn); fields["MESSAGE"] != "kept" ||
+		fields["PRIORITY"] != "3" {
+		t.Fatalf("unexpected message %v", fields)
+	}
+}
+
+func TestFieldName(t *testing.T) {
+	tests := map[string]string{
+		"sequent_id":  "SEQUENT_ID",
+		"dbus.sender": "DBUS_SENDER",
+		"_private":    "PRIVATE",
+		"1st":         "F_1ST",
+		"___":         "",
+	}
+	for key, expected := range tests {
+		if name := fieldName(key); name != expected {
+			t.Errorf("%s: expected %q, got %q", key, expected, name)
+		}
+	}
+}
+
+func TestNoJournal(t *testing.T) {
+	if _, err := newHandler(filepath.Join(t.TempDir(), "missing"), nil); err == nil {
+		t.Fatal("expected an error without a journal")
+	}
+}
@@ -0,0 +1,397 @@
+// Package jsonrpc exposes named sequents over JSON-RPC 2.0.
+//
+// A request for "name.Method" is a Call of Method on the sequent
+// registered as name; a notification (a request without an id) is a
+// Cast. The server can be mounted as an http.Handler or serve raw
+// stream connections where requests and responses are consecutive
+// JSON values.
+package jsonrpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/jsouthworth/seriatim"
+	"github.com/jsouthworth/seriatim/codec"
+)
+
+const version = "2.0"
+
+// Error codes defined by the JSON-RPC 2.0 specification, and the
+// server error code used for failures reported by sequents.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeServerError    = -32000
+)
+
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+type request struct {
+	Version string           `json:"jsonrpc"`
+	Method  string           `json:"method"`
+	Params  json.RawMessage  `json:"params,omitempty"`
+	Id      *json.RawMessage `json:"id,omitempty"`
+}
+
+type response struct {
+	Version string           `json:"jsonrpc"`
+	Result  interface{}      `json:"result"`
+	Error   *Error           `json:"error,omitempty"`
+	Id      *json.RawMessage `json:"id"`
+}
+
+// MarshalJSON writes result, null for methods returning nothing, unless
+// the response is an error, as one or the other must be present.
+func (r *response) MarshalJSON() ([]byte, error) {
+	if r.Error != nil {
+		return json.Marshal(&struct {
+			Version string           `json:"jsonrpc"`
+			Error   *Error           `json:"error"`
+			Id      *json.RawMessage `json:"id"`
+		}{r.Version, r.Error, r.Id})
+	}
+	type plain response
+	return json.Marshal((*plain)(r))
+}
+
+// A Decoder converts the params member of a request into the
+// arguments for method on a sequent.
+type Decoder interface {
+	Decode(s seriatim.Sequent, method string, params json.RawMessage) ([]interface{}, error)
+}
+
+type DecoderFunc func(s seriatim.Sequent, method string, params json.RawMessage) ([]interface{}, error)
+
+func (fn DecoderFunc) Decode(
+	s seriatim.Sequent,
+	method string,
+	params json.RawMessage,
+) ([]interface{}, error) {
+	return fn(s, method, params)
+}
+
+// PositionalDecoder decodes params given by position with codec.JSON
+// into the parameter types the sequent describes, or the generic JSON
+// types which the sequent converts to its parameter types where
+// possible. Named params are rejected.
+var PositionalDecoder = DecoderFunc(func(
+	s seriatim.Sequent,
+	method string,
+	params json.RawMessage,
+) ([]interface{}, error) {
+	params = bytes.TrimSpace(params)
+	if len(params) == 0 || bytes.Equal(params, []byte("null")) {
+		return nil, nil
+	}
+	if params[0] != '[' {
+		return nil, errors.New("only positional params are supported")
+	}
+	return codec.JSON.Decode(params, codec.ArgTypes(s, method))
+})
+
+type Server struct {
+	mu       sync.RWMutex
+	sequents map[string]seriatim.Sequent
+	decoder  Decoder
+}
+
+func NewServer() *Server {
+	return &Server{
+		sequents: make(map[string]seriatim.Sequent),
+		decoder:  PositionalDecoder,
+	}
+}
+
+func (srv *Server) Register(name string, s seriatim.Sequent) {
+	srv.mu.Lock()
+	srv.sequents[name] = s
+	srv.mu.Unlock()
+}
+
+func (srv *Server) Unregister(name string) {
+	srv.mu.Lock()
+	delete(srv.sequents, name)
+	srv.mu.Unlock()
+}
+
+func (srv *Server) SetDecoder(decoder Decoder) {
+	srv.mu.Lock()
+	srv.decoder = decoder
+	srv.mu.Unlock()
+}
+
+func (srv *Server) lookup(method string) (seriatim.Sequent, string, Decoder, bool) {
+	i := strings.LastIndex(method, ".")
+	if i < 0 {
+		return nil, "", nil, false
+	}
+	srv.mu.RLock()
+	defer srv.mu.RUnlock()
+	s, ok := srv.sequents[method[:i]]
+	return s, method[i+1:], srv.decoder, ok
+}
+
+// errorFor returns the error a request failed with err is answered
+// with. The sequent stopping, however it did, is reported with the
+// message of ErrSequentStop and the error itself as its data.
+func errorFor(err error) *Error {
+	var rpcErr *Error
+	switch {
+	case errors.As(err, &rpcErr):
+		return rpcErr
+	case errors.Is(err, seriatim.ErrUnknownMethod):
+		return &Error{Code: CodeMethodNotFound, Message: err.Error()}
+	case err == seriatim.ErrSequentStop:
+		return &Error{Code: CodeServerError, Message: err.Error()}
+	case errors.Is(err, seriatim.ErrSequentStop):
+		return &Error{
+			Code:    CodeServerError,
+			Message: seriatim.ErrSequentStop.Error(),
+			Data:    err.Error(),
+		}
+	}
+	return &Error{Code: CodeServerError, Message: err.Error()}
+}
+
+func result(values []interface{}) (interface{}, error) {
+	if n := len(values); n > 0 {
+		if err, ok := values[n-1].(error); ok {
+			return nil, err
+		}
+	}
+	switch len(values) {
+	case 0:
+		return nil, nil
+	case 1:
+		return values[0], nil
+	}
+	return values, nil
+}
+
+// start runs a single request up to the point of waiting for a
+// sequent: a notification is cast right away, while the Call of a
+// valid request is left to the returned function. It returns nil when
+// there is nothing to reply.
+func (srv *Server) start(raw json.RawMessage) func() *response {
+	var req request
+	if err := json.Unmarshal(raw, &req); err != nil || req.Version != version ||
+		req.Method == "" {
+		return answer(&response{
+			Version: version,
+			Error:   &Error{Code: CodeInvalidRequest, Message: "Invalid request"},
+		})
+	}
+	resp := &response{Version: version, Id: req.Id}
+	fail := func(err *Error) func() *response {
+		if req.Id == nil {
+			return nil
+		}
+		resp.Error = err
+		return answer(resp)
+	}
+
+	s, method, decoder, ok := srv.lookup(req.Method)
+	if !ok {
+		return fail(&Error{Code: CodeMethodNotFound, Message: "Method not found"})
+	}
+	args, err := decoder.Decode(s, method, req.Params)
+	if err != nil {
+		return fail(&Error{Code: CodeInvalidParams, Message: err.Error()})
+	}
+
+	if req.Id == nil {
+		s.Cast(method, args...)
+		return nil
+	}
+	return func() *response {
+		values, err := s.Call(method, args...)
+		if err != nil {
+			resp.Error = errorFor(err)
+			return resp
+		}
+		res, err := result(values)
+		if err != nil {
+			resp.Error = errorFor(err)
+			return resp
+		}
+		resp.Result = res
+		return resp
+	}
+}
+
+func answer(resp *response) func() *response {
+	return func() *response {
+		return resp
+	}
+}
+
+// Handle processes a request or batch of requests and returns the
+// encoded response, which is nil when there is nothing to reply.
+func (srv *Server) Handle(raw json.RawMessage) []byte {
+	if finish := srv.begin(raw); finish != nil {
+		return finish()
+	}
+	return nil
+}
+
+// begin starts a request or batch of requests as start does, casting
+// the notifications in order, and returns the function waiting for
+// the Calls and encoding the response, or nil if there is nothing to
+// reply.
+func (srv *Server) begin(raw json.RawMessage) func() []byte {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || raw[0] != '[' {
+		if !json.Valid(raw) {
+			return encoded(&response{
+				Version: version,
+				Error:   &Error{Code: CodeParseError, Message: "Parse error"},
+			})
+		}
+		finish := srv.start(raw)
+		if finish == nil {
+			return nil
+		}
+		return func() []byte {
+			return mustMarshal(finish())
+		}
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(raw, &batch); err != nil {
+		return encoded(&response{
+			Version: version,
+			Error:   &Error{Code: CodeParseError, Message: "Parse error"},
+		})
+	}
+	if len(batch) == 0 {
+		return encoded(&response{
+			Version: version,
+			Error:   &Error{Code: CodeInvalidRequest, Message: "Invalid request"},
+		})
+	}
+	var pending []func() *response
+	for _, raw := range batch {
+		if finish := srv.start(raw); finish != nil {
+			pending = append(pending, finish)
+		}
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	return func() []byte {
+		out := make([]*response, len(pending))
+		var wg sync.WaitGroup
+		for i, finish := range pending {
+			wg.Add(1)
+			go func(i int, finish func() *response) {
+				defer wg.Done()
+				out[i] = finish()
+			}(i, finish)
+		}
+		wg.Wait()
+		return mustMarshal(out)
+	}
+}
+
+func encoded(resp *response) func() []byte {
+	return func() []byte {
+		return mustMarshal(resp)
+	}
+}
+
+func mustMarshal(v interface{}) []byte {
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(&response{
+			Version: version,
+			Error:   &Error{Code: CodeInternalError, Message: err.Error()},
+		})
+	}
+	return b
+}
+
+func (srv *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	out := srv.Handle(body)
+	if out == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(out)
+}
+
+// ServeConn reads consecutive JSON values from conn and writes the
+// responses as they complete until conn is closed. Notifications are
+// cast in the order they are read, before the next value is read, so
+// that a sequent receives them in the order the client sent them;
+// requests are called concurrently, each answered as it completes.
+func (srv *Server) ServeConn(conn io.ReadWriteCloser) {
+	defer conn.Close()
+	dec := json.NewDecoder(conn)
+	var wlock sync.Mutex
+	var wg sync.WaitGroup
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			if _, ok := err.(*json.SyntaxError); ok {
+				wlock.Lock()
+				conn.Write(srv.Handle([]byte("{")))
+				wlock.Unlock()
+			}
+			break
+		}
+		finish := srv.begin(raw)
+		if finish == nil {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			out := finish()
+			wlock.Lock()
+			conn.Write(append(out, '\n'))
+			wlock.Unlock()
+		}()
+	}
+	wg.Wait()
+}
+
+// Serve accepts connections on l and serves each with ServeConn.
+func (srv *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go srv.ServeConn(conn)
+	}
+}
@@ -0,0 +1,296 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/jsouthworth/seriatim"
+)
+
+type counter struct {
+	n int
+}
+
+func (c *counter) Add(n int) int {
+	c.n += n
+	return c.n
+}
+
+func (c *counter) Get() int {
+	return c.n
+}
+
+func (c *counter) Reset() {
+	c.n = 0
+}
+
+func (c *counter) Crash() {
+	panic("broken")
+}
+
+func (c *counter) Fail() (int, error) {
+	return 0, errors.New("failed")
+}
+
+func newServer() (*Server, seriatim.Sequent) {
+	s := seriatim.NewSequent(&counter{})
+	srv := NewServer()
+	srv.Register("counter", s)
+	return srv, s
+}
+
+func decode(t *testing.T, out []byte) map[string]interface{} {
+	var resp map[string]interface{}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		t.Fatalf("%s: %v", out, err)
+	}
+	return resp
+}
+
+func errorCode(resp map[string]interface{}) int {
+	e, ok := resp["error"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	return int(e["code"].(float64))
+}
+
+func TestHandleCall(t *testing.T) {
+	srv, s := newServer()
+	defer s.Terminate(nil)
+
+	resp := decode(t, srv.Handle([]byte(
+		`{"jsonrpc":"2.0","method":"counter.Add","params":[2],"id":1}`)))
+	if resp["result"] != 2.0 || resp["id"] != 1.0 {
+		t.Fatalf("unexpected response %v", resp)
+	}
+}
+
+func TestHandleVoid(t *testing.T) {
+	srv, s := newServer()
+	defer s.Terminate(nil)
+
+	resp := decode(t, srv.Handle([]byte(
+		`{"jsonrpc":"2.0","method":"counter.Reset","id":1}`)))
+	result, ok := resp["result"]
+	if !ok || result != nil || resp["error"] != nil {
+		t.Fatalf("expected a null result, got %v", resp)
+	}
+	resp = decode(t, srv.Handle([]byte(
+		`{"jsonrpc":"2.0","method":"counter.Fail","id":2}`)))
+	if _, ok := resp["result"]; ok {
+		t.Fatalf("expected no result with the error, got %v", resp)
+	}
+}
+
+func TestHandleNotification(t *testing.T) {
+	srv, s := newServer()
+	defer s.Terminate(nil)
+
+	out := srv.Handle([]byte(`{"jsonrpc":"2.0","method":"counter.Add","params":[5]}`))
+	if out != nil {
+		t.Fatalf("notification got a response %s", out)
+	}
+	resp := decode(t, srv.Handle([]byte(
+		`{"jsonrpc":"2.0","method":"counter.Get","id":"a"}`)))
+	if resp["result"] != 5.0 {
+		t.Fatalf("cast was not delivered: %v", resp)
+	}
+}
+
+func TestHandleErrors(t *testing.T) {
+	srv, s := newServer()
+	defer s.Terminate(nil)
+
+	tests := map[string]int{
+		`{"jsonrpc":"2.0","method":"counter.Add","params":[1]`:             CodeParseError,
+		`{"method":"counter.Add","id":1}`:                                  CodeInvalidRequest,
+		`{"jsonrpc":"2.0","method":"nope.Add","id":1}`:                     CodeMethodNotFound,
+		`{"jsonrpc":"2.0","method":"counter.Missing","id":1}`:              CodeMethodNotFound,
+		`{"jsonrpc":"2.0","method":"counter.Add","params":{"n":1},"id":1}`: CodeInvalidParams,
+		`{"jsonrpc":"2.0","method":"counter.Fail","id":1}`:                 CodeServerError,
+		`[]`: CodeInvalidRequest,
+	}
+	for req, code := range tests {
+		resp := decode(t, srv.Handle([]byte(req)))
+		if got := errorCode(resp); got != code {
+			t.Errorf("%s: expected code %d, got %v", req, code, resp)
+		}
+	}
+}
+
+func TestHandleBatch(t *testing.T) {
+	srv, s := newServer()
+	defer s.Terminate(nil)
+
+	out := srv.Handle([]byte(`[
+		{"jsonrpc":"2.0","method":"counter.Add","params":[1],"id":1},
+		{"jsonrpc":"2.0","method":"counter.Add","params":[1]},
+		{"jsonrpc":"2.0","method":"counter.Missing","id":2}
+	]`))
+	var resps []map[string]interface{}
+	if err := json.Unmarshal(out, &resps); err != nil {
+		t.Fatalf("%s: %v", out, err)
+	}
+	if len(resps) != 2 {
+		t.Fatalf("expected 2 responses, got %s", out)
+	}
+	if resps[0]["id"] != 1.0 || errorCode(resps[1]) != CodeMethodNotFound {
+		t.Fatalf("unexpected responses %s", out)
+	}
+
+	out = srv.Handle([]byte(`[{"jsonrpc":"2.0","method":"counter.Add","params":[1]}]`))
+	if out != nil {
+		t.Fatalf("batch of notifications got a response %s", out)
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	srv, s := newServer()
+	defer s.Terminate(nil)
+	srv.SetDecoder(DecoderFunc(func(
+		s seriatim.Sequent,
+		method string,
+		params json.RawMessage,
+	) ([]interface{}, error) {
+		var named struct{ N int }
+		if err := json.Unmarshal(params, &named); err != nil {
+			return nil, err
+		}
+		return []interface{}{named.N}, nil
+	}))
+
+	resp := decode(t, srv.Handle([]byte(
+		`{"jsonrpc":"2.0","method":"counter.Add","params":{"n":3},"id":1}`)))
+	if resp["result"] != 3.0 {
+		t.Fatalf("unexpected response %v", resp)
+	}
+}
+
+func TestServeHTTP(t *testing.T) {
+	srv, s := newServer()
+	defer s.Terminate(nil)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	res, err := http.Post(ts.URL, "application/json", strings.NewReader(
+		`{"jsonrpc":"2.0","method":"counter.Add","params":[4],"id":7}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	var resp map[string]interface{}
+	if err := json.NewDecoder(res.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp["result"] != 4.0 || resp["id"] != 7.0 {
+		t.Fatalf("unexpected response %v", resp)
+	}
+
+	res, err = http.Post(ts.URL, "application/json", strings.NewReader(
+		`{"jsonrpc":"2.0","method":"counter.Add","params":[4]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusNoContent {
+		t.Fatalf("expected no content for notification, got %s", res.Status)
+	}
+
+	res, err = http.Get(ts.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected GET to be rejected, got %s", res.Status)
+	}
+}
+
+func TestServeConn(t *testing.T) {
+	srv, s := newServer()
+	defer s.Terminate(nil)
+	client, server := net.Pipe()
+	go srv.ServeConn(server)
+	defer client.Close()
+
+	go client.Write([]byte(`{"jsonrpc":"2.0","method":"counter.Add","params":[1]}
+		{"jsonrpc":"2.0","method":"counter.Get","id":1}`))
+	line, err := bufio.NewReader(client).ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := decode(t, line)
+	if resp["id"] != 1.0 {
+		t.Fatalf("unexpected response %v", resp)
+	}
+}
+
+type sequence struct {
+	seen []int
+}
+
+func (q *sequence) Append(n int) {
+	q.seen = append(q.seen, n)
+}
+
+func (q *sequence) Seen() []int {
+	return q.seen
+}
+
+func TestServeConnNotificationOrder(t *testing.T) {
+	s := seriatim.NewSequent(&sequence{})
+	defer s.Terminate(nil)
+	srv := NewServer()
+	srv.Register("seq", s)
+	client, server := net.Pipe()
+	go srv.ServeConn(server)
+	defer client.Close()
+
+	const n = 100
+	go func() {
+		for i := 0; i < n; i++ {
+			fmt.Fprintf(client,
+				`{"jsonrpc":"2.0","method":"seq.Append","params":[%d]}`, i)
+		}
+		client.Write([]byte(`{"jsonrpc":"2.0","method":"seq.Seen","id":1}`))
+	}()
+	line, err := bufio.NewReader(client).ReadBytes('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	var resp struct {
+		Result []int `json:"result"`
+	}
+	if err := json.Unmarshal(line, &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Result) != n {
+		t.Fatalf("expected %d notifications, got %v", n, resp.Result)
+	}
+	for i, got := range resp.Result {
+		if got != i {
+			t.Fatalf("notifications reordered: %v", resp.Result)
+		}
+	}
+}
+
+func TestHandleCrash(t *testing.T) {
+	srv, s := newServer()
+	defer s.Terminate(nil)
+
+	resp := decode(t, srv.Handle([]byte(
+		`{"jsonrpc":"2.0","method":"counter.Crash","id":1}`)))
+	e, _ := resp["error"].(map[string]interface{})
+	if errorCode(resp) != CodeServerError ||
+		e["message"] != seriatim.ErrSequentStop.Error() || e["data"] == nil {
+		t.Fatalf("expected the sequent to be reported stopped, got %v", resp)
+	}
+}
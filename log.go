@@ -0,0 +1,105 @@
+package seriatim
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// SequentIdKey is the attribute key under which the id of the sequent
+// an event is about is logged.
+const SequentIdKey = "sequent_id"
+
+type loggerHolder struct {
+	logger *slog.Logger
+}
+
+var logger atomic.Value
+
+// defaultLogger reports the crashes and panics of sequents to standard
+// error when no logger is set.
+var defaultLogger = slog.New(slog.NewTextHandler(os.Stderr,
+	&slog.HandlerOptions{Level: slog.LevelError}))
+
+// SetLogger routes the events reported by the package to l:
+//
+//	sequent crashed      error  a sequent crashed, see SetCrashSink
+//	method panicked      error  a method panicked, see WithPanicIsolation
+//	sequent terminated   info   debug if terminated without a reason
+//	message purged       debug  a request dropped unprocessed
+//
+// Passing nil restores the default, which prints crashes and panics
+// and their stacks to standard error. Sequents made with WithLogger
+// log to theirs instead.
+func SetLogger(l *slog.Logger) {
+	logger.Store(loggerHolder{logger: l})
+}
+
+// Logger returns the logger set with SetLogger, or nil if there is
+// none. Packages building on seriatim log their own events to it.
+func Logger() *slog.Logger {
+	holder, _ := logger.Load().(loggerHolder)
+	return holder.logger
+}
+
+// WithLogger logs the events of the sequent, as listed for SetLogger,
+// to l rather than to the package's logger.
+func WithLogger(l *slog.Logger) Option {
+	return func(a *sequent) {
+		a.logger = l
+	}
+}
+
+// log returns the logger the events of the sequent go to.
+func (a *sequent) log() *slog.Logger {
+	if a.logger != nil {
+		return a.logger
+	}
+	if l := Logger(); l != nil {
+		return l
+	}
+	return defaultLogger
+}
+
+// logCrash is the default CrashSink.
+func (a *sequent) logCrash(report *CrashReport) {
+	a.log().Error("sequent crashed",
+		SequentIdKey, report.Id,
+		"type", report.Type,
+		"method", report.Method,
+		"args", report.Args,
+		"reason", report.Reason,
+		"stack", string(report.Stack))
+}
+
+// logPanic reports a panic of method, with the stack of the panicking
+// goroutine when called from the function recovering it.
+func (a *sequent) logPanic(msg, method string, reason error) {
+	a.log().Error(msg,
+		SequentIdKey, a.Id(),
+		"type", a.typeName(),
+		"method", method,
+		"reason", reason,
+		"stack", string(debug.Stack()))
+}
+
+func (a *sequent) logTerminated(reason error, final State) {
+	level := slog.LevelInfo
+	if reason == nil {
+		level = slog.LevelDebug
+	}
+	a.log().Log(context.Background(), level, "sequent terminated",
+		SequentIdKey, a.Id(),
+		"type", a.typeName(),
+		"state", final.String(),
+		"reason", reason)
+}
+
+func (a *sequent) logPurged(method string) {
+	a.log().Debug("message purged",
+		SequentIdKey, a.Id(),
+		"type", a.typeName(),
+		"method", method)
+}
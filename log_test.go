@@ -0,0 +1,61 @@
+package seriatim
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestLoggerCrash(t *testing.T) {
+	var buf bytes.Buffer
+	SetLogger(slog.New(slog.NewTextHandler(&buf, nil)))
+	defer SetLogger(nil)
+
+	crash := NewSUT(false)
+	crash.Cast("Crash")
+	crash.WaitTerminate()
+	out := buf.String()
+	if !strings.Contains(out, "sequent crashed") ||
+		!strings.Contains(out, SequentIdKey+"=") ||
+		!strings.Contains(out, "stack=") {
+		t.Fatalf("crash not logged: %q", out)
+	}
+
+	SetLogger(nil)
+	if Logger() != nil {
+		t.Fatal("SetLogger(nil) did not restore the default")
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	var global bytes.Buffer
+	SetLogger(slog.New(slog.NewTextHandler(&global, nil)))
+	defer SetLogger(nil)
+
+	var buf bytes.Buffer
+	g := &gated{release: make(chan struct{})}
+	s := NewSequent(g, WithMailboxSize(4),
+		WithLogger(slog.New(slog.NewTextHandler(&buf,
+			&slog.HandlerOptions{Level: slog.LevelDebug}))))
+	s.Cast("Block")
+	s.Cast("Incr")
+	s.Cast("Incr")
+	s.Terminate(errors.New("finished"))
+	close(g.release)
+	<-s.Done()
+
+	out := buf.String()
+	if !strings.Contains(out, `msg="sequent terminated"`) ||
+		!strings.Contains(out, "reason=finished") {
+		t.Fatalf("termination not logged: %q", out)
+	}
+	if n := strings.Count(out, `msg="message purged"`); n < 2 ||
+		!strings.Contains(out, "method=Incr") {
+		t.Fatalf("purged messages not logged: %q", out)
+	}
+	if global.Len() != 0 {
+		t.Fatalf("events logged to the package's logger: %q", global.String())
+	}
+}
@@ -0,0 +1,47 @@
+package seriatim
+
+import (
+	"errors"
+	"time"
+)
+
+var ErrMethodTimeout = errors.New("Method timed out")
+
+// MethodSpec is an entry of a method table carrying options for the
+// method next to its function, which plain entries are:
+//
+//	NewSequentTable(val, map[string]interface{}{
+//		"Get":  val.Get,
+//		"Sync": MethodSpec{Func: val.Sync, Timeout: time.Second},
+//	})
+type MethodSpec struct {
+	Func interface{}
+	// Timeout is the deadline for processing the method, counted
+	// from when it is called or cast. A Call still waiting then
+	// fails with ErrMethodTimeout and a request whose deadline
+	// passed while it was queued is dropped, failing with
+	// ErrMethodTimeout where it is reported. A method already
+	// running is not interrupted. Zero means no deadline.
+	Timeout time.Duration
+	// Priority and Class are not interpreted by the sequent; they are
+	// reported by Methods for the code routing and scheduling
+	// requests.
+	Priority int
+	Class    string
+}
+
+// methodSpecs returns the MethodSpec entries of methods.
+func methodSpecs(methods map[string]interface{}) map[string]MethodSpec {
+	out := make(map[string]MethodSpec)
+	for name, method := range methods {
+		if spec, ok := method.(MethodSpec); ok {
+			out[name] = spec
+		}
+	}
+	return out
+}
+
+// expired reports whether the deadline of req passed.
+func (req *request) expired() bool {
+	return !req.deadline.IsZero() && time.Now().After(req.deadline)
+}
@@ -0,0 +1,111 @@
+package seriatim
+
+import (
+	"testing"
+	"time"
+)
+
+type slowStore struct {
+	started chan struct{}
+	release chan struct{}
+	synced  int
+}
+
+func (s *slowStore) Sync() int {
+	s.synced++
+	return s.synced
+}
+
+func (s *slowStore) Block() {
+	s.started <- struct{}{}
+	<-s.release
+}
+
+func newSlowStore() (*slowStore, Sequent) {
+	v := &slowStore{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	return v, NewSequentTable(v, map[string]interface{}{
+		"Sync": MethodSpec{
+			Func:     v.Sync,
+			Timeout:  10 * time.Millisecond,
+			Priority: 1,
+			Class:    "io",
+		},
+		"Block": v.Block,
+	}, WithMailboxSize(4))
+}
+
+func TestMethodSpecTimeout(t *testing.T) {
+	v, s := newSlowStore()
+	defer s.Terminate(nil)
+
+	rets, err := s.Call("Sync")
+	if err != nil || rets[0] != 1 {
+		t.Fatalf("unexpected %v %v", rets, err)
+	}
+
+	go s.Call("Block")
+	<-v.started
+	if _, err := s.Call("Sync"); err != ErrMethodTimeout {
+		t.Fatalf("expected ErrMethodTimeout, got %v", err)
+	}
+	errs := make(chan error, 1)
+	if err := s.CastNotify("Sync", errs); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(v.release)
+	if err := <-errs; err != ErrMethodTimeout {
+		t.Fatalf("expected ErrMethodTimeout, got %v", err)
+	}
+	// the expired requests were dropped
+	rets, err = s.Call("Sync")
+	if err != nil || rets[0] != 2 {
+		t.Fatalf("unexpected %v %v", rets, err)
+	}
+}
+
+func TestMethodSpecCallTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	s := NewSequentTable(&slowStore{}, map[string]interface{}{
+		"Stuck": MethodSpec{
+			Func:    func() { <-release },
+			Timeout: 10 * time.Millisecond,
+		},
+	})
+	defer s.Terminate(nil)
+	// taken well within d, the method outlives its own deadline
+	errs := make(chan error, 1)
+	go func() {
+		_, err := CallTimeout(s, "Stuck", time.Hour)
+		errs <- err
+	}()
+	select {
+	case err := <-errs:
+		if err != ErrMethodTimeout {
+			t.Fatalf("expected ErrMethodTimeout, got %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("CallTimeout waited past the deadline of the method")
+	}
+}
+
+func TestMethodSpecMethods(t *testing.T) {
+	_, s := newSlowStore()
+	defer s.Terminate(nil)
+	methods := s.Methods()
+	if len(methods) != 2 {
+		t.Fatalf("expected 2 methods, got %v", methods)
+	}
+	sync := methods[1]
+	if sync.Name != "Sync" || sync.Timeout != 10*time.Millisecond ||
+		sync.Priority != 1 || sync.Class != "io" {
+		t.Fatalf("unexpected %+v", sync)
+	}
+	if methods[0].Timeout != 0 {
+		t.Fatalf("unexpected %+v", methods[0])
+	}
+}
@@ -0,0 +1,63 @@
+package seriatim
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Metrics is told about every request processed by a sequent created
+// with WithMetrics, such as to feed the timings to monitoring. waited
+// is the time from the request being made to it being processed,
+// including any time spent blocked on a full mailbox, and took the
+// time its method ran; casts handled together by a BatchHandler share
+// the time of the batch evenly. Processed is called on the sequent's
+// goroutine and holds up its next request.
+type Metrics interface {
+	Processed(id uintptr, method string, waited, took time.Duration)
+}
+
+// MetricsFunc is a Metrics calling itself.
+type MetricsFunc func(id uintptr, method string, waited, took time.Duration)
+
+func (fn MetricsFunc) Processed(
+	id uintptr,
+	method string,
+	waited, took time.Duration,
+) {
+	fn(id, method, waited, took)
+}
+
+// WithMetrics reports every request processed by the sequent to m.
+func WithMetrics(m Metrics) Option {
+	return func(a *sequent) {
+		a.metrics = m
+	}
+}
+
+// record accounts for req having been processed from started for
+// took.
+func (a *sequent) record(req *request, started time.Time, took time.Duration) {
+	waited := started.Sub(req.made)
+	if req.reply != nil {
+		atomic.AddUint64(&a.calls, 1)
+	} else {
+		atomic.AddUint64(&a.casts, 1)
+	}
+	atomic.AddInt64(&a.queueNanos, int64(waited))
+	atomic.AddInt64(&a.processNanos, int64(took))
+	if a.metrics != nil {
+		a.metrics.Processed(a.Id(), req.name, waited, took)
+	}
+}
+
+// queued raises the high-water mark of the mailbox to its current
+// length.
+func (a *sequent) queued() {
+	n := int64(a.queue.Len())
+	for {
+		high := atomic.LoadInt64(&a.highWater)
+		if n <= high || atomic.CompareAndSwapInt64(&a.highWater, high, n) {
+			return
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package seriatim
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMetrics(t *testing.T) {
+	var mu sync.Mutex
+	var methods []string
+	var blocked time.Duration
+	metrics := MetricsFunc(func(id uintptr, method string, waited, took time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		methods = append(methods, method)
+		if method == "Block" {
+			blocked = took
+		}
+	})
+	g := &gated{release: make(chan struct{})}
+	s := NewSequent(g, WithMailboxSize(4), WithMetrics(metrics))
+	defer s.Terminate(nil)
+	if err := s.Cast("Block"); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := s.Cast("Incr"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(g.release)
+	if _, err := s.Call("Incr"); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := s.Stats()
+	if stats.Calls != 1 || stats.Casts != 4 || stats.Processed != 5 {
+		t.Fatalf("unexpected counts %+v", stats)
+	}
+	if stats.QueueHighWater < 3 {
+		t.Fatalf("expected a high-water mark of at least 3, got %d",
+			stats.QueueHighWater)
+	}
+	if stats.ProcessTime < 10*time.Millisecond ||
+		stats.QueueTime < 30*time.Millisecond {
+		t.Fatalf("unexpected times %+v", stats)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	want := []string{"Block", "Incr", "Incr", "Incr", "Incr"}
+	if !reflect.DeepEqual(methods, want) {
+		t.Fatalf("expected %v reported, got %v", want, methods)
+	}
+	if blocked < 10*time.Millisecond {
+		t.Fatalf("expected Block to take at least 10ms, took %v", blocked)
+	}
+}
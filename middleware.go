@@ -0,0 +1,116 @@
+package seriatim
+
+import (
+	"errors"
+	"reflect"
+	"time"
+)
+
+// ErrNoMiddleware is returned by Use for sequents not made by this
+// package.
+var ErrNoMiddleware = errors.New("Sequent does not support middleware")
+
+// Handler dispatches a request of a sequent, returning the results of
+// its method. The error a method returns is its last result; an error
+// returned by a Handler fails the request instead, as a panic isolated
+// by WithPanicIsolation does.
+type Handler func(inv Invocation) ([]interface{}, error)
+
+// Middleware wraps the Handler dispatching the requests of a sequent,
+// such as to log them, time them or validate their arguments. It may
+// change the invocation before passing it on to next, or answer it
+// without calling next at all.
+type Middleware func(next Handler) Handler
+
+// WithMiddleware dispatches the requests of the sequent through mw, as
+// if Use were called before any request could be made.
+func WithMiddleware(mw ...Middleware) Option {
+	return func(a *sequent) {
+		a.middleware = append(a.middleware, mw...)
+	}
+}
+
+// Use dispatches the requests of s processed from now on through mw,
+// on the goroutine of s, the first of mw seeing them first. Middleware
+// added by earlier calls sees them before mw does. The casts handled
+// together by a BatchHandler are not dispatched through middleware.
+// Like a Cast, Use blocks while the mailbox is full.
+func Use(s Sequent, mw ...Middleware) error {
+	a, ok := s.(*sequent)
+	if !ok {
+		return ErrNoMiddleware
+	}
+	if !a.accepting() {
+		return ErrSequentStop
+	}
+	req := &request{
+		method: reflect.ValueOf(func() {
+			a.middleware = append(a.middleware, mw...)
+			a.chain()
+		}),
+		made:  time.Now(),
+		owner: a,
+	}
+	return a.enqueue(req, nil)
+}
+
+// chain builds the Handler of the sequent from its middleware.
+func (a *sequent) chain() {
+	if len(a.middleware) == 0 {
+		a.handler = nil
+		return
+	}
+	h := Handler(a.invoke)
+	for i := len(a.middleware) - 1; i >= 0; i-- {
+		h = a.middleware[i](h)
+	}
+	a.handler = h
+}
+
+// invoke is the innermost Handler, calling the method of the current
+// value.
+func (a *sequent) invoke(inv Invocation) ([]interface{}, error) {
+	method, ok := a.current.methods[inv.Method]
+	if !ok {
+		return nil, ErrUnknownMethod
+	}
+	args, err := processMethodArguments(inv.Method, method, inv.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer a.withContext(method, args, a.dispatching)()
+	return processMethodReturns(callMethod(method, args)), nil
+}
+
+// dispatch calls the method of req, through the middleware if there is
+// any. Requests made by the package itself bypass it.
+func (a *sequent) dispatch(req *request) ([]reflect.Value, error) {
+	if a.handler == nil || req.name == "" {
+		defer a.withContext(req.method, req.args, req)()
+		return callMethod(req.method, req.args), nil
+	}
+	a.dispatching = req
+	defer func() { a.dispatching = nil }()
+	results, err := a.handler(Invocation{
+		Method: req.name,
+		Args:   processMethodReturns(callerArgs(req)),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resultValues(results), nil
+}
+
+// resultValues returns results as the values of the results of a
+// method, keeping a last error result an error for CastNotify.
+func resultValues(results []interface{}) []reflect.Value {
+	out := make([]reflect.Value, len(results))
+	for i := range results {
+		if err, ok := results[i].(error); ok && i == len(results)-1 {
+			out[i] = reflect.ValueOf(&err).Elem()
+			continue
+		}
+		out[i] = reflect.ValueOf(&results[i]).Elem()
+	}
+	return out
+}
@@ -0,0 +1,106 @@
+package seriatim
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type adder struct {
+	total int
+}
+
+func (a *adder) Add(n int) (int, error) {
+	if n == 0 {
+		return a.total, errors.New("Nothing to add")
+	}
+	a.total += n
+	return a.total, nil
+}
+
+func (a *adder) Crash() {
+	panic("crashed")
+}
+
+var errNegative = errors.New("Negative argument")
+
+func validate(next Handler) Handler {
+	return func(inv Invocation) ([]interface{}, error) {
+		for _, arg := range inv.Args {
+			if n, ok := arg.(int); ok && n < 0 {
+				return nil, errNegative
+			}
+		}
+		return next(inv)
+	}
+}
+
+func recovering(next Handler) Handler {
+	return func(inv Invocation) (rets []interface{}, err error) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				err = panicReason(rec)
+			}
+		}()
+		return next(inv)
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	var seen []string
+	logging := func(next Handler) Handler {
+		return func(inv Invocation) ([]interface{}, error) {
+			seen = append(seen, inv.String())
+			return next(inv)
+		}
+	}
+	s := NewSequent(&adder{}, WithMiddleware(logging, validate))
+	defer s.Terminate(nil)
+
+	if _, err := s.Call("Add", -1); err != errNegative {
+		t.Fatalf("expected errNegative, got %v", err)
+	}
+	errs := make(chan error, 1)
+	if err := s.CastNotify("Add", errs, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err == nil || err.Error() != "Nothing to add" {
+		t.Fatalf("expected the error of the method, got %v", err)
+	}
+	rets, err := s.Call("Add", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rets[0].(int) != 2 || rets[1] != nil {
+		t.Fatalf("unexpected results %v", rets)
+	}
+	want := []string{"Add(-1)", "Add(0)", "Add(2)"}
+	if !reflect.DeepEqual(seen, want) {
+		t.Fatalf("expected %v seen, got %v", want, seen)
+	}
+}
+
+func TestUse(t *testing.T) {
+	s := NewSequent(&adder{})
+	defer s.Terminate(nil)
+	if _, err := s.Call("Add", -1); err != nil {
+		t.Fatal(err)
+	}
+	if err := Use(s, recovering, validate); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Call("Add", -1); err != errNegative {
+		t.Fatalf("expected errNegative, got %v", err)
+	}
+	if _, err := s.Call("Crash"); err == nil || err.Error() != "crashed" {
+		t.Fatalf("expected the panic as an error, got %v", err)
+	}
+	if !s.Running() {
+		t.Fatal("a recovered panic crashed the sequent")
+	}
+	r := NewReplica(NewSequent(&adder{}), NewSequent(&adder{}))
+	defer r.Terminate(nil)
+	if err := Use(r, validate); err != ErrNoMiddleware {
+		t.Fatalf("expected ErrNoMiddleware, got %v", err)
+	}
+}
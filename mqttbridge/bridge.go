@@ -0,0 +1,191 @@
+// Package mqttbridge republishes emitted D-Bus signals to MQTT topics
+// and turns messages on subscribed topics into casts on sequents.
+//
+// The bridge works with any MQTT library through the small Client
+// interface, usually satisfied by a few lines wrapping the library's
+// client and token types.
+package mqttbridge
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jsouthworth/seriatim"
+	"github.com/jsouthworth/seriatim/dbus"
+)
+
+// Client is the subset of an MQTT client used by the bridge.
+type Client interface {
+	Publish(topic string, qos byte, retained bool, payload []byte) error
+	Subscribe(topic string, qos byte, handler func(topic string, payload []byte)) error
+	Unsubscribe(topic string) error
+}
+
+// Signals is the source of republished signals, usually a
+// *dbus.BusManager.
+type Signals interface {
+	Observe(fn func(*dbus.EmittedSignal)) (cancel func())
+}
+
+type Bridge struct {
+	client  Client
+	dropped uint64
+
+	mu     sync.Mutex
+	routes map[string]bool
+
+	// Topic maps a signal to the topic it is published on. The
+	// default publishes /a/b's com.example.Foo.Changed on
+	// prefix/a/b/com.example.Foo/Changed.
+	Topic func(signal *dbus.EmittedSignal) string
+	// Encode produces the payload of a signal, by default the JSON
+	// array of its body.
+	Encode func(signal *dbus.EmittedSignal) ([]byte, error)
+	// Decode produces the cast arguments for a message. By default
+	// a JSON array is spread over the arguments, any other JSON
+	// value is a single argument and anything else is passed as a
+	// string.
+	Decode func(topic string, payload []byte) ([]interface{}, error)
+	// OnError is called with publish, decode and cast failures.
+	OnError func(error)
+
+	QoS      byte
+	Retained bool
+	// Backlog is the number of signals queued for publishing;
+	// signals arriving while it is full are dropped rather than
+	// blocking the emitter.
+	Backlog int
+}
+
+func New(client Client, prefix string) *Bridge {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return &Bridge{
+		client: client,
+		routes: make(map[string]bool),
+		Topic: func(signal *dbus.EmittedSignal) string {
+			parts := make([]string, 0, 4)
+			if prefix != "" {
+				parts = append(parts, prefix)
+			}
+			if path := strings.Trim(string(signal.Path), "/"); path != "" {
+				parts = append(parts, path)
+			}
+			parts = append(parts, signal.Interface, signal.Member)
+			return strings.Join(parts, "/")
+		},
+		Encode: func(signal *dbus.EmittedSignal) ([]byte, error) {
+			body := signal.Body
+			if body == nil {
+				body = []interface{}{}
+			}
+			return json.Marshal(body)
+		},
+		Decode:  decodePayload,
+		Backlog: 64,
+	}
+}
+
+func decodePayload(topic string, payload []byte) ([]interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(payload, &value); err != nil {
+		return []interface{}{string(payload)}, nil
+	}
+	if args, ok := value.([]interface{}); ok {
+		return args, nil
+	}
+	return []interface{}{value}, nil
+}
+
+func (b *Bridge) error(err error) {
+	if b.OnError != nil {
+		b.OnError(err)
+	}
+}
+
+// Dropped reports how many signals were dropped because the backlog
+// was full.
+func (b *Bridge) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
+// Republish publishes the signals observed on signals until the
+// returned function is called.
+func (b *Bridge) Republish(signals Signals) (cancel func()) {
+	queue := make(chan *dbus.EmittedSignal, b.Backlog)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case signal := <-queue:
+				b.publish(signal)
+			case <-done:
+				return
+			}
+		}
+	}()
+	stop := signals.Observe(func(signal *dbus.EmittedSignal) {
+		select {
+		case queue <- signal:
+		default:
+			atomic.AddUint64(&b.dropped, 1)
+		}
+	})
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			stop()
+			close(done)
+		})
+	}
+}
+
+func (b *Bridge) publish(signal *dbus.EmittedSignal) {
+	payload, err := b.Encode(signal)
+	if err != nil {
+		b.error(fmt.Errorf("encoding %s.%s: %v",
+			signal.Interface, signal.Member, err))
+		return
+	}
+	topic := b.Topic(signal)
+	if err := b.client.Publish(topic, b.QoS, b.Retained, payload); err != nil {
+		b.error(fmt.Errorf("publishing %s: %v", topic, err))
+	}
+}
+
+// Route subscribes to topic, which may contain wildcards, and casts
+// method on s with the decoded arguments of every message received.
+func (b *Bridge) Route(topic string, s seriatim.Sequent, method string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.routes[topic] {
+		return fmt.Errorf("topic %s is already routed", topic)
+	}
+	err := b.client.Subscribe(topic, b.QoS, func(topic string, payload []byte) {
+		args, err := b.Decode(topic, payload)
+		if err != nil {
+			b.error(fmt.Errorf("decoding message on %s: %v", topic, err))
+			return
+		}
+		if err := s.Cast(method, args...); err != nil {
+			b.error(fmt.Errorf("casting %s from %s: %v", method, topic, err))
+		}
+	})
+	if err != nil {
+		return err
+	}
+	b.routes[topic] = true
+	return nil
+}
+
+func (b *Bridge) Unroute(topic string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.routes[topic] {
+		return fmt.Errorf("topic %s is not routed", topic)
+	}
+	delete(b.routes, topic)
+	return b.client.Unsubscribe(topic)
+}
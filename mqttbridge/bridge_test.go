@@ -0,0 +1,192 @@
+package mqttbridge
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jsouthworth/seriatim"
+	"github.com/jsouthworth/seriatim/dbus"
+)
+
+type message struct {
+	topic   string
+	payload string
+}
+
+type fakeClient struct {
+	mu        sync.Mutex
+	published chan message
+	handlers  map[string]func(string, []byte)
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{
+		published: make(chan message, 8),
+		handlers:  make(map[string]func(string, []byte)),
+	}
+}
+
+func (c *fakeClient) Publish(topic string, qos byte, retained bool, payload []byte) error {
+	c.published <- message{topic, string(payload)}
+	return nil
+}
+
+func (c *fakeClient) Subscribe(topic string, qos byte, handler func(string, []byte)) error {
+	c.mu.Lock()
+	c.handlers[topic] = handler
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *fakeClient) Unsubscribe(topic string) error {
+	c.mu.Lock()
+	delete(c.handlers, topic)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *fakeClient) deliver(filter, topic, payload string) bool {
+	c.mu.Lock()
+	handler, ok := c.handlers[filter]
+	c.mu.Unlock()
+	if ok {
+		handler(topic, []byte(payload))
+	}
+	return ok
+}
+
+type fakeSignals struct {
+	observer func(*dbus.EmittedSignal)
+}
+
+func (f *fakeSignals) Observe(fn func(*dbus.EmittedSignal)) func() {
+	f.observer = fn
+	return func() { f.observer = nil }
+}
+
+func TestRepublish(t *testing.T) {
+	client := newFakeClient()
+	signals := &fakeSignals{}
+	b := New(client, "home/")
+	cancel := b.Republish(signals)
+
+	signals.observer(&dbus.EmittedSignal{
+		Path:      "/sensors/kitchen",
+		Interface: "com.example.Sensor",
+		Member:    "Reading",
+		Body:      []interface{}{"temp", 21.5},
+	})
+	select {
+	case msg := <-client.published:
+		expected := message{
+			"home/sensors/kitchen/com.example.Sensor/Reading",
+			`["temp",21.5]`,
+		}
+		if msg != expected {
+			t.Fatalf("expected %v, got %v", expected, msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("signal was not published")
+	}
+
+	cancel()
+	if signals.observer != nil {
+		t.Fatal("cancel left the observer registered")
+	}
+}
+
+func TestTopicMapping(t *testing.T) {
+	client := newFakeClient()
+	signals := &fakeSignals{}
+	b := New(client, "")
+	b.Topic = func(signal *dbus.EmittedSignal) string {
+		return "events/" + signal.Member
+	}
+	defer b.Republish(signals)()
+
+	signals.observer(&dbus.EmittedSignal{Path: "/", Interface: "a.b", Member: "C"})
+	msg := <-client.published
+	if msg.topic != "events/C" || msg.payload != "[]" {
+		t.Fatalf("unexpected message %v", msg)
+	}
+}
+
+type recorder struct {
+	got [][]interface{}
+}
+
+func (r *recorder) Set(name string, value float64) {
+	r.got = append(r.got, []interface{}{name, value})
+}
+
+func (r *recorder) Get() [][]interface{} {
+	return r.got
+}
+
+func TestRoute(t *testing.T) {
+	client := newFakeClient()
+	s := seriatim.NewSequent(&recorder{})
+	defer s.Terminate(nil)
+	b := New(client, "")
+	var errs []error
+	b.OnError = func(err error) { errs = append(errs, err) }
+	decode := b.Decode
+	b.Decode = func(topic string, payload []byte) ([]interface{}, error) {
+		if topic == "set/bad" {
+			return nil, errors.New("bad payload")
+		}
+		return decode(topic, payload)
+	}
+
+	if err := b.Route("set/#", s, "Set"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Route("set/#", s, "Set"); err == nil {
+		t.Fatal("routing a topic twice should fail")
+	}
+	client.deliver("set/#", "set/a", `["a", 1]`)
+	client.deliver("set/#", "set/bad", `["b", 2]`)
+
+	rets, err := s.Call("Get")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]interface{}{{"a", 1.0}}
+	if !reflect.DeepEqual(rets[0], expected) {
+		t.Fatalf("expected %v, got %v", expected, rets[0])
+	}
+	if len(errs) != 1 {
+		t.Fatalf("expected the decode failure to be reported, got %v", errs)
+	}
+
+	if err := b.Unroute("set/#"); err != nil {
+		t.Fatal(err)
+	}
+	if client.deliver("set/#", "set/a", "[]") {
+		t.Fatal("unroute left the subscription")
+	}
+	if err := b.Unroute("set/#"); err == nil {
+		t.Fatal("unrouting an unknown topic should fail")
+	}
+}
+
+func TestDecodePayload(t *testing.T) {
+	tests := map[string][]interface{}{
+		`[1, "a"]`: {1.0, "a"},
+		`{"a": 1}`: {map[string]interface{}{"a": 1.0}},
+		`42`:       {42.0},
+		`on`:       {"on"},
+	}
+	for payload, expected := range tests {
+		got, err := decodePayload("t", []byte(payload))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("%s: expected %v, got %v", payload, expected, got)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Package natsremote lets Cast reach sequents in other processes
+// through NATS subjects.
+//
+// A process exports a sequent on a subject; other processes cast to
+// it through a Remote. Every cast is acknowledged once it has been
+// queued on the exported sequent, so Remote.Cast reports the same
+// errors as a local Cast: ErrUnknownMethod, argument mismatches and
+// ErrSequentStop.
+//
+// The arguments of a cast are encoded with the codec.Codec of the
+// Remote, whose name travels with them, so the exporting side decodes
+// them with the codec registered under that name.
+//
+// The package works with any NATS client through the small Conn
+// interface, usually satisfied by a few lines wrapping *nats.Conn.
+package natsremote
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jsouthworth/seriatim"
+	"github.com/jsouthworth/seriatim/codec"
+)
+
+// DefaultTimeout bounds how long a Remote waits for a cast to be
+// acknowledged.
+const DefaultTimeout = 5 * time.Second
+
+// Conn is the subset of a NATS connection used for remote casts.
+// Request publishes data with a reply subject and waits for the
+// first reply. Subscribe calls handler for every message on subject,
+// respond publishing to the message's reply subject.
+type Conn interface {
+	Request(subject string, data []byte, timeout time.Duration) ([]byte, error)
+	Subscribe(
+		subject string,
+		handler func(data []byte, respond func([]byte) error),
+	) (Subscription, error)
+}
+
+type Subscription interface {
+	Unsubscribe() error
+}
+
+// Error codes carried in acknowledgements for the errors a local
+// Cast is told apart by.
+const (
+	codeUnknownMethod = "unknown-method"
+	codeStopped       = "stopped"
+)
+
+// message is a cast; the message and its acknowledgement are gob
+// encoded, the arguments by the codec named in the message.
+type message struct {
+	Method string
+	Codec  string
+	Args   []byte
+}
+
+type castAck struct {
+	Code  string
+	Error string
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func ackFor(err error) *castAck {
+	switch {
+	case err == nil:
+		return &castAck{}
+	case errors.Is(err, seriatim.ErrUnknownMethod):
+		return &castAck{Code: codeUnknownMethod, Error: err.Error()}
+	case errors.Is(err, seriatim.ErrSequentStop):
+		return &castAck{Code: codeStopped, Error: err.Error()}
+	}
+	return &castAck{Error: err.Error()}
+}
+
+func (ack *castAck) err() error {
+	switch ack.Code {
+	case codeUnknownMethod:
+		return seriatim.ErrUnknownMethod
+	case codeStopped:
+		return seriatim.ErrSequentStop
+	}
+	if ack.Error != "" {
+		return errors.New(ack.Error)
+	}
+	return nil
+}
+
+// Export casts the messages received on subject to s until the
+// returned subscription is unsubscribed.
+func Export(conn Conn, subject string, s seriatim.Sequent) (Subscription, error) {
+	return conn.Subscribe(subject, func(data []byte, respond func([]byte) error) {
+		out, err := marshal(ackFor(cast(s, data)))
+		if err != nil {
+			return
+		}
+		respond(out)
+	})
+}
+
+// cast decodes the message in data and casts it to s, decoding the
+// arguments into the parameter types of its method where s describes
+// them.
+func cast(s seriatim.Sequent, data []byte) error {
+	var msg message
+	if err := unmarshal(data, &msg); err != nil {
+		return err
+	}
+	c, ok := codec.Lookup(msg.Codec)
+	if !ok {
+		return fmt.Errorf("Unknown codec %q", msg.Codec)
+	}
+	args, err := c.Decode(msg.Args, codec.ArgTypes(s, msg.Method))
+	if err != nil {
+		return err
+	}
+	return s.Cast(msg.Method, args...)
+}
+
+// Remote casts to a sequent exported on a subject.
+type Remote struct {
+	conn    Conn
+	subject string
+	codec   codec.Codec
+
+	// Timeout bounds how long Cast waits for the acknowledgement.
+	Timeout time.Duration
+}
+
+func NewRemote(conn Conn, subject string, c codec.Codec) *Remote {
+	return &Remote{
+		conn:    conn,
+		subject: subject,
+		codec:   c,
+		Timeout: DefaultTimeout,
+	}
+}
+
+func (r *Remote) Subject() string {
+	return r.subject
+}
+
+// Cast queues method on the remote sequent and returns once the
+// remote process has acknowledged it.
+func (r *Remote) Cast(name string, args ...interface{}) error {
+	return r.CastInvocation(seriatim.Invocation{Method: name, Args: args})
+}
+
+// CastInvocation casts inv like Cast.
+func (r *Remote) CastInvocation(inv seriatim.Invocation) error {
+	args, err := r.codec.Encode(inv.Args)
+	if err != nil {
+		return err
+	}
+	data, err := marshal(&message{
+		Method: inv.Method,
+		Codec:  r.codec.Name(),
+		Args:   args,
+	})
+	if err != nil {
+		return err
+	}
+	reply, err := r.conn.Request(r.subject, data, r.Timeout)
+	if err != nil {
+		return err
+	}
+	var ack castAck
+	if err := unmarshal(reply, &ack); err != nil {
+		return err
+	}
+	return ack.err()
+}
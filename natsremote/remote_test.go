@@ -0,0 +1,155 @@
+package natsremote
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jsouthworth/seriatim"
+	"github.com/jsouthworth/seriatim/codec"
+)
+
+var errNoResponders = errors.New("no responders")
+
+// memConn delivers requests to subscribers in the same process.
+type memConn struct {
+	mu       sync.Mutex
+	handlers map[string]func([]byte, func([]byte) error)
+}
+
+func newMemConn() *memConn {
+	return &memConn{handlers: make(map[string]func([]byte, func([]byte) error))}
+}
+
+func (c *memConn) Request(subject string, data []byte, timeout time.Duration) ([]byte, error) {
+	c.mu.Lock()
+	handler, ok := c.handlers[subject]
+	c.mu.Unlock()
+	if !ok {
+		return nil, errNoResponders
+	}
+	replies := make(chan []byte, 1)
+	go handler(data, func(reply []byte) error {
+		replies <- reply
+		return nil
+	})
+	select {
+	case reply := <-replies:
+		return reply, nil
+	case <-time.After(timeout):
+		return nil, errors.New("timeout")
+	}
+}
+
+type memSubscription struct {
+	conn    *memConn
+	subject string
+}
+
+func (s *memSubscription) Unsubscribe() error {
+	s.conn.mu.Lock()
+	delete(s.conn.handlers, s.subject)
+	s.conn.mu.Unlock()
+	return nil
+}
+
+func (c *memConn) Subscribe(
+	subject string,
+	handler func([]byte, func([]byte) error),
+) (Subscription, error) {
+	c.mu.Lock()
+	c.handlers[subject] = handler
+	c.mu.Unlock()
+	return &memSubscription{conn: c, subject: subject}, nil
+}
+
+type accumulator struct {
+	total int64
+	notes []string
+}
+
+func (a *accumulator) Add(n int64, note string) {
+	a.total += n
+	a.notes = append(a.notes, note)
+}
+
+func (a *accumulator) Total() (int64, []string) {
+	return a.total, a.notes
+}
+
+func testRemoteCast(t *testing.T, c codec.Codec) {
+	conn := newMemConn()
+	s := seriatim.NewSequent(&accumulator{})
+	sub, err := Export(conn, "acc", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remote := NewRemote(conn, "acc", c)
+
+	if err := remote.Cast("Add", int64(2), "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := remote.Cast("Add", int64(3), "b"); err != nil {
+		t.Fatal(err)
+	}
+	rets, err := s.Call("Total")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rets[0].(int64) != 5 || strings.Join(rets[1].([]string), "") != "ab" {
+		t.Fatalf("unexpected totals %v", rets)
+	}
+
+	if err := remote.Cast("Missing"); err != seriatim.ErrUnknownMethod {
+		t.Fatalf("expected ErrUnknownMethod, got %v", err)
+	}
+	if err := remote.Cast("Add", int64(1)); err == nil {
+		t.Fatal("expected an argument count error")
+	}
+	s.Terminate(nil)
+	for s.Running() {
+		time.Sleep(time.Millisecond)
+	}
+	if err := remote.Cast("Add", int64(1), "c"); err != seriatim.ErrSequentStop {
+		t.Fatalf("expected ErrSequentStop, got %v", err)
+	}
+
+	sub.Unsubscribe()
+	if err := remote.Cast("Add", int64(1), "c"); err != errNoResponders {
+		t.Fatalf("expected the transport error, got %v", err)
+	}
+}
+
+func TestRemoteCastJSON(t *testing.T) {
+	testRemoteCast(t, codec.JSON)
+}
+
+func TestRemoteCastGob(t *testing.T) {
+	testRemoteCast(t, codec.Gob)
+}
+
+func TestRemoteCastUnknownCodec(t *testing.T) {
+	conn := newMemConn()
+	s := seriatim.NewSequent(&accumulator{})
+	defer s.Terminate(nil)
+	if _, err := Export(conn, "acc", s); err != nil {
+		t.Fatal(err)
+	}
+	data, err := marshal(&message{Method: "Add", Codec: "cbor"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reply, err := conn.Request("acc", data, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ack castAck
+	if err := unmarshal(reply, &ack); err != nil {
+		t.Fatal(err)
+	}
+	if err := ack.err(); err == nil || err.Error() != `Unknown codec "cbor"` {
+		t.Fatalf("expected the unknown codec to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,261 @@
+// Package netrpc lets net/rpc clients drive sequents.
+//
+// The methods of a sequent do not have the shape net/rpc requires of
+// registered receivers, so instead of registering with an rpc.Server
+// the Server here reads requests from any rpc.ServerCodec and
+// dispatches "Service.Method" to the sequent registered as Service.
+// Existing clients made with rpc.NewClient, rpc.Dial or
+// jsonrpc.NewClient work unchanged:
+//
+//	srv := netrpc.NewServer()
+//	srv.Register("Accounts", s, seriatim.GetMethods(val))
+//	go srv.Accept(l)
+//	...
+//	client.Call("Accounts.Deposit", 10, &balance)
+//
+// A method taking one argument receives the args value of the call, a
+// method taking several receives the elements of an args value of type
+// []interface{}. A trailing error result becomes the error of the call;
+// a single remaining result is the reply and several are replied as a
+// []interface{}.
+package netrpc
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/jsouthworth/seriatim"
+)
+
+var errtype = reflect.TypeOf((*error)(nil)).Elem()
+
+type service struct {
+	sequent seriatim.Sequent
+	methods map[string]seriatim.MethodInfo
+}
+
+type Server struct {
+	mu       sync.RWMutex
+	services map[string]*service
+}
+
+func NewServer() *Server {
+	return &Server{services: make(map[string]*service)}
+}
+
+// Register serves s as name. methods is the method table the sequent
+// was created with and is only used for its parameter types; the
+// context.Context taken first by some methods is given by the sequent,
+// not read from the call.
+func (srv *Server) Register(
+	name string,
+	s seriatim.Sequent,
+	methods map[string]interface{},
+) error {
+	for method, fn := range methods {
+		typ := reflect.TypeOf(fn)
+		if typ == nil || typ.Kind() != reflect.Func {
+			return fmt.Errorf("%s.%s is not a function", name, method)
+		}
+	}
+	svc := &service{
+		sequent: s,
+		methods: make(map[string]seriatim.MethodInfo, len(methods)),
+	}
+	for _, info := range seriatim.DescribeMethods(methods) {
+		svc.methods[info.Name] = info
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if _, ok := srv.services[name]; ok {
+		return fmt.Errorf("service %s is already registered", name)
+	}
+	srv.services[name] = svc
+	return nil
+}
+
+func (srv *Server) Unregister(name string) {
+	srv.mu.Lock()
+	delete(srv.services, name)
+	srv.mu.Unlock()
+}
+
+func (srv *Server) lookup(serviceMethod string) (*service, seriatim.MethodInfo, error) {
+	dot := strings.LastIndex(serviceMethod, ".")
+	if dot < 0 {
+		return nil, seriatim.MethodInfo{}, fmt.Errorf(
+			"rpc: service/method request ill-formed: %s", serviceMethod)
+	}
+	name, method := serviceMethod[:dot], serviceMethod[dot+1:]
+	srv.mu.RLock()
+	svc, ok := srv.services[name]
+	srv.mu.RUnlock()
+	if !ok {
+		return nil, seriatim.MethodInfo{}, fmt.Errorf(
+			"rpc: can't find service %s", serviceMethod)
+	}
+	info, ok := svc.methods[method]
+	if !ok {
+		return nil, seriatim.MethodInfo{}, seriatim.ErrUnknownMethod
+	}
+	return svc, info, nil
+}
+
+func readArgs(codec rpc.ServerCodec, info seriatim.MethodInfo) ([]interface{}, error) {
+	switch len(info.Args) {
+	case 0:
+		return nil, codec.ReadRequestBody(nil)
+	case 1:
+		arg := reflect.New(info.Args[0])
+		if err := codec.ReadRequestBody(arg.Interface()); err != nil {
+			return nil, err
+		}
+		return []interface{}{arg.Elem().Interface()}, nil
+	}
+	var args []interface{}
+	if err := codec.ReadRequestBody(&args); err != nil {
+		return nil, err
+	}
+	return args, nil
+}
+
+func reply(info seriatim.MethodInfo, values []interface{}) (interface{}, error) {
+	if n := len(info.Returns); n > 0 && info.Returns[n-1] == errtype {
+		if err, _ := values[n-1].(error); err != nil {
+			return nil, err
+		}
+		values = values[:n-1]
+	}
+	switch len(values) {
+	case 0:
+		return struct{}{}, nil
+	case 1:
+		return values[0], nil
+	}
+	return values, nil
+}
+
+// ServeCodec serves requests read from codec until the client hangs
+// up, then closes codec.
+func (srv *Server) ServeCodec(codec rpc.ServerCodec) {
+	var sending sync.Mutex
+	respond := func(seq uint64, serviceMethod string, body interface{}, err error) {
+		resp := &rpc.Response{ServiceMethod: serviceMethod, Seq: seq}
+		if err != nil {
+			resp.Error = err.Error()
+			body = struct{}{}
+		}
+		sending.Lock()
+		codec.WriteResponse(resp, body)
+		sending.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	for {
+		var req rpc.Request
+		if err := codec.ReadRequestHeader(&req); err != nil {
+			break
+		}
+		svc, info, err := srv.lookup(req.ServiceMethod)
+		if err != nil {
+			if err := codec.ReadRequestBody(nil); err != nil {
+				break
+			}
+			respond(req.Seq, req.ServiceMethod, nil, err)
+			continue
+		}
+		args, err := readArgs(codec, info)
+		if err != nil {
+			respond(req.Seq, req.ServiceMethod, nil, err)
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				break
+			}
+			continue
+		}
+		wg.Add(1)
+		go func(seq uint64, serviceMethod string) {
+			defer wg.Done()
+			values, err := svc.sequent.Call(info.Name, args...)
+			var body interface{}
+			if err == nil {
+				body, err = reply(info, values)
+			}
+			respond(seq, serviceMethod, body, err)
+		}(req.Seq, req.ServiceMethod)
+	}
+	wg.Wait()
+	codec.Close()
+}
+
+// ServeConn serves a connection using the gob encoding of net/rpc.
+func (srv *Server) ServeConn(conn io.ReadWriteCloser) {
+	buf := bufio.NewWriter(conn)
+	srv.ServeCodec(&gobServerCodec{
+		rwc:    conn,
+		dec:    gob.NewDecoder(conn),
+		enc:    gob.NewEncoder(buf),
+		encBuf: buf,
+	})
+}
+
+// Accept serves every connection accepted on l with ServeConn until
+// l is closed.
+func (srv *Server) Accept(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go srv.ServeConn(conn)
+	}
+}
+
+// gobServerCodec is the codec net/rpc uses for its own connections,
+// which the standard library does not export.
+type gobServerCodec struct {
+	rwc    io.ReadWriteCloser
+	dec    *gob.Decoder
+	enc    *gob.Encoder
+	encBuf *bufio.Writer
+	closed bool
+}
+
+func (c *gobServerCodec) ReadRequestHeader(r *rpc.Request) error {
+	return c.dec.Decode(r)
+}
+
+func (c *gobServerCodec) ReadRequestBody(body interface{}) error {
+	return c.dec.Decode(body)
+}
+
+func (c *gobServerCodec) WriteResponse(r *rpc.Response, body interface{}) error {
+	if err := c.enc.Encode(r); err != nil {
+		if c.encBuf.Flush() == nil {
+			c.Close()
+		}
+		return err
+	}
+	if err := c.enc.Encode(body); err != nil {
+		if c.encBuf.Flush() == nil {
+			c.Close()
+		}
+		return err
+	}
+	return c.encBuf.Flush()
+}
+
+func (c *gobServerCodec) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.rwc.Close()
+}
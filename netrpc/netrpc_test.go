@@ -0,0 +1,125 @@
+package netrpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"testing"
+
+	"github.com/jsouthworth/seriatim"
+)
+
+type account struct {
+	balance int
+	owner   string
+}
+
+func (a *account) Deposit(n int) int {
+	a.balance += n
+	return a.balance
+}
+
+func (a *account) Withdraw(n int) (int, error) {
+	if n > a.balance {
+		return 0, errors.New("insufficient funds")
+	}
+	a.balance -= n
+	return a.balance, nil
+}
+
+func (a *account) Rename(first, last string) {
+	a.owner = first + " " + last
+}
+
+// Double takes a context from the sequent, not from its callers.
+func (a *account) Double(ctx context.Context, n int) int {
+	return 2 * n
+}
+
+func (a *account) Owner() string {
+	return a.owner
+}
+
+func newServer(t *testing.T) (*Server, seriatim.Sequent) {
+	val := &account{}
+	s := seriatim.NewSequent(val)
+	srv := NewServer()
+	if err := srv.Register("Accounts", s, seriatim.GetMethods(val)); err != nil {
+		t.Fatal(err)
+	}
+	if err := srv.Register("Accounts", s, seriatim.GetMethods(val)); err == nil {
+		t.Fatal("registering a service twice should fail")
+	}
+	return srv, s
+}
+
+func exercise(t *testing.T, client *rpc.Client) {
+	var balance int
+	if err := client.Call("Accounts.Deposit", 10, &balance); err != nil {
+		t.Fatal(err)
+	}
+	if balance != 10 {
+		t.Fatalf("expected balance 10, got %d", balance)
+	}
+
+	err := client.Call("Accounts.Withdraw", 100, &balance)
+	if err == nil || err.Error() != "insufficient funds" {
+		t.Fatalf("expected the method's error, got %v", err)
+	}
+	if err := client.Call("Accounts.Withdraw", 4, &balance); err != nil {
+		t.Fatal(err)
+	}
+	if balance != 6 {
+		t.Fatalf("expected balance 6, got %d", balance)
+	}
+
+	if err := client.Call("Accounts.Rename",
+		[]interface{}{"Ada", "Lovelace"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	var owner string
+	if err := client.Call("Accounts.Owner", struct{}{}, &owner); err != nil {
+		t.Fatal(err)
+	}
+	if owner != "Ada Lovelace" {
+		t.Fatalf("unexpected owner %q", owner)
+	}
+
+	var doubled int
+	if err := client.Call("Accounts.Double", 21, &doubled); err != nil {
+		t.Fatal(err)
+	}
+	if doubled != 42 {
+		t.Fatalf("expected 42, got %d", doubled)
+	}
+
+	err = client.Call("Accounts.Missing", 1, nil)
+	if err == nil || err.Error() != seriatim.ErrUnknownMethod.Error() {
+		t.Fatalf("expected an unknown method error, got %v", err)
+	}
+	if err := client.Call("Nope.Deposit", 1, nil); err == nil {
+		t.Fatal("expected an unknown service error")
+	}
+}
+
+func TestGobClient(t *testing.T) {
+	srv, s := newServer(t)
+	defer s.Terminate(nil)
+	local, far := net.Pipe()
+	go srv.ServeConn(far)
+	client := rpc.NewClient(local)
+	defer client.Close()
+	exercise(t, client)
+}
+
+func TestJSONClient(t *testing.T) {
+	srv, s := newServer(t)
+	defer s.Terminate(nil)
+	local, far := net.Pipe()
+	go srv.ServeCodec(jsonrpc.NewServerCodec(far))
+	client := jsonrpc.NewClient(local)
+	defer client.Close()
+	exercise(t, client)
+}
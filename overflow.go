@@ -0,0 +1,104 @@
+package seriatim
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+var ErrMailboxFull = errors.New("Mailbox full")
+
+// Overflow says what Cast does when the sequent's mailbox is full.
+// Calls always wait for room, as their callers wait for the reply
+// anyway. Mailboxes without a limit, whose Cap is 0, are never full.
+type Overflow int
+
+const (
+	// OverflowBlock waits for room in the mailbox.
+	OverflowBlock Overflow = iota
+	// OverflowDropNewest discards the cast being sent.
+	OverflowDropNewest
+	// OverflowDropOldest discards the oldest queued request to make
+	// room for the cast being sent. It is purged as if the sequent
+	// had stopped: a Call fails with ErrSequentStop and so does a
+	// CastNotify, on its channel. The requests the package makes of
+	// the sequent itself, such as for Replace, are never dropped;
+	// while one is queued the cast waits for room as with
+	// OverflowBlock.
+	OverflowDropOldest
+	// OverflowFail fails the cast with ErrMailboxFull.
+	OverflowFail
+)
+
+// WithOverflow sets what Casts do when the sequent's mailbox is full,
+// such as dropping signals bridged to a sequent that cannot keep up
+// rather than stalling their source.
+func WithOverflow(policy Overflow) Option {
+	return func(a *sequent) {
+		a.overflow = policy
+	}
+}
+
+// enqueueCast puts a cast in the mailbox, applying the overflow
+// policy if it is full.
+func (a *sequent) enqueueCast(req *request) error {
+	if a.overflow == OverflowBlock || a.queue.Cap() == 0 {
+		return a.enqueue(req, nil)
+	}
+	if queued, err := a.tryEnqueueCast(req); queued || err != nil {
+		return err
+	}
+	return a.enqueue(req, nil)
+}
+
+// tryEnqueueCast applies the overflow policy to put req in the full
+// mailbox, reporting false if it has to wait for room instead.
+func (a *sequent) tryEnqueueCast(req *request) (bool, error) {
+	a.enqueueMu.RLock()
+	defer a.enqueueMu.RUnlock()
+	for {
+		select {
+		case <-a.stopped:
+			return false, ErrSequentStop
+		default:
+		}
+		select {
+		case a.queue.Enqueue() <- req:
+			a.queued()
+			return true, nil
+		default:
+		}
+		switch a.overflow {
+		case OverflowDropNewest:
+			return true, nil
+		case OverflowFail:
+			return false, ErrMailboxFull
+		}
+		if !a.dropOldest() {
+			return false, nil
+		}
+	}
+}
+
+// dropOldest purges the oldest request in the mailbox, reporting false
+// if it may be one made by the package itself, which is never dropped.
+// The sequent may take the oldest request first, in which case there
+// is room anyway.
+func (a *sequent) dropOldest() bool {
+	a.controlMu.Lock()
+	defer a.controlMu.Unlock()
+	if atomic.LoadInt32(&a.controls) > 0 {
+		return false
+	}
+	select {
+	case msg := <-a.queue.Dequeue():
+		msg.Purged()
+	default:
+	}
+	return true
+}
+
+// countsControls reports whether req is a request made by the package
+// itself that is counted in the controls of the sequent.
+func (a *sequent) countsControls(req *request) bool {
+	return req.name == "" && a.overflow == OverflowDropOldest
+}
@@ -0,0 +1,128 @@
+package seriatim
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type recorder struct {
+	release chan struct{}
+	seen    []string
+}
+
+func (r *recorder) Block() {
+	<-r.release
+}
+
+func (r *recorder) Add(s string) {
+	r.seen = append(r.seen, s)
+}
+
+func (r *recorder) Seen() []string {
+	return r.seen
+}
+
+func TestOverflow(t *testing.T) {
+	tests := []struct {
+		policy Overflow
+		err    error
+		seen   []string
+	}{
+		{OverflowDropNewest, nil, []string{"a", "b"}},
+		{OverflowDropOldest, nil, []string{"b", "c"}},
+		{OverflowFail, ErrMailboxFull, []string{"a", "b"}},
+	}
+	for _, test := range tests {
+		r := &recorder{release: make(chan struct{})}
+		s := NewSequent(r, WithMailboxSize(2), WithOverflow(test.policy))
+		if err := s.Cast("Block"); err != nil {
+			t.Fatal(err)
+		}
+		for s.Stats().QueueLen != 0 {
+			time.Sleep(time.Millisecond)
+		}
+		for _, name := range []string{"a", "b"} {
+			if err := s.Cast("Add", name); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.Cast("Add", "c"); err != test.err {
+			t.Fatalf("policy %d: expected %v, got %v", test.policy, test.err, err)
+		}
+		close(r.release)
+		rets, err := s.Call("Seen")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(rets[0], test.seen) {
+			t.Fatalf("policy %d: expected %v, got %v", test.policy, test.seen, rets[0])
+		}
+		s.Terminate(nil)
+	}
+}
+
+func TestOverflowDropOldestPurges(t *testing.T) {
+	r := &recorder{release: make(chan struct{})}
+	s := NewSequent(r, WithMailboxSize(1), WithOverflow(OverflowDropOldest))
+	defer s.Terminate(nil)
+	if err := s.Cast("Block"); err != nil {
+		t.Fatal(err)
+	}
+	for s.Stats().QueueLen != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	errs := make(chan error, 1)
+	if err := s.CastNotify("Add", errs, "a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Cast("Add", "b"); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != ErrSequentStop {
+		t.Fatalf("expected the dropped cast to be purged, got %v", err)
+	}
+	close(r.release)
+}
+
+func TestOverflowDropOldestKeepsReplace(t *testing.T) {
+	r := &recorder{release: make(chan struct{})}
+	s := NewSequent(r, WithMailboxSize(2), WithOverflow(OverflowDropOldest))
+	defer s.Terminate(nil)
+	if err := s.Cast("Block"); err != nil {
+		t.Fatal(err)
+	}
+	for s.Stats().QueueLen != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	next := &recorder{}
+	if err := s.Replace(next); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Cast("Add", "a"); err != nil {
+		t.Fatal(err)
+	}
+	// the mailbox is full with the replacement oldest, so the cast
+	// waits for room rather than dropping it
+	errs := make(chan error, 1)
+	go func() {
+		errs <- s.Cast("Add", "b")
+	}()
+	select {
+	case err := <-errs:
+		t.Fatalf("expected the cast to wait, got %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(r.release)
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	rets, err := s.Call("Seen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(rets[0], []string{"a", "b"}) || len(r.seen) != 0 {
+		t.Fatalf("expected the replacement to see a and b, got %v and %v",
+			rets[0], r.seen)
+	}
+}
@@ -0,0 +1,158 @@
+package seriatim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrNotPipelined is returned by NewPipeline when a stage other than
+// the last is a sequent not made by this package, which cannot pass on
+// its results.
+var ErrNotPipelined = errors.New("Sequent cannot pass on its results")
+
+// Stage is a step of a Pipeline, the method of a sequent its input is
+// cast to.
+type Stage struct {
+	Sequent Sequent
+	Method  string
+}
+
+// StageError reports an input dropped by a Pipeline at stage Stage,
+// either because its method returned Err or because it did not take
+// the results of the stage before it, such as when it terminated.
+type StageError struct {
+	Stage  int
+	Method string
+	Err    error
+}
+
+func (e *StageError) Error() string {
+	return fmt.Sprintf("Stage %d (%s): %s", e.Stage, e.Method, e.Err)
+}
+
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// Pipeline passes what is sent to it through its stages: the results
+// of the method of each stage are cast to the method of the next one,
+// without a trailing error result, by the goroutine of the stage once
+// its method returns. Each stage therefore gets its input in the order
+// it was sent, and a stage whose mailbox is full holds up the stages
+// before it, down to Send, rather than letting inputs pile up:
+//
+//	p, err := seriatim.NewPipeline(
+//		seriatim.Stage{Sequent: decoder, Method: "Decode"},
+//		seriatim.Stage{Sequent: store, Method: "Store"},
+//	)
+//	...
+//	err = p.Send(frame)
+//
+// Inputs queued at a stage that terminates are dropped.
+type Pipeline struct {
+	// OnError, if set, is told about the inputs that were dropped
+	// because of an error. It is called on the goroutine of the
+	// stage that failed to pass them on and holds up the stage.
+	OnError func(*StageError)
+
+	stages []Stage
+}
+
+// NewPipeline returns a pipeline through stages, in order. It fails if
+// a sequent is a stage twice, which could hold itself up, or if a
+// stage other than the last cannot pass on its results.
+func NewPipeline(stages ...Stage) (*Pipeline, error) {
+	for i, stage := range stages {
+		for j := 0; j < i; j++ {
+			if stages[j].Sequent.Id() == stage.Sequent.Id() {
+				return nil, fmt.Errorf("Stage %d repeats stage %d", i, j)
+			}
+		}
+		a, ok := stage.Sequent.(*sequent)
+		if !ok {
+			if i < len(stages)-1 {
+				return nil, ErrNotPipelined
+			}
+			continue
+		}
+		if _, ok := a.table().methods[stage.Method]; !ok {
+			return nil, ErrUnknownMethod
+		}
+	}
+	return &Pipeline{stages: stages}, nil
+}
+
+// Send casts args to the first stage, blocking while its mailbox is
+// full. Inputs sent by one goroutine go through every stage in the
+// order they were sent.
+func (p *Pipeline) Send(args ...interface{}) error {
+	return p.SendContext(context.Background(), args...)
+}
+
+// SendContext is Send giving up with the error of ctx once it is done.
+func (p *Pipeline) SendContext(ctx context.Context, args ...interface{}) error {
+	if len(p.stages) == 0 {
+		return nil
+	}
+	err := p.cast(0, ctx.Done(), args)
+	if err != nil && ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// cast casts args to stage i, having its results passed on to the next
+// one.
+func (p *Pipeline) cast(i int, cancel <-chan struct{}, args []interface{}) error {
+	stage := p.stages[i]
+	a, ok := stage.Sequent.(*sequent)
+	if !ok {
+		return stage.Sequent.Cast(stage.Method, args...)
+	}
+	req, err := a.newRequest(nil, stage.Method, args...)
+	if err != nil {
+		return err
+	}
+	if !a.accepting() {
+		return ErrSequentStop
+	}
+	if i < len(p.stages)-1 {
+		req.then = func(returns []reflect.Value) {
+			p.forward(i, req, returns)
+		}
+	}
+	return a.enqueue(req, cancel)
+}
+
+// forward passes the results of req, processed by stage i, on to the
+// next stage.
+func (p *Pipeline) forward(i int, req *request, returns []reflect.Value) {
+	args, err := stageResults(req, returns)
+	if err == nil {
+		i++
+		err = p.cast(i, nil, args)
+	}
+	if err != nil && p.OnError != nil {
+		p.OnError(&StageError{
+			Stage:  i,
+			Method: p.stages[i].Method,
+			Err:    err,
+		})
+	}
+}
+
+// stageResults returns the results of req to pass on, without the
+// error its method returns last, or that error.
+func stageResults(req *request, returns []reflect.Value) ([]interface{}, error) {
+	args := processMethodReturns(returns)
+	t := req.method.Type()
+	if n := len(args); n > 0 && n == t.NumOut() && t.Out(n-1) == errorType {
+		if err, _ := args[n-1].(error); err != nil {
+			return nil, err
+		}
+		args = args[:n-1]
+	}
+	return args, nil
+}
@@ -0,0 +1,134 @@
+package seriatim
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+type parser struct{}
+
+func (parser) Parse(s string) (int, error) {
+	return strconv.Atoi(s)
+}
+
+type doubling struct{}
+
+func (doubling) Double(n int) int {
+	return 2 * n
+}
+
+type collector struct {
+	mu  sync.Mutex
+	got []int
+}
+
+func (c *collector) Collect(n int) {
+	c.mu.Lock()
+	c.got = append(c.got, n)
+	c.mu.Unlock()
+}
+
+func (c *collector) Got() []int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]int(nil), c.got...)
+}
+
+func TestPipeline(t *testing.T) {
+	c := &collector{}
+	parse := NewSequent(parser{})
+	double := NewSequent(doubling{}, WithMailboxSize(2))
+	collect := NewSequent(c)
+	defer parse.Terminate(nil)
+	defer double.Terminate(nil)
+	defer collect.Terminate(nil)
+	p, err := NewPipeline(
+		Stage{Sequent: parse, Method: "Parse"},
+		Stage{Sequent: double, Method: "Double"},
+		Stage{Sequent: collect, Method: "Collect"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan *StageError, 1)
+	p.OnError = func(err *StageError) {
+		errs <- err
+	}
+
+	want := make([]int, 0, 100)
+	for i := 0; i < 100; i++ {
+		if err := p.Send(strconv.Itoa(i)); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, 2*i)
+	}
+	if err := p.Send("x"); err != nil {
+		t.Fatal(err)
+	}
+	stageErr := <-errs
+	var numErr *strconv.NumError
+	if stageErr.Stage != 0 || stageErr.Method != "Parse" ||
+		!errors.As(stageErr, &numErr) {
+		t.Fatalf("unexpected error %v", stageErr)
+	}
+	// each stage has passed on everything sent before once it
+	// answers a call
+	if _, err := double.Call("Double", 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := collect.Call("Got"); err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Got(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v in order, got %v", want, got)
+	}
+}
+
+func TestPipelineStages(t *testing.T) {
+	parse := NewSequent(parser{})
+	defer parse.Terminate(nil)
+	double := NewSequent(doubling{})
+	defer double.Terminate(nil)
+	if _, err := NewPipeline(
+		Stage{Sequent: parse, Method: "Parse"},
+		Stage{Sequent: parse, Method: "Parse"},
+	); err == nil {
+		t.Fatal("expected a repeated stage to be rejected")
+	}
+	if _, err := NewPipeline(
+		Stage{Sequent: parse, Method: "Missing"},
+	); err != ErrUnknownMethod {
+		t.Fatalf("expected ErrUnknownMethod, got %v", err)
+	}
+	r := NewReplica(NewSequent(doubling{}), NewSequent(doubling{}))
+	defer r.Terminate(nil)
+	if _, err := NewPipeline(
+		Stage{Sequent: r, Method: "Double"},
+		Stage{Sequent: double, Method: "Double"},
+	); err != ErrNotPipelined {
+		t.Fatalf("expected ErrNotPipelined, got %v", err)
+	}
+
+	p, err := NewPipeline(
+		Stage{Sequent: parse, Method: "Parse"},
+		Stage{Sequent: double, Method: "Double"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan *StageError, 1)
+	p.OnError = func(err *StageError) {
+		errs <- err
+	}
+	double.Terminate(nil)
+	<-double.Done()
+	if err := p.Send("1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err.Stage != 1 || err.Err != ErrSequentStop {
+		t.Fatalf("expected the terminated stage reported, got %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package seriatim
+
+// Provider lends out values for sequents, such as a *sync.Pool holding
+// values wrapping connections or buffers.
+type Provider interface {
+	Get() interface{}
+	Put(interface{})
+}
+
+// WithProvider returns the sequent's value to p once it has been
+// terminated, before its supervisor is told, so that a restart can
+// check the value out again cheaply. The value of a sequent that
+// crashed is not returned as it may be inconsistent.
+func WithProvider(p Provider) Option {
+	return func(a *sequent) {
+		a.provider = p
+	}
+}
+
+// NewSequentFrom returns a sequent for a value checked out from p and
+// returned to it when the sequent is terminated.
+func NewSequentFrom(p Provider, opts ...Option) Sequent {
+	return NewSequent(p.Get(), append(opts, WithProvider(p))...)
+}
@@ -0,0 +1,56 @@
+package seriatim
+
+import "testing"
+
+type countingProvider struct {
+	free []*tally
+	puts int
+}
+
+func (p *countingProvider) Get() interface{} {
+	if len(p.free) == 0 {
+		return &tally{}
+	}
+	v := p.free[len(p.free)-1]
+	p.free = p.free[:len(p.free)-1]
+	return v
+}
+
+func (p *countingProvider) Put(v interface{}) {
+	p.puts++
+	p.free = append(p.free, v.(*tally))
+}
+
+func TestSequentProvider(t *testing.T) {
+	p := &countingProvider{}
+	s := NewSequentFrom(p)
+	if _, err := s.Call("Add", 2); err != nil {
+		t.Fatal(err)
+	}
+	first := s.(*sequent).val
+	s.Terminate(nil)
+	<-s.(*sequent).done
+	if p.puts != 1 {
+		t.Fatalf("value returned %d times", p.puts)
+	}
+
+	s = NewSequentFrom(p)
+	defer s.Terminate(nil)
+	if s.(*sequent).val != first {
+		t.Fatal("returned value not reused")
+	}
+	rets, err := s.Call("Total")
+	if err != nil || rets[0] != 2 {
+		t.Fatalf("unexpected %v %v", rets, err)
+	}
+}
+
+func TestSequentProviderCrash(t *testing.T) {
+	p := &countingProvider{}
+	s := NewSequent(&value{}, WithProvider(p))
+	s.Call("Crash")
+	<-s.(*sequent).done
+	if p.puts != 0 {
+		t.Fatal("crashed value returned")
+	}
+}
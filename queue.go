@@ -4,6 +4,18 @@ type Message interface {
 	Purged()
 }
 
+// Mailbox is the queue a sequent receives its requests from. Enqueue
+// and Dequeue may be called concurrently. Stop is called once the
+// sequent terminates; it calls Purged on the messages still queued and
+// closes the channel returned by Dequeue.
+type Mailbox interface {
+	Enqueue() chan<- Message
+	Dequeue() <-chan Message
+	Len() int
+	Cap() int
+	Stop()
+}
+
 type Queue struct {
 	queue chan Message
 }
@@ -0,0 +1,97 @@
+package seriatim
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Number of crashes remembered by the registry.
+const crashLogSize = 32
+
+type Crash struct {
+	Id     uintptr
+	Type   string
+	Method string
+	Reason error
+	Time   time.Time
+}
+
+var registry = struct {
+	sync.RWMutex
+	next     uint64
+	sequents map[*sequent]uint64
+	crashes  []Crash
+}{
+	sequents: make(map[*sequent]uint64),
+}
+
+func register(a *sequent) {
+	registry.Lock()
+	registry.next++
+	registry.sequents[a] = registry.next
+	registry.Unlock()
+}
+
+func unregister(a *sequent) {
+	registry.Lock()
+	delete(registry.sequents, a)
+	registry.Unlock()
+}
+
+func recordCrash(report *CrashReport) {
+	registry.Lock()
+	if len(registry.crashes) == crashLogSize {
+		registry.crashes = append(registry.crashes[:0], registry.crashes[1:]...)
+	}
+	registry.crashes = append(registry.crashes, Crash{
+		Id:     report.Id,
+		Type:   report.Type,
+		Method: report.Method,
+		Reason: report.Reason,
+		Time:   report.Time,
+	})
+	registry.Unlock()
+}
+
+// Sequents returns the running sequents in the order they were created.
+func Sequents() []Sequent {
+	registry.RLock()
+	type entry struct {
+		seq uint64
+		act *sequent
+	}
+	entries := make([]entry, 0, len(registry.sequents))
+	for act, seq := range registry.sequents {
+		entries = append(entries, entry{seq, act})
+	}
+	registry.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].seq < entries[j].seq
+	})
+	out := make([]Sequent, 0, len(entries))
+	for _, e := range entries {
+		out = append(out, e.act)
+	}
+	return out
+}
+
+// Lookup finds a running sequent by its Id.
+func Lookup(id uintptr) (Sequent, bool) {
+	for _, s := range Sequents() {
+		if s.Id() == id {
+			return s, true
+		}
+	}
+	return nil, false
+}
+
+// Crashes returns the most recent sequent crashes, oldest first.
+func Crashes() []Crash {
+	registry.RLock()
+	defer registry.RUnlock()
+	out := make([]Crash, len(registry.crashes))
+	copy(out, registry.crashes)
+	return out
+}
@@ -0,0 +1,45 @@
+package seriatim
+
+import (
+	"errors"
+	"testing"
+)
+
+type counter struct {
+	count int
+}
+
+func (c *counter) Increment() {
+	c.count++
+}
+
+func TestRegistry(t *testing.T) {
+	a := NewSequent(&counter{})
+	b := NewSequentTable(struct{}{}, GetMethods(&counter{}))
+	if a.Id() == b.Id() || b.Id() == 0 {
+		t.Fatal("sequents must have distinct ids")
+	}
+	for _, s := range []Sequent{a, b} {
+		if found, ok := Lookup(s.Id()); !ok || found != s {
+			t.Fatal("sequent not registered")
+		}
+	}
+	sut := NewSUT(false)
+	sut.Terminate(errors.New("TestRegistry"))
+	if _, ok := Lookup(sut.Id()); ok {
+		t.Fatal("terminated sequent still registered")
+	}
+
+	crash := NewSUT(false)
+	crash.Cast("Crash")
+	crash.WaitTerminate()
+	crashes := Crashes()
+	if len(crashes) == 0 || crashes[len(crashes)-1].Id != crash.Id() {
+		t.Fatal("crash not recorded")
+	}
+	if crashes[len(crashes)-1].Method != "Crash" {
+		t.Fatal("crashing method not recorded")
+	}
+	a.Terminate(nil)
+	b.Terminate(nil)
+}
@@ -0,0 +1,171 @@
+package remote
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/jsouthworth/seriatim"
+	"github.com/jsouthworth/seriatim/codec"
+)
+
+// Frames are a big endian uint32 length followed by that many bytes
+// of a gob encoded frame. Each frame is encoded on its own so a
+// connection never carries gob type state between frames.
+const maxFrameSize = 16 << 20
+
+type frameKind uint8
+
+const (
+	kindCall frameKind = iota + 1
+	kindCast
+	kindTerminate
+	kindReply
+)
+
+// Arguments and results are encoded by the codec named in the frame,
+// apart from error results which are carried in Errors by position as
+// codecs cannot encode them.
+type frame struct {
+	Kind    frameKind
+	Seq     uint64
+	Method  string
+	Codec   string
+	Args    []byte
+	Returns []byte
+	Errors  map[int]string
+	Code    string
+	Error   string
+}
+
+// Error codes for the errors a local sequent is told apart by, which
+// are matched with errors.Is.
+const (
+	codeUnknownMethod = "unknown-method"
+	codeStopped       = "stopped"
+)
+
+// RemoteError carries an error value returned by a method of the
+// remote sequent or an error the remote side failed with.
+type RemoteError struct {
+	Message string
+}
+
+func (e *RemoteError) Error() string {
+	return e.Message
+}
+
+func init() {
+	gob.Register(&RemoteError{})
+}
+
+func (f *frame) setError(err error) {
+	switch {
+	case err == nil:
+	case errors.Is(err, seriatim.ErrUnknownMethod):
+		f.Code = codeUnknownMethod
+	case errors.Is(err, seriatim.ErrSequentStop):
+		// such as a *CrashError
+		f.Code = codeStopped
+	default:
+		f.Error = err.Error()
+	}
+}
+
+func (f *frame) err() error {
+	switch f.Code {
+	case codeUnknownMethod:
+		return seriatim.ErrUnknownMethod
+	case codeStopped:
+		return seriatim.ErrSequentStop
+	}
+	if f.Error != "" {
+		return &RemoteError{Message: f.Error}
+	}
+	return nil
+}
+
+func (f *frame) codec() (codec.Codec, error) {
+	c, ok := codec.Lookup(f.Codec)
+	if !ok {
+		return nil, fmt.Errorf("Unknown codec %q", f.Codec)
+	}
+	return c, nil
+}
+
+// setReturns encodes values, moving the errors among them to Errors.
+func (f *frame) setReturns(c codec.Codec, values []interface{}) error {
+	for i, value := range values {
+		if err, ok := value.(error); ok {
+			if f.Errors == nil {
+				f.Errors = make(map[int]string)
+			}
+			f.Errors[i] = err.Error()
+			values[i] = nil
+		}
+	}
+	data, err := c.Encode(values)
+	if err != nil {
+		return err
+	}
+	f.Codec = c.Name()
+	f.Returns = data
+	return nil
+}
+
+// returns decodes the results, with the errors among them as
+// RemoteErrors.
+func (f *frame) returns() ([]interface{}, error) {
+	c, err := f.codec()
+	if err != nil {
+		return nil, err
+	}
+	values, err := c.Decode(f.Returns, nil)
+	if err != nil {
+		return nil, err
+	}
+	for i, msg := range f.Errors {
+		if i >= 0 && i < len(values) {
+			values[i] = &RemoteError{Message: msg}
+		}
+	}
+	return values, nil
+}
+
+func writeFrame(w io.Writer, f *frame) error {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, 4))
+	if err := gob.NewEncoder(&buf).Encode(f); err != nil {
+		return err
+	}
+	out := buf.Bytes()
+	if len(out)-4 > maxFrameSize {
+		return fmt.Errorf("frame of %d bytes exceeds the limit", len(out)-4)
+	}
+	binary.BigEndian.PutUint32(out, uint32(len(out)-4))
+	_, err := w.Write(out)
+	return err
+}
+
+func readFrame(r *bufio.Reader) (*frame, error) {
+	var size uint32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return nil, err
+	}
+	if size > maxFrameSize {
+		return nil, errors.New("frame exceeds the size limit")
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	f := new(frame)
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(f); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
@@ -0,0 +1,493 @@
+// Package remote backs a Sequent with an actor in another process.
+//
+// A process serves a sequent on a listener with Serve or
+// ListenAndServe; other processes Dial it and get a Sequent whose
+// Call, Cast and Terminate are carried out by the served sequent with
+// the same results and errors. Casts are acknowledged once queued so
+// that they report the same errors as a local Cast. When the
+// connection is lost the dialed sequent stops and its supervisor is
+// told why.
+//
+// Arguments and results are encoded with the codec chosen by the
+// dialing side, gob unless NewClientCodec is used, and the served side
+// answers with the same codec, which must be registered in both
+// processes. With gob, types other than the basic ones must be
+// registered with gob.Register in both processes.
+package remote
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"reflect"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jsouthworth/seriatim"
+	"github.com/jsouthworth/seriatim/codec"
+)
+
+var ErrConnectionLost = errors.New("Connection to remote sequent lost")
+
+// ListenAndServe serves s on the given network address.
+func ListenAndServe(network, addr string, s seriatim.Sequent) error {
+	l, err := net.Listen(network, addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return Serve(l, s)
+}
+
+// Serve serves s to every connection accepted on l until l is closed.
+func Serve(l net.Listener, s seriatim.Sequent) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go ServeConn(conn, s)
+	}
+}
+
+// ServeConn serves s on a single connection until it is closed.
+func ServeConn(conn net.Conn, s seriatim.Sequent) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	var wlock sync.Mutex
+	reply := func(f *frame) error {
+		wlock.Lock()
+		defer wlock.Unlock()
+		return writeFrame(conn, f)
+	}
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	for {
+		req, err := readFrame(r)
+		if err != nil {
+			return
+		}
+		resp := &frame{Kind: kindReply, Seq: req.Seq}
+		switch req.Kind {
+		case kindCall:
+			c, args, err := decodeArgs(s, req)
+			if err != nil {
+				resp.setError(err)
+				reply(resp)
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				values, err := s.Call(req.Method, args...)
+				if err == nil {
+					err = resp.setReturns(c, values)
+				}
+				resp.setError(err)
+				reply(resp)
+			}()
+		case kindCast:
+			_, args, err := decodeArgs(s, req)
+			if err == nil {
+				err = s.Cast(req.Method, args...)
+			}
+			resp.setError(err)
+			reply(resp)
+		case kindTerminate:
+			if !s.State().Final() {
+				var reason error
+				if req.Error != "" {
+					reason = &RemoteError{Message: req.Error}
+				}
+				s.Terminate(reason)
+			}
+			reply(resp)
+			return
+		}
+	}
+}
+
+// decodeArgs decodes the arguments of req into the parameter types of
+// its method where s describes them.
+func decodeArgs(s seriatim.Sequent, req *frame) (codec.Codec, []interface{}, error) {
+	c, err := req.codec()
+	if err != nil {
+		return nil, nil, err
+	}
+	args, err := c.Decode(req.Args, codec.ArgTypes(s, req.Method))
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, args, nil
+}
+
+type client struct {
+	processed  uint64
+	conn       net.Conn
+	codec      codec.Codec
+	addr       string
+	supervisor seriatim.Supervisor
+	running    atomic.Value
+	wlock      sync.Mutex
+
+	mu      sync.Mutex
+	seq     uint64
+	pending map[uint64]chan *frame
+	stopped bool
+	// terminating is set by Terminate so the connection closing
+	// afterwards is reported with the caller's reason.
+	terminating bool
+	reason      error
+	// watchers receive the state changes, see StateChanges.
+	watchers []chan seriatim.State
+	// done is closed once stopped is set, with stopReason.
+	done       chan struct{}
+	stopReason error
+}
+
+// Dial connects to a sequent served at addr. supervisor, which may be
+// nil, is notified when the remote sequent is terminated or the
+// connection is lost.
+func Dial(network, addr string, supervisor seriatim.Supervisor) (seriatim.Sequent, error) {
+	conn, err := net.Dial(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn, supervisor), nil
+}
+
+// NewClient returns a Sequent backed by the sequent served on conn.
+func NewClient(conn net.Conn, supervisor seriatim.Supervisor) seriatim.Sequent {
+	return NewClientCodec(conn, supervisor, codec.Gob)
+}
+
+// NewClientCodec is like NewClient with arguments and results encoded
+// by c.
+func NewClientCodec(
+	conn net.Conn,
+	supervisor seriatim.Supervisor,
+	codec codec.Codec,
+) seriatim.Sequent {
+	c := &client{
+		conn:       conn,
+		codec:      codec,
+		addr:       conn.RemoteAddr().String(),
+		supervisor: supervisor,
+		pending:    make(map[uint64]chan *frame),
+		done:       make(chan struct{}),
+	}
+	c.running.Store(true)
+	go c.read()
+	return c
+}
+
+func (c *client) read() {
+	r := bufio.NewReader(c.conn)
+	for {
+		f, err := readFrame(r)
+		if err != nil {
+			c.mu.Lock()
+			reason := ErrConnectionLost
+			if c.terminating {
+				reason = c.reason
+			}
+			c.mu.Unlock()
+			c.stop(reason)
+			return
+		}
+		c.mu.Lock()
+		ch, ok := c.pending[f.Seq]
+		delete(c.pending, f.Seq)
+		c.mu.Unlock()
+		if ok {
+			ch <- f
+		}
+	}
+}
+
+// stop marks the sequent stopped, fails the pending requests and
+// notifies the supervisor once.
+func (c *client) stop(reason error) {
+	c.mu.Lock()
+	if c.stopped {
+		c.mu.Unlock()
+		return
+	}
+	c.stopped = true
+	c.running.Store(false)
+	pending := c.pending
+	c.pending = make(map[uint64]chan *frame)
+	c.notify(seriatim.StateStopped)
+	c.stopReason = reason
+	close(c.done)
+	c.mu.Unlock()
+
+	c.conn.Close()
+	for _, ch := range pending {
+		close(ch)
+	}
+	if c.supervisor != nil {
+		c.supervisor.SequentTerminated(reason, c.Id())
+	}
+}
+
+func (c *client) request(f *frame) (*frame, error) {
+	return c.requestContext(context.Background(), f)
+}
+
+// requestContext stops waiting for the reply to f once ctx is done.
+func (c *client) requestContext(ctx context.Context, f *frame) (*frame, error) {
+	ch, err := c.send(f)
+	if err != nil {
+		return nil, err
+	}
+	select {
+	case resp, ok := <-ch:
+		if !ok {
+			return nil, seriatim.ErrSequentStop
+		}
+		return resp, nil
+	case <-ctx.Done():
+		c.mu.Lock()
+		delete(c.pending, f.Seq)
+		c.mu.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+// send writes f and returns the channel its reply is delivered on,
+// which is closed if the sequent stops first.
+func (c *client) send(f *frame) (<-chan *frame, error) {
+	ch := make(chan *frame, 1)
+	c.mu.Lock()
+	if c.stopped {
+		c.mu.Unlock()
+		return nil, seriatim.ErrSequentStop
+	}
+	c.seq++
+	f.Seq = c.seq
+	c.pending[f.Seq] = ch
+	c.mu.Unlock()
+
+	c.wlock.Lock()
+	err := writeFrame(c.conn, f)
+	c.wlock.Unlock()
+	if err != nil {
+		c.mu.Lock()
+		delete(c.pending, f.Seq)
+		c.mu.Unlock()
+		return nil, err
+	}
+	return ch, nil
+}
+
+func (c *client) Id() uintptr {
+	return reflect.ValueOf(c).Pointer()
+}
+
+// invocation returns the frame invoking name with args.
+func (c *client) invocation(
+	kind frameKind,
+	name string,
+	args []interface{},
+) (*frame, error) {
+	data, err := c.codec.Encode(args)
+	if err != nil {
+		return nil, err
+	}
+	return &frame{
+		Kind:   kind,
+		Method: name,
+		Codec:  c.codec.Name(),
+		Args:   data,
+	}, nil
+}
+
+func (c *client) Call(name string, args ...interface{}) ([]interface{}, error) {
+	return c.CallContext(context.Background(), name, args...)
+}
+
+// CallContext stops waiting for the reply once ctx is done, but the
+// request has been sent and the remote sequent still processes it.
+func (c *client) CallContext(
+	ctx context.Context,
+	name string,
+	args ...interface{},
+) ([]interface{}, error) {
+	f, err := c.invocation(kindCall, name, args)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.requestContext(ctx, f)
+	if err != nil {
+		return nil, err
+	}
+	if err := resp.err(); err != nil {
+		return nil, err
+	}
+	atomic.AddUint64(&c.processed, 1)
+	return resp.returns()
+}
+
+func (c *client) Cast(name string, args ...interface{}) error {
+	f, err := c.invocation(kindCast, name, args)
+	if err != nil {
+		return err
+	}
+	resp, err := c.request(f)
+	if err != nil {
+		return err
+	}
+	if err := resp.err(); err != nil {
+		return err
+	}
+	atomic.AddUint64(&c.processed, 1)
+	return nil
+}
+
+// CastNotify is carried out as a call whose reply is awaited in the
+// background, so unlike Cast it is not ordered with the requests sent
+// after it.
+func (c *client) CastNotify(
+	name string,
+	errs chan<- error,
+	args ...interface{},
+) error {
+	f, err := c.invocation(kindCall, name, args)
+	if err != nil {
+		return err
+	}
+	ch, err := c.send(f)
+	if err != nil {
+		return err
+	}
+	go func() {
+		resp, ok := <-ch
+		err := seriatim.ErrSequentStop
+		if ok {
+			atomic.AddUint64(&c.processed, 1)
+			err = resp.err()
+			if err == nil {
+				var values []interface{}
+				values, err = resp.returns()
+				if err == nil {
+					err = returnedError(values)
+				}
+			}
+		}
+		if err != nil && errs != nil {
+			errs <- err
+		}
+	}()
+	return nil
+}
+
+// returnedError returns the error a method returned as its last
+// result, if any.
+func returnedError(values []interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+	err, _ := values[len(values)-1].(error)
+	return err
+}
+
+func (c *client) Running() bool {
+	return c.running.Load().(bool)
+}
+
+func (c *client) State() seriatim.State {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.state()
+}
+
+func (c *client) state() seriatim.State {
+	switch {
+	case c.stopped:
+		return seriatim.StateStopped
+	case c.terminating:
+		return seriatim.StateStopping
+	}
+	return seriatim.StateRunning
+}
+
+func (c *client) StateChanges() <-chan seriatim.State {
+	ch := make(chan seriatim.State, 3)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	ch <- c.state()
+	if c.stopped {
+		close(ch)
+		return ch
+	}
+	c.watchers = append(c.watchers, ch)
+	return ch
+}
+
+// notify sends state to the watchers; it is called with c.mu held.
+func (c *client) notify(state seriatim.State) {
+	for _, ch := range c.watchers {
+		ch <- state
+		if state.Final() {
+			close(ch)
+		}
+	}
+	if state.Final() {
+		c.watchers = nil
+	}
+}
+
+func (c *client) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the reason given to Terminate, or ErrConnectionLost if
+// the connection was lost first.
+func (c *client) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stopReason
+}
+
+func (c *client) Terminate(reason error) {
+	f := &frame{Kind: kindTerminate}
+	if reason != nil {
+		f.Error = reason.Error()
+	}
+	c.mu.Lock()
+	if !c.terminating && !c.stopped {
+		c.notify(seriatim.StateStopping)
+	}
+	c.terminating = true
+	c.reason = reason
+	c.mu.Unlock()
+	c.request(f)
+	c.stop(reason)
+}
+
+// Replace returns seriatim.ErrCannotReplace as values cannot be sent
+// to the served sequent.
+func (c *client) Replace(val interface{}) error {
+	return seriatim.ErrCannotReplace
+}
+
+// Methods returns nil as the method types of the served sequent are
+// not known to this process.
+func (c *client) Methods() []seriatim.MethodInfo {
+	return nil
+}
+
+func (c *client) Stats() seriatim.Stats {
+	c.mu.Lock()
+	pending := len(c.pending)
+	c.mu.Unlock()
+	return seriatim.Stats{
+		Id:        c.Id(),
+		Type:      "remote " + c.addr,
+		Running:   c.Running(),
+		QueueLen:  pending,
+		Processed: atomic.LoadUint64(&c.processed),
+	}
+}
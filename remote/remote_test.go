@@ -0,0 +1,279 @@
+package remote
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jsouthworth/seriatim"
+	"github.com/jsouthworth/seriatim/codec"
+)
+
+type account struct {
+	balance int
+}
+
+func (a *account) Deposit(n int) int {
+	a.balance += n
+	return a.balance
+}
+
+func (a *account) Withdraw(n int) (int, error) {
+	if n > a.balance {
+		return a.balance, errors.New("insufficient funds")
+	}
+	a.balance -= n
+	return a.balance, nil
+}
+
+func (a *account) Crash() {
+	panic("broken")
+}
+
+type supervisor chan error
+
+func (s supervisor) SequentTerminated(reason error, id uintptr) {
+	s <- reason
+}
+
+func serve(t *testing.T, s seriatim.Sequent) net.Listener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go Serve(l, s)
+	return l
+}
+
+func TestRemoteCallCast(t *testing.T) {
+	s := seriatim.NewSequent(&account{})
+	defer s.Terminate(nil)
+	l := serve(t, s)
+	defer l.Close()
+
+	r, err := Dial("tcp", l.Addr().String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.(*client).conn.Close()
+
+	if err := r.Cast("Deposit", 10); err != nil {
+		t.Fatal(err)
+	}
+	rets, err := r.Call("Deposit", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rets) != 1 || rets[0] != 15 {
+		t.Fatalf("unexpected returns %v", rets)
+	}
+
+	rets, err = r.Call("Withdraw", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rets[0] != 15 {
+		t.Fatalf("unexpected balance %v", rets[0])
+	}
+	if rerr, ok := rets[1].(error); !ok || rerr.Error() != "insufficient funds" {
+		t.Fatalf("expected the returned error, got %#v", rets[1])
+	}
+	rets, err = r.Call("Withdraw", 5)
+	if err != nil || rets[1] != nil {
+		t.Fatalf("expected a nil error return, got %v %v", rets, err)
+	}
+
+	if _, err := r.Call("Missing"); err != seriatim.ErrUnknownMethod {
+		t.Fatalf("expected ErrUnknownMethod, got %v", err)
+	}
+	if err := r.Cast("Missing"); err != seriatim.ErrUnknownMethod {
+		t.Fatalf("expected ErrUnknownMethod, got %v", err)
+	}
+	if _, err := r.Call("Deposit"); err == nil {
+		t.Fatal("expected an argument count error")
+	}
+	if stats := r.Stats(); stats.Processed != 4 || !stats.Running {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestRemoteCastNotify(t *testing.T) {
+	s := seriatim.NewSequent(&account{})
+	defer s.Terminate(nil)
+	l := serve(t, s)
+	defer l.Close()
+
+	r, err := Dial("tcp", l.Addr().String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.(*client).conn.Close()
+
+	errs := make(chan error, 1)
+	if err := r.CastNotify("Withdraw", errs, 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err == nil || err.Error() != "insufficient funds" {
+		t.Fatalf("expected the returned error, got %v", err)
+	}
+}
+
+func TestRemoteTerminate(t *testing.T) {
+	s := seriatim.NewSequent(&account{})
+	l := serve(t, s)
+	defer l.Close()
+
+	sup := make(supervisor, 1)
+	r, err := Dial("tcp", l.Addr().String(), sup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reason := errors.New("done")
+	r.Terminate(reason)
+	if got := <-sup; got != reason {
+		t.Fatalf("expected the terminate reason, got %v", got)
+	}
+	if r.Running() {
+		t.Fatal("dialed sequent still running")
+	}
+	for i := 0; s.Running(); i++ {
+		if i > 1000 {
+			t.Fatal("served sequent was not terminated")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := r.Call("Deposit", 1); err != seriatim.ErrSequentStop {
+		t.Fatalf("expected ErrSequentStop, got %v", err)
+	}
+}
+
+func TestRemoteCrash(t *testing.T) {
+	s := seriatim.NewSequent(&account{})
+	l := serve(t, s)
+	defer l.Close()
+
+	r, err := Dial("tcp", l.Addr().String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.(*client).conn.Close()
+	// the *CrashError the call fails with is a stop of the sequent
+	if _, err := r.Call("Crash"); err != seriatim.ErrSequentStop {
+		t.Fatalf("expected ErrSequentStop, got %v", err)
+	}
+}
+
+func TestRemoteConnectionLost(t *testing.T) {
+	s := seriatim.NewSequent(&account{})
+	defer s.Terminate(nil)
+	local, far := net.Pipe()
+	go ServeConn(far, s)
+
+	sup := make(supervisor, 1)
+	r := NewClient(local, sup)
+	if _, err := r.Call("Deposit", 1); err != nil {
+		t.Fatal(err)
+	}
+	far.Close()
+	if got := <-sup; got != ErrConnectionLost {
+		t.Fatalf("expected ErrConnectionLost, got %v", got)
+	}
+	<-r.Done()
+	if err := r.Err(); err != ErrConnectionLost {
+		t.Fatalf("expected Err to be ErrConnectionLost, got %v", err)
+	}
+	if err := r.Cast("Deposit", 1); err != seriatim.ErrSequentStop {
+		t.Fatalf("expected ErrSequentStop, got %v", err)
+	}
+}
+
+func TestRemoteCodec(t *testing.T) {
+	s := seriatim.NewSequent(&account{})
+	defer s.Terminate(nil)
+	l := serve(t, s)
+	defer l.Close()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewClientCodec(conn, nil, codec.JSON)
+	defer conn.Close()
+
+	rets, err := r.Call("Deposit", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// results decode into the generic JSON types
+	if len(rets) != 1 || rets[0] != float64(10) {
+		t.Fatalf("unexpected returns %#v", rets)
+	}
+	rets, err = r.Call("Withdraw", 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rerr, ok := rets[1].(error); !ok || rerr.Error() != "insufficient funds" {
+		t.Fatalf("expected the returned error, got %#v", rets[1])
+	}
+	if _, err := r.Call("Deposit", "ten"); err == nil {
+		t.Fatal("expected an error decoding the argument")
+	}
+}
+
+func TestRemoteStateChanges(t *testing.T) {
+	s := seriatim.NewSequent(&account{})
+	l := serve(t, s)
+	defer l.Close()
+
+	r, err := Dial("tcp", l.Addr().String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := r.StateChanges()
+	r.Terminate(nil)
+	var states []seriatim.State
+	for state := range changes {
+		states = append(states, state)
+	}
+	expected := []seriatim.State{
+		seriatim.StateRunning,
+		seriatim.StateStopping,
+		seriatim.StateStopped,
+	}
+	if len(states) != len(expected) {
+		t.Fatalf("unexpected states %v", states)
+	}
+	for i := range expected {
+		if states[i] != expected[i] {
+			t.Fatalf("unexpected states %v", states)
+		}
+	}
+}
+
+func TestRemoteCallContext(t *testing.T) {
+	release := make(chan struct{})
+	s := seriatim.NewSequentTable(struct{ n int }{}, map[string]interface{}{
+		"Wait": func() { <-release },
+	})
+	defer s.Terminate(nil)
+	l := serve(t, s)
+	defer l.Close()
+
+	r, err := Dial("tcp", l.Addr().String(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.(*client).conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := r.CallContext(ctx, "Wait"); err != context.DeadlineExceeded {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	close(release)
+	if _, err := r.Call("Wait"); err != nil {
+		t.Fatal(err)
+	}
+}
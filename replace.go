@@ -0,0 +1,96 @@
+package seriatim
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var (
+	// ErrNoValue is returned by Replace when given a nil value.
+	ErrNoValue = errors.New("No value")
+	// ErrCannotReplace is returned by Replace on sequents whose value
+	// cannot be replaced, such as remote ones.
+	ErrCannotReplace = errors.New("Sequent value cannot be replaced")
+)
+
+// methodTable holds the methods of a value of a sequent.
+type methodTable struct {
+	methods  map[string]reflect.Value
+	specs    map[string]MethodSpec
+	typeName string
+	// snapshot is the Snapshot method of the value, see Snapshot.
+	snapshot reflect.Value
+}
+
+func newMethodTable(val interface{}, methods map[string]interface{}) *methodTable {
+	table := &methodTable{
+		methods:  convertMethods(methods),
+		specs:    methodSpecs(methods),
+		typeName: fmt.Sprintf("%T", val),
+	}
+	removeHooks(val, table.methods)
+	table.snapshot = snapshotMethod(table.methods)
+	return table
+}
+
+// table returns the methods requests are made against.
+func (a *sequent) table() *methodTable {
+	return a.latest.Load().(*methodTable)
+}
+
+// Replace has the sequent process the requests made after those
+// already queued with the methods of val instead of its current value,
+// such as to upgrade the implementation of an object exported on D-Bus
+// without removing it. The sequent keeps its Id and its mailbox; the
+// requests made from now on are checked against the methods of val and
+// the queued ones are processed by val too, failing with
+// ErrUnknownMethod or an argument error if val has no such method.
+// val takes over from the current value once the requests queued
+// before it have been processed, which Replace does not wait for; any
+// state to carry over is val's to take. Lifecycle hooks are not
+// called. Like a Call, Replace blocks while the mailbox is full.
+func (a *sequent) Replace(val interface{}) error {
+	if val == nil {
+		return ErrNoValue
+	}
+	if !a.accepting() {
+		return ErrSequentStop
+	}
+	table := newMethodTable(val, GetMethods(val))
+	a.replaceMu.Lock()
+	defer a.replaceMu.Unlock()
+	req := &request{
+		method: reflect.ValueOf(func() {
+			a.val, a.current = val, table
+		}),
+		made: time.Now(),
+	}
+	if err := a.enqueue(req, nil); err != nil {
+		return err
+	}
+	// after the swap is queued so that the requests made against
+	// table are processed after it
+	a.latest.Store(table)
+	return nil
+}
+
+// rebind makes req, made against the methods of a value since
+// replaced, a request to the current one.
+func (a *sequent) rebind(req *request) error {
+	if req.table == nil || req.table == a.current {
+		return nil
+	}
+	method, ok := a.current.methods[req.name]
+	if !ok {
+		return ErrUnknownMethod
+	}
+	args, err := processMethodArguments(req.name, method,
+		processMethodReturns(callerArgs(req))...)
+	if err != nil {
+		return err
+	}
+	req.method, req.args, req.table = method, args, a.current
+	return nil
+}
@@ -0,0 +1,101 @@
+package seriatim
+
+import "testing"
+
+// doubler counts in steps of two and can be reset, unlike gated.
+type doubler struct {
+	n int
+}
+
+func (d *doubler) Incr() int {
+	d.n += 2
+	return d.n
+}
+
+func (d *doubler) Reset() {
+	d.n = 0
+}
+
+func TestReplace(t *testing.T) {
+	g := &gated{release: make(chan struct{})}
+	s := NewSequent(g, WithMailboxSize(4))
+	defer s.Terminate(nil)
+	id := s.Id()
+	if err := s.Cast("Block"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Cast("Incr"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Replace(&doubler{n: 10}); err != nil {
+		t.Fatal(err)
+	}
+	// made against the new value while the old one is still blocked
+	if err := s.Cast("Reset"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Cast("Block"); err != ErrUnknownMethod {
+		t.Fatalf("expected ErrUnknownMethod, got %v", err)
+	}
+	close(g.release)
+
+	rets, err := s.Call("Incr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rets[0].(int) != 2 {
+		t.Fatalf("expected the new value to process the requests, got %v",
+			rets[0])
+	}
+	if g.n != 1 {
+		t.Fatalf("expected the queued Incr to reach the old value, got %d",
+			g.n)
+	}
+	if s.Id() != id {
+		t.Fatalf("expected the Id %v to be kept, got %v", id, s.Id())
+	}
+	if stats := s.Stats(); stats.Type != "*seriatim.doubler" {
+		t.Fatalf("expected the type of the new value, got %q", stats.Type)
+	}
+}
+
+func TestReplaceQueued(t *testing.T) {
+	g := &gated{release: make(chan struct{})}
+	s := NewSequent(g, WithMailboxSize(4))
+	defer s.Terminate(nil)
+	if err := s.Cast("Block"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Replace(&doubler{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Replace(g); err != nil {
+		t.Fatal(err)
+	}
+	results := make(chan error, 1)
+	go func() {
+		_, err := s.Call("Block")
+		results <- err
+	}()
+	close(g.release)
+	if err := <-results; err != nil {
+		t.Fatalf("expected the last value to process the call, got %v", err)
+	}
+}
+
+func TestReplaceErrors(t *testing.T) {
+	s := NewSequent(&gated{})
+	if err := s.Replace(nil); err != ErrNoValue {
+		t.Fatalf("expected ErrNoValue, got %v", err)
+	}
+	s.Terminate(nil)
+	<-s.Done()
+	if err := s.Replace(&doubler{}); err != ErrSequentStop {
+		t.Fatalf("expected ErrSequentStop, got %v", err)
+	}
+	r := NewReplica(NewSequent(&gated{}), NewSequent(&gated{}))
+	defer r.Terminate(nil)
+	if err := r.Replace(&doubler{}); err != ErrCannotReplace {
+		t.Fatalf("expected ErrCannotReplace, got %v", err)
+	}
+}
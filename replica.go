@@ -0,0 +1,188 @@
+package seriatim
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+)
+
+var ErrNoStandby = errors.New("No standby")
+
+// Replica is a Sequent processing requests on a primary while a warm
+// standby receives a copy of every cast, in the same order, so that it
+// holds the state the casts built when the primary fails and can take
+// over right away through Promote. Calls are not copied; methods
+// changing state should be cast for the standby to follow them. A
+// standby that stops is dropped.
+type Replica struct {
+	// castMu keeps the casts in order across the primary and the
+	// standby without holding mu while they block.
+	castMu  sync.Mutex
+	mu      sync.Mutex
+	primary Sequent
+	standby Sequent
+}
+
+func NewReplica(primary, standby Sequent) *Replica {
+	return &Replica{
+		primary: primary,
+		standby: standby,
+	}
+}
+
+// Primary returns the sequent processing requests.
+func (r *Replica) Primary() Sequent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.primary
+}
+
+// Standby returns the standby, or nil if there is none.
+func (r *Replica) Standby() Sequent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.standby
+}
+
+// SetStandby replaces the standby, which only sees the casts made from
+// then on.
+func (r *Replica) SetStandby(s Sequent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.standby = s
+}
+
+// Promote makes the standby the primary, leaving the replica without a
+// standby, and terminates the previous primary if it is still running.
+func (r *Replica) Promote() error {
+	r.mu.Lock()
+	if r.standby == nil {
+		r.mu.Unlock()
+		return ErrNoStandby
+	}
+	old := r.primary
+	r.primary, r.standby = r.standby, nil
+	r.mu.Unlock()
+	if !old.State().Final() {
+		old.Terminate(nil)
+	}
+	return nil
+}
+
+func (r *Replica) Id() uintptr {
+	return reflect.ValueOf(r).Pointer()
+}
+
+func (r *Replica) Call(name string, args ...interface{}) ([]interface{}, error) {
+	return r.Primary().Call(name, args...)
+}
+
+func (r *Replica) CallContext(
+	ctx context.Context,
+	name string,
+	args ...interface{},
+) ([]interface{}, error) {
+	return r.Primary().CallContext(ctx, name, args...)
+}
+
+func (r *Replica) Cast(name string, args ...interface{}) error {
+	return r.CastNotify(name, nil, args...)
+}
+
+// CastNotify reports the errors of the primary only.
+func (r *Replica) CastNotify(
+	name string,
+	errs chan<- error,
+	args ...interface{},
+) error {
+	// held across both casts so the standby sees them in the order
+	// the primary does
+	r.castMu.Lock()
+	defer r.castMu.Unlock()
+	r.mu.Lock()
+	primary, standby := r.primary, r.standby
+	r.mu.Unlock()
+	if err := primary.CastNotify(name, errs, args...); err != nil {
+		return err
+	}
+	if standby != nil && standby.Cast(name, args...) != nil {
+		r.mu.Lock()
+		if r.standby == standby {
+			r.standby = nil
+		}
+		r.mu.Unlock()
+	}
+	return nil
+}
+
+func (r *Replica) Running() bool {
+	return r.Primary().Running()
+}
+
+func (r *Replica) State() State {
+	return r.Primary().State()
+}
+
+// StateChanges follows the sequent that is the primary when it is
+// called.
+func (r *Replica) StateChanges() <-chan State {
+	return r.Primary().StateChanges()
+}
+
+// Done follows the sequent that is the primary when it is called.
+func (r *Replica) Done() <-chan struct{} {
+	return r.Primary().Done()
+}
+
+func (r *Replica) Err() error {
+	return r.Primary().Err()
+}
+
+// Terminate terminates the primary and the standby.
+func (r *Replica) Terminate(reason error) {
+	r.mu.Lock()
+	primary, standby := r.primary, r.standby
+	r.mu.Unlock()
+	primary.Terminate(reason)
+	if standby != nil {
+		standby.Terminate(reason)
+	}
+}
+
+// Replace returns ErrCannotReplace as the primary and the standby
+// need values of their own; replace theirs instead.
+func (r *Replica) Replace(val interface{}) error {
+	return ErrCannotReplace
+}
+
+// Suspend suspends the primary and the standby, those that are
+// Suspenders.
+func (r *Replica) Suspend() {
+	r.suspenders(Suspender.Suspend)
+}
+
+// Resume resumes the primary and the standby, those that are
+// Suspenders.
+func (r *Replica) Resume() {
+	r.suspenders(Suspender.Resume)
+}
+
+func (r *Replica) suspenders(fn func(Suspender)) {
+	r.mu.Lock()
+	primary, standby := r.primary, r.standby
+	r.mu.Unlock()
+	for _, s := range []Sequent{primary, standby} {
+		if s, ok := s.(Suspender); ok {
+			fn(s)
+		}
+	}
+}
+
+func (r *Replica) Stats() Stats {
+	return r.Primary().Stats()
+}
+
+func (r *Replica) Methods() []MethodInfo {
+	return r.Primary().Methods()
+}
@@ -0,0 +1,123 @@
+package seriatim
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+type tally struct {
+	total int
+}
+
+func (t *tally) Add(n int) {
+	t.total += n
+}
+
+func (t *tally) Total() int {
+	return t.total
+}
+
+func TestReplicaPromote(t *testing.T) {
+	primary := NewSequent(&tally{})
+	standby := NewSequent(&tally{})
+	r := NewReplica(primary, standby)
+	defer r.Terminate(nil)
+
+	for i := 1; i <= 3; i++ {
+		if err := r.Cast("Add", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// calls are not copied
+	if _, err := r.Call("Total"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := r.Promote(); err != nil {
+		t.Fatal(err)
+	}
+	if r.Primary() != standby || r.Standby() != nil {
+		t.Fatal("standby not promoted")
+	}
+	if primary.Running() {
+		t.Fatal("previous primary still running")
+	}
+	rets, err := r.Call("Total")
+	if err != nil || rets[0] != 6 {
+		t.Fatalf("standby missed casts: %v %v", rets, err)
+	}
+	if err := r.Promote(); err != ErrNoStandby {
+		t.Fatalf("expected ErrNoStandby, got %v", err)
+	}
+}
+
+func TestReplicaDropsStoppedStandby(t *testing.T) {
+	primary := NewSequent(&tally{})
+	standby := NewSequent(&tally{})
+	r := NewReplica(primary, standby)
+	defer r.Terminate(nil)
+
+	standby.Terminate(nil)
+	if err := r.Cast("Add", 1); err != nil {
+		t.Fatal(err)
+	}
+	if r.Standby() != nil {
+		t.Fatal("stopped standby kept")
+	}
+	rets, err := r.Call("Total")
+	if err != nil || rets[0] != 1 {
+		t.Fatalf("unexpected %v %v", rets, err)
+	}
+}
+
+func TestReplicaCastBlocked(t *testing.T) {
+	r := &recorder{release: make(chan struct{})}
+	released := make(chan struct{})
+	close(released)
+	primary := NewSequent(r, WithMailboxSize(1))
+	replica := NewReplica(primary, NewSequent(&recorder{release: released}))
+	defer replica.Terminate(nil)
+	if err := replica.Cast("Block"); err != nil {
+		t.Fatal(err)
+	}
+	for primary.Stats().QueueLen != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if err := replica.Cast("Add", "a"); err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- replica.Cast("Add", "b")
+	}()
+	time.Sleep(10 * time.Millisecond)
+	// the cast blocked on the full mailbox of the primary does not
+	// hold up the replica
+	standby := NewSequent(&recorder{release: released})
+	done := make(chan struct{})
+	go func() {
+		replica.SetStandby(standby)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("SetStandby blocked behind the cast")
+	}
+	close(r.release)
+	if err := <-errs; err != nil {
+		t.Fatal(err)
+	}
+	if err := replica.Cast("Add", "c"); err != nil {
+		t.Fatal(err)
+	}
+	rets, err := standby.Call("Seen")
+	if err != nil || !reflect.DeepEqual(rets[0], []string{"c"}) {
+		t.Fatalf("expected the new standby to see c, got %v %v", rets, err)
+	}
+	rets, err = replica.Call("Seen")
+	if err != nil || !reflect.DeepEqual(rets[0], []string{"a", "b", "c"}) {
+		t.Fatalf("expected the primary to see every cast, got %v %v", rets, err)
+	}
+}
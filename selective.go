@@ -0,0 +1,140 @@
+package seriatim
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+// ErrNotSelective is returned by Only and AcceptAll for sequents not
+// made by this package.
+var ErrNotSelective = errors.New("Sequent does not support selective receive")
+
+// methodSet is the set of methods a sequent accepts, nil for all.
+type methodSet map[string]bool
+
+// WithOnly starts the sequent accepting only the requests for methods,
+// as if Only were called before any request could be made.
+func WithOnly(methods ...string) Option {
+	return func(a *sequent) {
+		a.only.Store(newMethodSet(methods))
+	}
+}
+
+// Only makes s process only the requests for methods from now on, as a
+// selective receive does. The requests for other methods stay queued,
+// in the order they were made, until they are accepted again by a
+// later Only or by AcceptAll, before the requests queued after them.
+// This lets a sequent go through phases, such as being initialised by
+// an Init call, without failing the requests made in the meantime.
+// Terminating s is not held up and purges the requests held back.
+//
+// The requests held back count against the capacity of the mailbox:
+// once as many are held as it holds, s takes no more requests from it
+// until some are accepted, so it fills up and its overflow policy
+// applies to the casts made then and Calls wait. The requests for the
+// methods accepted wait too if they are queued behind, so with a
+// bounded mailbox the phase may have to be ended from outside s.
+func Only(s Sequent, methods ...string) error {
+	a, ok := s.(*sequent)
+	if !ok {
+		return ErrNotSelective
+	}
+	table := a.table()
+	for _, name := range methods {
+		if _, ok := table.methods[name]; !ok {
+			return ErrUnknownMethod
+		}
+	}
+	a.setOnly(newMethodSet(methods))
+	return nil
+}
+
+// AcceptAll makes s process the requests for all its methods again,
+// starting with those held back by Only.
+func AcceptAll(s Sequent) error {
+	a, ok := s.(*sequent)
+	if !ok {
+		return ErrNotSelective
+	}
+	a.setOnly(nil)
+	return nil
+}
+
+func newMethodSet(methods []string) methodSet {
+	set := make(methodSet, len(methods))
+	for _, name := range methods {
+		set[name] = true
+	}
+	return set
+}
+
+func (a *sequent) setOnly(set methodSet) {
+	a.only.Store(set)
+	// to process the requests accepted now
+	a.wakeUp()
+}
+
+// accepts reports whether req may be processed now. Requests made by
+// the package itself, which have no name, always are.
+func (a *sequent) accepts(req *request) bool {
+	set, _ := a.only.Load().(methodSet)
+	return set == nil || req.name == "" || set[req.name]
+}
+
+// hold keeps req back if it is not accepted now, reporting whether it
+// did.
+func (a *sequent) hold(req *request) bool {
+	if a.accepts(req) {
+		return false
+	}
+	a.heldMu.Lock()
+	defer a.heldMu.Unlock()
+	if atomic.LoadUint32(&a.dead) != 0 {
+		req.Purged()
+		return true
+	}
+	a.held = append(a.held, req)
+	return true
+}
+
+// heldFull reports whether as many requests are held back as the
+// mailbox holds. Unbounded mailboxes have no limit.
+func (a *sequent) heldFull() bool {
+	limit := a.queue.Cap()
+	if limit == 0 {
+		return false
+	}
+	a.heldMu.Lock()
+	defer a.heldMu.Unlock()
+	return len(a.held) >= limit
+}
+
+// release returns the oldest request held back that is accepted now,
+// if any.
+func (a *sequent) release() *request {
+	a.heldMu.Lock()
+	defer a.heldMu.Unlock()
+	for i, req := range a.held {
+		if a.accepts(req) {
+			a.held = append(a.held[:i], a.held[i+1:]...)
+			return req
+		}
+	}
+	return nil
+}
+
+// failHeld answers the requests held back with err, or purges them if
+// err is nil.
+func (a *sequent) failHeld(err error) {
+	a.heldMu.Lock()
+	held := a.held
+	a.held = nil
+	a.heldMu.Unlock()
+	for _, req := range held {
+		if err == nil {
+			req.Purged()
+		} else {
+			req.fail(err)
+		}
+	}
+}
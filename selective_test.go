@@ -0,0 +1,128 @@
+package seriatim
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type phased struct {
+	self   Sequent
+	inited bool
+	uses   []int
+}
+
+func (p *phased) OnStart(s Sequent) {
+	p.self = s
+}
+
+func (p *phased) Init() {
+	p.inited = true
+	AcceptAll(p.self)
+}
+
+func (p *phased) Use(n int) bool {
+	p.uses = append(p.uses, n)
+	return p.inited
+}
+
+func (p *phased) Uses() []int {
+	return p.uses
+}
+
+func TestOnly(t *testing.T) {
+	s := NewSequent(&phased{}, WithOnly("Init"), WithMailboxSize(8))
+	defer s.Terminate(nil)
+
+	results := make(chan bool, 2)
+	for i := 0; i < 2; i++ {
+		s.Cast("Use", i)
+	}
+	go func() {
+		rets, err := s.Call("Use", 2)
+		if err != nil {
+			t.Error(err)
+		}
+		results <- rets[0].(bool)
+	}()
+	select {
+	case <-results:
+		t.Fatal("Use processed before Init")
+	case <-time.After(10 * time.Millisecond):
+	}
+	if _, err := s.Call("Init"); err != nil {
+		t.Fatal(err)
+	}
+	if inited := <-results; !inited {
+		t.Fatal("Use processed before Init")
+	}
+	rets, err := s.Call("Uses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	uses := rets[0].([]int)
+	if len(uses) != 3 || uses[0] != 0 || uses[1] != 1 || uses[2] != 2 {
+		t.Fatalf("expected the held requests in order, got %v", uses)
+	}
+}
+
+func TestOnlyBounded(t *testing.T) {
+	s := NewSequent(&phased{}, WithOnly("Init"), WithMailboxSize(2),
+		WithOverflow(OverflowFail))
+	defer s.Terminate(nil)
+
+	for i := 0; i < 2; i++ {
+		if err := s.Cast("Use", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for s.Stats().QueueLen != 0 {
+		time.Sleep(time.Millisecond)
+	}
+	// held back as many as the mailbox holds, the sequent leaves the
+	// next ones in the mailbox
+	for i := 2; i < 4; i++ {
+		if err := s.Cast("Use", i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	if err := s.Cast("Use", 4); err != ErrMailboxFull {
+		t.Fatalf("expected ErrMailboxFull, got %v", err)
+	}
+	if err := AcceptAll(s); err != nil {
+		t.Fatal(err)
+	}
+	rets, err := s.Call("Uses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if uses := rets[0].([]int); len(uses) != 4 || uses[3] != 3 {
+		t.Fatalf("unexpected uses %v", uses)
+	}
+}
+
+func TestOnlyTerminate(t *testing.T) {
+	s := NewSequent(&phased{}, WithOnly("Init"))
+	errs := make(chan error, 1)
+	go func() {
+		_, err := s.Call("Use", 1)
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	s.Terminate(errors.New("finished"))
+	if err := <-errs; err != ErrSequentStop {
+		t.Fatalf("expected ErrSequentStop, got %v", err)
+	}
+}
+
+func TestOnlyErrors(t *testing.T) {
+	s := NewSequent(&phased{})
+	defer s.Terminate(nil)
+	if err := Only(s, "Missing"); err != ErrUnknownMethod {
+		t.Fatalf("expected ErrUnknownMethod, got %v", err)
+	}
+	if err := Only(struct{ Sequent }{s}, "Init"); err != ErrNotSelective {
+		t.Fatalf("expected ErrNotSelective, got %v", err)
+	}
+}
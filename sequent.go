@@ -1,19 +1,84 @@
 package seriatim
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"os"
+	"log/slog"
 	"reflect"
 	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"time"
 )
 
 var (
 	ErrSequentStop   = errors.New("Sequent stopped")
+	ErrSequentDied   = errors.New("Sequent died")
 	ErrUnknownMethod = errors.New("Unknown method")
 )
 
+// CrashError is returned by the Call that crashed a sequent and by the
+// Calls that were queued behind it. It matches ErrSequentDied and the
+// reason of the crash, as well as ErrSequentStop since the sequent is
+// no longer running.
+type CrashError struct {
+	// Method is the name of the method that crashed the sequent.
+	Method string
+	Reason error
+}
+
+func (e *CrashError) Error() string {
+	return fmt.Sprintf("Sequent died in %s: %s", e.Method, e.Reason)
+}
+
+func (e *CrashError) Unwrap() []error {
+	return []error{ErrSequentDied, e.Reason}
+}
+
+func (e *CrashError) Is(target error) bool {
+	return target == ErrSequentStop
+}
+
+// ArgCountError is returned when a method is given a different number
+// of arguments than it has parameters. The arguments of a variadic
+// method end with the slice of the variadic ones.
+type ArgCountError struct {
+	Method string
+	Want   int
+	Have   int
+}
+
+func (e *ArgCountError) Error() string {
+	plural := "s"
+	if e.Want == 1 {
+		plural = ""
+	}
+	return fmt.Sprintf("Method %s takes %d argument%s, have %d",
+		e.Method, e.Want, plural, e.Have)
+}
+
+// ArgTypeError is returned when an argument of a method cannot be
+// converted to the type of its parameter. Have is nil for an untyped
+// nil argument.
+type ArgTypeError struct {
+	Method string
+	Index  int
+	Have   reflect.Type
+	Want   reflect.Type
+}
+
+func (e *ArgTypeError) Error() string {
+	have := "nil"
+	if e.Have != nil {
+		have = "type " + e.Have.String()
+	}
+	return fmt.Sprintf("Argument %d of method %s: %s is not assignable to type %s",
+		e.Index, e.Method, have, e.Want)
+}
+
 type Supervisor interface {
 	SequentTerminated(err error, pid uintptr)
 }
@@ -21,27 +86,220 @@ type Supervisor interface {
 type Sequent interface {
 	Id() uintptr
 	Call(name string, args ...interface{}) ([]interface{}, error)
+	// CallContext calls like Call but gives up waiting for the
+	// request to be queued or answered once ctx is done, returning
+	// ctx.Err(). A request given up on after it was queued is still
+	// processed.
+	CallContext(
+		ctx context.Context,
+		name string,
+		args ...interface{},
+	) ([]interface{}, error)
 	Cast(name string, args ...interface{}) error
+	// CastNotify casts like Cast and sends the error to errs if
+	// processing the request fails: the error returned by a method
+	// whose last result is an error, a *CrashError or ErrSequentStop
+	// if the request is dropped when the sequent stops. Nothing is
+	// sent on success. The send blocks the sequent, so errs should be
+	// buffered or drained.
+	CastNotify(name string, errs chan<- error, args ...interface{}) error
+	// Running reports whether the sequent is starting or running. It
+	// is false while the sequent is suspended, though requests are
+	// still accepted then.
 	Running() bool
+	// State returns the lifecycle state of the sequent.
+	State() State
+	// StateChanges returns a channel receiving the current state and
+	// every change after it. It is closed after the final state.
+	StateChanges() <-chan State
+	// Done returns a channel closed once the sequent has terminated.
+	Done() <-chan struct{}
+	// Err returns the reason the sequent terminated with once Done is
+	// closed, such as the one given to Terminate or the reason of a
+	// crash, and nil before.
+	Err() error
 	Terminate(error)
+	// Replace has the sequent process its requests with the methods
+	// of val from now on, keeping its Id and mailbox. Sequents that
+	// cannot return ErrCannotReplace.
+	Replace(val interface{}) error
+	Stats() Stats
+	// Methods describes the methods that can be called, sorted by
+	// name.
+	Methods() []MethodInfo
+}
+
+// MethodInfo describes a method of a sequent.
+type MethodInfo struct {
+	Name    string
+	Args    []reflect.Type
+	Returns []reflect.Type
+	// Variadic is set when the last of Args is the slice of the
+	// variadic arguments.
+	Variadic bool
+	// Timeout, Priority and Class are those of the MethodSpec the
+	// method was given by, if any.
+	Timeout  time.Duration
+	Priority int
+	Class    string
+}
+
+// String returns the signature of the method as Go would write it.
+func (m MethodInfo) String() string {
+	var b strings.Builder
+	b.WriteString(m.Name)
+	b.WriteByte('(')
+	for i, arg := range m.Args {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		if m.Variadic && i == len(m.Args)-1 {
+			b.WriteString("..." + arg.Elem().String())
+			continue
+		}
+		b.WriteString(arg.String())
+	}
+	b.WriteByte(')')
+	switch len(m.Returns) {
+	case 0:
+	case 1:
+		b.WriteString(" " + m.Returns[0].String())
+	default:
+		b.WriteString(" (")
+		for i, ret := range m.Returns {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(ret.String())
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+// DescribeMethods returns the MethodInfo of each function in methods,
+// sorted by name, skipping the values that are not functions.
+func DescribeMethods(methods map[string]interface{}) []MethodInfo {
+	return describeMethods(convertMethods(methods), methodSpecs(methods))
+}
+
+func describeMethods(
+	methods map[string]reflect.Value,
+	specs map[string]MethodSpec,
+) []MethodInfo {
+	out := make([]MethodInfo, 0, len(methods))
+	for name, method := range methods {
+		typ := method.Type()
+		spec := specs[name]
+		skip := 0
+		if takesContext(typ) {
+			skip = 1
+		}
+		info := MethodInfo{
+			Name:     name,
+			Args:     make([]reflect.Type, typ.NumIn()-skip),
+			Returns:  make([]reflect.Type, typ.NumOut()),
+			Variadic: typ.IsVariadic(),
+			Timeout:  spec.Timeout,
+			Priority: spec.Priority,
+			Class:    spec.Class,
+		}
+		for i := range info.Args {
+			info.Args[i] = typ.In(i + skip)
+		}
+		for i := range info.Returns {
+			info.Returns[i] = typ.Out(i)
+		}
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+type Stats struct {
+	Id        uintptr
+	Type      string
+	Running   bool
+	QueueLen  int
+	QueueCap  int
+	Processed uint64
+	// QueueHighWater is the longest the queue has been.
+	QueueHighWater int
+	// Calls and Casts count the requests processed of each kind.
+	Calls uint64
+	Casts uint64
+	// QueueTime is the total time processed requests waited to be
+	// processed and ProcessTime the total time their methods ran.
+	QueueTime   time.Duration
+	ProcessTime time.Duration
+	// AllocBytes and Allocs estimate the memory and the number of
+	// objects allocated by its methods, if created WithAllocSampling.
+	AllocBytes uint64
+	Allocs     uint64
+	// Tags are the labels of the sequent, see WithTags.
+	Tags []string
+}
+
+// Option configures a sequent at creation.
+type Option func(*sequent)
+
+// WithMailbox makes the sequent receive its requests from m instead of
+// a Queue of capacity 1. m must not be shared with other sequents.
+func WithMailbox(m Mailbox) Option {
+	return func(a *sequent) {
+		a.queue = m
+	}
+}
+
+// WithMailboxSize sets the capacity of the sequent's mailbox, which
+// defaults to 1. Casts only block once that many requests are queued.
+// Sizes below 1 are ignored.
+func WithMailboxSize(n int) Option {
+	return func(a *sequent) {
+		if q := NewQueue(n); q != nil {
+			a.queue = q
+		}
+	}
 }
 
-func NewSequent(val interface{}) Sequent {
-	return NewSupervisedSequentTable(val, GetMethods(val), nil)
+// NewSequent returns a sequent processing requests for the exported
+// methods of val one at a time. Methods whose first parameter is a
+// context.Context are given one by the sequent rather than by their
+// callers:
+//
+//	func (p *poller) Poll(ctx context.Context, url string) error
+//
+// is called as s.Call("Poll", url). The context is canceled once the
+// sequent is asked to terminate, so that a long-running method can
+// give up early, and has the earliest of the deadline of the method,
+// see MethodSpec, and that of the context given to CallContext.
+func NewSequent(val interface{}, opts ...Option) Sequent {
+	return NewSupervisedSequentTable(val, GetMethods(val), nil, opts...)
 }
 
-func NewSequentTable(val interface{}, methods map[string]interface{}) Sequent {
-	return NewSupervisedSequentTable(val, methods, nil)
+func NewSequentTable(
+	val interface{},
+	methods map[string]interface{},
+	opts ...Option,
+) Sequent {
+	return NewSupervisedSequentTable(val, methods, nil, opts...)
 }
 
-func NewSupervisedSequent(val interface{}, supervisor Supervisor) Sequent {
-	return NewSupervisedSequentTable(val, GetMethods(val), supervisor)
+func NewSupervisedSequent(
+	val interface{},
+	supervisor Supervisor,
+	opts ...Option,
+) Sequent {
+	return NewSupervisedSequentTable(val, GetMethods(val), supervisor, opts...)
 }
 
 func NewSupervisedSequentTable(
 	val interface{},
 	methods map[string]interface{},
 	supervisor Supervisor,
+	opts ...Option,
 ) Sequent {
 	if val == nil {
 		return nil
@@ -50,33 +308,155 @@ func NewSupervisedSequentTable(
 		val:        val,
 		supervisor: supervisor,
 	}
+	for _, opt := range opts {
+		opt(act)
+	}
 	act.init(methods)
 	return act
 }
 
 type reply struct {
 	returns []reflect.Value
+	err     error
 }
 
 type request struct {
-	method reflect.Value
-	args   []reflect.Value
-	reply  chan<- reply
+	name     string
+	method   reflect.Value
+	args     []reflect.Value
+	reply    chan<- reply
+	errs     chan<- error
+	deadline time.Time
+	// callerDeadline is the deadline of the context given to
+	// CallContext, see NewSequent.
+	callerDeadline time.Time
+	claim          uint32
+	// answered is set once the request is answered, see answer.
+	answered uint32
+	// made is when the request was made, to tell how long it
+	// waited.
+	made time.Time
+	// owner is the sequent the request was made of, to log it being
+	// purged, and table the methods it was made against.
+	owner *sequent
+	table *methodTable
+	// then is called with the results of the method once it
+	// returned, see Pipeline.
+	then func(returns []reflect.Value)
 }
 
 func (msg *request) Purged() {
+	if !msg.answer() {
+		return
+	}
+	if msg.owner != nil {
+		msg.owner.logPurged(msg.name)
+	}
 	if msg.reply != nil {
 		close(msg.reply)
 	}
+	if msg.errs != nil {
+		msg.errs <- ErrSequentStop
+	}
+}
+
+func (msg *request) fail(err error) {
+	if !msg.answer() {
+		return
+	}
+	if msg.reply != nil {
+		msg.reply <- reply{err: err}
+	}
+	if msg.errs != nil {
+		msg.errs <- err
+	}
 }
 
 type sequent struct {
-	queue      *Queue
+	// the counters of Stats, first for 64-bit alignment of atomic ops
+	processed    uint64
+	calls        uint64
+	casts        uint64
+	queueNanos   int64
+	processNanos int64
+	highWater    int64
+	allocBytes   uint64
+	allocObjects uint64
+
+	queue      Mailbox
 	supervisor Supervisor
-	val        interface{}
-	methods    map[string]reflect.Value
-	kill       chan error
-	running    atomic.Value
+	id         uintptr
+	// val is the value processing the requests and current its
+	// methods, only used by the sequent's goroutine once it runs;
+	// latest holds the *methodTable requests are made against, see
+	// Replace.
+	val       interface{}
+	current   *methodTable
+	latest    atomic.Value
+	replaceMu sync.Mutex
+	kill      chan error
+	// stopping is set once termination has been requested, so that
+	// only the first request reaches kill.
+	stopping uint32
+	// dead is set once the sequent has terminated, possibly while
+	// its goroutine was abandoned in a method.
+	dead uint32
+	// pending is the last request taken from the mailbox to be
+	// answered, failed by TerminateWithTimeout if it abandons the
+	// goroutine before it is.
+	pending atomic.Value
+	// stopped is closed when the sequent starts terminating, waking
+	// senders blocked on a full mailbox; enqueueMu keeps the mailbox
+	// from being stopped while a send is under way.
+	stopped   chan struct{}
+	enqueueMu sync.RWMutex
+	// controls counts the requests made by the package itself that
+	// may be in the mailbox of a sequent dropping the oldest request
+	// on overflow, which are never dropped; controlMu keeps one from
+	// being queued while the oldest request is dropped.
+	controls  int32
+	controlMu sync.Mutex
+	done      chan struct{}
+	// reason is what the sequent terminated with, set before done is
+	// closed.
+	reason    error
+	lifecycle lifecycle
+	batchSize int
+	overflow  Overflow
+	// isolatePanics keeps the sequent running when a method panics.
+	isolatePanics bool
+	provider      Provider
+	metrics       Metrics
+	logger        *slog.Logger
+	// allocEvery is how many requests each sampled one accounts for,
+	// see WithAllocSampling, and allocTick counts them.
+	allocEvery uint64
+	allocTick  uint64
+	// middleware and the handler chained from it are only used by
+	// the sequent's goroutine once it runs, see Use.
+	middleware []Middleware
+	handler    Handler
+	// dispatching is the request being dispatched through the
+	// handler.
+	dispatching *request
+	// ctx is the context given to methods, canceled once the sequent
+	// is asked to terminate.
+	ctx    context.Context
+	cancel context.CancelFunc
+	// only holds the methodSet accepted, see Only, and held the
+	// requests held back, in order. wake wakes the sequent when
+	// either that or its suspension changes.
+	only   atomic.Value
+	heldMu sync.Mutex
+	held   []*request
+	wake   chan struct{}
+	// snapshot holds the latest *snapshotted of the value, see
+	// Snapshot, and published whether there is one, only used by
+	// the sequent's goroutine once it runs.
+	snapshot  atomic.Value
+	published bool
+	// tags label the sequent, see WithTags.
+	tags []string
 }
 
 func (a *sequent) newRequest(
@@ -84,128 +464,472 @@ func (a *sequent) newRequest(
 	name string,
 	args ...interface{},
 ) (*request, error) {
-	method, ok := a.methods[name]
+	table := a.table()
+	method, ok := table.methods[name]
 	if !ok {
 		return nil, ErrUnknownMethod
 	}
 
-	arg_values, err := processMethodArguments(method, args...)
+	arg_values, err := processMethodArguments(name, method, args...)
 	if err != nil {
 		return nil, err
 	}
-	return &request{
+	req := &request{
+		name:   name,
 		method: method,
 		args:   arg_values,
 		reply:  replych,
-	}, nil
+		made:   time.Now(),
+		owner:  a,
+		table:  table,
+	}
+	if timeout := table.specs[name].Timeout; timeout > 0 {
+		req.deadline = req.made.Add(timeout)
+	}
+	return req, nil
 }
 
 func (a *sequent) Id() uintptr {
-	return reflect.ValueOf(a.val).Pointer()
+	return a.id
+}
+
+// identify returns the id of the sequent, that of its first value.
+func (a *sequent) identify() uintptr {
+	val := reflect.ValueOf(a.val)
+	switch val.Kind() {
+	case reflect.Ptr, reflect.Chan, reflect.Func, reflect.Map,
+		reflect.Slice, reflect.UnsafePointer:
+		return val.Pointer()
+	}
+	// values without identity are identified by their sequent
+	return reflect.ValueOf(a).Pointer()
+}
+
+func (a *sequent) typeName() string {
+	return a.table().typeName
+}
+
+func (a *sequent) Stats() Stats {
+	return Stats{
+		Id:             a.Id(),
+		Type:           a.typeName(),
+		Running:        a.Running(),
+		QueueLen:       a.queue.Len(),
+		QueueCap:       a.queue.Cap(),
+		QueueHighWater: int(atomic.LoadInt64(&a.highWater)),
+		Processed:      atomic.LoadUint64(&a.processed),
+		Calls:          atomic.LoadUint64(&a.calls),
+		Casts:          atomic.LoadUint64(&a.casts),
+		QueueTime:      time.Duration(atomic.LoadInt64(&a.queueNanos)),
+		ProcessTime:    time.Duration(atomic.LoadInt64(&a.processNanos)),
+		AllocBytes:     atomic.LoadUint64(&a.allocBytes),
+		Allocs:         atomic.LoadUint64(&a.allocObjects),
+		Tags:           append([]string(nil), a.tags...),
+	}
 }
 
 func (a *sequent) Call(name string, args ...interface{}) ([]interface{}, error) {
-	replych := make(chan reply)
+	return a.CallContext(context.Background(), name, args...)
+}
+
+func (a *sequent) CallContext(
+	ctx context.Context,
+	name string,
+	args ...interface{},
+) ([]interface{}, error) {
+	// buffered so a request can be answered after its caller gave up
+	replych := make(chan reply, 1)
 	req, err := a.newRequest(replych, name, args...)
 	if err != nil {
 		return nil, err
 	}
+	if deadline, ok := ctx.Deadline(); ok {
+		req.callerDeadline = deadline
+	}
 
-	if !a.Running() {
+	if !a.accepting() {
 		return nil, ErrSequentStop
 	}
 
-	a.queue.Enqueue() <- req
+	var expired <-chan time.Time
+	if !req.deadline.IsZero() {
+		timer := time.NewTimer(time.Until(req.deadline))
+		defer timer.Stop()
+		expired = timer.C
+	}
+	if err := a.enqueue(req, ctx.Done()); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	select {
+	case reply, ok := <-replych:
+		return answer(reply, ok)
+	case <-expired:
+		return nil, ErrMethodTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
 
-	reply, ok := <-replych
+// answer returns the results of a Call from its reply, received with
+// ok set unless the reply channel was closed.
+func answer(reply reply, ok bool) ([]interface{}, error) {
 	if !ok {
 		// sequent terminated and channel closed
 		return nil, ErrSequentStop
 	}
-
+	if reply.err != nil {
+		return nil, reply.err
+	}
 	return processMethodReturns(reply.returns), nil
 }
 
 func (a *sequent) Cast(name string, args ...interface{}) error {
+	return a.CastNotify(name, nil, args...)
+}
+
+func (a *sequent) CastNotify(
+	name string,
+	errs chan<- error,
+	args ...interface{},
+) error {
 	req, err := a.newRequest(nil, name, args...)
 	if err != nil {
 		return err
 	}
+	req.errs = errs
+
+	if !a.accepting() {
+		return ErrSequentStop
+	}
 
-	if !a.Running() {
+	return a.enqueueCast(req)
+}
+
+// enqueue puts req in the mailbox unless the sequent terminates or
+// cancel is closed first.
+func (a *sequent) enqueue(req *request, cancel <-chan struct{}) error {
+	if !a.countsControls(req) {
+		return a.send(req, cancel)
+	}
+	// counted before it can be in the mailbox, see dropOldest
+	a.controlMu.Lock()
+	atomic.AddInt32(&a.controls, 1)
+	a.controlMu.Unlock()
+	err := a.send(req, cancel)
+	if err != nil {
+		atomic.AddInt32(&a.controls, -1)
+	}
+	return err
+}
+
+func (a *sequent) send(req *request, cancel <-chan struct{}) error {
+	a.enqueueMu.RLock()
+	defer a.enqueueMu.RUnlock()
+	select {
+	case <-a.stopped:
 		return ErrSequentStop
+	default:
 	}
+	select {
+	case a.queue.Enqueue() <- req:
+		a.queued()
+		return nil
+	case <-a.stopped:
+		return ErrSequentStop
+	case <-cancel:
+		return ErrSequentStop
+	}
+}
 
-	a.queue.Enqueue() <- req
-	return nil
+func (a *sequent) Methods() []MethodInfo {
+	table := a.table()
+	return describeMethods(table.methods, table.specs)
 }
 
 func (a *sequent) Running() bool {
-	return a.running.Load().(bool)
+	return a.lifecycle.load() <= StateRunning
 }
 
+// accepting reports whether requests may be made of the sequent,
+// which they may while it is suspended.
+func (a *sequent) accepting() bool {
+	return a.lifecycle.load() <= StateSuspended
+}
+
+func (a *sequent) State() State {
+	return a.lifecycle.load()
+}
+
+func (a *sequent) StateChanges() <-chan State {
+	return a.lifecycle.changes()
+}
+
+func (a *sequent) Done() <-chan struct{} {
+	return a.done
+}
+
+func (a *sequent) Err() error {
+	select {
+	case <-a.done:
+		return a.reason
+	default:
+		return nil
+	}
+}
+
+// Terminate latches the request to terminate and returns without
+// waiting for the sequent to finish the message it is processing. The
+// sequent stops accepting requests right away and terminates once the
+// current message completes, purging the ones queued. Only the first
+// reason is kept; calling Terminate again, or after the sequent
+// crashed, does nothing.
 func (a *sequent) Terminate(reason error) {
+	if !atomic.CompareAndSwapUint32(&a.stopping, 0, 1) {
+		return
+	}
+	a.lifecycle.advance(StateStopping)
+	a.cancel()
 	a.kill <- reason
 }
 
 func (a *sequent) init(methods map[string]interface{}) {
-	a.methods = convertMethods(methods)
-	a.queue = NewQueue(1)
-	a.running.Store(true)
-	a.kill = make(chan error)
+	a.id = a.identify()
+	a.current = newMethodTable(a.val, methods)
+	a.latest.Store(a.current)
+	a.chain()
+	if a.queue == nil {
+		a.queue = NewQueue(1)
+	}
+	if a.batchSize == 0 {
+		a.batchSize = DefaultBatchSize
+	}
+	a.ctx, a.cancel = context.WithCancel(context.Background())
+	a.kill = make(chan error, 1)
+	a.wake = make(chan struct{}, 1)
+	a.stopped = make(chan struct{})
+	a.done = make(chan struct{})
+	register(a)
 	go a.run()
 }
 
-func (a *sequent) terminate(reason error) {
+// terminate ends the sequent in the final state, reporting whether it
+// did rather than an earlier call.
+func (a *sequent) terminate(reason error, final State) bool {
+	if !atomic.CompareAndSwapUint32(&a.dead, 0, 1) {
+		return false
+	}
+	a.terminated(reason, final)
+	return true
+}
+
+// terminated finishes terminating the sequent once it is dead.
+func (a *sequent) terminated(reason error, final State) {
+	a.logTerminated(reason, final)
+	a.cancel()
+	unregister(a)
 	if a.supervisor != nil {
 		a.supervisor.SequentTerminated(reason, a.Id())
 	}
+	close(a.stopped)
+	a.enqueueMu.Lock()
 	a.queue.Stop()
+	a.enqueueMu.Unlock()
+	a.failHeld(nil)
+	a.lifecycle.advance(final)
+	a.reason = reason
+	close(a.done)
 }
 
 func (a *sequent) processRequest(req *request) {
-	returns := req.method.Call(req.args)
+	if a.countsControls(req) {
+		atomic.AddInt32(&a.controls, -1)
+	}
+	if !req.take() {
+		// its caller gave up with CallTimeout
+		return
+	}
+	a.pending.Store(req)
+	if req.expired() {
+		req.fail(ErrMethodTimeout)
+		return
+	}
+	if err := a.rebind(req); err != nil {
+		req.fail(err)
+		return
+	}
+	var returns []reflect.Value
+	var failed error
+	started := time.Now()
+	allocs := a.sampleAllocs()
+	err := a.guard(req.name, func() {
+		returns, failed = a.dispatch(req)
+		a.publish()
+	})
+	a.recordAllocs(allocs)
+	a.record(req, started, time.Since(started))
+	atomic.AddUint64(&a.processed, 1)
+	if err == nil {
+		err = failed
+	}
+	if err != nil {
+		req.fail(err)
+		return
+	}
+	if !req.answer() {
+		return
+	}
 	if req.reply != nil {
 		req.reply <- reply{
 			returns: returns,
 		}
 	}
+	if req.errs != nil {
+		if err := returnedError(returns); err != nil {
+			req.errs <- err
+		}
+	}
+	if req.then != nil {
+		req.then(returns)
+	}
+}
+
+// failQueued answers the Calls waiting in the mailbox with err right
+// away rather than leaving them to be purged when the sequent
+// terminates.
+func (a *sequent) failQueued(err error) {
+	for {
+		select {
+		case msg, ok := <-a.queue.Dequeue():
+			if !ok {
+				return
+			}
+			msg.(*request).fail(err)
+		default:
+			return
+		}
+	}
 }
 
 func (a *sequent) run() {
-	var req *request
+	// req is being processed, next was dequeued while collecting a
+	// batch and is processed after it.
+	var req, next *request
 	defer func() {
 		if rec := recover(); rec != nil {
-			err, ok := rec.(error)
-			if !ok {
-				err = fmt.Errorf("%s", rec)
+			if a.abandoned() {
+				return
 			}
-			a.running.Store(false)
-			if req.reply != nil {
-				close(req.reply)
+			err := panicReason(rec)
+			atomic.StoreUint32(&a.stopping, 1)
+			a.lifecycle.advance(StateStopping)
+			crash := &CrashError{Method: req.name, Reason: err}
+			req.fail(crash)
+			if next != nil {
+				next.fail(crash)
 			}
+			a.failHeld(crash)
+			a.failQueued(crash)
 			//ideally error would hold the stack where it was
 			//generated.
-			fmt.Fprintln(os.Stderr, err)
-			debug.PrintStack()
-			a.terminate(err)
+			a.crashed(req, err, debug.Stack())
+			a.terminate(err, StateCrashed)
 		}
 	}()
 
+	req = &request{name: "Snapshot"}
+	a.publish()
+	if starter, ok := a.val.(Starter); ok {
+		req = &request{name: "OnStart"}
+		starter.OnStart(a)
+		a.publish()
+	}
+	a.lifecycle.advance(StateRunning)
+
 loop:
 	for {
+		if a.abandoned() {
+			return
+		}
+		// Control messages are taken before the mailbox so they are
+		// not stuck behind its backlog, whatever the Mailbox.
 		select {
-		case msg, ok := <-a.queue.Dequeue():
-			if !ok {
-				break loop
-			}
-			req = msg.(*request)
-			a.processRequest(req)
 		case reason := <-a.kill:
-			a.running.Store(false)
-			a.terminate(reason)
+			req = &request{name: "OnTerminate"}
+			a.exit(reason)
+			continue
+		default:
+		}
+		if a.lifecycle.load() == StateSuspended {
+			select {
+			case <-a.wake:
+			case reason := <-a.kill:
+				req = &request{name: "OnTerminate"}
+				a.exit(reason)
+			}
+			continue
 		}
+		first := a.release()
+		if first == nil && a.heldFull() {
+			// nothing more is taken from the mailbox until
+			// some of the held requests are accepted
+			select {
+			case <-a.wake:
+			case reason := <-a.kill:
+				req = &request{name: "OnTerminate"}
+				a.exit(reason)
+			}
+			continue
+		}
+		if first == nil {
+			select {
+			case msg, ok := <-a.queue.Dequeue():
+				if !ok {
+					break loop
+				}
+				first = msg.(*request)
+			case <-a.wake:
+				continue
+			case reason := <-a.kill:
+				req = &request{name: "OnTerminate"}
+				a.exit(reason)
+				continue
+			}
+		}
+		batcher, batching := a.val.(BatchHandler)
+		for next = first; next != nil; {
+			req, next = next, nil
+			if a.hold(req) {
+				continue
+			}
+			if !batching || !req.batchable() {
+				a.processRequest(req)
+				continue
+			}
+			var batch []*request
+			batch, next = a.collectBatch(req)
+			a.pending.Store(next)
+			req = &request{name: "HandleBatch"}
+			a.processBatch(batcher, batch)
+		}
+	}
+}
+
+func (a *sequent) exit(reason error) {
+	if a.abandoned() {
+		return
+	}
+	a.lifecycle.advance(StateStopping)
+	if terminator, ok := a.val.(Terminator); ok {
+		terminator.OnTerminate(reason)
+	}
+	if a.provider != nil {
+		a.provider.Put(a.val)
 	}
+	a.terminate(reason, StateStopped)
 }
 
 func GetMethods(receiver interface{}) map[string]interface{} {
@@ -229,8 +953,11 @@ func GetMethods(receiver interface{}) map[string]interface{} {
 func convertMethods(methods map[string]interface{}) map[string]reflect.Value {
 	out := make(map[string]reflect.Value)
 	for name, method := range methods {
+		if spec, ok := method.(MethodSpec); ok {
+			method = spec.Func
+		}
 		value := reflect.ValueOf(method)
-		if value.Kind() != reflect.Func {
+		if value.Kind() != reflect.Func || value.IsNil() {
 			continue
 		}
 		out[name] = value
@@ -238,33 +965,93 @@ func convertMethods(methods map[string]interface{}) map[string]reflect.Value {
 	return out
 }
 
-func processMethodArguments(method reflect.Value, args ...interface{}) ([]reflect.Value, error) {
+func processMethodArguments(
+	name string,
+	method reflect.Value,
+	args ...interface{},
+) ([]reflect.Value, error) {
 	method_type := method.Type()
-	if len(args) != method_type.NumIn() {
-		return nil, fmt.Errorf("Not enough arguments need %d, have %d",
-			method_type.NumIn(),
-			len(args))
-	}
 	out := make([]reflect.Value, 0, method_type.NumIn())
+	if takesContext(method_type) {
+		// set when the request is processed
+		out = append(out, reflect.Zero(contextType))
+	}
+	if len(args) != method_type.NumIn()-len(out) {
+		return nil, &ArgCountError{
+			Method: name,
+			Want:   method_type.NumIn() - len(out),
+			Have:   len(args),
+		}
+	}
+	skip := len(out)
 	for i := 0; i < len(args); i++ {
 		arg := reflect.ValueOf(args[i])
-		param := method_type.In(i)
+		param := method_type.In(i + skip)
 		arg_type := reflect.TypeOf(args[i])
-		if arg_type.ConvertibleTo(param) {
-			arg = arg.Convert(param)
-		} else if !arg_type.AssignableTo(param) {
-			return nil, fmt.Errorf(
-				"Argument %d of type %s is not assignable type %s",
-				i,
-				arg_type,
-				param,
-			)
+		if arg_type == nil {
+			// untyped nil is the zero value of the types that
+			// can be nil
+			switch param.Kind() {
+			case reflect.Ptr, reflect.Interface, reflect.Map,
+				reflect.Slice, reflect.Chan, reflect.Func:
+				out = append(out, reflect.Zero(param))
+				continue
+			}
+			return nil, &ArgTypeError{
+				Method: name,
+				Index:  i,
+				Want:   param,
+			}
+		}
+		arg, err := convertArgument(name, i, arg, param)
+		if err != nil {
+			return nil, err
 		}
 		out = append(out, arg)
 	}
 	return out, nil
 }
 
+// shortArray reports whether arg is a slice too short to convert to
+// the array or array pointer type param, which would panic.
+func shortArray(arg reflect.Value, param reflect.Type) bool {
+	if arg.Kind() != reflect.Slice {
+		return false
+	}
+	switch {
+	case param.Kind() == reflect.Array:
+		return arg.Len() < param.Len()
+	case param.Kind() == reflect.Ptr && param.Elem().Kind() == reflect.Array:
+		return arg.Len() < param.Elem().Len()
+	}
+	return false
+}
+
+// callMethod calls method with args checked by processMethodArguments;
+// the arguments of variadic methods end with the slice of the variadic
+// ones.
+func callMethod(method reflect.Value, args []reflect.Value) []reflect.Value {
+	if method.Type().IsVariadic() {
+		return method.CallSlice(args)
+	}
+	return method.Call(args)
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// returnedError returns the error a method returned as its last
+// result, if any.
+func returnedError(values []reflect.Value) error {
+	if len(values) == 0 {
+		return nil
+	}
+	last := values[len(values)-1]
+	if last.Type() != errorType || last.IsNil() {
+		return nil
+	}
+	return last.Interface().(error)
+}
+
 func processMethodReturns(values []reflect.Value) []interface{} {
 	out := make([]interface{}, 0, len(values))
 	for _, val := range values {
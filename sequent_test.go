@@ -1,10 +1,14 @@
 package seriatim
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/leanovate/gopter"
 	"github.com/leanovate/gopter/commands"
@@ -160,7 +164,7 @@ var genCallCommand = gen.Const(&commands.ProtoCommand{
 var genCallMissingParamCommand = gen.Const(&commands.ProtoCommand{
 	Name: "CallMissingParam",
 	RunFunc: func(sut commands.SystemUnderTest) commands.Result {
-		exp := errors.New("Not enough arguments need 1, have 0")
+		exp := &ArgCountError{Method: "Public", Want: 1, Have: 0}
 		_, err := sut.(*sutSequent).Call("Public")
 		return reflect.DeepEqual(exp, err)
 	},
@@ -175,7 +179,11 @@ var genCallMissingParamCommand = gen.Const(&commands.ProtoCommand{
 var genCallWrongParamCommand = gen.Const(&commands.ProtoCommand{
 	Name: "CallWrongParam",
 	RunFunc: func(sut commands.SystemUnderTest) commands.Result {
-		exp := errors.New("Argument 0 of type string is not assignable type bool")
+		exp := &ArgTypeError{
+			Method: "Public",
+			Have:   reflect.TypeOf(""),
+			Want:   reflect.TypeOf(false),
+		}
 		err := sut.(*sutSequent).Cast("Public", "false")
 		return reflect.DeepEqual(exp, err)
 	},
@@ -218,10 +226,13 @@ var genCallUnknownMethodCommand = gen.Const(&commands.ProtoCommand{
 var genCallCrashCommand = gen.Const(&commands.ProtoCommand{
 	Name: "CallCrashMethod",
 	RunFunc: func(sut commands.SystemUnderTest) commands.Result {
-		if _, err := sut.(*sutSequent).Call("Crash"); err != ErrSequentStop {
+		_, err := sut.(*sutSequent).Call("Crash")
+		var crash *CrashError
+		if !errors.As(err, &crash) || crash.Method != "Crash" ||
+			!errors.Is(err, ErrSequentDied) {
 			return false
 		}
-		err := sut.(*sutSequent).WaitTerminate()
+		err = sut.(*sutSequent).WaitTerminate()
 		// Runtime errors are technically a different type, so
 		// just check the error string is what we expect
 		return err.Error() == ErrIndexOutOfRange.Error()
@@ -255,7 +266,7 @@ var genCastCommand = gen.Const(&commands.ProtoCommand{
 var genCastMissingParamCommand = gen.Const(&commands.ProtoCommand{
 	Name: "CastMissingParam",
 	RunFunc: func(sut commands.SystemUnderTest) commands.Result {
-		exp := errors.New("Not enough arguments need 1, have 0")
+		exp := &ArgCountError{Method: "Broadcast", Want: 1, Have: 0}
 		err := sut.(*sutSequent).Cast("Broadcast")
 		return reflect.DeepEqual(exp, err)
 	},
@@ -270,7 +281,11 @@ var genCastMissingParamCommand = gen.Const(&commands.ProtoCommand{
 var genCastWrongParamCommand = gen.Const(&commands.ProtoCommand{
 	Name: "CastWrongParam",
 	RunFunc: func(sut commands.SystemUnderTest) commands.Result {
-		exp := errors.New("Argument 0 of type string is not assignable type bool")
+		exp := &ArgTypeError{
+			Method: "Broadcast",
+			Have:   reflect.TypeOf(""),
+			Want:   reflect.TypeOf(false),
+		}
 		err := sut.(*sutSequent).Cast("Broadcast", "foobar")
 		return reflect.DeepEqual(exp, err)
 	},
@@ -390,3 +405,304 @@ func TestSequentTable(t *testing.T) {
 		t.Error("Incorrectly able to call invalid function")
 	}
 }
+
+// countingMailbox is a Mailbox instrumenting a Queue.
+type countingMailbox struct {
+	*Queue
+	enqueues int32
+}
+
+func (m *countingMailbox) Enqueue() chan<- Message {
+	atomic.AddInt32(&m.enqueues, 1)
+	return m.Queue.Enqueue()
+}
+
+func TestSequentMailbox(t *testing.T) {
+	m := &countingMailbox{Queue: NewQueue(4)}
+	s := NewSequent(&value{}, WithMailbox(m))
+	defer s.Terminate(nil)
+	if err := s.Cast("Broadcast", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Call("Public", true); err != nil {
+		t.Fatal(err)
+	}
+	if n := atomic.LoadInt32(&m.enqueues); n != 2 {
+		t.Fatalf("mailbox used for %d of 2 requests", n)
+	}
+	if s.Stats().QueueCap != 4 {
+		t.Fatal("Stats does not report the mailbox")
+	}
+}
+
+func TestSequentMailboxSize(t *testing.T) {
+	s := NewSequent(&value{}, WithMailboxSize(8))
+	defer s.Terminate(nil)
+	for i := 0; i < 8; i++ {
+		if err := s.Cast("Broadcast", true); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.Stats().QueueCap != 8 {
+		t.Fatal("mailbox size not applied")
+	}
+	d := NewSequent(&value{}, WithMailboxSize(0))
+	defer d.Terminate(nil)
+	if d.Stats().QueueCap != 1 {
+		t.Fatal("invalid mailbox size not ignored")
+	}
+}
+
+type slowCrash struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *slowCrash) Crash() {
+	close(s.started)
+	<-s.release
+	panic("slowCrash")
+}
+
+func (s *slowCrash) Other() {}
+
+func TestSequentCrashFailsQueuedCalls(t *testing.T) {
+	v := &slowCrash{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	s := NewSequent(v, WithMailboxSize(4))
+	errs := make(chan error, 4)
+	go func() {
+		_, err := s.Call("Crash")
+		errs <- err
+	}()
+	<-v.started
+	for i := 0; i < 3; i++ {
+		go func() {
+			_, err := s.Call("Other")
+			errs <- err
+		}()
+	}
+	for s.Stats().QueueLen != 3 {
+		time.Sleep(time.Millisecond)
+	}
+	close(v.release)
+	for i := 0; i < 4; i++ {
+		err := <-errs
+		var crash *CrashError
+		if !errors.As(err, &crash) || crash.Method != "Crash" {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if !errors.Is(err, ErrSequentDied) || !errors.Is(err, ErrSequentStop) {
+			t.Fatalf("%v does not match the sentinel errors", err)
+		}
+	}
+}
+
+var errRejected = errors.New("rejected")
+
+type validator struct{}
+
+func (validator) Check(ok bool) (int, error) {
+	if !ok {
+		return 0, errRejected
+	}
+	return 1, nil
+}
+
+func (validator) Crash() {
+	panic("validator")
+}
+
+func TestSequentCastNotify(t *testing.T) {
+	s := NewSequent(validator{}, WithMailboxSize(4))
+	errs := make(chan error, 4)
+	if err := s.CastNotify("Check", errs, true); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CastNotify("Check", errs, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CastNotify("Crash", errs); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errs; err != errRejected {
+		t.Fatalf("expected the returned error, got %v", err)
+	}
+	var crash *CrashError
+	if err := <-errs; !errors.As(err, &crash) || crash.Method != "Crash" {
+		t.Fatalf("expected the crash, got %v", err)
+	}
+	if err := s.CastNotify("Check", errs, "x"); err == nil {
+		t.Fatal("invalid arguments not reported when casting")
+	}
+}
+
+type sleeper struct{}
+
+func (sleeper) Sleep() {
+	time.Sleep(time.Millisecond)
+}
+
+func TestSequentTerminatePriority(t *testing.T) {
+	s := NewSequent(sleeper{}, WithMailboxSize(64))
+	for i := 0; i < 64; i++ {
+		s.Cast("Sleep")
+	}
+	s.Terminate(nil)
+	<-s.(*sequent).done
+	// the message being processed when Terminate was called and at
+	// most the one taken before the kill could be received
+	if n := s.Stats().Processed; n > 2 {
+		t.Fatalf("%d messages processed before terminating", n)
+	}
+}
+
+func TestSequentTerminateBusy(t *testing.T) {
+	v := &batcher{
+		started: make(chan struct{}),
+		release: make(chan struct{}),
+	}
+	s := NewSequent(v)
+	go s.Call("Wait")
+	<-v.started
+	s.Terminate(errors.New("busy"))
+	s.Terminate(errors.New("again"))
+	if s.Running() {
+		t.Fatal("terminating sequent reported running")
+	}
+	if err := s.Cast("Add", "x"); err != ErrSequentStop {
+		t.Fatalf("expected ErrSequentStop, got %v", err)
+	}
+	select {
+	case <-s.(*sequent).done:
+		t.Fatal("terminated before the current message completed")
+	default:
+	}
+	close(v.release)
+	<-s.(*sequent).done
+}
+
+func TestSequentTerminateTwice(t *testing.T) {
+	s := NewSequent(sleeper{})
+	s.Terminate(nil)
+	<-s.(*sequent).done
+	s.Terminate(errors.New("again"))
+	if s.Running() {
+		t.Fatal("terminated sequent reported running")
+	}
+}
+
+func TestSequentTerminateAfterCrash(t *testing.T) {
+	s := NewSequent(validator{})
+	if _, err := s.Call("Crash"); err == nil {
+		t.Fatal("expected the crash")
+	}
+	<-s.(*sequent).done
+	s.Terminate(nil)
+	if s.Running() {
+		t.Fatal("crashed sequent reported running")
+	}
+}
+
+func TestSequentTerminateWhileSending(t *testing.T) {
+	s := NewSequent(sleeper{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if err := s.Cast("Sleep"); err != nil {
+					return
+				}
+			}
+		}()
+	}
+	time.Sleep(5 * time.Millisecond)
+	for i := 0; i < 4; i++ {
+		go s.Terminate(nil)
+	}
+	wg.Wait()
+	<-s.(*sequent).done
+}
+
+type described struct{ n int }
+
+func (d *described) Sum(base int, more ...int) (int, error) {
+	return base, nil
+}
+
+func (d *described) Reset() {}
+
+func TestSequentMethods(t *testing.T) {
+	s := NewSequent(&described{})
+	defer s.Terminate(nil)
+	methods := s.Methods()
+	if len(methods) != 2 {
+		t.Fatalf("expected 2 methods, got %v", methods)
+	}
+	if got := methods[0].String(); got != "Reset()" {
+		t.Errorf("unexpected signature %q", got)
+	}
+	sum := methods[1]
+	if !sum.Variadic || sum.Args[1] != reflect.TypeOf([]int(nil)) {
+		t.Errorf("unexpected variadic description %+v", sum)
+	}
+	if got := sum.String(); got != "Sum(int, ...int) (int, error)" {
+		t.Errorf("unexpected signature %q", got)
+	}
+}
+
+func TestSequentArgumentErrors(t *testing.T) {
+	s := NewSequent(&value{})
+	defer s.Terminate(nil)
+	_, err := s.Call("Public")
+	if err == nil || err.Error() != "Method Public takes 1 argument, have 0" {
+		t.Fatalf("unexpected %v", err)
+	}
+	_, err = s.Call("Public", true, false)
+	var count *ArgCountError
+	if !errors.As(err, &count) || count.Want != 1 || count.Have != 2 {
+		t.Fatalf("unexpected %v", err)
+	}
+	_, err = s.Call("Public", "x")
+	if err == nil || err.Error() !=
+		"Argument 0 of method Public: type string is not assignable to type bool" {
+		t.Fatalf("unexpected %v", err)
+	}
+	_, err = s.Call("Public", nil)
+	var typ *ArgTypeError
+	if !errors.As(err, &typ) || typ.Have != nil || typ.Index != 0 {
+		t.Fatalf("unexpected %v", err)
+	}
+}
+
+func TestSequentCallContext(t *testing.T) {
+	b := &blocker{release: make(chan struct{})}
+	s := NewSequent(b, WithMailboxSize(1))
+	defer s.Terminate(nil)
+	if err := s.Cast("Block"); err != nil {
+		t.Fatal(err)
+	}
+
+	// waiting for the reply
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := s.CallContext(ctx, "Block"); err != context.DeadlineExceeded {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+
+	// waiting for room in the mailbox, now holding the abandoned Call
+	ctx, cancel = context.WithCancel(context.Background())
+	go cancel()
+	if _, err := s.CallContext(ctx, "Block"); err != context.Canceled {
+		t.Fatalf("expected Canceled, got %v", err)
+	}
+
+	close(b.release)
+	if _, err := s.CallContext(context.Background(), "Block"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,104 @@
+package seriatimtest
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrInjected is the reason of the crashes caused by a Chaos.
+var ErrInjected = errors.New("Injected crash")
+
+// Chaos injects failures into method tables so supervision and restart
+// logic can be exercised. Wrap the table given to
+// seriatim.NewSupervisedSequentTable or dbus.NewObjectFromTable:
+//
+//	chaos := seriatimtest.NewChaos(1)
+//	chaos.PanicRate = 0.1
+//	s := seriatim.NewSupervisedSequentTable(val,
+//		chaos.Methods(seriatim.GetMethods(val)), supervisor)
+//
+// Injected panics terminate the sequent with ErrInjected as the reason.
+type Chaos struct {
+	panics uint64
+	delays uint64
+
+	mu   sync.Mutex
+	rand *rand.Rand
+
+	// PanicRate is the fraction of invocations that panic instead of
+	// running the method.
+	PanicRate float64
+	// DelayRate is the fraction of invocations delayed by up to
+	// MaxDelay before running.
+	DelayRate float64
+	MaxDelay  time.Duration
+}
+
+// NewChaos returns a Chaos injecting nothing until its rates are set.
+// The same seed injects the same failures into the same sequence of
+// invocations.
+func NewChaos(seed int64) *Chaos {
+	return &Chaos{
+		rand:     rand.New(rand.NewSource(seed)),
+		MaxDelay: 10 * time.Millisecond,
+	}
+}
+
+// Panics reports the number of panics injected.
+func (c *Chaos) Panics() uint64 {
+	return atomic.LoadUint64(&c.panics)
+}
+
+// Delays reports the number of invocations delayed.
+func (c *Chaos) Delays() uint64 {
+	return atomic.LoadUint64(&c.delays)
+}
+
+// Methods returns a copy of methods whose functions are subject to
+// the injected failures. Entries that are not functions are kept as
+// they are.
+func (c *Chaos) Methods(methods map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(methods))
+	for name, method := range methods {
+		value := reflect.ValueOf(method)
+		if value.Kind() != reflect.Func {
+			out[name] = method
+			continue
+		}
+		out[name] = c.wrap(value).Interface()
+	}
+	return out
+}
+
+func (c *Chaos) wrap(method reflect.Value) reflect.Value {
+	return reflect.MakeFunc(method.Type(), func(args []reflect.Value) []reflect.Value {
+		crash, delay := c.roll()
+		if delay > 0 {
+			atomic.AddUint64(&c.delays, 1)
+			time.Sleep(delay)
+		}
+		if crash {
+			atomic.AddUint64(&c.panics, 1)
+			panic(ErrInjected)
+		}
+		if method.Type().IsVariadic() {
+			return method.CallSlice(args)
+		}
+		return method.Call(args)
+	})
+}
+
+func (c *Chaos) roll() (bool, time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	crash := c.rand.Float64() < c.PanicRate
+	var delay time.Duration
+	if c.rand.Float64() < c.DelayRate && c.MaxDelay > 0 {
+		delay = time.Duration(c.rand.Int63n(int64(c.MaxDelay))) + 1
+	}
+	return crash, delay
+}
@@ -0,0 +1,75 @@
+package seriatimtest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jsouthworth/seriatim"
+)
+
+type adder struct{}
+
+func (adder) Add(a, b int) int {
+	return a + b
+}
+
+type supervisor chan error
+
+func (s supervisor) SequentTerminated(reason error, id uintptr) {
+	s <- reason
+}
+
+func TestChaosPanics(t *testing.T) {
+	chaos := NewChaos(1)
+	chaos.PanicRate = 1
+	terminated := make(supervisor, 1)
+	seq := seriatim.NewSupervisedSequentTable(adder{},
+		chaos.Methods(seriatim.GetMethods(adder{})), terminated)
+
+	if _, err := seq.Call("Add", 1, 2); !errors.Is(err, ErrInjected) {
+		t.Fatalf("expected the call to fail, got %v", err)
+	}
+	if reason := <-terminated; reason != ErrInjected {
+		t.Fatalf("unexpected reason %v", reason)
+	}
+	if chaos.Panics() != 1 {
+		t.Fatalf("expected 1 panic, got %d", chaos.Panics())
+	}
+}
+
+func TestChaosDelays(t *testing.T) {
+	chaos := NewChaos(1)
+	chaos.DelayRate = 1
+	chaos.MaxDelay = time.Millisecond
+	seq := seriatim.NewSequentTable(adder{},
+		chaos.Methods(seriatim.GetMethods(adder{})))
+	defer seq.Terminate(nil)
+
+	ret, err := seq.Call("Add", 1, 2)
+	if err != nil || ret[0] != 3 {
+		t.Fatalf("unexpected result %v %v", ret, err)
+	}
+	if chaos.Delays() != 1 || chaos.Panics() != 0 {
+		t.Fatalf("expected 1 delay, got %d", chaos.Delays())
+	}
+}
+
+func TestChaosSeed(t *testing.T) {
+	sequence := func() []bool {
+		chaos := NewChaos(42)
+		chaos.PanicRate = 0.5
+		var out []bool
+		for i := 0; i < 32; i++ {
+			crash, _ := chaos.roll()
+			out = append(out, crash)
+		}
+		return out
+	}
+	a, b := sequence(), sequence()
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatal("the same seed injected different failures")
+		}
+	}
+}
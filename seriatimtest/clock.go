@@ -0,0 +1,113 @@
+package seriatimtest
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is a fake clock. Its time only moves when Advance is called,
+// which runs the timers that become due in the order of their
+// deadlines.
+type Clock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*Timer
+	next   uint64
+}
+
+// NewClock returns a clock set to now.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+type Timer struct {
+	clock *Clock
+	when  time.Time
+	seq   uint64
+	fn    func()
+	C     <-chan time.Time
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *Clock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+// AfterFunc calls f on the goroutine advancing the clock once d has
+// passed.
+func (c *Clock) AfterFunc(d time.Duration, f func()) *Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.next++
+	t := &Timer{clock: c, when: c.now.Add(d), seq: c.next, fn: f}
+	c.timers = append(c.timers, t)
+	return t
+}
+
+// NewTimer returns a timer sending the time on its channel once d has
+// passed. The channel is buffered so Advance never blocks on it.
+func (c *Clock) NewTimer(d time.Duration) *Timer {
+	ch := make(chan time.Time, 1)
+	var t *Timer
+	t = c.AfterFunc(d, func() {
+		ch <- t.when
+	})
+	t.C = ch
+	return t
+}
+
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C
+}
+
+// Stop prevents the timer from firing and reports whether it had yet
+// to.
+func (t *Timer) Stop() bool {
+	c := t.clock
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, timer := range c.timers {
+		if timer == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Advance moves the clock forward by d, firing every timer due by the
+// new time. Timers set by the ones firing run too if they fall within
+// d.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	end := c.now.Add(d)
+	c.mu.Unlock()
+	for {
+		c.mu.Lock()
+		sort.Slice(c.timers, func(i, j int) bool {
+			a, b := c.timers[i], c.timers[j]
+			if a.when.Equal(b.when) {
+				return a.seq < b.seq
+			}
+			return a.when.Before(b.when)
+		})
+		if len(c.timers) == 0 || c.timers[0].when.After(end) {
+			c.now = end
+			c.mu.Unlock()
+			return
+		}
+		t := c.timers[0]
+		c.timers = c.timers[1:]
+		if t.when.After(c.now) {
+			c.now = t.when
+		}
+		c.mu.Unlock()
+		t.fn()
+	}
+}
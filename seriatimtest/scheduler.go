@@ -0,0 +1,216 @@
+// Package seriatimtest provides helpers for testing code built on
+// seriatim deterministically, without sleeping until messages have
+// probably been processed.
+//
+// A Scheduler creates sequents whose mailboxes it holds itself. Casts
+// and Calls made to them wait until the test delivers them with Step
+// or RunUntilIdle, in the order they were sent across all of the
+// scheduler's sequents. A Clock stands in for the time package in
+// values that take one, firing timers only when it is advanced.
+package seriatimtest
+
+import (
+	"context"
+	"sync"
+
+	"github.com/jsouthworth/seriatim"
+)
+
+type message struct {
+	sequent *sequent
+	name    string
+	args    []interface{}
+	reply   chan reply
+	errs    chan<- error
+}
+
+type reply struct {
+	returns []interface{}
+	err     error
+}
+
+type Scheduler struct {
+	mu      sync.Mutex
+	pending []*message
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{}
+}
+
+// NewSequent returns a sequent for val whose messages are delivered by
+// the scheduler. Calls to it block until they are delivered, so they
+// have to be made from a goroutine other than the one stepping.
+func (s *Scheduler) NewSequent(val interface{}) seriatim.Sequent {
+	return s.NewSupervisedSequentTable(val, seriatim.GetMethods(val), nil)
+}
+
+func (s *Scheduler) NewSequentTable(
+	val interface{},
+	methods map[string]interface{},
+) seriatim.Sequent {
+	return s.NewSupervisedSequentTable(val, methods, nil)
+}
+
+func (s *Scheduler) NewSupervisedSequent(
+	val interface{},
+	supervisor seriatim.Supervisor,
+) seriatim.Sequent {
+	return s.NewSupervisedSequentTable(val, seriatim.GetMethods(val), supervisor)
+}
+
+func (s *Scheduler) NewSupervisedSequentTable(
+	val interface{},
+	methods map[string]interface{},
+	supervisor seriatim.Supervisor,
+) seriatim.Sequent {
+	inner := seriatim.NewSupervisedSequentTable(val, methods, supervisor)
+	if inner == nil {
+		return nil
+	}
+	return &sequent{
+		Sequent:   inner,
+		scheduler: s,
+		methods:   methods,
+	}
+}
+
+// Pending reports the number of messages sent and not yet delivered.
+func (s *Scheduler) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.pending)
+}
+
+// Step delivers the oldest pending message and waits for it to be
+// processed. It reports false if there was none.
+func (s *Scheduler) Step() bool {
+	s.mu.Lock()
+	if len(s.pending) == 0 {
+		s.mu.Unlock()
+		return false
+	}
+	msg := s.pending[0]
+	s.pending = s.pending[1:]
+	s.mu.Unlock()
+
+	returns, err := msg.sequent.Sequent.Call(msg.name, msg.args...)
+	if msg.reply != nil {
+		msg.reply <- reply{returns: returns, err: err}
+	}
+	if msg.errs != nil {
+		if err == nil && len(returns) != 0 {
+			err, _ = returns[len(returns)-1].(error)
+		}
+		if err != nil {
+			msg.errs <- err
+		}
+	}
+	return true
+}
+
+// RunUntilIdle steps until no messages are pending, including those
+// sent while processing, and returns the number delivered.
+func (s *Scheduler) RunUntilIdle() int {
+	var n int
+	for s.Step() {
+		n++
+	}
+	return n
+}
+
+func (s *Scheduler) send(msg *message) {
+	s.mu.Lock()
+	s.pending = append(s.pending, msg)
+	s.mu.Unlock()
+}
+
+func (s *Scheduler) pendingFor(seq *sequent) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var n int
+	for _, msg := range s.pending {
+		if msg.sequent == seq {
+			n++
+		}
+	}
+	return n
+}
+
+// sequent wraps a real sequent, which processes the messages the
+// scheduler delivers, so crashes, termination and supervision behave
+// as they do outside of tests.
+type sequent struct {
+	seriatim.Sequent
+	scheduler *Scheduler
+	methods   map[string]interface{}
+}
+
+// validate checks a message the way the wrapped sequent would, so
+// invalid ones fail when sent instead of when delivered.
+func (seq *sequent) validate(name string, args []interface{}) error {
+	err := seriatim.CheckArguments(name, seq.methods[name], args...)
+	if err != nil {
+		return err
+	}
+	if seq.State() >= seriatim.StateStopping {
+		return seriatim.ErrSequentStop
+	}
+	return nil
+}
+
+func (seq *sequent) Call(name string, args ...interface{}) ([]interface{}, error) {
+	return seq.CallContext(context.Background(), name, args...)
+}
+
+// CallContext gives up waiting once ctx is done, leaving the message
+// for the scheduler to deliver.
+func (seq *sequent) CallContext(
+	ctx context.Context,
+	name string,
+	args ...interface{},
+) ([]interface{}, error) {
+	if err := seq.validate(name, args); err != nil {
+		return nil, err
+	}
+	replych := make(chan reply, 1)
+	seq.scheduler.send(&message{
+		sequent: seq,
+		name:    name,
+		args:    args,
+		reply:   replych,
+	})
+	select {
+	case r := <-replych:
+		return r.returns, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (seq *sequent) Cast(name string, args ...interface{}) error {
+	return seq.CastNotify(name, nil, args...)
+}
+
+func (seq *sequent) CastNotify(
+	name string,
+	errs chan<- error,
+	args ...interface{},
+) error {
+	if err := seq.validate(name, args); err != nil {
+		return err
+	}
+	seq.scheduler.send(&message{
+		sequent: seq,
+		name:    name,
+		args:    args,
+		errs:    errs,
+	})
+	return nil
+}
+
+func (seq *sequent) Stats() seriatim.Stats {
+	stats := seq.Sequent.Stats()
+	stats.QueueLen = seq.scheduler.pendingFor(seq)
+	return stats
+}
@@ -0,0 +1,178 @@
+package seriatimtest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jsouthworth/seriatim"
+)
+
+type recorder struct {
+	name string
+	log  *[]string
+}
+
+func (r *recorder) Record(what string) {
+	*r.log = append(*r.log, r.name+":"+what)
+}
+
+func (r *recorder) Len() int {
+	return len(*r.log)
+}
+
+func (r *recorder) Crash() {
+	var a []int
+	a[2] = 2
+}
+
+func TestSchedulerOrder(t *testing.T) {
+	var log []string
+	s := NewScheduler()
+	a := s.NewSequent(&recorder{name: "a", log: &log})
+	b := s.NewSequent(&recorder{name: "b", log: &log})
+
+	a.Cast("Record", "1")
+	b.Cast("Record", "2")
+	a.Cast("Record", "3")
+	if s.Pending() != 3 || len(log) != 0 {
+		t.Fatalf("casts delivered before stepping: %v", log)
+	}
+	if a.Stats().QueueLen != 2 {
+		t.Fatalf("expected 2 messages queued for a, got %d", a.Stats().QueueLen)
+	}
+	if !s.Step() || len(log) != 1 || log[0] != "a:1" {
+		t.Fatalf("unexpected log after one step %v", log)
+	}
+	if n := s.RunUntilIdle(); n != 2 || s.Step() {
+		t.Fatalf("expected 2 more messages, got %d", n)
+	}
+	if log[1] != "b:2" || log[2] != "a:3" {
+		t.Fatalf("messages delivered out of order %v", log)
+	}
+	a.Terminate(nil)
+	b.Terminate(nil)
+}
+
+func TestSchedulerCall(t *testing.T) {
+	var log []string
+	s := NewScheduler()
+	seq := s.NewSequent(&recorder{log: &log})
+	seq.Cast("Record", "x")
+
+	done := make(chan []interface{})
+	go func() {
+		ret, _ := seq.Call("Len")
+		done <- ret
+	}()
+	for s.Pending() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	s.RunUntilIdle()
+	if ret := <-done; ret[0] != 1 {
+		t.Fatalf("unexpected result %v", ret)
+	}
+	seq.Terminate(nil)
+}
+
+func TestSchedulerValidation(t *testing.T) {
+	s := NewScheduler()
+	seq := s.NewSequent(&recorder{log: new([]string)})
+	if err := seq.Cast("Missing"); err != seriatim.ErrUnknownMethod {
+		t.Fatalf("expected unknown method, got %v", err)
+	}
+	if err := seq.Cast("Record", struct{}{}); err == nil {
+		t.Fatal("expected an argument error")
+	}
+	if err := seq.Cast("Record"); err == nil {
+		t.Fatal("expected an argument count error")
+	}
+	if s.Pending() != 0 {
+		t.Fatal("invalid messages were queued")
+	}
+
+	seq.Cast("Crash")
+	seq.Cast("Record", "lost")
+	s.RunUntilIdle()
+	if seq.Running() {
+		t.Fatal("sequent survived a crash")
+	}
+	if err := seq.Cast("Record", "late"); err != seriatim.ErrSequentStop {
+		t.Fatalf("expected stopped, got %v", err)
+	}
+}
+
+func TestClock(t *testing.T) {
+	start := time.Unix(0, 0)
+	c := NewClock(start)
+	var fired []int
+	c.AfterFunc(2*time.Second, func() { fired = append(fired, 2) })
+	c.AfterFunc(time.Second, func() {
+		fired = append(fired, 1)
+		c.AfterFunc(time.Second, func() { fired = append(fired, 3) })
+	})
+	stopped := c.AfterFunc(time.Second, func() { fired = append(fired, 0) })
+	timer := c.NewTimer(5 * time.Second)
+	if !stopped.Stop() || stopped.Stop() {
+		t.Fatal("Stop should report only the first stop")
+	}
+
+	c.Advance(2 * time.Second)
+	if len(fired) != 3 || fired[0] != 1 || fired[1] != 2 || fired[2] != 3 {
+		t.Fatalf("timers fired out of order %v", fired)
+	}
+	if c.Since(start) != 2*time.Second {
+		t.Fatalf("unexpected time %v", c.Now())
+	}
+	select {
+	case <-timer.C:
+		t.Fatal("timer fired early")
+	default:
+	}
+	c.Advance(3 * time.Second)
+	if when := <-timer.C; !when.Equal(start.Add(5 * time.Second)) {
+		t.Fatalf("unexpected timer time %v", when)
+	}
+}
+
+func TestClockDrivesSequent(t *testing.T) {
+	var log []string
+	s := NewScheduler()
+	c := NewClock(time.Unix(0, 0))
+	seq := s.NewSequent(&recorder{log: &log})
+	c.AfterFunc(time.Minute, func() { seq.Cast("Record", "tick") })
+
+	c.Advance(time.Second)
+	if s.RunUntilIdle() != 0 {
+		t.Fatal("timer fired early")
+	}
+	c.Advance(time.Minute)
+	if s.RunUntilIdle() != 1 || log[0] != ":tick" {
+		t.Fatalf("unexpected log %v", log)
+	}
+	seq.Terminate(nil)
+}
+
+func (r *recorder) Fail(what string) error {
+	return errors.New(what)
+}
+
+func TestSchedulerCastNotify(t *testing.T) {
+	var log []string
+	s := NewScheduler()
+	seq := s.NewSequent(&recorder{log: &log})
+	defer seq.Terminate(nil)
+	errs := make(chan error, 2)
+	seq.CastNotify("Record", errs, "x")
+	seq.CastNotify("Fail", errs, "y")
+	if len(errs) != 0 {
+		t.Fatal("error reported before delivery")
+	}
+	s.RunUntilIdle()
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %d", len(errs))
+	}
+	if err := <-errs; err.Error() != "y" {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
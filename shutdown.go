@@ -0,0 +1,85 @@
+package seriatim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrShutdown is the reason sequents stopped by ShutdownAll terminate
+// with.
+var ErrShutdown = errors.New("Sequent shut down")
+
+// ShutdownError reports the sequents ShutdownAll could not stop before
+// its context was done.
+type ShutdownError struct {
+	Failed []Sequent
+	Err    error
+}
+
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("%d sequents failed to stop: %s", len(e.Failed), e.Err)
+}
+
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
+}
+
+// ShutdownAll terminates the running sequents with ErrShutdown, newest
+// first, so that a sequent is stopped before the ones that existed
+// when it was created and that it may depend on. Each sequent first
+// processes the requests already in its mailbox. Once ctx is done the
+// sequents still running are left alone and returned in a
+// *ShutdownError.
+func ShutdownAll(ctx context.Context) error {
+	seqs := Sequents()
+	var failed []Sequent
+	for i := len(seqs) - 1; i >= 0; i-- {
+		a := seqs[i].(*sequent)
+		if !a.shutdown(ctx) {
+			failed = append(failed, a)
+		}
+	}
+	if len(failed) != 0 {
+		return &ShutdownError{Failed: failed, Err: ctx.Err()}
+	}
+	return nil
+}
+
+// shutdown drains and terminates the sequent, reporting whether it
+// stopped before ctx was done.
+func (a *sequent) shutdown(ctx context.Context) bool {
+	a.flush(ctx)
+	if ctx.Err() == nil {
+		a.Terminate(ErrShutdown)
+	}
+	select {
+	case <-a.done:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// flush waits until the requests queued before it have been processed.
+func (a *sequent) flush(ctx context.Context) {
+	select {
+	case <-a.done:
+		return
+	default:
+	}
+	replych := make(chan reply, 1)
+	req := &request{
+		method: reflect.ValueOf(func() {}),
+		reply:  replych,
+	}
+	if a.enqueue(req, ctx.Done()) != nil {
+		return
+	}
+	select {
+	case <-replych:
+	case <-a.done:
+	case <-ctx.Done():
+	}
+}
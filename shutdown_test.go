@@ -0,0 +1,78 @@
+package seriatim
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type stopOrder struct {
+	mu  sync.Mutex
+	ids []uintptr
+}
+
+func (s *stopOrder) SequentTerminated(err error, id uintptr) {
+	if err != ErrShutdown {
+		panic(err)
+	}
+	s.mu.Lock()
+	s.ids = append(s.ids, id)
+	s.mu.Unlock()
+}
+
+type blocker struct {
+	release chan struct{}
+}
+
+func (b *blocker) Block() {
+	<-b.release
+}
+
+func TestShutdownAll(t *testing.T) {
+	var order stopOrder
+	first := NewSupervisedSequent(&counter{}, &order)
+	c := &counter{}
+	second := NewSupervisedSequent(c, &order)
+	for i := 0; i < 10; i++ {
+		second.Cast("Increment")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := ShutdownAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if c.count != 10 {
+		t.Fatalf("mailbox not drained, processed %d of 10", c.count)
+	}
+	if len(order.ids) != 2 ||
+		order.ids[0] != second.Id() || order.ids[1] != first.Id() {
+		t.Fatal("sequents not stopped newest first")
+	}
+	if len(Sequents()) != 0 {
+		t.Fatal("sequents still registered")
+	}
+}
+
+func TestShutdownAllDeadline(t *testing.T) {
+	b := &blocker{release: make(chan struct{})}
+	stuck := NewSequent(b)
+	stuck.Cast("Block")
+
+	ctx, cancel := context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	err := ShutdownAll(ctx)
+	var serr *ShutdownError
+	if !errors.As(err, &serr) || len(serr.Failed) != 1 ||
+		serr.Failed[0] != stuck {
+		t.Fatalf("stuck sequent not reported: %v", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	close(b.release)
+	stuck.Terminate(nil)
+}
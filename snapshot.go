@@ -0,0 +1,77 @@
+package seriatim
+
+import (
+	"errors"
+	"reflect"
+)
+
+// ErrNoSnapshot is returned by Snapshot for sequents whose value has
+// no Snapshot method, or that are not made by this package.
+var ErrNoSnapshot = errors.New("Sequent does not publish snapshots")
+
+// snapshotted holds the latest snapshot of a sequent.
+type snapshotted struct {
+	val interface{}
+}
+
+// snapshotMethod returns the Snapshot method of methods, if it takes
+// no arguments and returns one result.
+func snapshotMethod(methods map[string]reflect.Value) reflect.Value {
+	method, ok := methods["Snapshot"]
+	if !ok {
+		return reflect.Value{}
+	}
+	if t := method.Type(); t.NumIn() != 0 || t.NumOut() != 1 {
+		return reflect.Value{}
+	}
+	return method
+}
+
+// Snapshot returns the latest snapshot of the value of s without
+// waiting for s, so that readers, such as a dashboard or a query of
+// all the entries of a table, are not held up behind the requests
+// changing the value. A value publishes snapshots by having a method
+//
+//	Snapshot() T
+//
+// returning a copy of its state, which the sequent calls on its
+// goroutine as it begins to run, once it has started and after each
+// request it processes, before answering it; a caller therefore sees
+// the changes made by its own Calls. Until the first snapshot is
+// taken Snapshot returns nil and ErrNoSnapshot. A Snapshot method
+// that panics then crashes the sequent like OnStart would. A request
+// that panics is not followed by a snapshot, so readers keep the one
+// taken before it.
+//
+// The copy is shared by every reader and must not change once
+// returned, so Snapshot should copy what the value goes on to change.
+// It is taken after every request, so it should be cheap, such as
+// copying a small struct or sharing data the value never changes in
+// place. Snapshot can still be called like any other method.
+func Snapshot(s Sequent) (interface{}, error) {
+	a, ok := s.(*sequent)
+	if !ok {
+		return nil, ErrNoSnapshot
+	}
+	snap, ok := a.snapshot.Load().(*snapshotted)
+	if !ok || snap == nil {
+		return nil, ErrNoSnapshot
+	}
+	return snap.val, nil
+}
+
+// publish takes a snapshot of the current value, if it has a Snapshot
+// method, or withdraws the one taken before a Replace by a value
+// without one.
+func (a *sequent) publish() {
+	method := a.current.snapshot
+	if !method.IsValid() {
+		if a.published {
+			a.snapshot.Store((*snapshotted)(nil))
+			a.published = false
+		}
+		return
+	}
+	a.snapshot.Store(&snapshotted{val: method.Call(nil)[0].Interface()})
+	a.published = true
+}
@@ -0,0 +1,133 @@
+package seriatim
+
+import (
+	"testing"
+	"time"
+)
+
+type inventory struct {
+	items map[string]int
+}
+
+func (v *inventory) Add(name string, n int) {
+	v.items[name] += n
+}
+
+func (v *inventory) Snapshot() map[string]int {
+	out := make(map[string]int, len(v.items))
+	for name, n := range v.items {
+		out[name] = n
+	}
+	return out
+}
+
+func (v *inventory) Panic() {
+	v.items["broken"] = 1
+	panic("broken")
+}
+
+func TestSnapshot(t *testing.T) {
+	s := NewSequent(&inventory{items: map[string]int{"bolts": 1}},
+		WithPanicIsolation())
+	defer s.Terminate(nil)
+	snap := firstSnapshot(s)
+	if got := snap.(map[string]int); got["bolts"] != 1 {
+		t.Fatalf("Got %v, want 1 bolt", got)
+	}
+	if _, err := s.Call("Add", "bolts", 2); err != nil {
+		t.Fatal(err)
+	}
+	snap, _ = Snapshot(s)
+	if got := snap.(map[string]int); got["bolts"] != 3 {
+		t.Fatalf("Got %v, want 3 bolts", got)
+	}
+	if _, err := s.Call("Panic"); err == nil {
+		t.Fatal("Expected the panic to be returned")
+	}
+	snap, _ = Snapshot(s)
+	if got := snap.(map[string]int); got["broken"] != 0 {
+		t.Fatalf("Got %v, want the snapshot from before the panic", got)
+	}
+}
+
+func TestSnapshotNotHeldUp(t *testing.T) {
+	g := &gatedInventory{inventory: inventory{items: map[string]int{}}}
+	g.release = make(chan struct{})
+	s := NewSequent(g)
+	defer s.Terminate(nil)
+	defer close(g.release)
+	firstSnapshot(s)
+	if err := s.Cast("Block"); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		_, err := Snapshot(s)
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Snapshot waited for the sequent")
+	}
+}
+
+// firstSnapshot waits for s to take its first snapshot.
+func firstSnapshot(s Sequent) interface{} {
+	for {
+		if snap, err := Snapshot(s); err != ErrNoSnapshot {
+			return snap
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+type gatedInventory struct {
+	inventory
+	release chan struct{}
+}
+
+func (g *gatedInventory) Block() {
+	<-g.release
+}
+
+func TestSnapshotReplace(t *testing.T) {
+	s := NewSequent(&inventory{items: map[string]int{}})
+	defer s.Terminate(nil)
+	if err := s.Replace(&setting{}); err != nil {
+		t.Fatal(err)
+	}
+	// a Call is processed after the Replace
+	if _, err := s.Call("Value"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Snapshot(s); err != ErrNoSnapshot {
+		t.Fatalf("Got %v, want %v", err, ErrNoSnapshot)
+	}
+}
+
+func TestSnapshotNone(t *testing.T) {
+	s := NewSequent(&gated{release: make(chan struct{})})
+	defer s.Terminate(nil)
+	if _, err := Snapshot(s); err != ErrNoSnapshot {
+		t.Fatalf("Got %v, want %v", err, ErrNoSnapshot)
+	}
+}
+
+type brokenSnapshot struct{}
+
+func (brokenSnapshot) Snapshot() int {
+	panic("broken")
+}
+
+func TestSnapshotPanics(t *testing.T) {
+	// the panic crashes the sequent rather than its creator
+	s := NewSequent(brokenSnapshot{})
+	<-s.Done()
+	if s.State() != StateCrashed {
+		t.Fatalf("Got %v, want %v", s.State(), StateCrashed)
+	}
+}
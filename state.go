@@ -0,0 +1,124 @@
+package seriatim
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+)
+
+// State is a step in the lifecycle of a sequent. A sequent goes through
+// the states in order, skipping some, and ends either Stopped or
+// Crashed.
+type State int32
+
+const (
+	// StateStarting sequents accept requests but have not started
+	// processing them yet.
+	StateStarting State = iota
+	StateRunning
+	// StateSuspended sequents accept requests but leave them queued
+	// until resumed; see Suspender. Unlike the other states it is
+	// left for StateRunning again.
+	StateSuspended
+	// StateStopping sequents were asked to terminate, or crashed, and
+	// no longer accept requests.
+	StateStopping
+	StateStopped
+	StateCrashed
+)
+
+func (s State) String() string {
+	switch s {
+	case StateStarting:
+		return "starting"
+	case StateRunning:
+		return "running"
+	case StateSuspended:
+		return "suspended"
+	case StateStopping:
+		return "stopping"
+	case StateStopped:
+		return "stopped"
+	case StateCrashed:
+		return "crashed"
+	}
+	return fmt.Sprintf("State(%d)", int32(s))
+}
+
+// Final reports whether s is a state a sequent does not leave.
+func (s State) Final() bool {
+	return s >= StateStopped
+}
+
+// lifecycle tracks the state of a sequent for its watchers.
+type lifecycle struct {
+	state    int32
+	mu       sync.Mutex
+	watchers []chan State
+}
+
+func (l *lifecycle) load() State {
+	return State(atomic.LoadInt32(&l.state))
+}
+
+// advance moves to s unless the current state is already as far,
+// reporting whether it did.
+func (l *lifecycle) advance(s State) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.load() >= s {
+		return false
+	}
+	atomic.StoreInt32(&l.state, int32(s))
+	for _, ch := range l.watchers {
+		ch <- s
+		if s.Final() {
+			close(ch)
+		}
+	}
+	if s.Final() {
+		l.watchers = nil
+	}
+	return true
+}
+
+// toggle moves to s if the current state is one of from, which may
+// be further than s, reporting whether it did. Watchers whose channel
+// has no room for it beyond the states still to be advanced to miss
+// the change, so that advancing never blocks.
+func (l *lifecycle) toggle(to State, from ...State) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	current := l.load()
+	allowed := false
+	for _, s := range from {
+		allowed = allowed || current == s
+	}
+	if !allowed {
+		return false
+	}
+	atomic.StoreInt32(&l.state, int32(to))
+	for _, ch := range l.watchers {
+		if len(ch) < cap(ch)-int(StateCrashed+1) {
+			ch <- to
+		}
+	}
+	return true
+}
+
+// changes returns a channel receiving the current state and every
+// change after it, closed after the final state. It is buffered for
+// all the states so advancing never blocks, and as many suspensions.
+func (l *lifecycle) changes() <-chan State {
+	ch := make(chan State, 2*(StateCrashed+1))
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	current := l.load()
+	ch <- current
+	if current.Final() {
+		close(ch)
+		return ch
+	}
+	l.watchers = append(l.watchers, ch)
+	return ch
+}
@@ -0,0 +1,61 @@
+package seriatim
+
+import "testing"
+
+func collectStates(ch <-chan State) []State {
+	var out []State
+	for s := range ch {
+		out = append(out, s)
+	}
+	return out
+}
+
+func checkStates(t *testing.T, got []State, final ...State) {
+	t.Helper()
+	if len(got) < len(final) {
+		t.Fatalf("unexpected states %v", got)
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i] <= got[i-1] {
+			t.Fatalf("states out of order %v", got)
+		}
+	}
+	tail := got[len(got)-len(final):]
+	for i := range final {
+		if tail[i] != final[i] {
+			t.Fatalf("expected to end with %v, got %v", final, got)
+		}
+	}
+}
+
+func TestSequentStateStopped(t *testing.T) {
+	s := NewSequent(&tally{})
+	changes := s.StateChanges()
+	if _, err := s.Call("Total"); err != nil {
+		t.Fatal(err)
+	}
+	if s.State() != StateRunning {
+		t.Fatalf("expected running, got %v", s.State())
+	}
+	s.Terminate(nil)
+	if s.Running() {
+		t.Fatal("stopping sequent reported running")
+	}
+	checkStates(t, collectStates(changes), StateStopping, StateStopped)
+	if s.State() != StateStopped {
+		t.Fatalf("expected stopped, got %v", s.State())
+	}
+	// watching a stopped sequent gets the final state only
+	checkStates(t, collectStates(s.StateChanges()), StateStopped)
+}
+
+func TestSequentStateCrashed(t *testing.T) {
+	s := NewSequent(&value{})
+	changes := s.StateChanges()
+	s.Call("Crash")
+	checkStates(t, collectStates(changes), StateStopping, StateCrashed)
+	s.Terminate(nil)
+	if s.State() != StateCrashed {
+		t.Fatalf("expected crashed, got %v", s.State())
+	}
+}
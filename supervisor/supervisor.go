@@ -0,0 +1,756 @@
+// Package supervisor runs a set of sequents that depend on each other
+// and restarts them when they terminate.
+//
+// Each child is described by a ChildSpec naming the children it
+// depends on. Children are started so that a child's dependencies are
+// running before it, and stopped in the reverse order. When a child
+// terminates on its own it is restarted, and so are the children
+// depending on it, directly or not, since they may hold on to the
+// sequent that went away:
+//
+//	s, err := supervisor.New(
+//		supervisor.ChildSpec{Name: "conn", Start: dial},
+//		supervisor.ChildSpec{Name: "session", Start: login,
+//			DependsOn: []string{"conn"}},
+//	)
+//	if err != nil {
+//		return err
+//	}
+//	s.Start()
+//	defer s.Stop(nil)
+//
+// Setting Strategy restarts other children instead, as supervisors of
+// Erlang/OTP do, and a child's Restart type says whether it is
+// restarted at all. Children can be added with StartChild, stopped
+// with TerminateChild and listed with WhichChildren while the
+// supervisor runs.
+//
+// A child restarted more than MaxRestarts times within RestartWindow is
+// quarantined rather than restarted again: Child returns a stand-in
+// failing every request with ErrQuarantined until the child is revived
+// by Revive or once QuarantineFor has passed.
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/jsouthworth/seriatim"
+)
+
+var ErrQuarantined = errors.New("Child quarantined")
+
+// ChildSpec describes a child of a Supervisor.
+type ChildSpec struct {
+	Name string
+	// Start creates the child's sequent supervised by the given
+	// supervisor. It is called on every (re)start of the child,
+	// once its dependencies are running, and may look them up with
+	// Child.
+	Start func(supervisor seriatim.Supervisor) seriatim.Sequent
+	// DependsOn names the children that have to be running before
+	// this one is started, which cannot be temporary.
+	DependsOn []string
+	// Restart says when the child is restarted after it terminates
+	// on its own, Permanent by default.
+	Restart RestartType
+}
+
+// RestartType says when a child is restarted.
+type RestartType int
+
+const (
+	// Permanent children are always restarted.
+	Permanent RestartType = iota
+	// Transient children are restarted if they terminate with an
+	// error, and left stopped if they terminate with a nil reason.
+	Transient
+	// Temporary children are never restarted; they are removed once
+	// they terminate on their own or are stopped along with a child
+	// being restarted.
+	Temporary
+)
+
+func (r RestartType) String() string {
+	switch r {
+	case Permanent:
+		return "Permanent"
+	case Transient:
+		return "Transient"
+	case Temporary:
+		return "Temporary"
+	}
+	return fmt.Sprintf("RestartType(%d)", int(r))
+}
+
+// restarts reports whether a child terminating with reason is
+// restarted.
+func (r RestartType) restarts(reason error) bool {
+	switch r {
+	case Transient:
+		return reason != nil
+	case Temporary:
+		return false
+	}
+	return true
+}
+
+// ChildInfo describes a child of a Supervisor, see WhichChildren.
+type ChildInfo struct {
+	Name    string
+	Restart RestartType
+	// Sequent is the running sequent of the child, nil if it is not
+	// running.
+	Sequent     seriatim.Sequent
+	Quarantined bool
+	// Terminated is set for a child stopped by TerminateChild until
+	// it is restarted by RestartChild.
+	Terminated bool
+}
+
+// Strategy says which children are restarted when one terminates on
+// its own.
+type Strategy int
+
+const (
+	// RestartDependents restarts the child and the children depending
+	// on it, directly or not.
+	RestartDependents Strategy = iota
+	// OneForOne restarts only the child.
+	OneForOne
+	// OneForAll restarts every child.
+	OneForAll
+	// RestForOne restarts the child and the children started after
+	// it.
+	RestForOne
+)
+
+func (s Strategy) String() string {
+	switch s {
+	case RestartDependents:
+		return "RestartDependents"
+	case OneForOne:
+		return "OneForOne"
+	case OneForAll:
+		return "OneForAll"
+	case RestForOne:
+		return "RestForOne"
+	}
+	return fmt.Sprintf("Strategy(%d)", int(s))
+}
+
+type child struct {
+	spec    ChildSpec
+	sequent seriatim.Sequent
+	done    chan struct{}
+	// stopping is set when the supervisor terminates the child so
+	// that its termination does not restart it.
+	stopping bool
+	// restarts holds the times of the restarts within the window.
+	restarts    []time.Time
+	quarantined bool
+	revive      *time.Timer
+	// terminated is set by TerminateChild, keeping the child and its
+	// dependents from being started.
+	terminated bool
+	// starting is set while the Start of the child runs.
+	starting bool
+}
+
+type Supervisor struct {
+	// Strategy is how children are restarted, RestartDependents by
+	// default.
+	Strategy Strategy
+	// MaxRestarts is the number of restarts of a child allowed within
+	// RestartWindow before it is quarantined, zero for no limit.
+	MaxRestarts   int
+	RestartWindow time.Duration
+	// QuarantineFor is how long a child stays quarantined before it
+	// is revived, zero to wait for Revive.
+	QuarantineFor time.Duration
+
+	mu       sync.Mutex
+	children []*child // in start order
+	running  map[uintptr]*child
+	started  bool
+	stopped  bool
+	// starting counts the children whose Start runs, and early holds
+	// the reasons of the sequents that terminated meanwhile, before
+	// they could be known to be running.
+	starting int
+	early    map[uintptr]error
+}
+
+// New returns a supervisor for the given children, which are not
+// started until Start is called. It fails if names are not unique, a
+// child depends on one that does not exist or dependencies form a
+// cycle.
+func New(specs ...ChildSpec) (*Supervisor, error) {
+	ordered, err := startOrder(specs)
+	if err != nil {
+		return nil, err
+	}
+	s := &Supervisor{
+		running: make(map[uintptr]*child),
+	}
+	for _, spec := range ordered {
+		s.children = append(s.children, &child{spec: spec})
+	}
+	return s, nil
+}
+
+// startOrder sorts specs so that every child comes after its
+// dependencies, keeping the given order otherwise.
+func startOrder(specs []ChildSpec) ([]ChildSpec, error) {
+	byName := make(map[string]ChildSpec, len(specs))
+	for _, spec := range specs {
+		if _, ok := byName[spec.Name]; ok {
+			return nil, fmt.Errorf("Duplicate child %q", spec.Name)
+		}
+		byName[spec.Name] = spec
+	}
+	const (
+		visiting = iota + 1
+		visited
+	)
+	state := make(map[string]int, len(specs))
+	out := make([]ChildSpec, 0, len(specs))
+	var visit func(spec ChildSpec) error
+	visit = func(spec ChildSpec) error {
+		switch state[spec.Name] {
+		case visiting:
+			return fmt.Errorf("Dependency cycle through child %q", spec.Name)
+		case visited:
+			return nil
+		}
+		state[spec.Name] = visiting
+		for _, name := range spec.DependsOn {
+			dep, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("Child %q depends on unknown child %q",
+					spec.Name, name)
+			}
+			if dep.Restart == Temporary {
+				return fmt.Errorf("Child %q depends on temporary child %q",
+					spec.Name, name)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[spec.Name] = visited
+		out = append(out, spec)
+		return nil
+	}
+	for _, spec := range specs {
+		if err := visit(spec); err != nil {
+			return nil, err
+		}
+	}
+	return out, nil
+}
+
+// Start starts the children that are not quarantined or terminated,
+// dependencies first.
+func (s *Supervisor) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.started, s.stopped = true, false
+	for _, c := range s.children {
+		if s.stopped {
+			return
+		}
+		if s.startable(c) {
+			s.startChild(c)
+		}
+	}
+}
+
+// Stop terminates the children with reason, dependents first, waiting
+// for each to stop before terminating the next.
+func (s *Supervisor) Stop(reason error) {
+	s.mu.Lock()
+	s.stopped = true
+	children := make([]*child, len(s.children))
+	copy(children, s.children)
+	s.mu.Unlock()
+	s.stopChildren(children, reason)
+}
+
+// Child returns the running sequent of the named child, a stand-in
+// failing with ErrQuarantined if it is quarantined, or nil if it is
+// not running.
+func (s *Supervisor) Child(name string) seriatim.Sequent {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.child(name)
+	switch {
+	case c == nil:
+		return nil
+	case c.quarantined:
+		return &quarantined{name: name}
+	}
+	return c.sequent
+}
+
+// Quarantined reports whether the named child is quarantined.
+func (s *Supervisor) Quarantined(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.child(name)
+	return c != nil && c.quarantined
+}
+
+// Revive restarts a quarantined child together with its dependents,
+// which may hold on to the stand-in, and forgets its past restarts.
+func (s *Supervisor) Revive(name string) error {
+	s.mu.Lock()
+	c := s.child(name)
+	if c == nil || !c.quarantined {
+		s.mu.Unlock()
+		return fmt.Errorf("Child %q is not quarantined", name)
+	}
+	c.quarantined = false
+	c.restarts = nil
+	if c.revive != nil {
+		c.revive.Stop()
+		c.revive = nil
+	}
+	s.mu.Unlock()
+	s.restartFrom(c)
+	return nil
+}
+
+// StartChild adds a child to the supervisor, after the others, and
+// starts it if the supervisor is running, returning its sequent. The
+// child may depend on the children already added. It fails if the
+// name is taken, if a dependency is unknown or temporary, or if the
+// child does not start, in which case it is not added.
+func (s *Supervisor) StartChild(spec ChildSpec) (seriatim.Sequent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.child(spec.Name) != nil {
+		return nil, fmt.Errorf("Duplicate child %q", spec.Name)
+	}
+	for _, name := range spec.DependsOn {
+		dep := s.child(name)
+		switch {
+		case dep == nil:
+			return nil, fmt.Errorf("Child %q depends on unknown child %q",
+				spec.Name, name)
+		case dep.spec.Restart == Temporary:
+			return nil, fmt.Errorf("Child %q depends on temporary child %q",
+				spec.Name, name)
+		}
+	}
+	c := &child{spec: spec}
+	s.children = append(s.children, c)
+	if !s.started || s.stopped || !s.startable(c) {
+		return nil, nil
+	}
+	seq := s.startChild(c)
+	if seq == nil {
+		s.remove(c)
+		return nil, fmt.Errorf("Child %q did not start", spec.Name)
+	}
+	return seq, nil
+}
+
+// TerminateChild terminates the named child with reason, after the
+// children depending on it, directly or not. It is not restarted, nor
+// are they, until RestartChild is called; a temporary child is
+// removed instead, as are temporary dependents.
+func (s *Supervisor) TerminateChild(name string, reason error) error {
+	s.mu.Lock()
+	c := s.child(name)
+	if c == nil {
+		s.mu.Unlock()
+		return fmt.Errorf("Unknown child %q", name)
+	}
+	c.terminated = true
+	affected := s.dependents(c)
+	s.mu.Unlock()
+
+	s.stopChildren(affected, reason)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range affected {
+		if c.spec.Restart == Temporary {
+			s.remove(c)
+		}
+	}
+	return nil
+}
+
+// RestartChild restarts the named child together with its dependents,
+// whether it is running or was terminated by TerminateChild, without
+// counting it against MaxRestarts. If the supervisor is not running
+// they are started by Start. Temporary children cannot be restarted.
+func (s *Supervisor) RestartChild(name string) error {
+	s.mu.Lock()
+	c := s.child(name)
+	switch {
+	case c == nil:
+		s.mu.Unlock()
+		return fmt.Errorf("Unknown child %q", name)
+	case c.spec.Restart == Temporary:
+		s.mu.Unlock()
+		return fmt.Errorf("Child %q is temporary", name)
+	}
+	c.terminated = false
+	running := s.started && !s.stopped
+	affected := s.dependents(c)
+	s.mu.Unlock()
+	if running {
+		s.restartChildren(affected)
+	}
+	return nil
+}
+
+// WhichChildren describes the children of the supervisor, in start
+// order.
+func (s *Supervisor) WhichChildren() []ChildInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]ChildInfo, 0, len(s.children))
+	for _, c := range s.children {
+		out = append(out, ChildInfo{
+			Name:        c.spec.Name,
+			Restart:     c.spec.Restart,
+			Sequent:     c.sequent,
+			Quarantined: c.quarantined,
+			Terminated:  c.terminated,
+		})
+	}
+	return out
+}
+
+func (s *Supervisor) child(name string) *child {
+	for _, c := range s.children {
+		if c.spec.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// SequentTerminated restarts a child that terminated on its own,
+// together with its dependents.
+func (s *Supervisor) SequentTerminated(reason error, id uintptr) {
+	s.mu.Lock()
+	c, ok := s.running[id]
+	if !ok {
+		if s.starting > 0 {
+			// it may be a child whose Start has yet to return
+			if s.early == nil {
+				s.early = make(map[uintptr]error)
+			}
+			s.early[id] = reason
+		}
+		s.mu.Unlock()
+		return
+	}
+	restart := s.terminated(c, reason)
+	s.mu.Unlock()
+	if restart {
+		// The terminated sequent purges its mailbox only after
+		// this returns, releasing dependents blocked calling it.
+		go s.restart(c)
+	}
+}
+
+// terminated forgets the sequent of c, which terminated with reason,
+// reporting whether c is to be restarted.
+func (s *Supervisor) terminated(c *child, reason error) bool {
+	delete(s.running, c.sequent.Id())
+	c.sequent = nil
+	close(c.done)
+	restart := !c.stopping && !s.stopped && c.spec.Restart.restarts(reason)
+	if !c.stopping && c.spec.Restart == Temporary {
+		s.remove(c)
+	}
+	return restart
+}
+
+func (s *Supervisor) restart(failed *child) {
+	s.mu.Lock()
+	s.countRestart(failed)
+	s.mu.Unlock()
+	s.restartFrom(failed)
+}
+
+// countRestart records a restart of c, quarantining it if it exceeds
+// the budget.
+func (s *Supervisor) countRestart(c *child) {
+	if s.MaxRestarts <= 0 {
+		return
+	}
+	now := time.Now()
+	kept := c.restarts[:0]
+	for _, at := range c.restarts {
+		if now.Sub(at) < s.RestartWindow {
+			kept = append(kept, at)
+		}
+	}
+	c.restarts = append(kept, now)
+	if len(c.restarts) <= s.MaxRestarts {
+		return
+	}
+	c.quarantined = true
+	c.restarts = nil
+	if s.QuarantineFor > 0 {
+		name := c.spec.Name
+		c.revive = time.AfterFunc(s.QuarantineFor, func() {
+			s.Revive(name)
+		})
+	}
+}
+
+// restartFrom stops the children restarted with failed by the
+// strategy and starts them again, and failed unless it is quarantined.
+func (s *Supervisor) restartFrom(failed *child) {
+	s.mu.Lock()
+	affected := s.restarted(failed)
+	s.mu.Unlock()
+	// failed is already stopped
+	s.restartChildren(affected)
+}
+
+// restartChildren stops children and starts them again, in start
+// order, but for the temporary ones, which are removed.
+func (s *Supervisor) restartChildren(children []*child) {
+	s.stopChildren(children, seriatim.ErrSequentStop)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, c := range children {
+		if c.spec.Restart == Temporary {
+			s.remove(c)
+		}
+	}
+	for _, c := range children {
+		if s.stopped {
+			return
+		}
+		if c.spec.Restart != Temporary && s.startable(c) {
+			s.startChild(c)
+		}
+	}
+}
+
+// startable reports whether c is to be started: it is not running,
+// quarantined or terminated, nor does it depend on a child that was
+// terminated.
+func (s *Supervisor) startable(c *child) bool {
+	if c.sequent != nil || c.starting || c.quarantined {
+		return false
+	}
+	return !s.blocked(c)
+}
+
+// blocked reports whether c or a child it depends on, directly or not,
+// was terminated by TerminateChild.
+func (s *Supervisor) blocked(c *child) bool {
+	if c.terminated {
+		return true
+	}
+	for _, name := range c.spec.DependsOn {
+		if dep := s.child(name); dep != nil && s.blocked(dep) {
+			return true
+		}
+	}
+	return false
+}
+
+// remove forgets c, once it is stopped.
+func (s *Supervisor) remove(c *child) {
+	for i, other := range s.children {
+		if other == c {
+			s.children = append(s.children[:i:i], s.children[i+1:]...)
+			break
+		}
+	}
+	if c.revive != nil {
+		c.revive.Stop()
+		c.revive = nil
+	}
+}
+
+// restarted returns the children restarted with c, c included, in
+// start order.
+func (s *Supervisor) restarted(c *child) []*child {
+	switch s.Strategy {
+	case OneForOne:
+		return []*child{c}
+	case OneForAll:
+		return append([]*child(nil), s.children...)
+	case RestForOne:
+		for i, other := range s.children {
+			if other == c {
+				return append([]*child(nil), s.children[i:]...)
+			}
+		}
+	}
+	return s.dependents(c)
+}
+
+// dependents returns c followed by the children depending on it, in
+// start order.
+func (s *Supervisor) dependents(c *child) []*child {
+	affected := map[string]bool{c.spec.Name: true}
+	out := []*child{c}
+	for _, other := range s.children {
+		if affected[other.spec.Name] {
+			continue
+		}
+		for _, name := range other.spec.DependsOn {
+			if affected[name] {
+				affected[other.spec.Name] = true
+				out = append(out, other)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// startChild starts c, returning its sequent. It is called with s.mu
+// held, which it releases while Start runs so that Start may look up
+// the children c depends on. A child terminating before Start returns
+// is handled once it is known to be running.
+func (s *Supervisor) startChild(c *child) seriatim.Sequent {
+	c.starting = true
+	s.starting++
+	s.mu.Unlock()
+	seq := c.spec.Start(s)
+	s.mu.Lock()
+	c.starting = false
+	s.starting--
+	reason, early := error(nil), false
+	if seq != nil {
+		reason, early = s.early[seq.Id()]
+		delete(s.early, seq.Id())
+	}
+	if s.starting == 0 {
+		s.early = nil
+	}
+	if seq == nil {
+		return nil
+	}
+	c.sequent = seq
+	c.done = make(chan struct{})
+	c.stopping = false
+	s.running[seq.Id()] = c
+	switch {
+	case early:
+		if s.terminated(c, reason) {
+			go s.restart(c)
+		}
+	case s.stopped:
+		// stopped while Start ran
+		c.stopping = true
+		go seq.Terminate(seriatim.ErrSequentStop)
+	}
+	return seq
+}
+
+// stopChildren terminates children in reverse order, waiting for
+// each to stop.
+func (s *Supervisor) stopChildren(children []*child, reason error) {
+	for i := len(children) - 1; i >= 0; i-- {
+		c := children[i]
+		s.mu.Lock()
+		seq, done := c.sequent, c.done
+		c.stopping = true
+		s.mu.Unlock()
+		if seq == nil {
+			continue
+		}
+		// Terminate may block, as it does for remote sequents
+		// until the other end answers.
+		go seq.Terminate(reason)
+		<-done
+	}
+}
+
+// quarantined stands in for a quarantined child.
+type quarantined struct {
+	name string
+}
+
+func (q *quarantined) Id() uintptr {
+	return reflect.ValueOf(q).Pointer()
+}
+
+func (q *quarantined) Call(name string, args ...interface{}) ([]interface{}, error) {
+	return nil, ErrQuarantined
+}
+
+func (q *quarantined) CallContext(
+	ctx context.Context,
+	name string,
+	args ...interface{},
+) ([]interface{}, error) {
+	return nil, ErrQuarantined
+}
+
+func (q *quarantined) Cast(name string, args ...interface{}) error {
+	return ErrQuarantined
+}
+
+func (q *quarantined) CastNotify(
+	name string,
+	errs chan<- error,
+	args ...interface{},
+) error {
+	return ErrQuarantined
+}
+
+func (q *quarantined) Running() bool {
+	return false
+}
+
+func (q *quarantined) Terminate(error) {
+}
+
+func (q *quarantined) State() seriatim.State {
+	return seriatim.StateCrashed
+}
+
+func (q *quarantined) StateChanges() <-chan seriatim.State {
+	ch := make(chan seriatim.State, 1)
+	ch <- seriatim.StateCrashed
+	close(ch)
+	return ch
+}
+
+// Done returns a closed channel as the stand-in is never running.
+func (q *quarantined) Done() <-chan struct{} {
+	done := make(chan struct{})
+	close(done)
+	return done
+}
+
+func (q *quarantined) Err() error {
+	return ErrQuarantined
+}
+
+func (q *quarantined) Replace(val interface{}) error {
+	return ErrQuarantined
+}
+
+func (q *quarantined) Stats() seriatim.Stats {
+	return seriatim.Stats{
+		Id:   q.Id(),
+		Type: "quarantined " + q.name,
+	}
+}
+
+func (q *quarantined) Methods() []seriatim.MethodInfo {
+	return nil
+}
@@ -0,0 +1,400 @@
+package supervisor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jsouthworth/seriatim"
+)
+
+type node struct {
+	name string
+}
+
+func (n *node) Crash() {
+	panic("crash")
+}
+
+type events struct {
+	mu  sync.Mutex
+	log []string
+}
+
+func (e *events) add(event string) {
+	e.mu.Lock()
+	e.log = append(e.log, event)
+	e.mu.Unlock()
+}
+
+func (e *events) reset() {
+	e.mu.Lock()
+	e.log = nil
+	e.mu.Unlock()
+}
+
+func (e *events) String() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return strings.Join(e.log, " ")
+}
+
+type observer struct {
+	name   string
+	events *events
+	next   seriatim.Supervisor
+}
+
+func (o *observer) SequentTerminated(reason error, id uintptr) {
+	o.events.add("stop:" + o.name)
+	o.next.SequentTerminated(reason, id)
+}
+
+func (e *events) spec(name string, deps ...string) ChildSpec {
+	return ChildSpec{
+		Name: name,
+		Start: func(sup seriatim.Supervisor) seriatim.Sequent {
+			e.add("start:" + name)
+			return seriatim.NewSupervisedSequent(&node{name: name},
+				&observer{name: name, events: e, next: sup})
+		},
+		DependsOn: deps,
+	}
+}
+
+func waitFor(t *testing.T, e *events, want string) {
+	deadline := time.Now().Add(time.Second)
+	for e.String() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %q, want %q", e.String(), want)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestDependencyOrder(t *testing.T) {
+	e := &events{}
+	s, err := New(
+		e.spec("device", "session"),
+		e.spec("session", "conn"),
+		e.spec("conn"),
+		e.spec("other"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	waitFor(t, e, "start:conn start:session start:device start:other")
+
+	e.reset()
+	s.Child("session").Cast("Crash")
+	waitFor(t, e, "stop:session stop:device start:session start:device")
+
+	e.reset()
+	s.Stop(nil)
+	waitFor(t, e, "stop:other stop:device stop:session stop:conn")
+	if s.Child("conn") != nil {
+		t.Fatal("child running after Stop")
+	}
+}
+
+func TestInvalidSpecs(t *testing.T) {
+	e := &events{}
+	for _, specs := range [][]ChildSpec{
+		{e.spec("a"), e.spec("a")},
+		{e.spec("a", "b")},
+		{e.spec("a", "b"), e.spec("b", "c"), e.spec("c", "a")},
+	} {
+		if _, err := New(specs...); err == nil {
+			t.Errorf("specs %v accepted", specs)
+		}
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	e := &events{}
+	s, err := New(e.spec("conn"), e.spec("session", "conn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.MaxRestarts = 1
+	s.RestartWindow = time.Minute
+	s.Start()
+	defer s.Stop(nil)
+	waitFor(t, e, "start:conn start:session")
+
+	e.reset()
+	s.Child("conn").Cast("Crash")
+	waitFor(t, e, "stop:conn stop:session start:conn start:session")
+
+	e.reset()
+	s.Child("conn").Cast("Crash")
+	waitFor(t, e, "stop:conn stop:session start:session")
+	if !s.Quarantined("conn") {
+		t.Fatal("child not quarantined")
+	}
+	if _, err := s.Child("conn").Call("Crash"); err != ErrQuarantined {
+		t.Fatalf("expected ErrQuarantined, got %v", err)
+	}
+
+	e.reset()
+	if err := s.Revive("conn"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, e, "stop:session start:conn start:session")
+	if err := s.Revive("conn"); err == nil {
+		t.Fatal("revived a running child")
+	}
+}
+
+func TestQuarantineTimer(t *testing.T) {
+	e := &events{}
+	s, err := New(e.spec("conn"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.MaxRestarts = 1
+	s.RestartWindow = time.Minute
+	s.QuarantineFor = 10 * time.Millisecond
+	s.Start()
+	defer s.Stop(nil)
+	waitFor(t, e, "start:conn")
+
+	e.reset()
+	s.Child("conn").Cast("Crash")
+	waitFor(t, e, "stop:conn start:conn")
+	e.reset()
+	s.Child("conn").Cast("Crash")
+	// revived by the timer
+	waitFor(t, e, "stop:conn start:conn")
+	if s.Quarantined("conn") {
+		t.Fatal("child still quarantined")
+	}
+}
+
+func TestGoroutineChild(t *testing.T) {
+	e := &events{}
+	s, err := New(ChildSpec{
+		Name: "poller",
+		Start: func(sup seriatim.Supervisor) seriatim.Sequent {
+			e.add("start:poller")
+			return seriatim.Go(sup, func(ctx context.Context) error {
+				<-ctx.Done()
+				return nil
+			})
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	waitFor(t, e, "start:poller")
+
+	e.reset()
+	s.Child("poller").Terminate(errors.New("poll failed"))
+	waitFor(t, e, "start:poller")
+	s.Stop(nil)
+	if s.Child("poller") != nil {
+		t.Fatal("poller still running once stopped")
+	}
+}
+
+func TestStrategies(t *testing.T) {
+	for strategy, want := range map[Strategy]string{
+		RestartDependents: "stop:b stop:d start:b start:d",
+		OneForOne:         "stop:b start:b",
+		OneForAll:         "stop:b stop:d stop:c stop:a start:a start:b start:c start:d",
+		RestForOne:        "stop:b stop:d stop:c start:b start:c start:d",
+	} {
+		e := &events{}
+		s, err := New(e.spec("a"), e.spec("b"), e.spec("c"), e.spec("d", "b"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		s.Strategy = strategy
+		s.Start()
+		waitFor(t, e, "start:a start:b start:c start:d")
+
+		e.reset()
+		s.Child("b").Cast("Crash")
+		waitFor(t, e, want)
+		s.Stop(nil)
+	}
+}
+
+func TestDynamicChildren(t *testing.T) {
+	e := &events{}
+	s, err := New(e.spec("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// added before Start, started by it
+	if seq, err := s.StartChild(e.spec("b", "a")); seq != nil || err != nil {
+		t.Fatalf("got %v, %v before Start", seq, err)
+	}
+	s.Start()
+	defer s.Stop(nil)
+	waitFor(t, e, "start:a start:b")
+
+	e.reset()
+	seq, err := s.StartChild(e.spec("c", "b"))
+	if err != nil || seq == nil || s.Child("c") != seq {
+		t.Fatalf("got %v, %v, want child c running", seq, err)
+	}
+	waitFor(t, e, "start:c")
+	for _, spec := range []ChildSpec{
+		e.spec("c"),
+		e.spec("d", "x"),
+		{Name: "d", Start: func(seriatim.Supervisor) seriatim.Sequent {
+			return nil
+		}},
+	} {
+		if _, err := s.StartChild(spec); err == nil {
+			t.Errorf("child %q accepted", spec.Name)
+		}
+	}
+
+	e.reset()
+	if err := s.TerminateChild("b", nil); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, e, "stop:c stop:b")
+	var got []string
+	for _, info := range s.WhichChildren() {
+		got = append(got, fmt.Sprintf("%s:%t:%t",
+			info.Name, info.Sequent != nil, info.Terminated))
+	}
+	if want := "a:true:false b:false:true c:false:false"; strings.Join(got, " ") != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+
+	e.reset()
+	if err := s.RestartChild("b"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, e, "start:b start:c")
+	e.reset()
+	if err := s.RestartChild("b"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, e, "stop:c stop:b start:b start:c")
+	if err := s.TerminateChild("x", nil); err == nil {
+		t.Fatal("terminated an unknown child")
+	}
+}
+
+func TestRestartTypes(t *testing.T) {
+	e := &events{}
+	transient := e.spec("transient")
+	transient.Restart = Transient
+	temporary := e.spec("temporary")
+	temporary.Restart = Temporary
+	s, err := New(transient, temporary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	defer s.Stop(nil)
+	waitFor(t, e, "start:transient start:temporary")
+
+	e.reset()
+	s.Child("transient").Cast("Crash")
+	waitFor(t, e, "stop:transient start:transient")
+	e.reset()
+	s.Child("transient").Terminate(nil)
+	waitFor(t, e, "stop:transient")
+	s.Child("temporary").Cast("Crash")
+	waitFor(t, e, "stop:transient stop:temporary")
+	// neither is restarted
+	time.Sleep(20 * time.Millisecond)
+	waitFor(t, e, "stop:transient stop:temporary")
+	infos := s.WhichChildren()
+	if len(infos) != 1 || infos[0].Name != "transient" || infos[0].Sequent != nil {
+		t.Fatalf("got %+v, want transient stopped and temporary removed", infos)
+	}
+	if err := s.RestartChild("transient"); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, e, "stop:transient stop:temporary start:transient")
+
+	if _, err := New(temporary, e.spec("a", "temporary")); err == nil {
+		t.Fatal("child depending on a temporary child accepted")
+	}
+}
+
+func TestStartReadsDependency(t *testing.T) {
+	e := &events{}
+	var s *Supervisor
+	session := ChildSpec{
+		Name: "session",
+		Start: func(sup seriatim.Supervisor) seriatim.Sequent {
+			conn := s.Child("conn")
+			if conn == nil || !conn.Running() {
+				e.add("start:session without conn")
+				return nil
+			}
+			e.add(fmt.Sprintf("start:session children:%d",
+				len(s.WhichChildren())))
+			return seriatim.NewSupervisedSequent(&node{name: "session"},
+				&observer{name: "session", events: e, next: sup})
+		},
+		DependsOn: []string{"conn"},
+	}
+	var err error
+	s, err = New(e.spec("conn"), session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	go func() {
+		s.Start()
+		close(started)
+	}()
+	select {
+	case <-started:
+	case <-time.After(time.Second):
+		t.Fatal("Start deadlocked")
+	}
+	defer s.Stop(nil)
+	waitFor(t, e, "start:conn start:session children:2")
+
+	e.reset()
+	s.Child("conn").Cast("Crash")
+	waitFor(t, e, "stop:conn stop:session start:conn start:session children:2")
+}
+
+func TestTerminatedDuringStart(t *testing.T) {
+	e := &events{}
+	starts := 0
+	s, err := New(ChildSpec{
+		Name: "flaky",
+		Start: func(sup seriatim.Supervisor) seriatim.Sequent {
+			starts++
+			e.add(fmt.Sprintf("start:%d", starts))
+			seq := seriatim.NewSupervisedSequent(&node{name: "flaky"}, sup)
+			if starts == 1 {
+				seq.Terminate(errors.New("failed at once"))
+				<-seq.Done()
+			}
+			return seq
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Start()
+	defer s.Stop(nil)
+	// restarted though it terminated before it was known to run
+	waitFor(t, e, "start:1 start:2")
+	deadline := time.Now().Add(time.Second)
+	for s.Child("flaky") == nil || !s.Child("flaky").Running() {
+		if time.Now().After(deadline) {
+			t.Fatal("child not running")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
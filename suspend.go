@@ -0,0 +1,35 @@
+package seriatim
+
+// Suspender is implemented by the sequents that can be suspended,
+// which those made by this package are, such as by a supervisor
+// reconfiguring or upgrading the program around them.
+type Suspender interface {
+	// Suspend stops the sequent processing requests once the one
+	// being processed, if any, completes. Requests are still
+	// accepted, queuing in the mailbox, and the sequent can still be
+	// terminated. Its state is StateSuspended until resumed.
+	Suspend()
+	// Resume has a suspended sequent process its requests again, in
+	// the order they were made.
+	Resume()
+}
+
+func (a *sequent) Suspend() {
+	if a.lifecycle.toggle(StateSuspended, StateStarting, StateRunning) {
+		a.wakeUp()
+	}
+}
+
+func (a *sequent) Resume() {
+	if a.lifecycle.toggle(StateRunning, StateSuspended) {
+		a.wakeUp()
+	}
+}
+
+// wakeUp has the sequent look again at what it may process.
+func (a *sequent) wakeUp() {
+	select {
+	case a.wake <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,71 @@
+package seriatim
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSuspendResume(t *testing.T) {
+	s := NewSequent(&gated{release: make(chan struct{})}, WithMailboxSize(4))
+	defer s.Terminate(nil)
+	changes := s.StateChanges()
+	s.(Suspender).Suspend()
+	if s.State() != StateSuspended || s.Running() {
+		t.Fatalf("expected a suspended sequent, got %v", s.State())
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.Cast("Incr"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	results := make(chan int, 1)
+	go func() {
+		rets, err := s.Call("Incr")
+		if err != nil {
+			t.Error(err)
+		}
+		results <- rets[0].(int)
+	}()
+	select {
+	case <-results:
+		t.Fatal("request processed while suspended")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	s.(Suspender).Resume()
+	if n := <-results; n != 3 {
+		t.Fatalf("expected the queued requests in order, got %d", n)
+	}
+	if !s.Running() {
+		t.Fatal("resumed sequent not running")
+	}
+	for state := range changes {
+		if state == StateSuspended {
+			break
+		}
+	}
+	if state := <-changes; state != StateRunning {
+		t.Fatalf("expected resuming to be seen, got %v", state)
+	}
+}
+
+func TestSuspendTerminate(t *testing.T) {
+	s := NewSequent(&gated{release: make(chan struct{})})
+	s.(Suspender).Suspend()
+	errs := make(chan error, 1)
+	go func() {
+		_, err := s.Call("Incr")
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	s.Terminate(nil)
+	<-s.Done()
+	if err := <-errs; err != ErrSequentStop {
+		t.Fatalf("expected ErrSequentStop, got %v", err)
+	}
+	s.(Suspender).Resume()
+	if s.State() != StateStopped {
+		t.Fatalf("expected a stopped sequent, got %v", s.State())
+	}
+}
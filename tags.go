@@ -0,0 +1,35 @@
+package seriatim
+
+// WithTags labels the sequent with tags, such as "device", so that it
+// can be found with the others labelled alike by Tagged. Tags cannot
+// be changed once the sequent is created.
+func WithTags(tags ...string) Option {
+	return func(a *sequent) {
+		a.tags = append(a.tags, tags...)
+	}
+}
+
+// Tagged returns the running sequents labelled with tag, in the order
+// they were created, such as to flush every device at once:
+//
+//	for _, s := range seriatim.Tagged("device") {
+//		s.Cast("Flush")
+//	}
+func Tagged(tag string) []Sequent {
+	var out []Sequent
+	for _, s := range Sequents() {
+		if s.(*sequent).tagged(tag) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func (a *sequent) tagged(tag string) bool {
+	for _, t := range a.tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
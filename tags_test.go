@@ -0,0 +1,28 @@
+package seriatim
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTagged(t *testing.T) {
+	a := NewSequent(&counter{}, WithTags("device", "disk"))
+	b := NewSequent(&counter{}, WithTags("device"))
+	c := NewSequent(&counter{})
+	defer b.Terminate(nil)
+	defer c.Terminate(nil)
+	if got := Tagged("device"); !reflect.DeepEqual(got, []Sequent{a, b}) {
+		t.Fatalf("Got %v, want %v", got, []Sequent{a, b})
+	}
+	if got := Tagged("disk"); !reflect.DeepEqual(got, []Sequent{a}) {
+		t.Fatalf("Got %v, want %v", got, []Sequent{a})
+	}
+	if tags := a.Stats().Tags; !reflect.DeepEqual(tags, []string{"device", "disk"}) {
+		t.Fatalf("Got tags %q", tags)
+	}
+	a.Terminate(nil)
+	<-a.Done()
+	if got := Tagged("disk"); len(got) != 0 {
+		t.Fatalf("Got %v, want no terminated sequent", got)
+	}
+}
@@ -0,0 +1,77 @@
+package seriatim
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrTerminateTimeout is the reason a sequent given up on by
+// TerminateWithTimeout is reported to its supervisor with.
+var ErrTerminateTimeout = errors.New("Sequent did not terminate in time")
+
+// TerminateWithTimeout terminates s with reason once it has processed
+// the requests already in its mailbox, as ShutdownAll does. If s has
+// not stopped within d, such as when a method never returns, the
+// goroutine running it is abandoned: s crashes right away with
+// ErrTerminateTimeout, reported to its supervisor and to the callers
+// of the stuck method and of the requests still waiting, and the
+// method's result is discarded whenever it returns. Sequents other
+// than those made by this package are terminated and waited for,
+// returning ErrTerminateTimeout if they have not stopped within d.
+func TerminateWithTimeout(s Sequent, reason error, d time.Duration) error {
+	if seq, ok := s.(*sequent); ok {
+		return seq.terminateWithTimeout(reason, d)
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	changes := s.StateChanges()
+	go s.Terminate(reason)
+	for {
+		select {
+		case state, ok := <-changes:
+			if !ok || state.Final() {
+				return nil
+			}
+		case <-timer.C:
+			return ErrTerminateTimeout
+		}
+	}
+}
+
+func (a *sequent) terminateWithTimeout(reason error, d time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+	a.flush(ctx)
+	a.Terminate(reason)
+	select {
+	case <-a.done:
+		return nil
+	case <-ctx.Done():
+	}
+	if !atomic.CompareAndSwapUint32(&a.dead, 0, 1) {
+		// it stopped on its own meanwhile
+		return nil
+	}
+	if req, _ := a.pending.Load().(*request); req != nil {
+		req.fail(ErrTerminateTimeout)
+	}
+	a.failHeld(ErrTerminateTimeout)
+	a.failQueued(ErrTerminateTimeout)
+	a.terminated(ErrTerminateTimeout, StateCrashed)
+	a.crashed(nil, ErrTerminateTimeout, nil)
+	return ErrTerminateTimeout
+}
+
+// answer claims the answer to req, failing if it was already answered,
+// such as by TerminateWithTimeout while its method was stuck.
+func (req *request) answer() bool {
+	return atomic.CompareAndSwapUint32(&req.answered, 0, 1)
+}
+
+// abandoned reports whether the sequent was terminated while its
+// goroutine was still running, which then has nothing left to do.
+func (a *sequent) abandoned() bool {
+	return atomic.LoadUint32(&a.dead) != 0
+}
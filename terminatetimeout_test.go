@@ -0,0 +1,109 @@
+package seriatim
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type reasons chan error
+
+func (r reasons) SequentTerminated(err error, id uintptr) {
+	r <- err
+}
+
+func TestTerminateWithTimeoutDrains(t *testing.T) {
+	g := &gated{release: make(chan struct{})}
+	close(g.release)
+	s := NewSequent(g, WithMailboxSize(4))
+	for i := 0; i < 3; i++ {
+		if err := s.Cast("Incr"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := TerminateWithTimeout(s, nil, time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if g.n != 3 || s.State() != StateStopped {
+		t.Fatalf("expected 3 casts processed before stopping, got %d, %v",
+			g.n, s.State())
+	}
+}
+
+func TestTerminateWithTimeoutAbandons(t *testing.T) {
+	g := &gated{release: make(chan struct{})}
+	terminated := make(reasons, 1)
+	s := NewSupervisedSequent(g, terminated, WithMailboxSize(4))
+	if err := s.Cast("Block"); err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error, 1)
+	if err := s.CastNotify("Incr", errs); err != nil {
+		t.Fatal(err)
+	}
+
+	reason := errors.New("shutting down")
+	if err := TerminateWithTimeout(s, reason, 20*time.Millisecond); err != ErrTerminateTimeout {
+		t.Fatalf("expected ErrTerminateTimeout, got %v", err)
+	}
+	if err := <-terminated; err != ErrTerminateTimeout {
+		t.Fatalf("supervisor was told %v", err)
+	}
+	if s.State() != StateCrashed {
+		t.Fatalf("expected the sequent to be dead, is %v", s.State())
+	}
+	if err := <-errs; err != ErrTerminateTimeout {
+		t.Fatalf("expected the queued cast to fail, got %v", err)
+	}
+
+	// the abandoned method returning must not terminate it again
+	close(g.release)
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case err := <-terminated:
+		t.Fatalf("supervisor told again with %v", err)
+	default:
+	}
+}
+
+type stuck struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *stuck) Block() string {
+	close(s.started)
+	<-s.release
+	return "done"
+}
+
+func TestTerminateWithTimeoutFailsStuckCall(t *testing.T) {
+	v := &stuck{started: make(chan struct{}), release: make(chan struct{})}
+	s := NewSequent(v)
+	type result struct {
+		rets []interface{}
+		err  error
+	}
+	results := make(chan result, 1)
+	go func() {
+		rets, err := s.Call("Block")
+		results <- result{rets, err}
+	}()
+	<-v.started
+	if err := TerminateWithTimeout(s, nil, 20*time.Millisecond); err != ErrTerminateTimeout {
+		t.Fatalf("expected ErrTerminateTimeout, got %v", err)
+	}
+	// answered when the goroutine is abandoned, not when the method
+	// returns
+	select {
+	case res := <-results:
+		if res.err != ErrTerminateTimeout {
+			t.Fatalf("expected the stuck call to fail, got %v %v",
+				res.rets, res.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("stuck call not failed")
+	}
+	close(v.release)
+	time.Sleep(10 * time.Millisecond)
+}
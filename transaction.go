@@ -0,0 +1,104 @@
+package seriatim
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Participant is a sequent taking part in Transact, with the arguments
+// of its Prepare method.
+type Participant struct {
+	Sequent Sequent
+	Args    []interface{}
+}
+
+// TransactionError reports the participant that failed Transact, by
+// its index, and the method that failed, Prepare or Commit.
+type TransactionError struct {
+	Index  int
+	Method string
+	Err    error
+	// Rollback holds the errors of the Rollbacks made because
+	// Prepare failed.
+	Rollback error
+}
+
+func (e *TransactionError) Error() string {
+	msg := fmt.Sprintf("Participant %d failed to %s: %s",
+		e.Index, e.Method, e.Err)
+	if e.Rollback != nil {
+		msg += fmt.Sprintf(" (rollback: %s)", e.Rollback)
+	}
+	return msg
+}
+
+func (e *TransactionError) Unwrap() error {
+	return e.Err
+}
+
+// Transact updates the values of several sequents all or nothing, such
+// as to apply a configuration spanning them, in two phases. Their
+// methods
+//
+//	Prepare(args...) error
+//	Commit() error
+//	Rollback() error
+//
+// are called, each returning an error or nothing. Prepare is called on
+// the participants in order, with their Args, and should check and
+// stage the update without applying it, holding back other changes
+// that would conflict with it. If a Prepare fails, Rollback is called
+// on the participants that were asked to prepare, in reverse order and
+// including the one that failed, which may have prepared in part, and
+// Transact returns a *TransactionError. Otherwise Commit is called on
+// every participant, which should not fail as the participants already
+// committed are not rolled back; the first failure is returned.
+//
+// ctx bounds the Prepare phase. A participant whose Prepare is given
+// up on may still prepare later and is rolled back after it does.
+// Commit and Rollback are always made.
+func Transact(ctx context.Context, participants ...Participant) error {
+	for i, p := range participants {
+		err := transactionCall(p.Sequent.CallContext(ctx, "Prepare", p.Args...))
+		if err == nil {
+			continue
+		}
+		var rollback []error
+		for j := i; j >= 0; j-- {
+			err := transactionCall(participants[j].Sequent.Call("Rollback"))
+			if err != nil {
+				rollback = append(rollback,
+					fmt.Errorf("Participant %d: %w", j, err))
+			}
+		}
+		return &TransactionError{
+			Index:    i,
+			Method:   "Prepare",
+			Err:      err,
+			Rollback: errors.Join(rollback...),
+		}
+	}
+	var failed error
+	for i, p := range participants {
+		err := transactionCall(p.Sequent.Call("Commit"))
+		if err != nil && failed == nil {
+			failed = &TransactionError{Index: i, Method: "Commit", Err: err}
+		}
+	}
+	return failed
+}
+
+// transactionCall returns the error of a Call of a method of a
+// participant, or the error it returned.
+func transactionCall(rets []interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+	if n := len(rets); n > 0 {
+		if err, ok := rets[n-1].(error); ok {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package seriatim
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+type setting struct {
+	value, staged int
+	prepared      bool
+	log           *[]string
+	name          string
+}
+
+func (s *setting) Prepare(value int) error {
+	*s.log = append(*s.log, s.name+" prepare")
+	if value < 0 {
+		return errors.New("Negative value")
+	}
+	s.staged, s.prepared = value, true
+	return nil
+}
+
+func (s *setting) Commit() error {
+	*s.log = append(*s.log, s.name+" commit")
+	if !s.prepared {
+		return errors.New("Not prepared")
+	}
+	s.value, s.prepared = s.staged, false
+	return nil
+}
+
+func (s *setting) Rollback() {
+	*s.log = append(*s.log, s.name+" rollback")
+	s.prepared = false
+}
+
+func (s *setting) Value() int {
+	return s.value
+}
+
+// stalled prepares once released.
+type stalled struct {
+	release    chan struct{}
+	commit     error
+	rolledBack bool
+}
+
+func (s *stalled) Prepare() {
+	<-s.release
+}
+
+func (s *stalled) Commit() error {
+	return s.commit
+}
+
+func (s *stalled) Rollback() {
+	s.rolledBack = true
+}
+
+func (s *stalled) RolledBack() bool {
+	return s.rolledBack
+}
+
+func newSettings(t *testing.T, names ...string) ([]Sequent, *[]string) {
+	// the participants are called one at a time, so they may share
+	// their log
+	log := &[]string{}
+	out := make([]Sequent, len(names))
+	for i, name := range names {
+		s := NewSequent(&setting{log: log, name: name})
+		t.Cleanup(func() { s.Terminate(nil) })
+		out[i] = s
+	}
+	return out, log
+}
+
+func settingValue(t *testing.T, s Sequent) int {
+	t.Helper()
+	rets, err := s.Call("Value")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rets[0].(int)
+}
+
+func equalLog(t *testing.T, got *[]string, want ...string) {
+	t.Helper()
+	if len(*got) != len(want) {
+		t.Fatalf("Got log %q, want %q", *got, want)
+	}
+	for i := range want {
+		if (*got)[i] != want[i] {
+			t.Fatalf("Got log %q, want %q", *got, want)
+		}
+	}
+}
+
+func TestTransact(t *testing.T) {
+	s, log := newSettings(t, "a", "b")
+	err := Transact(context.Background(),
+		Participant{Sequent: s[0], Args: []interface{}{1}},
+		Participant{Sequent: s[1], Args: []interface{}{2}},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	equalLog(t, log, "a prepare", "b prepare", "a commit", "b commit")
+	if v := settingValue(t, s[0]); v != 1 {
+		t.Fatalf("Got %d, want 1", v)
+	}
+	if v := settingValue(t, s[1]); v != 2 {
+		t.Fatalf("Got %d, want 2", v)
+	}
+}
+
+func TestTransactRollback(t *testing.T) {
+	s, log := newSettings(t, "a", "b", "c")
+	err := Transact(context.Background(),
+		Participant{Sequent: s[0], Args: []interface{}{1}},
+		Participant{Sequent: s[1], Args: []interface{}{-1}},
+		Participant{Sequent: s[2], Args: []interface{}{3}},
+	)
+	var terr *TransactionError
+	if !errors.As(err, &terr) {
+		t.Fatalf("Got %v, want a TransactionError", err)
+	}
+	if terr.Index != 1 || terr.Method != "Prepare" || terr.Rollback != nil {
+		t.Fatalf("Got %+v", terr)
+	}
+	equalLog(t, log, "a prepare", "b prepare", "b rollback", "a rollback")
+	if v := settingValue(t, s[0]); v != 0 {
+		t.Fatalf("Got %d, want 0", v)
+	}
+}
+
+func TestTransactRollbackError(t *testing.T) {
+	s, _ := newSettings(t, "b")
+	stuck := errors.New("Rollback stuck")
+	failing := NewSequentTable(&setting{}, map[string]interface{}{
+		"Prepare":  func() error { return nil },
+		"Rollback": func() error { return stuck },
+	})
+	defer failing.Terminate(nil)
+	err := Transact(context.Background(),
+		Participant{Sequent: failing},
+		Participant{Sequent: s[0], Args: []interface{}{-1}},
+	)
+	var terr *TransactionError
+	if !errors.As(err, &terr) || terr.Index != 1 {
+		t.Fatalf("Got %v, want a TransactionError for participant 1", err)
+	}
+	if !errors.Is(terr.Rollback, stuck) {
+		t.Fatalf("Got rollback errors %v, want %v", terr.Rollback, stuck)
+	}
+}
+
+func TestTransactCallError(t *testing.T) {
+	s, log := newSettings(t, "a", "b")
+	err := Transact(context.Background(),
+		Participant{Sequent: s[0], Args: []interface{}{1}},
+		Participant{Sequent: s[1], Args: []interface{}{"two"}},
+	)
+	var terr *TransactionError
+	if !errors.As(err, &terr) || terr.Index != 1 {
+		t.Fatalf("Got %v, want a TransactionError for participant 1", err)
+	}
+	var argErr *ArgTypeError
+	if !errors.As(err, &argErr) {
+		t.Fatalf("Got %v, want an ArgTypeError", terr.Err)
+	}
+	equalLog(t, log, "a prepare", "b rollback", "a rollback")
+}
+
+func TestTransactCanceled(t *testing.T) {
+	s, log := newSettings(t, "a")
+	st := &stalled{release: make(chan struct{})}
+	slow := NewSequent(st)
+	defer slow.Terminate(nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- Transact(ctx,
+			Participant{Sequent: s[0], Args: []interface{}{1}},
+			Participant{Sequent: slow},
+		)
+	}()
+	var err error
+	select {
+	case err = <-errs:
+		t.Fatalf("Transact returned %v before rolling back", err)
+	case <-time.After(50 * time.Millisecond):
+		// the rollback of slow waits for its prepare
+		close(st.release)
+		err = <-errs
+	}
+	var terr *TransactionError
+	if !errors.As(err, &terr) || terr.Index != 1 ||
+		!errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Got %v, want participant 1 to time out", err)
+	}
+	equalLog(t, log, "a prepare", "a rollback")
+	if rets, err := slow.Call("RolledBack"); err != nil || !rets[0].(bool) {
+		t.Fatalf("Got %v, %v, want the participant rolled back", rets, err)
+	}
+}
+
+func TestTransactCommitError(t *testing.T) {
+	s, log := newSettings(t, "b")
+	st := &stalled{
+		release: make(chan struct{}),
+		commit:  errors.New("Disk full"),
+	}
+	close(st.release)
+	failing := NewSequent(st)
+	defer failing.Terminate(nil)
+	err := Transact(context.Background(),
+		Participant{Sequent: failing},
+		Participant{Sequent: s[0], Args: []interface{}{2}},
+	)
+	var terr *TransactionError
+	if !errors.As(err, &terr) || terr.Index != 0 || terr.Method != "Commit" ||
+		terr.Err != st.commit {
+		t.Fatalf("Got %v, want the commit of participant 0 to fail", err)
+	}
+	// the other participants commit anyway
+	equalLog(t, log, "b prepare", "b commit")
+	if v := settingValue(t, s[0]); v != 2 {
+		t.Fatalf("Got %d, want 2", v)
+	}
+}
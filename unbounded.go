@@ -0,0 +1,128 @@
+package seriatim
+
+import (
+	"sync/atomic"
+)
+
+// segmentSize is the number of messages held by each segment of an
+// UnboundedQueue.
+const segmentSize = 64
+
+type segment struct {
+	msgs [segmentSize]Message
+	next *segment
+}
+
+// UnboundedQueue is a Mailbox without a limit, so that sending to it
+// never blocks for long, such as for casts whose producers must not
+// wait on a slow sequent. It holds its messages in a list of segments
+// grown and released as they fill and empty, moved between its
+// channels by a goroutine of its own until Stop.
+type UnboundedQueue struct {
+	length int64 // first for 64-bit alignment of atomic ops
+	in     chan Message
+	out    chan Message
+	stop   chan struct{}
+	done   chan struct{}
+
+	// owned by the goroutine
+	head, tail *segment
+	// first is the index of the head's first message, last the
+	// index after the tail's last message.
+	first, last int
+}
+
+func NewUnboundedQueue() *UnboundedQueue {
+	q := &UnboundedQueue{
+		in:   make(chan Message),
+		out:  make(chan Message),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	q.head = &segment{}
+	q.tail = q.head
+	go q.run()
+	return q
+}
+
+// WithUnboundedMailbox makes the sequent receive its requests from an
+// UnboundedQueue, so that Casts never block on a busy sequent.
+func WithUnboundedMailbox() Option {
+	return WithMailbox(NewUnboundedQueue())
+}
+
+func (q *UnboundedQueue) Enqueue() chan<- Message {
+	return q.in
+}
+
+func (q *UnboundedQueue) Dequeue() <-chan Message {
+	return q.out
+}
+
+// Len returns the number of messages queued.
+func (q *UnboundedQueue) Len() int {
+	return int(atomic.LoadInt64(&q.length))
+}
+
+// Cap returns 0 as the queue has no limit.
+func (q *UnboundedQueue) Cap() int {
+	return 0
+}
+
+// Stop purges the queued messages and closes the channel returned by
+// Dequeue. Nothing may be sent to the queue once Stop is called.
+func (q *UnboundedQueue) Stop() {
+	close(q.stop)
+	<-q.done
+}
+
+func (q *UnboundedQueue) run() {
+	defer close(q.done)
+	for {
+		var out chan Message
+		var front Message
+		if q.Len() > 0 {
+			out = q.out
+			front = q.head.msgs[q.first]
+		}
+		select {
+		case msg := <-q.in:
+			q.push(msg)
+		case out <- front:
+			q.pop()
+		case <-q.stop:
+			for q.Len() > 0 {
+				q.pop().Purged()
+			}
+			close(q.out)
+			return
+		}
+	}
+}
+
+func (q *UnboundedQueue) push(msg Message) {
+	if q.last == segmentSize {
+		q.tail.next = &segment{}
+		q.tail = q.tail.next
+		q.last = 0
+	}
+	q.tail.msgs[q.last] = msg
+	q.last++
+	atomic.AddInt64(&q.length, 1)
+}
+
+func (q *UnboundedQueue) pop() Message {
+	msg := q.head.msgs[q.first]
+	q.head.msgs[q.first] = nil
+	q.first++
+	atomic.AddInt64(&q.length, -1)
+	switch {
+	case q.first == segmentSize && q.head.next != nil:
+		q.head = q.head.next
+		q.first = 0
+	case q.head == q.tail && q.first == q.last:
+		// reuse the only segment once it empties
+		q.first, q.last = 0, 0
+	}
+	return msg
+}
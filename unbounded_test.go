@@ -0,0 +1,78 @@
+package seriatim
+
+import (
+	"testing"
+	"time"
+)
+
+type countedMsg struct {
+	n      int
+	purged *int
+}
+
+func (m *countedMsg) Purged() {
+	*m.purged++
+}
+
+func TestUnboundedQueue(t *testing.T) {
+	const n = 3*segmentSize + 5
+	q := NewUnboundedQueue()
+	purged := 0
+	for i := 0; i < n; i++ {
+		q.Enqueue() <- &countedMsg{n: i, purged: &purged}
+	}
+	if q.Len() != n {
+		t.Fatalf("expected %d queued, got %d", n, q.Len())
+	}
+	for i := 0; i < n-10; i++ {
+		msg := (<-q.Dequeue()).(*countedMsg)
+		if msg.n != i {
+			t.Fatalf("expected message %d, got %d", i, msg.n)
+		}
+	}
+	q.Enqueue() <- &countedMsg{n: n, purged: &purged}
+	q.Stop()
+	if purged != 11 {
+		t.Fatalf("expected 11 messages purged, got %d", purged)
+	}
+	if _, ok := <-q.Dequeue(); ok {
+		t.Fatal("expected the queue to be closed")
+	}
+}
+
+func TestUnboundedMailbox(t *testing.T) {
+	g := &gated{release: make(chan struct{})}
+	s := NewSequent(g, WithUnboundedMailbox())
+	defer s.Terminate(nil)
+	if err := s.Cast("Block"); err != nil {
+		t.Fatal(err)
+	}
+
+	casts := make(chan error)
+	go func() {
+		for i := 0; i < 1000; i++ {
+			if err := s.Cast("Incr"); err != nil {
+				casts <- err
+				return
+			}
+		}
+		casts <- nil
+	}()
+	select {
+	case err := <-casts:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("casts blocked on a busy sequent")
+	}
+
+	close(g.release)
+	rets, err := s.Call("Incr")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rets[0] != 1001 {
+		t.Fatalf("expected 1001 increments, got %v", rets[0])
+	}
+}
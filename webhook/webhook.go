@@ -0,0 +1,230 @@
+// Package webhook forwards D-Bus signals to HTTP endpoints.
+//
+// Each forwarded signal is POSTed as a JSON object to every Target
+// whose filters match it. Network errors and 5xx or 429 responses are
+// retried with exponential backoff; other failures are final.
+// Deliveries are made in order by a single worker so a slow endpoint
+// delays the ones after it, and signals arriving while the backlog is
+// full are dropped rather than blocking the bus.
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jsouthworth/seriatim/dbus"
+)
+
+// Target is an endpoint and the signals sent to it. Empty filters
+// match any value.
+type Target struct {
+	URL       string
+	Path      string
+	Interface string
+	Member    string
+	Header    http.Header
+}
+
+func (t *Target) matches(signal *dbus.EmittedSignal) bool {
+	return (t.Path == "" || t.Path == string(signal.Path)) &&
+		(t.Interface == "" || t.Interface == signal.Interface) &&
+		(t.Member == "" || t.Member == signal.Member)
+}
+
+// Event is the JSON body of a delivery.
+type Event struct {
+	Path      string        `json:"path"`
+	Interface string        `json:"interface"`
+	Member    string        `json:"member"`
+	Sender    string        `json:"sender,omitempty"`
+	Body      []interface{} `json:"body"`
+	Time      time.Time     `json:"time"`
+}
+
+type delivery struct {
+	target  *Target
+	payload []byte
+}
+
+type Forwarder struct {
+	targets []*Target
+	dropped uint64
+
+	mu      sync.Mutex
+	queue   chan *delivery
+	done    chan struct{}
+	cancels []func()
+	closed  bool
+
+	Client *http.Client
+	// MaxAttempts bounds the number of times a delivery is tried.
+	MaxAttempts int
+	// Backoff is the delay before the first retry; it doubles
+	// after every failed attempt up to MaxBackoff.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// Backlog is the number of deliveries waiting to be made.
+	Backlog int
+	// OnError is called with deliveries that failed for good.
+	OnError func(error)
+}
+
+func New(targets ...Target) *Forwarder {
+	f := &Forwarder{
+		Client:      http.DefaultClient,
+		MaxAttempts: 5,
+		Backoff:     500 * time.Millisecond,
+		MaxBackoff:  30 * time.Second,
+		Backlog:     256,
+	}
+	for i := range targets {
+		target := targets[i]
+		f.targets = append(f.targets, &target)
+	}
+	return f
+}
+
+// Dropped reports how many deliveries were dropped because the backlog
+// was full.
+func (f *Forwarder) Dropped() uint64 {
+	return atomic.LoadUint64(&f.dropped)
+}
+
+// Forward delivers the signals of source, a *dbus.BusManager for the
+// signals it emits or the value of its Received method for those it
+// receives, until Close is called.
+func (f *Forwarder) Forward(source dbus.SignalSource) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return fmt.Errorf("forwarder is closed")
+	}
+	if f.queue == nil {
+		f.queue = make(chan *delivery, f.Backlog)
+		f.done = make(chan struct{})
+		go f.run(f.queue, f.done)
+	}
+	f.cancels = append(f.cancels, source.Observe(f.observe))
+	return nil
+}
+
+// Close stops forwarding and waits for the queued deliveries.
+func (f *Forwarder) Close() {
+	f.mu.Lock()
+	if f.closed {
+		f.mu.Unlock()
+		return
+	}
+	f.closed = true
+	for _, cancel := range f.cancels {
+		cancel()
+	}
+	queue, done := f.queue, f.done
+	if queue != nil {
+		close(queue)
+	}
+	f.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+func (f *Forwarder) observe(signal *dbus.EmittedSignal) {
+	var payload []byte
+	for _, target := range f.targets {
+		if !target.matches(signal) {
+			continue
+		}
+		if payload == nil {
+			var err error
+			payload, err = json.Marshal(&Event{
+				Path:      string(signal.Path),
+				Interface: signal.Interface,
+				Member:    signal.Member,
+				Sender:    signal.Sender,
+				Body:      signal.Body,
+				Time:      time.Now(),
+			})
+			if err != nil {
+				f.error(fmt.Errorf("encoding %s.%s: %v",
+					signal.Interface, signal.Member, err))
+				return
+			}
+		}
+		f.mu.Lock()
+		if !f.closed {
+			select {
+			case f.queue <- &delivery{target: target, payload: payload}:
+			default:
+				atomic.AddUint64(&f.dropped, 1)
+			}
+		}
+		f.mu.Unlock()
+	}
+}
+
+func (f *Forwarder) error(err error) {
+	if f.OnError != nil {
+		f.OnError(err)
+	}
+}
+
+func (f *Forwarder) run(queue <-chan *delivery, done chan<- struct{}) {
+	defer close(done)
+	for d := range queue {
+		if err := f.deliver(d); err != nil {
+			f.error(err)
+		}
+	}
+}
+
+func (f *Forwarder) deliver(d *delivery) error {
+	backoff := f.Backoff
+	var err error
+	for attempt := 1; ; attempt++ {
+		var retry bool
+		retry, err = f.post(d)
+		if err == nil || !retry || attempt >= f.MaxAttempts {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > f.MaxBackoff {
+			backoff = f.MaxBackoff
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("delivering to %s: %v", d.target.URL, err)
+	}
+	return nil
+}
+
+// post makes one attempt at a delivery and reports whether a failure
+// is worth retrying.
+func (f *Forwarder) post(d *delivery) (bool, error) {
+	req, err := http.NewRequest("POST", d.target.URL, bytes.NewReader(d.payload))
+	if err != nil {
+		return false, err
+	}
+	for key, values := range d.target.Header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := f.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("%s", resp.Status)
+	}
+	return false, fmt.Errorf("%s", resp.Status)
+}
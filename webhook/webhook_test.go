@@ -0,0 +1,131 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jsouthworth/seriatim/dbus"
+)
+
+type fakeSource struct {
+	observer func(*dbus.EmittedSignal)
+}
+
+func (s *fakeSource) Observe(fn func(*dbus.EmittedSignal)) func() {
+	s.observer = fn
+	return func() { s.observer = nil }
+}
+
+type endpoint struct {
+	mu       sync.Mutex
+	failures int
+	status   int
+	events   []Event
+	attempts int
+	headers  []string
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.attempts++
+	if e.failures > 0 {
+		e.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	if e.status != 0 {
+		w.WriteHeader(e.status)
+		return
+	}
+	var event Event
+	json.NewDecoder(r.Body).Decode(&event)
+	e.events = append(e.events, event)
+	e.headers = append(e.headers, r.Header.Get("X-Token"))
+}
+
+func newForwarder(targets ...Target) *Forwarder {
+	f := New(targets...)
+	f.Backoff = time.Millisecond
+	f.MaxBackoff = 4 * time.Millisecond
+	return f
+}
+
+func TestForwardFilters(t *testing.T) {
+	ep := &endpoint{failures: 2}
+	srv := httptest.NewServer(ep)
+	defer srv.Close()
+
+	f := newForwarder(Target{
+		URL:       srv.URL,
+		Interface: "com.example.Sensor",
+		Header:    http.Header{"X-Token": {"secret"}},
+	})
+	source := &fakeSource{}
+	if err := f.Forward(source); err != nil {
+		t.Fatal(err)
+	}
+	source.observer(&dbus.EmittedSignal{
+		Path: "/a", Interface: "com.example.Other", Member: "Changed",
+	})
+	source.observer(&dbus.EmittedSignal{
+		Path:      "/kitchen",
+		Interface: "com.example.Sensor",
+		Member:    "Reading",
+		Body:      []interface{}{21.5},
+		Sender:    ":1.7",
+	})
+	f.Close()
+	if source.observer != nil {
+		t.Fatal("Close left the source observed")
+	}
+	if err := f.Forward(source); err == nil {
+		t.Fatal("Forward after Close should fail")
+	}
+
+	if ep.attempts != 3 || len(ep.events) != 1 {
+		t.Fatalf("expected 1 delivery after 3 attempts, got %d after %d",
+			len(ep.events), ep.attempts)
+	}
+	event := ep.events[0]
+	if event.Path != "/kitchen" || event.Member != "Reading" ||
+		event.Sender != ":1.7" || len(event.Body) != 1 {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if ep.headers[0] != "secret" {
+		t.Fatalf("target headers not sent: %v", ep.headers)
+	}
+}
+
+func TestForwardGivesUp(t *testing.T) {
+	retried := &endpoint{failures: 10}
+	rejected := &endpoint{status: http.StatusBadRequest}
+	srv1 := httptest.NewServer(retried)
+	defer srv1.Close()
+	srv2 := httptest.NewServer(rejected)
+	defer srv2.Close()
+
+	f := newForwarder(Target{URL: srv1.URL}, Target{URL: srv2.URL})
+	f.MaxAttempts = 3
+	var errs []error
+	f.OnError = func(err error) { errs = append(errs, err) }
+	source := &fakeSource{}
+	f.Forward(source)
+	source.observer(&dbus.EmittedSignal{Path: "/", Interface: "a.b", Member: "C"})
+	f.Close()
+
+	if retried.attempts != 3 {
+		t.Fatalf("expected 3 attempts, got %d", retried.attempts)
+	}
+	if rejected.attempts != 1 {
+		t.Fatalf("client errors should not be retried, got %d attempts",
+			rejected.attempts)
+	}
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 failed deliveries, got %v", errs)
+	}
+}
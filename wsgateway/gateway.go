@@ -0,0 +1,278 @@
+// Package wsgateway serves sequents and emitted D-Bus signals to
+// WebSocket clients such as browser dashboards.
+//
+// Clients send JSON messages with an op of call, cast, subscribe or
+// unsubscribe:
+//
+//	{"id": 1, "op": "call", "sequent": "counter", "method": "Add", "args": [1]}
+//	{"id": 2, "op": "subscribe", "interface": "com.example.Counter"}
+//	{"id": 3, "op": "unsubscribe", "subscription": 2}
+//
+// Every request with an id is answered with a message carrying the
+// same id and either a result or an error. Casts, subscribe and
+// unsubscribe are handled in the order they are received, so a
+// sequent receives the casts of a client in the order they were sent;
+// calls are made concurrently and answered as they complete. Signals matching a
+// subscription are pushed with the id of the subscribe request as
+// their subscription; empty path, interface and member fields of a
+// subscription match anything.
+package wsgateway
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jsouthworth/seriatim"
+	"github.com/jsouthworth/seriatim/dbus"
+	"golang.org/x/net/websocket"
+)
+
+// Signals is the source of pushed signals, usually a *dbus.BusManager.
+type Signals interface {
+	Observe(fn func(*dbus.EmittedSignal)) (cancel func())
+}
+
+type request struct {
+	Id           uint64        `json:"id,omitempty"`
+	Op           string        `json:"op"`
+	Sequent      string        `json:"sequent,omitempty"`
+	Method       string        `json:"method,omitempty"`
+	Args         []interface{} `json:"args,omitempty"`
+	Path         string        `json:"path,omitempty"`
+	Interface    string        `json:"interface,omitempty"`
+	Member       string        `json:"member,omitempty"`
+	Subscription uint64        `json:"subscription,omitempty"`
+}
+
+type reply struct {
+	Id     uint64        `json:"id,omitempty"`
+	Result []interface{} `json:"result,omitempty"`
+	Error  string        `json:"error,omitempty"`
+	Signal *signal       `json:"signal,omitempty"`
+}
+
+type signal struct {
+	Subscription uint64        `json:"subscription"`
+	Path         string        `json:"path"`
+	Interface    string        `json:"interface"`
+	Member       string        `json:"member"`
+	Body         []interface{} `json:"body"`
+}
+
+type subscription struct {
+	path, iface, member string
+}
+
+func (sub subscription) matches(sig *dbus.EmittedSignal) bool {
+	return (sub.path == "" || sub.path == string(sig.Path)) &&
+		(sub.iface == "" || sub.iface == sig.Interface) &&
+		(sub.member == "" || sub.member == sig.Member)
+}
+
+// Gateway is an http.Handler upgrading requests to WebSocket
+// connections.
+type Gateway struct {
+	mu       sync.RWMutex
+	sequents map[string]seriatim.Sequent
+	signals  Signals
+	dropped  uint64
+
+	// Backlog is the number of signals buffered for each
+	// connection; signals arriving while a client's backlog is
+	// full are dropped rather than blocking the emitter.
+	Backlog int
+}
+
+// New returns a Gateway pushing signals from signals, which may be nil
+// when only calls are served.
+func New(signals Signals) *Gateway {
+	return &Gateway{
+		sequents: make(map[string]seriatim.Sequent),
+		signals:  signals,
+		Backlog:  64,
+	}
+}
+
+func (g *Gateway) Register(name string, s seriatim.Sequent) {
+	g.mu.Lock()
+	g.sequents[name] = s
+	g.mu.Unlock()
+}
+
+func (g *Gateway) Unregister(name string) {
+	g.mu.Lock()
+	delete(g.sequents, name)
+	g.mu.Unlock()
+}
+
+// Dropped reports how many signals were dropped for slow clients.
+func (g *Gateway) Dropped() uint64 {
+	return atomic.LoadUint64(&g.dropped)
+}
+
+func (g *Gateway) lookup(name string) (seriatim.Sequent, error) {
+	g.mu.RLock()
+	s, ok := g.sequents[name]
+	g.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no sequent named %q", name)
+	}
+	return s, nil
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	websocket.Handler(g.serve).ServeHTTP(w, r)
+}
+
+type conn struct {
+	gateway *Gateway
+	ws      *websocket.Conn
+	wlock   sync.Mutex
+	events  chan *signal
+	done    chan struct{}
+
+	mu     sync.Mutex
+	subs   map[uint64]subscription
+	cancel func()
+}
+
+func (g *Gateway) serve(ws *websocket.Conn) {
+	c := &conn{
+		gateway: g,
+		ws:      ws,
+		events:  make(chan *signal, g.Backlog),
+		done:    make(chan struct{}),
+		subs:    make(map[uint64]subscription),
+	}
+	go c.push()
+	var wg sync.WaitGroup
+	for {
+		var req request
+		if err := websocket.JSON.Receive(ws, &req); err != nil {
+			break
+		}
+		if req.Op != "call" {
+			c.handle(&req)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.handle(&req)
+		}()
+	}
+	c.mu.Lock()
+	if c.cancel != nil {
+		c.cancel()
+	}
+	c.mu.Unlock()
+	close(c.done)
+	wg.Wait()
+}
+
+func (c *conn) send(r *reply) {
+	c.wlock.Lock()
+	websocket.JSON.Send(c.ws, r)
+	c.wlock.Unlock()
+}
+
+func (c *conn) push() {
+	for {
+		select {
+		case sig := <-c.events:
+			c.send(&reply{Signal: sig})
+		case <-c.done:
+			return
+		}
+	}
+}
+
+func (c *conn) observe(sig *dbus.EmittedSignal) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, sub := range c.subs {
+		if !sub.matches(sig) {
+			continue
+		}
+		select {
+		case c.events <- &signal{
+			Subscription: id,
+			Path:         string(sig.Path),
+			Interface:    sig.Interface,
+			Member:       sig.Member,
+			Body:         sig.Body,
+		}:
+		default:
+			atomic.AddUint64(&c.gateway.dropped, 1)
+		}
+	}
+}
+
+func (c *conn) handle(req *request) {
+	result, err := c.dispatch(req)
+	if req.Id == 0 {
+		return
+	}
+	r := &reply{Id: req.Id, Result: result}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	c.send(r)
+}
+
+func (c *conn) dispatch(req *request) ([]interface{}, error) {
+	switch req.Op {
+	case "call":
+		s, err := c.gateway.lookup(req.Sequent)
+		if err != nil {
+			return nil, err
+		}
+		values, err := s.Call(req.Method, req.Args...)
+		if err != nil {
+			return nil, err
+		}
+		if n := len(values); n > 0 {
+			if err, ok := values[n-1].(error); ok {
+				return nil, err
+			}
+		}
+		return values, nil
+	case "cast":
+		s, err := c.gateway.lookup(req.Sequent)
+		if err != nil {
+			return nil, err
+		}
+		return nil, s.Cast(req.Method, req.Args...)
+	case "subscribe":
+		if c.gateway.signals == nil {
+			return nil, errors.New("signals are not available")
+		}
+		if req.Id == 0 {
+			return nil, errors.New("subscribe requires an id")
+		}
+		c.mu.Lock()
+		c.subs[req.Id] = subscription{
+			path:   req.Path,
+			iface:  req.Interface,
+			member: req.Member,
+		}
+		if c.cancel == nil {
+			c.cancel = c.gateway.signals.Observe(c.observe)
+		}
+		c.mu.Unlock()
+		return nil, nil
+	case "unsubscribe":
+		c.mu.Lock()
+		_, ok := c.subs[req.Subscription]
+		delete(c.subs, req.Subscription)
+		c.mu.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("no subscription %d", req.Subscription)
+		}
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown op %q", req.Op)
+}
@@ -0,0 +1,167 @@
+package wsgateway
+
+import (
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jsouthworth/seriatim"
+	"github.com/jsouthworth/seriatim/dbus"
+	"golang.org/x/net/websocket"
+)
+
+type counter struct {
+	n int
+}
+
+func (c *counter) Add(n int) int {
+	c.n += n
+	return c.n
+}
+
+func (c *counter) Fail() (int, error) {
+	return 0, errors.New("failed")
+}
+
+type fakeSignals struct {
+	mu        sync.Mutex
+	observers map[int]func(*dbus.EmittedSignal)
+	next      int
+	observed  chan struct{}
+}
+
+func newFakeSignals() *fakeSignals {
+	return &fakeSignals{
+		observers: make(map[int]func(*dbus.EmittedSignal)),
+		observed:  make(chan struct{}, 1),
+	}
+}
+
+func (f *fakeSignals) Observe(fn func(*dbus.EmittedSignal)) func() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	id := f.next
+	f.next++
+	f.observers[id] = fn
+	f.observed <- struct{}{}
+	return func() {
+		f.mu.Lock()
+		delete(f.observers, id)
+		f.mu.Unlock()
+	}
+}
+
+func (f *fakeSignals) emit(sig *dbus.EmittedSignal) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, fn := range f.observers {
+		fn(sig)
+	}
+}
+
+func dial(t *testing.T, g *Gateway) (*websocket.Conn, func()) {
+	srv := httptest.NewServer(g)
+	url := "ws" + strings.TrimPrefix(srv.URL, "http")
+	ws, err := websocket.Dial(url, "", srv.URL)
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	return ws, func() {
+		ws.Close()
+		srv.Close()
+	}
+}
+
+func roundTrip(t *testing.T, ws *websocket.Conn, req *request) *reply {
+	if err := websocket.JSON.Send(ws, req); err != nil {
+		t.Fatal(err)
+	}
+	var r reply
+	if err := websocket.JSON.Receive(ws, &r); err != nil {
+		t.Fatal(err)
+	}
+	return &r
+}
+
+func TestCall(t *testing.T) {
+	s := seriatim.NewSequent(&counter{})
+	defer s.Terminate(nil)
+	g := New(nil)
+	g.Register("counter", s)
+	ws, done := dial(t, g)
+	defer done()
+
+	if err := websocket.JSON.Send(ws, &request{
+		Op: "cast", Sequent: "counter", Method: "Add", Args: []interface{}{2},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	r := roundTrip(t, ws, &request{
+		Id: 1, Op: "call", Sequent: "counter", Method: "Add", Args: []interface{}{3},
+	})
+	if r.Id != 1 || r.Error != "" || len(r.Result) != 1 {
+		t.Fatalf("unexpected reply %+v", r)
+	}
+	// the cast is handled before the call sent after it
+	if n := r.Result[0].(float64); n != 5 {
+		t.Fatalf("unexpected result %v", n)
+	}
+
+	tests := map[string]*request{
+		`no sequent named "nope"`:   {Id: 2, Op: "call", Sequent: "nope"},
+		"Unknown method":            {Id: 3, Op: "call", Sequent: "counter", Method: "Missing"},
+		"failed":                    {Id: 4, Op: "call", Sequent: "counter", Method: "Fail"},
+		`unknown op "bogus"`:        {Id: 5, Op: "bogus"},
+		"signals are not available": {Id: 6, Op: "subscribe"},
+	}
+	for expected, req := range tests {
+		r := roundTrip(t, ws, req)
+		if r.Id != req.Id || r.Error != expected {
+			t.Errorf("expected error %q, got %+v", expected, r)
+		}
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	signals := newFakeSignals()
+	g := New(signals)
+	ws, done := dial(t, g)
+	defer done()
+
+	r := roundTrip(t, ws, &request{Id: 7, Op: "subscribe", Interface: "com.example.Foo"})
+	if r.Id != 7 || r.Error != "" {
+		t.Fatalf("unexpected reply %+v", r)
+	}
+	<-signals.observed
+
+	signals.emit(&dbus.EmittedSignal{
+		Path: "/foo", Interface: "com.example.Bar", Member: "Changed",
+	})
+	signals.emit(&dbus.EmittedSignal{
+		Path:      "/foo",
+		Interface: "com.example.Foo",
+		Member:    "Changed",
+		Body:      []interface{}{"x"},
+	})
+	var pushed reply
+	if err := websocket.JSON.Receive(ws, &pushed); err != nil {
+		t.Fatal(err)
+	}
+	sig := pushed.Signal
+	if sig == nil || sig.Subscription != 7 || sig.Interface != "com.example.Foo" ||
+		len(sig.Body) != 1 || sig.Body[0] != "x" {
+		t.Fatalf("unexpected push %+v", pushed)
+	}
+
+	r = roundTrip(t, ws, &request{Id: 8, Op: "unsubscribe", Subscription: 7})
+	if r.Error != "" {
+		t.Fatalf("unexpected reply %+v", r)
+	}
+	r = roundTrip(t, ws, &request{Id: 9, Op: "unsubscribe", Subscription: 7})
+	if r.Error != "no subscription 7" {
+		t.Fatalf("unexpected reply %+v", r)
+	}
+}